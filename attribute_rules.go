@@ -0,0 +1,350 @@
+package kmip
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// 3 Attributes
+//
+// Each attribute has a set of rules which govern who sets it, whether it can be changed once set, and
+// whether an object may hold more than one instance of it.  The rules are defined with each attribute
+// in section 3 of the 1.4 spec, and section 4 of the 2.0 spec.
+
+var (
+	// ErrUnsupportedAttribute is returned when a request refers to an attribute which isn't in the
+	// AttributeRules.  It carries the Invalid Field result reason.
+	ErrUnsupportedAttribute = errors.New("kmip: unsupported attribute")
+
+	// ErrAttributeNotApplicable is returned when an attribute doesn't apply to the object's type.  It carries
+	// the Invalid Field result reason.
+	ErrAttributeNotApplicable = errors.New("kmip: attribute does not apply to object type")
+
+	// ErrAttributeReadOnly is returned when a client tries to set, modify or delete an attribute it isn't
+	// permitted to.  It carries the Permission Denied result reason.
+	ErrAttributeReadOnly = errors.New("kmip: attribute is read only")
+
+	// ErrAttributeSingleValued is returned when a client tries to add a second instance of a single instance
+	// attribute.  It carries the Invalid Field result reason.
+	ErrAttributeSingleValued = errors.New("kmip: attribute is single valued")
+
+	// ErrAttributeNotFound is returned when the object doesn't have the attribute.  It carries the Item
+	// Not Found result reason.
+	ErrAttributeNotFound = errors.New("kmip: attribute not found")
+
+	// ErrAttributeInstanceNotFound is returned when the object has the attribute, but not the requested
+	// instance of it.  It carries the Item Not Found result reason.
+	ErrAttributeInstanceNotFound = errors.New("kmip: attribute instance not found")
+)
+
+// AttributeRule describes how an attribute may be used.
+type AttributeRule struct {
+	// MultiInstance is true if an object may hold more than one instance of the attribute.
+	MultiInstance bool
+	// ServerOnly is true if the attribute is only ever set by the server.  Clients may not supply it
+	// when an object is created or registered, nor add, modify or delete it.
+	ServerOnly bool
+	// ClientModifiable is true if clients may change the attribute's value with Modify Attribute.
+	ClientModifiable bool
+	// ClientDeletable is true if clients may remove the attribute with Delete Attribute.
+	ClientDeletable bool
+	// States restricts adding, modifying or deleting the attribute to objects in these states.  If nil,
+	// the attribute may be changed in any state.
+	States []kmip14.State
+	// ObjectTypes are the types of object the attribute applies to.  If nil, it applies to all types.
+	ObjectTypes []kmip14.ObjectType
+}
+
+// AppliesTo returns true if the attribute applies to the object type.
+func (r *AttributeRule) AppliesTo(objectType kmip14.ObjectType) bool {
+	if r.ObjectTypes == nil {
+		return true
+	}
+
+	for _, t := range r.ObjectTypes {
+		if t == objectType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *AttributeRule) changeableIn(state kmip14.State) bool {
+	if r.States == nil || state == 0 {
+		return true
+	}
+
+	for _, s := range r.States {
+		if s == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AttributeRules maps attribute tags to their rules.  AttributeRules14 holds the rules for 1.4; the
+// kmip20 package holds the rules for 2.0.
+//
+// Attributes with names starting with "x-" or "y-" are custom attributes.  If the rules include
+// kmip14.TagCustomAttribute, client custom attributes ("x-") follow its rule, and server custom
+// attributes ("y-") are server only.
+type AttributeRules map[ttlv.Tag]AttributeRule
+
+var (
+	keyObjectTypes = []kmip14.ObjectType{
+		kmip14.ObjectTypeSymmetricKey,
+		kmip14.ObjectTypePublicKey,
+		kmip14.ObjectTypePrivateKey,
+		kmip14.ObjectTypeSplitKey,
+		kmip14.ObjectTypeTemplate,
+		kmip14.ObjectTypePGPKey,
+	}
+	keyAndCertificateObjectTypes = append([]kmip14.ObjectType{
+		kmip14.ObjectTypeCertificate,
+	}, keyObjectTypes...)
+	cryptographicObjectTypes = append([]kmip14.ObjectType{
+		kmip14.ObjectTypeSecretData,
+	}, keyAndCertificateObjectTypes...)
+	certificateObjectTypes = []kmip14.ObjectType{
+		kmip14.ObjectTypeCertificate,
+	}
+)
+
+// AttributeRules14 are the attribute rules from section 3 of the 1.4 spec.
+var AttributeRules14 = AttributeRules{
+	kmip14.TagUniqueIdentifier: {ServerOnly: true},
+	kmip14.TagName:             {MultiInstance: true, ClientModifiable: true, ClientDeletable: true},
+	kmip14.TagObjectType:       {ServerOnly: true},
+
+	kmip14.TagCryptographicAlgorithm:  {ObjectTypes: keyAndCertificateObjectTypes},
+	kmip14.TagCryptographicLength:     {ObjectTypes: keyAndCertificateObjectTypes},
+	kmip14.TagCryptographicParameters: {MultiInstance: true, ClientModifiable: true, ClientDeletable: true, ObjectTypes: keyAndCertificateObjectTypes},
+	kmip14.TagCryptographicDomainParameters: {
+		ObjectTypes: []kmip14.ObjectType{kmip14.ObjectTypePublicKey, kmip14.ObjectTypePrivateKey, kmip14.ObjectTypeTemplate},
+	},
+
+	kmip14.TagCertificateType:                {ServerOnly: true, ObjectTypes: certificateObjectTypes},
+	kmip14.TagCertificateLength:              {ServerOnly: true, ObjectTypes: certificateObjectTypes},
+	kmip14.TagX_509CertificateIdentifier:     {ServerOnly: true, ObjectTypes: certificateObjectTypes},
+	kmip14.TagX_509CertificateSubject:        {ServerOnly: true, ObjectTypes: certificateObjectTypes},
+	kmip14.TagX_509CertificateIssuer:         {ServerOnly: true, ObjectTypes: certificateObjectTypes},
+	kmip14.TagCertificateIdentifier:          {ServerOnly: true, ObjectTypes: certificateObjectTypes},
+	kmip14.TagCertificateSubject:             {ServerOnly: true, ObjectTypes: certificateObjectTypes},
+	kmip14.TagCertificateIssuer:              {ServerOnly: true, ObjectTypes: certificateObjectTypes},
+	kmip14.TagDigitalSignatureAlgorithm:      {ServerOnly: true, MultiInstance: true, ObjectTypes: []kmip14.ObjectType{kmip14.ObjectTypeCertificate, kmip14.ObjectTypePGPKey}},
+	kmip14.TagDigest:                         {ServerOnly: true, MultiInstance: true},
+	kmip14.TagOperationPolicyName:            {ClientModifiable: true, ClientDeletable: true},
+	kmip14.TagCryptographicUsageMask:         {ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagLeaseTime:                      {ServerOnly: true, ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagUsageLimits:                    {ClientModifiable: true, ObjectTypes: keyObjectTypes},
+	kmip14.TagState:                          {ServerOnly: true, ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagInitialDate:                    {ServerOnly: true},
+	kmip14.TagActivationDate:                 {ClientModifiable: true, ClientDeletable: true, States: []kmip14.State{kmip14.StatePreActive}, ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagProcessStartDate:               {ClientModifiable: true, States: []kmip14.State{kmip14.StatePreActive}, ObjectTypes: []kmip14.ObjectType{kmip14.ObjectTypeSymmetricKey, kmip14.ObjectTypeSplitKey, kmip14.ObjectTypeTemplate}},
+	kmip14.TagProtectStopDate:                {ClientModifiable: true, States: []kmip14.State{kmip14.StatePreActive}, ObjectTypes: []kmip14.ObjectType{kmip14.ObjectTypeSymmetricKey, kmip14.ObjectTypeSplitKey, kmip14.ObjectTypeTemplate}},
+	kmip14.TagDeactivationDate:               {ClientModifiable: true, ClientDeletable: true, States: []kmip14.State{kmip14.StatePreActive, kmip14.StateActive}, ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagDestroyDate:                    {ServerOnly: true},
+	kmip14.TagCompromiseOccurrenceDate:       {ServerOnly: true},
+	kmip14.TagCompromiseDate:                 {ServerOnly: true},
+	kmip14.TagRevocationReason:               {ServerOnly: true},
+	kmip14.TagArchiveDate:                    {ServerOnly: true},
+	kmip14.TagObjectGroup:                    {MultiInstance: true, ClientModifiable: true, ClientDeletable: true},
+	kmip14.TagFresh:                          {},
+	kmip14.TagLink:                           {MultiInstance: true, ClientModifiable: true, ClientDeletable: true, ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagApplicationSpecificInformation: {MultiInstance: true, ClientModifiable: true, ClientDeletable: true},
+	kmip14.TagContactInformation:             {ClientModifiable: true, ClientDeletable: true},
+	kmip14.TagLastChangeDate:                 {ServerOnly: true},
+	kmip14.TagCustomAttribute:                {MultiInstance: true, ClientModifiable: true, ClientDeletable: true},
+	kmip14.TagAlternativeName:                {MultiInstance: true, ClientModifiable: true, ClientDeletable: true},
+	kmip14.TagKeyValuePresent:                {ServerOnly: true, ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagKeyValueLocation:               {MultiInstance: true, ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagOriginalCreationDate:           {},
+	kmip14.TagRandomNumberGenerator:          {ObjectTypes: keyObjectTypes},
+	kmip14.TagPKCS_12FriendlyName:            {ClientModifiable: true, ClientDeletable: true, ObjectTypes: []kmip14.ObjectType{kmip14.ObjectTypePrivateKey, kmip14.ObjectTypeCertificate}},
+	kmip14.TagDescription:                    {ClientModifiable: true, ClientDeletable: true},
+	kmip14.TagComment:                        {ClientModifiable: true, ClientDeletable: true},
+	kmip14.TagSensitive:                      {ClientModifiable: true, ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagAlwaysSensitive:                {ServerOnly: true, ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagExtractable:                    {ClientModifiable: true, ObjectTypes: cryptographicObjectTypes},
+	kmip14.TagNeverExtractable:               {ServerOnly: true, ObjectTypes: cryptographicObjectTypes},
+}
+
+// Rule returns the rule for the named attribute.  Returns false if the attribute isn't in the rules.
+func (r AttributeRules) Rule(name string) (AttributeRule, bool) {
+	if strings.HasPrefix(name, "x-") || strings.HasPrefix(name, "y-") {
+		rule, ok := r[kmip14.TagCustomAttribute]
+		if ok && name[0] == 'y' {
+			rule = AttributeRule{MultiInstance: rule.MultiInstance, ServerOnly: true}
+		}
+
+		return rule, ok
+	}
+
+	tag, err := ttlv.DefaultRegistry.ParseTag(name)
+	if err != nil {
+		return AttributeRule{}, false
+	}
+
+	rule, ok := r[tag]
+
+	return rule, ok
+}
+
+// rule returns the rule for an attribute a client is setting on obj, or an error if there is no rule,
+// or the attribute doesn't apply to obj.
+func (r AttributeRules) rule(obj *ManagedObject, name string) (AttributeRule, error) {
+	rule, ok := r.Rule(name)
+	if !ok {
+		return rule, WithResultReason(merry.WithUserMessagef(merry.Here(ErrUnsupportedAttribute), "unsupported attribute: %s", name), kmip14.ResultReasonInvalidField)
+	}
+
+	if !rule.AppliesTo(obj.ObjectType) {
+		return rule, WithResultReason(merry.WithUserMessagef(merry.Here(ErrAttributeNotApplicable), "%s does not apply to %s objects", name, obj.ObjectType.String()), kmip14.ResultReasonInvalidField)
+	}
+
+	return rule, nil
+}
+
+func attributeReadOnlyError(name, change string) error {
+	return WithResultReason(merry.WithUserMessagef(merry.Here(ErrAttributeReadOnly), "%s may not be %s by the client", name, change), kmip14.ResultReasonPermissionDenied)
+}
+
+func attributeSingleValuedError(name string) error {
+	return WithResultReason(merry.WithUserMessagef(merry.Here(ErrAttributeSingleValued), "%s may only have one instance", name), kmip14.ResultReasonInvalidField)
+}
+
+// findAttribute returns the instance of the named attribute with the index, or an error if there isn't one.
+func findAttribute(obj *ManagedObject, name string, idx int) (*Attribute, error) {
+	found := false
+
+	for i := range obj.Attribute {
+		if obj.Attribute[i].AttributeName != name {
+			continue
+		}
+
+		if obj.Attribute[i].AttributeIndex == idx {
+			return &obj.Attribute[i], nil
+		}
+
+		found = true
+	}
+
+	if found {
+		return nil, WithResultReason(merry.WithUserMessagef(merry.Here(ErrAttributeInstanceNotFound), "%s has no instance with index %d", name, idx), kmip14.ResultReasonItemNotFound)
+	}
+
+	return nil, WithResultReason(merry.WithUserMessagef(merry.Here(ErrAttributeNotFound), "object has no %s attribute", name), kmip14.ResultReasonItemNotFound)
+}
+
+// InitAttributes validates the attributes a client supplied for a new object, e.g. in the Template-Attribute
+// of a Create request, and adds them to obj.  Each attribute must apply to the object's type, may not be
+// server only, and single instance attributes may only be supplied once.  The instances of each attribute
+// are given Attribute Indexes in the order they are supplied, starting from 0.
+func (r AttributeRules) InitAttributes(obj *ManagedObject, attrs []Attribute) error {
+	for _, attr := range attrs {
+		rule, err := r.rule(obj, attr.AttributeName)
+		if err != nil {
+			return err
+		}
+
+		if rule.ServerOnly {
+			return attributeReadOnlyError(attr.AttributeName, "set")
+		}
+
+		if !rule.MultiInstance && obj.GetAttribute(attr.AttributeName) != nil {
+			return attributeSingleValuedError(attr.AttributeName)
+		}
+
+		obj.Attribute = append(obj.Attribute, Attribute{
+			AttributeName:  attr.AttributeName,
+			AttributeIndex: obj.nextAttributeIndex(attr.AttributeName),
+			AttributeValue: attr.AttributeValue,
+		})
+	}
+
+	return nil
+}
+
+// AddAttribute adds a new attribute instance to obj, on behalf of a client.  The attribute's Attribute Index
+// is ignored: the instance is given the next index for the attribute, and indexes are never reused, even if
+// the instance which held an index has been deleted.  Returns the added instance.
+func (r AttributeRules) AddAttribute(obj *ManagedObject, attr Attribute) (*Attribute, error) {
+	rule, err := r.rule(obj, attr.AttributeName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case rule.ServerOnly:
+		return nil, attributeReadOnlyError(attr.AttributeName, "added")
+	case !rule.MultiInstance && obj.GetAttribute(attr.AttributeName) != nil:
+		return nil, attributeSingleValuedError(attr.AttributeName)
+	case !rule.changeableIn(ObjectState(obj)):
+		return nil, wrongStateError(obj, "adding "+attr.AttributeName)
+	}
+
+	obj.Attribute = append(obj.Attribute, Attribute{
+		AttributeName:  attr.AttributeName,
+		AttributeIndex: obj.nextAttributeIndex(attr.AttributeName),
+		AttributeValue: attr.AttributeValue,
+	})
+
+	return &obj.Attribute[len(obj.Attribute)-1], nil
+}
+
+// ModifyAttribute replaces the value of the instance of the attribute with the same name and index, on
+// behalf of a client.  Returns the modified instance.
+func (r AttributeRules) ModifyAttribute(obj *ManagedObject, attr Attribute) (*Attribute, error) {
+	rule, err := r.rule(obj, attr.AttributeName)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := findAttribute(obj, attr.AttributeName, attr.AttributeIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case rule.ServerOnly || !rule.ClientModifiable:
+		return nil, attributeReadOnlyError(attr.AttributeName, "modified")
+	case !rule.changeableIn(ObjectState(obj)):
+		return nil, wrongStateError(obj, "modifying "+attr.AttributeName)
+	}
+
+	existing.AttributeValue = attr.AttributeValue
+
+	return existing, nil
+}
+
+// DeleteAttribute removes the instance of the named attribute with the index, on behalf of a client.
+// Returns the removed instance.
+func (r AttributeRules) DeleteAttribute(obj *ManagedObject, name string, idx int) (*Attribute, error) {
+	rule, err := r.rule(obj, name)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := findAttribute(obj, name, idx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case rule.ServerOnly || !rule.ClientDeletable:
+		return nil, attributeReadOnlyError(name, "deleted")
+	case !rule.changeableIn(ObjectState(obj)):
+		return nil, wrongStateError(obj, "deleting "+name)
+	}
+
+	deleted := *existing
+	obj.RemoveAttribute(name, idx)
+
+	return &deleted, nil
+}
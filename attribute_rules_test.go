@@ -0,0 +1,152 @@
+package kmip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeRules_InitAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs []Attribute
+		err   error
+	}{
+		{
+			name: "valid",
+			attrs: []Attribute{
+				NewAttributeFromTag(kmip14.TagName, 0, Name{NameValue: "a", NameType: kmip14.NameTypeUninterpretedTextString}),
+				NewAttributeFromTag(kmip14.TagName, 0, Name{NameValue: "b", NameType: kmip14.NameTypeUninterpretedTextString}),
+				NewAttributeFromTag(kmip14.TagCryptographicUsageMask, 0, kmip14.CryptographicUsageMaskEncrypt),
+				{AttributeName: "x-custom", AttributeValue: "value"},
+			},
+		},
+		{
+			name:  "server only",
+			attrs: []Attribute{NewAttributeFromTag(kmip14.TagState, 0, kmip14.StateActive)},
+			err:   ErrAttributeReadOnly,
+		},
+		{
+			name:  "server custom attribute",
+			attrs: []Attribute{{AttributeName: "y-custom", AttributeValue: "value"}},
+			err:   ErrAttributeReadOnly,
+		},
+		{
+			name: "single instance",
+			attrs: []Attribute{
+				NewAttributeFromTag(kmip14.TagContactInformation, 0, "a"),
+				NewAttributeFromTag(kmip14.TagContactInformation, 1, "b"),
+			},
+			err: ErrAttributeSingleValued,
+		},
+		{
+			name:  "not applicable",
+			attrs: []Attribute{NewAttributeFromTag(kmip14.TagProcessStartDate, 0, time.Now())},
+			err:   ErrAttributeNotApplicable,
+		},
+		{
+			name:  "unsupported",
+			attrs: []Attribute{{AttributeName: "Not An Attribute", AttributeValue: "value"}},
+			err:   ErrUnsupportedAttribute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj := newTestSecretData("secret")
+
+			err := AttributeRules14.InitAttributes(obj, test.attrs)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			// instances are indexed in the order they were supplied
+			assert.Equal(t, 1, obj.Attribute[1].AttributeIndex)
+			assert.Equal(t, "b", obj.Attribute[1].AttributeValue.(Name).NameValue)
+		})
+	}
+}
+
+func TestAttributeRules_changes(t *testing.T) {
+	var l Lifecycle
+
+	obj := newTestSecretData("secret")
+	require.NoError(t, AttributeRules14.InitAttributes(obj, []Attribute{
+		NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group0"),
+		NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group1"),
+	}))
+	l.Init(obj)
+
+	// the index of a deleted instance isn't reused, even if it was the highest
+	deleted, err := AttributeRules14.DeleteAttribute(obj, kmip14.TagObjectGroup.CanonicalName(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "group1", deleted.AttributeValue)
+
+	added, err := AttributeRules14.AddAttribute(obj, NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group2"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, added.AttributeIndex)
+
+	_, err = AttributeRules14.DeleteAttribute(obj, kmip14.TagObjectGroup.CanonicalName(), 1)
+	require.ErrorIs(t, err, ErrAttributeInstanceNotFound)
+	assert.Equal(t, kmip14.ResultReasonItemNotFound, GetResultReason(err))
+
+	// the index survives storage
+	store := &MemoryObjectStore{}
+	require.NoError(t, store.Update(context.Background(), func(tx ObjectTx) error {
+		if _, err := tx.Create(obj); err != nil {
+			return err
+		}
+
+		if _, err := AttributeRules14.DeleteAttribute(obj, kmip14.TagObjectGroup.CanonicalName(), 2); err != nil {
+			return err
+		}
+
+		return tx.Put(obj)
+	}))
+	require.NoError(t, store.View(context.Background(), func(tx ObjectTx) error {
+		var err error
+		obj, err = tx.Get(obj.UniqueIdentifier)
+
+		return err
+	}))
+
+	added, err = AttributeRules14.AddAttribute(obj, NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group3"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, added.AttributeIndex)
+
+	modified, err := AttributeRules14.ModifyAttribute(obj, NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group4"))
+	require.NoError(t, err)
+	assert.Equal(t, "group4", modified.AttributeValue)
+	assert.Equal(t, "group4", obj.GetAttributeTag(kmip14.TagObjectGroup).AttributeValue)
+
+	_, err = AttributeRules14.AddAttribute(obj, NewAttributeFromTag(kmip14.TagState, 0, kmip14.StateActive))
+	require.ErrorIs(t, err, ErrAttributeReadOnly)
+	assert.Equal(t, kmip14.ResultReasonPermissionDenied, GetResultReason(err))
+
+	_, err = AttributeRules14.ModifyAttribute(obj, NewAttributeFromTag(kmip14.TagCryptographicUsageMask, 0, kmip14.CryptographicUsageMaskEncrypt))
+	require.ErrorIs(t, err, ErrAttributeNotFound)
+
+	_, err = AttributeRules14.AddAttribute(obj, NewAttributeFromTag(kmip14.TagCryptographicUsageMask, 0, kmip14.CryptographicUsageMaskEncrypt))
+	require.NoError(t, err)
+
+	_, err = AttributeRules14.AddAttribute(obj, NewAttributeFromTag(kmip14.TagCryptographicUsageMask, 0, kmip14.CryptographicUsageMaskDecrypt))
+	require.ErrorIs(t, err, ErrAttributeSingleValued)
+
+	_, err = AttributeRules14.ModifyAttribute(obj, NewAttributeFromTag(kmip14.TagCryptographicUsageMask, 0, kmip14.CryptographicUsageMaskDecrypt))
+	require.ErrorIs(t, err, ErrAttributeReadOnly)
+
+	// the Activation Date may only be changed while the object is Pre-Active
+	_, err = AttributeRules14.AddAttribute(obj, NewAttributeFromTag(kmip14.TagActivationDate, 0, time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+	require.NoError(t, l.Activate(obj))
+
+	_, err = AttributeRules14.DeleteAttribute(obj, kmip14.TagActivationDate.CanonicalName(), 0)
+	require.ErrorIs(t, err, ErrWrongKeyLifecycleState)
+}
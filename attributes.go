@@ -68,3 +68,30 @@ type CryptographicParameters struct {
 	PSource                       []byte                           `ttlv:",omitempty"`
 	TrailerField                  int                              `ttlv:",omitempty"`
 }
+
+// Cryptographic Domain Parameters 3.7
+//
+// The Cryptographic Domain Parameters attribute is a structure that contains a set of OPTIONAL fields that MAY
+// need to be specified in the Create Key Pair Request Payload. Specific fields MAY only pertain to certain types
+// of Managed Cryptographic Objects. The domain parameter Qlength corresponds to the bit length of parameter Q
+// (refer to [SEC2] and [SP800-56A]). Qlength applies to algorithms such as DSA and DH. The bit length of
+// parameter P (refer to [SEC2] and [SP800-56A]) is specified separately by setting the Cryptographic Length
+// attribute. Recommended Curve is applicable to elliptic curve algorithms such as ECDSA, ECDH, and ECMQV.
+type CryptographicDomainParameters struct {
+	Qlength          int                     `ttlv:",omitempty"`
+	RecommendedCurve kmip14.RecommendedCurve `ttlv:",omitempty"`
+}
+
+// Link 3.35
+//
+// The Link attribute is a structure used to create a link from one Managed Cryptographic
+// Object to another, closely related target Managed Cryptographic Object. The link has a type, and the
+// allowed types differ, depending on the Object Type of the Managed Cryptographic Object. The Linked
+// Object Identifier identifies the target Managed Cryptographic Object by its Unique Identifier. The link
+// contains information about the association between the Managed Objects (e.g., the private key
+// corresponding to a public key; the parent certificate for a certificate in a chain; or for a derived
+// symmetric key, the base key from which it was derived).
+type Link struct {
+	LinkType               kmip14.LinkType
+	LinkedObjectIdentifier string
+}
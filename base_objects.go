@@ -125,6 +125,9 @@ type AttestationCredentialValue struct {
 //
 // The Key Block SHALL contain a Key Wrapping Data structure if the key in the Key Value field is
 // wrapped (i.e., encrypted, or MACed/signed, or both).
+//
+// When the Key Value is wrapped with TTLV Encoding, the Key Value is a Byte String rather than a structure.
+// KeyValue.KeyMaterial then holds the wrapped bytes, and the Key Block is marshaled accordingly.
 type KeyBlock struct {
 	KeyFormatType          kmip14.KeyFormatType
 	KeyCompressionType     kmip14.KeyCompressionType     `ttlv:",omitempty"`
@@ -134,6 +137,80 @@ type KeyBlock struct {
 	KeyWrappingData        *KeyWrappingData
 }
 
+// keyBlock is the encoding of a KeyBlock.  Its Key Value is either a KeyValue structure, or the Byte String
+// of a wrapped Key Value.
+type keyBlock struct {
+	KeyFormatType          kmip14.KeyFormatType
+	KeyCompressionType     kmip14.KeyCompressionType     `ttlv:",omitempty"`
+	KeyValue               interface{}                   `ttlv:",omitempty"`
+	CryptographicAlgorithm kmip14.CryptographicAlgorithm `ttlv:",omitempty"`
+	CryptographicLength    int                           `ttlv:",omitempty"`
+	KeyWrappingData        *KeyWrappingData
+}
+
+// wrappedKeyValue returns the wrapped Key Value, if the Key Value is wrapped with TTLV Encoding.
+func (kb *KeyBlock) wrappedKeyValue() ([]byte, bool) {
+	if kb.KeyValue == nil || kb.KeyWrappingData == nil || kb.KeyWrappingData.EncodingOption == kmip14.EncodingOptionNoEncoding {
+		return nil, false
+	}
+
+	b, ok := kb.KeyValue.KeyMaterial.([]byte)
+
+	return b, ok
+}
+
+// MarshalTTLV implements ttlv.Marshaler.  A Key Value wrapped with TTLV Encoding is encoded as a Byte String.
+func (kb KeyBlock) MarshalTTLV(e *ttlv.Encoder, tag ttlv.Tag) error {
+	v := keyBlock{
+		KeyFormatType:          kb.KeyFormatType,
+		KeyCompressionType:     kb.KeyCompressionType,
+		CryptographicAlgorithm: kb.CryptographicAlgorithm,
+		CryptographicLength:    kb.CryptographicLength,
+		KeyWrappingData:        kb.KeyWrappingData,
+	}
+
+	if wrapped, ok := kb.wrappedKeyValue(); ok {
+		v.KeyValue = wrapped
+	} else if kb.KeyValue != nil {
+		v.KeyValue = kb.KeyValue
+	}
+
+	return e.EncodeValue(tag, &v)
+}
+
+// UnmarshalTTLV implements ttlv.Unmarshaler.  A Key Value which is a Byte String is decoded into the
+// KeyMaterial of the KeyValue.  Structured Key Material is decoded into a pointer to the Transparent*Key
+// struct of the Key Format Type, or the type registered with RegisterKeyMaterialType.
+func (kb *KeyBlock) UnmarshalTTLV(d *ttlv.Decoder, t ttlv.TTLV) error {
+	var v keyBlock
+	if err := d.DecodeValue(&v, t); err != nil {
+		return err
+	}
+
+	*kb = KeyBlock{
+		KeyFormatType:          v.KeyFormatType,
+		KeyCompressionType:     v.KeyCompressionType,
+		CryptographicAlgorithm: v.CryptographicAlgorithm,
+		CryptographicLength:    v.CryptographicLength,
+		KeyWrappingData:        v.KeyWrappingData,
+	}
+
+	switch kv := v.KeyValue.(type) {
+	case []byte:
+		kb.KeyValue = &KeyValue{KeyMaterial: kv}
+	case ttlv.TTLV:
+		kb.KeyValue = &KeyValue{}
+
+		if err := d.DecodeValue(kb.KeyValue, kv); err != nil {
+			return err
+		}
+
+		return kb.decodeKeyMaterial(d)
+	}
+
+	return nil
+}
+
 // KeyValue 2.1.4 Table 8
 //
 // The Key Value is used only inside a Key Block and is either a Byte String or a structure (see Table 8):
@@ -145,11 +222,10 @@ type KeyBlock struct {
 //     (and possibly wrapped with) the key material itself.
 //   - The Key Value Byte String is either the wrapped TTLV-encoded (see Section 9.1) Key Value structure, or
 //     the wrapped un-encoded value of the Byte String Key Material field.
-//
-// TODO: Unmarshaler impl which unmarshals correct KeyMaterial type.
 type KeyValue struct {
 	// KeyMaterial should be []byte, one of the Transparent*Key structs, or a custom struct if KeyFormatType is
-	// an extension.
+	// an extension.  When a Key Block is decoded, see KeyBlock.UnmarshalTTLV, structured Key Material is a
+	// pointer to one of these structs.
 	KeyMaterial interface{}
 	Attribute   []Attribute
 }
@@ -215,6 +291,26 @@ type MACSignatureKeyInformation struct {
 	CryptographicParameters *CryptographicParameters
 }
 
+// KeyWrappingSpecification 2.1.6 Table 12
+//
+// This is a separate structure (see Table 12) that is defined for operations that provide the option to
+// return wrapped keys. The Key Wrapping Specification SHALL be included inside the operation request if
+// clients request the server to return a wrapped key. If Cryptographic Parameters are specified in the
+// Encryption Key Information and/or the MAC/Signature Key Information of the Key Wrapping Specification,
+// then the server SHALL verify that they match one of the instances of the Cryptographic Parameters
+// attribute of the corresponding key. If Cryptographic Parameters are omitted, then the server SHALL use
+// the Cryptographic Parameters attribute with the lowest Attribute Index of the corresponding key.
+//
+// The Attribute Names name the attributes of the key to be wrapped along with the key material, in the
+// Key Value structure.
+type KeyWrappingSpecification struct {
+	WrappingMethod             kmip14.WrappingMethod
+	EncryptionKeyInformation   *EncryptionKeyInformation   `ttlv:",omitempty"`
+	MACSignatureKeyInformation *MACSignatureKeyInformation `ttlv:",omitempty"`
+	AttributeName              []string                    `ttlv:",omitempty"`
+	EncodingOption             kmip14.EncodingOption       `ttlv:",omitempty" default:"TTLVEncoding"`
+}
+
 // TransparentSymmetricKey 2.1.7.1 Table 14
 //
 // If the Key Format Type in the Key Block is Transparent Symmetric Key, then Key Material is a
@@ -438,13 +534,13 @@ func (t *TemplateAttribute) GetIdx(s string, idx int) *Attribute {
 // GetTag returns a reference to the first Attribute in the list matching the tag.
 // Returns nil if not found.
 func (t *TemplateAttribute) GetTag(tag ttlv.Tag) *Attribute {
-	return t.Get(tag.String())
+	return t.Get(tag.CanonicalName())
 }
 
 // GetTagIdx returns a reference to the first Attribute in the list matching the tag and index.
 // Returns nil if not found.
 func (t *TemplateAttribute) GetTagIdx(tag ttlv.Tag, idx int) *Attribute {
-	return t.GetIdx(tag.String(), idx)
+	return t.GetIdx(tag.CanonicalName(), idx)
 }
 
 func (t *TemplateAttribute) GetAll(s string) []Attribute {
@@ -468,5 +564,5 @@ func (t *TemplateAttribute) Append(tag ttlv.Tag, value interface{}) {
 }
 
 func (t *TemplateAttribute) GetAllTag(tag ttlv.Tag) []Attribute {
-	return t.GetAll(tag.String())
+	return t.GetAll(tag.CanonicalName())
 }
@@ -0,0 +1,378 @@
+package kmip
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/flume"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/google/uuid"
+)
+
+var clientLog = flume.New("kmip_client")
+
+// ErrConnClosed is returned when sending a message on a connection which has been closed.
+var ErrConnClosed = errors.New("kmip: connection closed")
+
+// Client is a KMIP client connection.
+//
+// Requests are sent one at a time: each call to Send or Do waits for the response before the next
+// request is written to the connection.  Client is safe for concurrent use.
+//
+// A KMIP server may also send requests to the client, such as Notify and Put operations.  These are handled
+// by the ItemHandlers registered with Handle.  If no handler is registered for an operation, the
+// client responds with Operation Not Supported.  Each server request is handled on its own goroutine,
+// so handlers may send requests with the same Client.
+type Client struct {
+	// ProtocolVersion is sent in the header of each request.  It defaults to 1.4.  It should
+	// be set before the first request is sent.
+	ProtocolVersion ProtocolVersion
+
+	conn    net.Conn
+	dec     *ttlv.Decoder
+	writeMu sync.Mutex
+
+	mux      OperationMux
+	exchange *exchange
+
+	ctx      context.Context //nolint:containedctx
+	cancel   context.CancelFunc
+	closed   chan struct{}
+	closeErr error
+}
+
+// NewClient creates a Client which sends requests over conn.  The Client takes ownership of conn,
+// and closes it when the Client is closed.
+func NewClient(conn net.Conn) *Client {
+	ctx, cancel := context.WithCancel(flume.WithLogger(context.Background(), clientLog))
+
+	c := &Client{
+		ProtocolVersion: ProtocolVersion{
+			ProtocolVersionMajor: 1,
+			ProtocolVersionMinor: 4,
+		},
+		conn:     conn,
+		dec:      ttlv.NewDecoder(bufio.NewReader(conn)),
+		exchange: newExchange(),
+		ctx:      ctx,
+		cancel:   cancel,
+		closed:   make(chan struct{}),
+	}
+
+	go c.readLoop()
+
+	return c
+}
+
+// Dial connects to the KMIP server at addr, and returns a new Client.  If config is not nil,
+// the connection uses TLS.
+func Dial(network, addr string, config *tls.Config) (*Client, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if config != nil {
+		conn, err = tls.Dial(network, addr, config)
+	} else {
+		conn, err = net.Dial(network, addr)
+	}
+
+	if err != nil {
+		return nil, merry.Wrap(err)
+	}
+
+	return NewClient(conn), nil
+}
+
+// Handle registers the handler for an operation initiated by the server, such as Notify or Put.
+func (c *Client) Handle(op kmip14.Operation, handler ItemHandler) {
+	c.mux.Handle(op, handler)
+}
+
+// Close closes the connection.  Any pending requests return ErrConnClosed.
+func (c *Client) Close() error {
+	c.cancel()
+	err := c.conn.Close()
+	<-c.closed
+
+	return err
+}
+
+// Send sends a request message to the server, and returns the response message.
+func (c *Client) Send(ctx context.Context, msg *RequestMessage) (*ResponseMessage, error) {
+	return c.exchange.roundTrip(ctx, c.closed, msg, c.writeMessage)
+}
+
+// Do sends a single operation to the server, and decodes the response payload into respPayload.  If
+// the operation fails, the returned error carries the Result Reason, which can be retrieved with
+// GetResultReason.  respPayload may be nil if the response payload should be discarded.
+func (c *Client) Do(ctx context.Context, op kmip14.Operation, reqPayload, respPayload interface{}) error {
+	resp, err := c.Send(ctx, newSingleItemRequest(c.ProtocolVersion, op, reqPayload))
+	if err != nil {
+		return err
+	}
+
+	return decodeSingleItemResponse(resp, op, respPayload)
+}
+
+// RotateKey replaces a symmetric key with a new one, using Re-key, and returns the Unique Identifier of the
+// replacement.  The replacement gets the attributes of the existing key, and takes over its Names.  If offset is
+// zero, the replacement's dates are copied from the existing key.  Otherwise, its Activation Date is offset from
+// its Initial Date, and its other dates are shifted accordingly.
+func (c *Client) RotateKey(ctx context.Context, uniqueIdentifier string, offset time.Duration) (string, error) {
+	req := ReKeyRequestPayload{UniqueIdentifier: uniqueIdentifier}
+	if offset != 0 {
+		req.Offset = &offset
+	}
+
+	var resp ReKeyResponsePayload
+	if err := c.Do(ctx, kmip14.OperationReKey, &req, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.UniqueIdentifier, nil
+}
+
+// RotateKeyPair replaces a private key, and the public key linked to it, with a new key pair, using Re-key Key
+// Pair.  It returns the Unique Identifiers of the replacement private and public keys.  offset is used as by
+// RotateKey.
+func (c *Client) RotateKeyPair(ctx context.Context, privateKeyUniqueIdentifier string, offset time.Duration) (string, string, error) {
+	req := ReKeyKeyPairRequestPayload{PrivateKeyUniqueIdentifier: privateKeyUniqueIdentifier}
+	if offset != 0 {
+		req.Offset = &offset
+	}
+
+	var resp ReKeyKeyPairResponsePayload
+	if err := c.Do(ctx, kmip14.OperationReKeyKeyPair, &req, &resp); err != nil {
+		return "", "", err
+	}
+
+	return resp.PrivateKeyUniqueIdentifier, resp.PublicKeyUniqueIdentifier, nil
+}
+
+func (c *Client) writeMessage(b []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := c.conn.Write(b)
+
+	return merry.Wrap(err)
+}
+
+// readLoop reads messages from the server until the connection is closed.  Responses are delivered
+// to the pending request, and requests initiated by the server are dispatched to the registered handlers.
+func (c *Client) readLoop() {
+	defer close(c.closed)
+
+	for {
+		msg, err := c.dec.NextTTLV()
+		if err != nil {
+			c.closeErr = err
+			return
+		}
+
+		switch msg.Tag() {
+		case kmip14.TagResponseMessage:
+			var resp ResponseMessage
+			if err := ttlv.Unmarshal(msg, &resp); err != nil {
+				clientLog.Error("kmip: invalid response message", "error", err)
+
+				if !c.exchange.deliver(nil, merry.Prepend(err, "decoding response")) {
+					clientLog.Info("kmip: discarding unexpected response message")
+				}
+
+				continue
+			}
+
+			if !c.exchange.deliver(&resp, nil) {
+				clientLog.Info("kmip: discarding unexpected response message")
+			}
+		case kmip14.TagRequestMessage:
+			// served on its own goroutine, so a handler which sends a request
+			// doesn't block the reader its response depends on
+			go c.serveRequest(msg)
+		default:
+			clientLog.Error("kmip: unexpected message", "tag", msg.Tag())
+		}
+	}
+}
+
+// serveRequest handles a request initiated by the server.
+func (c *Client) serveRequest(msg ttlv.TTLV) {
+	h := StandardProtocolHandler{
+		MessageHandler:  &c.mux,
+		ProtocolVersion: c.ProtocolVersion,
+	}
+
+	h.ServeKMIP(c.ctx, &Request{
+		TTLV:       msg,
+		RemoteAddr: c.conn.RemoteAddr().String(),
+		LocalAddr:  c.conn.LocalAddr().String(),
+	}, clientWriter{c: c})
+}
+
+type clientWriter struct {
+	c *Client
+}
+
+func (w clientWriter) Write(p []byte) (int, error) {
+	if err := w.c.writeMessage(p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// exchange serializes request/response round trips over a connection whose
+// messages are read by a separate goroutine.  KMIP doesn't correlate responses to
+// requests, other than by order, so only one request may be outstanding at a time.
+type exchange struct {
+	// sem holds a token while a round trip is in progress
+	sem chan struct{}
+
+	mu      sync.Mutex
+	waiting chan exchangeResult
+}
+
+// exchangeResult is a response, or the error which prevented it from being read.
+type exchangeResult struct {
+	resp *ResponseMessage
+	err  error
+}
+
+func newExchange() *exchange {
+	return &exchange{sem: make(chan struct{}, 1)}
+}
+
+// roundTrip writes the message, and waits for the response to be delivered.  If the first
+// batch item has a Unique Batch Item ID, responses with a different ID are discarded: they are
+// late responses to earlier round trips which were abandoned.
+func (x *exchange) roundTrip(ctx context.Context, closed <-chan struct{}, msg *RequestMessage, write func([]byte) error) (*ResponseMessage, error) {
+	select {
+	case x.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, merry.Wrap(ctx.Err())
+	case <-closed:
+		return nil, merry.Wrap(ErrConnClosed)
+	}
+
+	defer func() { <-x.sem }()
+
+	b, err := ttlv.Marshal(msg)
+	if err != nil {
+		return nil, merry.Prepend(err, "encoding request")
+	}
+
+	ch := make(chan exchangeResult, 1)
+
+	x.mu.Lock()
+	x.waiting = ch
+	x.mu.Unlock()
+
+	defer func() {
+		x.mu.Lock()
+		x.waiting = nil
+		x.mu.Unlock()
+	}()
+
+	if err := write(b); err != nil {
+		return nil, err
+	}
+
+	var batchItemID []byte
+	if len(msg.BatchItem) > 0 {
+		batchItemID = msg.BatchItem[0].UniqueBatchItemID
+	}
+
+	for {
+		select {
+		case res := <-ch:
+			if res.err != nil {
+				return nil, res.err
+			}
+
+			resp := res.resp
+			if len(batchItemID) == 0 || len(resp.BatchItem) == 0 || bytes.Equal(batchItemID, resp.BatchItem[0].UniqueBatchItemID) {
+				return resp, nil
+			}
+		case <-ctx.Done():
+			return nil, merry.Wrap(ctx.Err())
+		case <-closed:
+			return nil, merry.Wrap(ErrConnClosed)
+		}
+	}
+}
+
+// deliver passes a response, or the error decoding it, to the pending round
+// trip.  Returns false if no round trip was waiting for it.
+func (x *exchange) deliver(resp *ResponseMessage, err error) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.waiting == nil {
+		return false
+	}
+
+	select {
+	case x.waiting <- exchangeResult{resp: resp, err: err}:
+		return true
+	default:
+		return false
+	}
+}
+
+func newSingleItemRequest(v ProtocolVersion, op kmip14.Operation, payload interface{}) *RequestMessage {
+	biID := uuid.New()
+
+	return &RequestMessage{
+		RequestHeader: RequestHeader{
+			ProtocolVersion: v,
+			BatchCount:      1,
+		},
+		BatchItem: []RequestBatchItem{
+			{
+				Operation:         op,
+				UniqueBatchItemID: biID[:],
+				RequestPayload:    payload,
+			},
+		},
+	}
+}
+
+// decodeSingleItemResponse checks the result of the single batch item in resp, and decodes
+// its payload into v.
+func decodeSingleItemResponse(resp *ResponseMessage, op kmip14.Operation, v interface{}) error {
+	if len(resp.BatchItem) != 1 {
+		return merry.Errorf("kmip: expected 1 batch item in response, got %d", len(resp.BatchItem))
+	}
+
+	bi := &resp.BatchItem[0]
+	if bi.ResultStatus != kmip14.ResultStatusSuccess {
+		err := merry.Errorf("kmip: %s failed: %s: %s", op, bi.ResultReason, bi.ResultMessage)
+		if bi.ResultReason != kmip14.ResultReason(0) {
+			return WithResultReason(err, bi.ResultReason)
+		}
+
+		return err
+	}
+
+	if v == nil || bi.ResponsePayload == nil {
+		return nil
+	}
+
+	payload, err := coerceToTTLV(bi.ResponsePayload)
+	if err != nil {
+		return err
+	}
+
+	return ttlv.Unmarshal(payload, v)
+}
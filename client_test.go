@@ -0,0 +1,157 @@
+package kmip
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Do(t *testing.T) {
+	mux := &OperationMux{}
+	mux.Handle(kmip14.OperationDiscoverVersions, &DiscoverVersionsHandler{
+		SupportedVersions: []ProtocolVersion{{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4}},
+	})
+
+	addr := startTestServer(t, &Server{}, mux)
+
+	client, err := Dial("tcp", addr, nil)
+	require.NoError(t, err)
+
+	defer client.Close()
+
+	var resp DiscoverVersionsResponsePayload
+	require.NoError(t, client.Do(context.Background(), kmip14.OperationDiscoverVersions, DiscoverVersionsRequestPayload{}, &resp))
+	assert.Equal(t, []ProtocolVersion{{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4}}, resp.ProtocolVersion)
+
+	err = client.Do(context.Background(), kmip14.OperationQuery, nil, nil)
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReasonOperationNotSupported, GetResultReason(err))
+}
+
+func TestServerConn_NotifyAndPut(t *testing.T) {
+	// room for the request sent by the notify handler too
+	conns := make(chan *ServerConn, 2)
+
+	mux := &OperationMux{}
+	mux.Handle(kmip14.OperationDiscoverVersions, ItemHandlerFunc(func(_ context.Context, req *Request) (*ResponseBatchItem, error) {
+		conns <- req.Conn
+		return &ResponseBatchItem{ResponsePayload: DiscoverVersionsResponsePayload{}}, nil
+	}))
+
+	srv := &Server{}
+	addr := startTestServer(t, srv, mux)
+
+	client, err := Dial("tcp", addr, nil)
+	require.NoError(t, err)
+
+	defer client.Close()
+
+	notified := make(chan *NotifyRequestPayload, 1)
+	client.Handle(kmip14.OperationNotify, &NotifyHandler{
+		Notify: func(ctx context.Context, payload *NotifyRequestPayload) (*NotifyResponsePayload, error) {
+			// handlers may call back into the client
+			if err := client.Do(ctx, kmip14.OperationDiscoverVersions, DiscoverVersionsRequestPayload{}, nil); err != nil {
+				return nil, err
+			}
+
+			notified <- payload
+
+			return &NotifyResponsePayload{}, nil
+		},
+	})
+
+	put := make(chan *PutRequestPayload, 1)
+	client.Handle(kmip14.OperationPut, &PutHandler{
+		Put: func(_ context.Context, payload *PutRequestPayload) (*PutResponsePayload, error) {
+			put <- payload
+			return &PutResponsePayload{}, nil
+		},
+	})
+
+	require.NoError(t, client.Do(context.Background(), kmip14.OperationDiscoverVersions, DiscoverVersionsRequestPayload{}, nil))
+
+	var sc *ServerConn
+	select {
+	case sc = <-conns:
+	case <-time.After(time.Second):
+		require.Fail(t, "handler not called")
+	}
+
+	require.NotNil(t, sc)
+	assert.Same(t, sc, srv.Conn(sc.ID()))
+	assert.Equal(t, []*ServerConn{sc}, srv.Conns())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, sc.Notify(ctx, &NotifyRequestPayload{
+		UniqueIdentifier: "key1",
+		Attribute:        []Attribute{{AttributeName: "Object Group", AttributeValue: "group1"}},
+	}))
+
+	n := <-notified
+	assert.Equal(t, "key1", n.UniqueIdentifier)
+	require.Len(t, n.Attribute, 1)
+	assert.Equal(t, "group1", n.Attribute[0].AttributeValue)
+
+	require.NoError(t, sc.Put(ctx, &PutRequestPayload{
+		UniqueIdentifier: "key2",
+		PutFunction:      kmip14.PutFunctionNew,
+		SecretData: &SecretData{
+			SecretDataType: kmip14.SecretDataTypePassword,
+			KeyBlock: KeyBlock{
+				KeyFormatType: kmip14.KeyFormatTypeOpaque,
+				KeyValue:      &KeyValue{KeyMaterial: []byte("secret")},
+			},
+		},
+	}))
+
+	p := <-put
+	assert.Equal(t, "key2", p.UniqueIdentifier)
+	assert.Equal(t, kmip14.PutFunctionNew, p.PutFunction)
+	require.NotNil(t, p.SecretData)
+
+	// operations without a client handler fail
+	err = sc.do(ctx, kmip14.OperationQuery, nil)
+	assert.Equal(t, kmip14.ResultReasonOperationNotSupported, GetResultReason(err))
+
+	// the connection is forgotten once the client disconnects
+	require.NoError(t, client.Close())
+	assert.Eventually(t, func() bool { return srv.Conn(sc.ID()) == nil }, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, sc.Notify(ctx, &NotifyRequestPayload{UniqueIdentifier: "key1"}), ErrConnClosed)
+}
+
+func TestClient_invalidResponse(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+
+	client := NewClient(clientConn)
+	defer client.Close()
+
+	go func() {
+		dec := ttlv.NewDecoder(bufio.NewReader(serverConn))
+		if _, err := dec.NextTTLV(); err != nil {
+			return
+		}
+
+		// a Response Message whose Response Header is an Integer
+		_, _ = serverConn.Write(ttlv.TTLV{
+			0x42, 0x00, 0x7b, 0x01, 0x00, 0x00, 0x00, 0x10,
+			0x42, 0x00, 0x7a, 0x02, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+		})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := client.Do(ctx, kmip14.OperationDiscoverVersions, DiscoverVersionsRequestPayload{}, nil)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "decoding response")
+}
@@ -0,0 +1,145 @@
+package kmip
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// cryptographicAlgorithmEd25519 is the Ed25519 Cryptographic Algorithm, added in 2.0.
+const cryptographicAlgorithmEd25519 kmip14.CryptographicAlgorithm = 0x00000037
+
+// NewPrivateKeyBlock returns a Key Block holding a private key, an *rsa.PrivateKey, *ecdsa.PrivateKey or
+// ed25519.PrivateKey, in the Key Format Type.  A zero format means PKCS#8.  The Cryptographic Algorithm is RSA,
+// ECDSA or Ed25519, and the Cryptographic Length is the size of the modulus or of the curve.  The supported
+// formats are those of ConvertKeyBlock.
+func NewPrivateKeyBlock(key crypto.PrivateKey, format kmip14.KeyFormatType) (*KeyBlock, error) {
+	if format == 0 {
+		format = kmip14.KeyFormatTypePKCS_8
+	}
+
+	var pub crypto.PublicKey
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		pub = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		pub = &k.PublicKey
+	case ed25519.PrivateKey:
+		pub = k.Public()
+	default:
+		return nil, keyFormatTypeNotSupportedf("unsupported private key type: %T", key)
+	}
+
+	alg, length, err := cryptographicAlgorithm(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	material, err := encodePrivateKey(key, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyBlock{
+		KeyFormatType:          format,
+		KeyValue:               &KeyValue{KeyMaterial: material},
+		CryptographicAlgorithm: alg,
+		CryptographicLength:    length,
+	}, nil
+}
+
+// NewPublicKeyBlock returns a Key Block holding a public key, an *rsa.PublicKey, *ecdsa.PublicKey or
+// ed25519.PublicKey, in the Key Format Type.  A zero format means X.509.  Elliptic curve points are uncompressed;
+// use ConvertKeyBlock to compress them.  The Cryptographic Algorithm and Length are set as by NewPrivateKeyBlock.
+func NewPublicKeyBlock(key crypto.PublicKey, format kmip14.KeyFormatType) (*KeyBlock, error) {
+	if format == 0 {
+		format = kmip14.KeyFormatTypeX_509
+	}
+
+	alg, length, err := cryptographicAlgorithm(key)
+	if err != nil {
+		return nil, err
+	}
+
+	material, err := encodePublicKey(key, format, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyBlock{
+		KeyFormatType:          format,
+		KeyValue:               &KeyValue{KeyMaterial: material},
+		CryptographicAlgorithm: alg,
+		CryptographicLength:    length,
+	}, nil
+}
+
+// PrivateKey returns the private key held by the Key Block, as an *rsa.PrivateKey, *ecdsa.PrivateKey or
+// ed25519.PrivateKey.  It supports the private key formats of ConvertKeyBlock.  The Key Block must not be wrapped.
+func (kb *KeyBlock) PrivateKey() (crypto.PrivateKey, error) {
+	if err := kb.checkKeyValue(); err != nil {
+		return nil, err
+	}
+
+	return parsePrivateKey(kb)
+}
+
+// PublicKey returns the public key held by the Key Block, as an *rsa.PublicKey, *ecdsa.PublicKey or
+// ed25519.PublicKey.  It supports the public key formats of ConvertKeyBlock.  The Key Block must not be wrapped.
+func (kb *KeyBlock) PublicKey() (crypto.PublicKey, error) {
+	if err := kb.checkKeyValue(); err != nil {
+		return nil, err
+	}
+
+	return parsePublicKey(kb)
+}
+
+func (kb *KeyBlock) checkKeyValue() error {
+	if kb.KeyValue == nil || kb.KeyWrappingData != nil {
+		return WithResultReason(merry.UserError("the key material isn't available"), kmip14.ResultReasonKeyValueNotPresent)
+	}
+
+	return nil
+}
+
+// cryptographicAlgorithm returns the Cryptographic Algorithm and Cryptographic Length of a public key.
+func cryptographicAlgorithm(key crypto.PublicKey) (kmip14.CryptographicAlgorithm, int, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return kmip14.CryptographicAlgorithmRSA, k.N.BitLen(), nil
+	case *ecdsa.PublicKey:
+		return kmip14.CryptographicAlgorithmECDSA, k.Curve.Params().BitSize, nil
+	case ed25519.PublicKey:
+		return cryptographicAlgorithmEd25519, 256, nil
+	}
+
+	return 0, 0, keyFormatTypeNotSupportedf("unsupported public key type: %T", key)
+}
+
+// NewCertificate returns an X.509 Certificate object holding the certificate's DER encoding.
+func NewCertificate(cert *x509.Certificate) *Certificate {
+	return &Certificate{
+		CertificateType:  kmip14.CertificateTypeX_509,
+		CertificateValue: cert.Raw,
+	}
+}
+
+// X509Certificate parses the Certificate Value of an X.509 Certificate.
+func (c *Certificate) X509Certificate() (*x509.Certificate, error) {
+	if c.CertificateType != kmip14.CertificateTypeX_509 {
+		return nil, WithResultReason(merry.UserErrorf("unsupported Certificate Type: %s", c.CertificateType.String()), kmip14.ResultReasonFeatureNotSupported)
+	}
+
+	cert, err := x509.ParseCertificate(c.CertificateValue)
+	if err != nil {
+		return nil, WithResultReason(merry.Prepend(err, "invalid certificate"), kmip14.ResultReasonCryptographicFailure)
+	}
+
+	return cert, nil
+}
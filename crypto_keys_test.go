@@ -0,0 +1,158 @@
+package kmip
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyBlock_cryptoKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		key            crypto.Signer
+		alg            kmip14.CryptographicAlgorithm
+		length         int
+		privateFormats []kmip14.KeyFormatType
+		publicFormats  []kmip14.KeyFormatType
+	}{
+		{
+			name:           "RSA",
+			key:            rsaKey,
+			alg:            kmip14.CryptographicAlgorithmRSA,
+			length:         2048,
+			privateFormats: []kmip14.KeyFormatType{kmip14.KeyFormatTypePKCS_1, kmip14.KeyFormatTypeTransparentRSAPrivateKey},
+			publicFormats:  []kmip14.KeyFormatType{kmip14.KeyFormatTypePKCS_1, kmip14.KeyFormatTypeTransparentRSAPublicKey},
+		},
+		{
+			name:           "ECDSA",
+			key:            ecKey,
+			alg:            kmip14.CryptographicAlgorithmECDSA,
+			length:         256,
+			privateFormats: []kmip14.KeyFormatType{kmip14.KeyFormatTypeECPrivateKey, kmip14.KeyFormatTypeTransparentECDSAPrivateKey, kmip14.KeyFormatTypeTransparentECDHPrivateKey},
+			publicFormats:  []kmip14.KeyFormatType{kmip14.KeyFormatTypeTransparentECPublicKey, kmip14.KeyFormatTypeTransparentECDSAPublicKey},
+		},
+		{
+			name:   "Ed25519",
+			key:    edKey,
+			alg:    cryptographicAlgorithmEd25519,
+			length: 256,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, format := range append([]kmip14.KeyFormatType{0}, test.privateFormats...) {
+				kb, err := NewPrivateKeyBlock(test.key, format)
+				require.NoError(t, err, format.String())
+				assert.Equal(t, test.alg, kb.CryptographicAlgorithm)
+				assert.Equal(t, test.length, kb.CryptographicLength)
+
+				if format == 0 {
+					assert.Equal(t, kmip14.KeyFormatTypePKCS_8, kb.KeyFormatType)
+				}
+
+				// transparent keys are read back after decoding, as they would be in a client
+				b, err := ttlv.Marshal(kb)
+				require.NoError(t, err)
+
+				var decoded KeyBlock
+				require.NoError(t, ttlv.Unmarshal(b, &decoded))
+
+				key, err := decoded.PrivateKey()
+				require.NoError(t, err, format.String())
+				assert.True(t, test.key.(interface{ Equal(crypto.PrivateKey) bool }).Equal(key), format.String())
+			}
+
+			for _, format := range append([]kmip14.KeyFormatType{0}, test.publicFormats...) {
+				kb, err := NewPublicKeyBlock(test.key.Public(), format)
+				require.NoError(t, err, format.String())
+				assert.Equal(t, test.alg, kb.CryptographicAlgorithm)
+				assert.Equal(t, test.length, kb.CryptographicLength)
+
+				if format == 0 {
+					assert.Equal(t, kmip14.KeyFormatTypeX_509, kb.KeyFormatType)
+				}
+
+				b, err := ttlv.Marshal(kb)
+				require.NoError(t, err)
+
+				var decoded KeyBlock
+				require.NoError(t, ttlv.Unmarshal(b, &decoded))
+
+				key, err := decoded.PublicKey()
+				require.NoError(t, err, format.String())
+				assert.True(t, test.key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(key), format.String())
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := NewPrivateKeyBlock(edKey, kmip14.KeyFormatTypeTransparentRSAPrivateKey)
+		require.ErrorIs(t, err, ErrKeyFormatTypeNotSupported)
+
+		_, err = NewPublicKeyBlock("not a key", 0)
+		require.ErrorIs(t, err, ErrKeyFormatTypeNotSupported)
+
+		kb, err := NewPrivateKeyBlock(rsaKey, 0)
+		require.NoError(t, err)
+
+		_, err = kb.PublicKey()
+		require.ErrorIs(t, err, ErrKeyFormatTypeNotSupported)
+
+		kb.KeyWrappingData = &KeyWrappingData{WrappingMethod: kmip14.WrappingMethodEncrypt}
+		_, err = kb.PrivateKey()
+		assert.Equal(t, kmip14.ResultReasonKeyValueNotPresent, GetResultReason(err))
+	})
+}
+
+func TestCertificate_X509Certificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	c := NewCertificate(cert)
+	assert.Equal(t, kmip14.CertificateTypeX_509, c.CertificateType)
+	assert.Equal(t, der, c.CertificateValue)
+
+	parsed, err := c.X509Certificate()
+	require.NoError(t, err)
+	assert.True(t, cert.Equal(parsed))
+
+	c.CertificateType = kmip14.CertificateTypePGP
+	_, err = c.X509Certificate()
+	assert.Equal(t, kmip14.ResultReasonFeatureNotSupported, GetResultReason(err))
+}
@@ -0,0 +1,322 @@
+package kmip
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// The Encrypt, Decrypt, Sign, Signature Verify, MAC and MAC Verify operations may be streamed, to process
+// more data than fits in one message.  The first request sets the Init Indicator, and the server returns a
+// Correlation Value, which identifies the stream in the following requests.  The last request sets the Final
+// Indicator.
+
+// ErrInvalidCorrelationValue is returned by servers when a streaming request's Correlation Value doesn't
+// identify an open stream of the same operation.  KMIP 1.x doesn't have a result reason for this, so it carries the
+// Item Not Found result reason.  KMIP 2.0 servers should report it with the Invalid Correlation Value result reason
+// instead.
+var ErrInvalidCorrelationValue = errors.New("kmip: invalid correlation value")
+
+// ErrTooManyStreams is returned by servers when a client tries to open more streams than the server permits.  It
+// carries the Permission Denied result reason.  KMIP 2.0 servers should report it with the Server Limit Exceeded
+// result reason instead.
+var ErrTooManyStreams = errors.New("kmip: too many open streams")
+
+// ErrStreamBufferFull is returned by servers when a client sends a stream more data than the server will buffer
+// for it.  Like ErrTooManyStreams, it carries the Permission Denied result reason, and KMIP 2.0 servers should
+// report it with the Server Limit Exceeded result reason instead.
+var ErrStreamBufferFull = errors.New("kmip: stream buffer full")
+
+// ErrStreamClosed is returned when writing to a stream which has been closed.
+var ErrStreamClosed = errors.New("kmip: stream closed")
+
+// DefaultStreamChunkSize is the most data the streaming writers send in each request, unless their ChunkSize is
+// set.
+const DefaultStreamChunkSize = 64 << 10
+
+// cryptoStream buffers the data written to a streaming writer, and sends it to the server in chunks.
+type cryptoStream struct {
+	// ChunkSize is the most data sent in each request.  If zero, DefaultStreamChunkSize is used.  It must be
+	// set before the first Write.
+	ChunkSize int
+
+	buf              []byte
+	started, closed  bool
+	err              error
+	correlationValue []byte
+
+	// send sends the next chunk, and returns the Correlation Value from the response.  The first chunk is sent
+	// with init set, and the last with final set.
+	send func(data []byte, init, final bool, correlationValue []byte) ([]byte, error)
+}
+
+// Write buffers p, and sends the buffered data to the server when there is a chunk of it.  Once a request fails,
+// the stream is abandoned: the error is returned by every following Write and Close.
+func (s *cryptoStream) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, ErrStreamClosed
+	}
+
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	chunkSize := s.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+
+	written := 0
+
+	for len(s.buf)+len(p) > chunkSize {
+		n := chunkSize - len(s.buf)
+		s.buf = append(s.buf, p[:n]...)
+
+		if err := s.flush(false); err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+		written += n
+	}
+
+	s.buf = append(s.buf, p...)
+
+	return written + len(p), nil
+}
+
+// Close sends the rest of the data to the server, with the Final Indicator, and completes the operation.
+func (s *cryptoStream) Close() error {
+	if s.closed {
+		return s.err
+	}
+
+	s.closed = true
+
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.flush(true)
+}
+
+func (s *cryptoStream) flush(final bool) error {
+	correlationValue, err := s.send(s.buf, !s.started, final, s.correlationValue)
+	s.started = true
+	s.buf = s.buf[:0]
+
+	if err != nil {
+		s.err = err
+		return err
+	}
+
+	if correlationValue != nil {
+		s.correlationValue = correlationValue
+	}
+
+	return nil
+}
+
+// writeOutput writes the output of a request to w, failing the stream if the write fails.
+func writeOutput(w io.Writer, p []byte) error {
+	if len(p) == 0 || w == nil {
+		return nil
+	}
+
+	_, err := w.Write(p)
+
+	return merry.Prepend(err, "writing output")
+}
+
+// EncryptWriter encrypts the data written to it with a streaming Encrypt operation, and writes the ciphertext to
+// an io.Writer as the server returns it.  Close must be called to finish the encryption.
+type EncryptWriter struct {
+	cryptoStream
+
+	ivCounterNonce []byte
+	tag            []byte
+}
+
+// NewEncryptWriter returns an EncryptWriter which writes the ciphertext to w.  req holds the Unique Identifier,
+// Cryptographic Parameters, IV/Counter/Nonce and Authenticated Encryption Additional Data, which are sent with
+// the first request.  Its Data and streaming fields are ignored.
+func (c *Client) NewEncryptWriter(ctx context.Context, w io.Writer, req EncryptRequestPayload) *EncryptWriter {
+	ew := &EncryptWriter{}
+	ew.send = func(data []byte, init, final bool, correlationValue []byte) ([]byte, error) {
+		payload := EncryptRequestPayload{
+			Data:             data,
+			CorrelationValue: correlationValue,
+			InitIndicator:    init,
+			FinalIndicator:   final,
+		}
+
+		if init {
+			payload.UniqueIdentifier = req.UniqueIdentifier
+			payload.CryptographicParameters = req.CryptographicParameters
+			payload.IVCounterNonce = req.IVCounterNonce
+			payload.AuthenticatedEncryptionAdditionalData = req.AuthenticatedEncryptionAdditionalData
+		}
+
+		var resp EncryptResponsePayload
+		if err := c.Do(ctx, kmip14.OperationEncrypt, &payload, &resp); err != nil {
+			return nil, err
+		}
+
+		if resp.IVCounterNonce != nil {
+			ew.ivCounterNonce = resp.IVCounterNonce
+		}
+
+		if resp.AuthenticatedEncryptionTag != nil {
+			ew.tag = resp.AuthenticatedEncryptionTag
+		}
+
+		return resp.CorrelationValue, writeOutput(w, resp.Data)
+	}
+
+	return ew
+}
+
+// IVCounterNonce returns the IV/Counter/Nonce generated by the server, if the Cryptographic Parameters set Random
+// IV.  It's available once the first request has been sent.
+func (ew *EncryptWriter) IVCounterNonce() []byte {
+	return ew.ivCounterNonce
+}
+
+// AuthenticatedEncryptionTag returns the tag of an authenticated encryption mode, like GCM, after Close.
+func (ew *EncryptWriter) AuthenticatedEncryptionTag() []byte {
+	return ew.tag
+}
+
+// DecryptWriter decrypts the data written to it with a streaming Decrypt operation, and writes the plaintext to
+// an io.Writer as the server returns it.  Close must be called to finish the decryption.
+//
+// With authenticated encryption modes, the tag is only checked when the stream is closed: if Close fails, the
+// plaintext written so far must not be trusted.
+type DecryptWriter struct {
+	cryptoStream
+}
+
+// NewDecryptWriter returns a DecryptWriter which writes the plaintext to w.  req holds the Unique Identifier,
+// Cryptographic Parameters, IV/Counter/Nonce and Authenticated Encryption Additional Data, which are sent with
+// the first request, and the Authenticated Encryption Tag, which is sent with the last.  Its Data and streaming
+// fields are ignored.
+func (c *Client) NewDecryptWriter(ctx context.Context, w io.Writer, req DecryptRequestPayload) *DecryptWriter {
+	dw := &DecryptWriter{}
+	dw.send = func(data []byte, init, final bool, correlationValue []byte) ([]byte, error) {
+		payload := DecryptRequestPayload{
+			Data:             data,
+			CorrelationValue: correlationValue,
+			InitIndicator:    init,
+			FinalIndicator:   final,
+		}
+
+		if init {
+			payload.UniqueIdentifier = req.UniqueIdentifier
+			payload.CryptographicParameters = req.CryptographicParameters
+			payload.IVCounterNonce = req.IVCounterNonce
+			payload.AuthenticatedEncryptionAdditionalData = req.AuthenticatedEncryptionAdditionalData
+		}
+
+		if final {
+			payload.AuthenticatedEncryptionTag = req.AuthenticatedEncryptionTag
+		}
+
+		var resp DecryptResponsePayload
+		if err := c.Do(ctx, kmip14.OperationDecrypt, &payload, &resp); err != nil {
+			return nil, err
+		}
+
+		return resp.CorrelationValue, writeOutput(w, resp.Data)
+	}
+
+	return dw
+}
+
+// SignWriter signs the data written to it with a streaming Sign operation.  After Close, Signature returns the
+// signature.
+type SignWriter struct {
+	cryptoStream
+
+	signature []byte
+}
+
+// NewSignWriter returns a SignWriter.  req holds the Unique Identifier and Cryptographic Parameters, which are sent
+// with the first request.  Its Data, Digested Data and streaming fields are ignored.
+func (c *Client) NewSignWriter(ctx context.Context, req SignRequestPayload) *SignWriter {
+	sw := &SignWriter{}
+	sw.send = func(data []byte, init, final bool, correlationValue []byte) ([]byte, error) {
+		payload := SignRequestPayload{
+			Data:             data,
+			CorrelationValue: correlationValue,
+			InitIndicator:    init,
+			FinalIndicator:   final,
+		}
+
+		if init {
+			payload.UniqueIdentifier = req.UniqueIdentifier
+			payload.CryptographicParameters = req.CryptographicParameters
+		}
+
+		var resp SignResponsePayload
+		if err := c.Do(ctx, kmip14.OperationSign, &payload, &resp); err != nil {
+			return nil, err
+		}
+
+		sw.signature = resp.SignatureData
+
+		return resp.CorrelationValue, nil
+	}
+
+	return sw
+}
+
+// Signature returns the signature, after Close.
+func (sw *SignWriter) Signature() []byte {
+	return sw.signature
+}
+
+// MACWriter computes the MAC of the data written to it with a streaming MAC operation.  After Close, MAC returns
+// the MAC.
+type MACWriter struct {
+	cryptoStream
+
+	mac []byte
+}
+
+// NewMACWriter returns a MACWriter.  req holds the Unique Identifier and Cryptographic Parameters, which are sent
+// with the first request.  Its Data and streaming fields are ignored.
+func (c *Client) NewMACWriter(ctx context.Context, req MACRequestPayload) *MACWriter {
+	mw := &MACWriter{}
+	mw.send = func(data []byte, init, final bool, correlationValue []byte) ([]byte, error) {
+		payload := MACRequestPayload{
+			Data:             data,
+			CorrelationValue: correlationValue,
+			InitIndicator:    init,
+			FinalIndicator:   final,
+		}
+
+		if init {
+			payload.UniqueIdentifier = req.UniqueIdentifier
+			payload.CryptographicParameters = req.CryptographicParameters
+		}
+
+		var resp MACResponsePayload
+		if err := c.Do(ctx, kmip14.OperationMAC, &payload, &resp); err != nil {
+			return nil, err
+		}
+
+		mw.mac = resp.MACData
+
+		return resp.CorrelationValue, nil
+	}
+
+	return mw
+}
+
+// MAC returns the MAC, after Close.
+func (mw *MACWriter) MAC() []byte {
+	return mw.mac
+}
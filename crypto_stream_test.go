@@ -0,0 +1,73 @@
+package kmip
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCryptoStream(t *testing.T) {
+	type request struct {
+		data        string
+		init, final bool
+		corr        string
+	}
+
+	var requests []request
+
+	s := cryptoStream{ChunkSize: 4}
+	s.send = func(data []byte, init, final bool, correlationValue []byte) ([]byte, error) {
+		requests = append(requests, request{data: string(data), init: init, final: final, corr: string(correlationValue)})
+		return []byte("corr"), nil
+	}
+
+	n, err := s.Write([]byte("abc"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Empty(t, requests)
+
+	n, err = s.Write([]byte("defghijkl"))
+	require.NoError(t, err)
+	assert.Equal(t, 9, n)
+
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, []request{
+		{data: "abcd", init: true},
+		{data: "efgh", corr: "corr"},
+		{data: "ijkl", corr: "corr", final: true},
+	}, requests)
+
+	_, err = s.Write([]byte("more"))
+	require.ErrorIs(t, err, ErrStreamClosed)
+
+	t.Run("single request", func(t *testing.T) {
+		requests = nil
+		s := cryptoStream{send: func(data []byte, init, final bool, correlationValue []byte) ([]byte, error) {
+			requests = append(requests, request{data: string(data), init: init, final: final, corr: string(correlationValue)})
+			return nil, nil
+		}}
+
+		_, err := s.Write([]byte("abc"))
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+		assert.Equal(t, []request{{data: "abc", init: true, final: true}}, requests)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		failed := errors.New("failed")
+		s := cryptoStream{ChunkSize: 2, send: func([]byte, bool, bool, []byte) ([]byte, error) {
+			return nil, failed
+		}}
+
+		n, err := s.Write([]byte("abcd"))
+		require.ErrorIs(t, err, failed)
+		assert.Equal(t, 0, n)
+
+		_, err = s.Write([]byte("e"))
+		require.ErrorIs(t, err, failed)
+		require.ErrorIs(t, s.Close(), failed)
+	})
+}
@@ -7,7 +7,7 @@
 // the different types of managed objects, request and response bodies, etc.  Not all Structures
 // are represented here yet, but the ones that are can be used as examples.
 //
-// There is also a partial implementation of a server, and an example of a client.  There is
-// currently no Client type for KMIP, but it is simple to open a socket overwhich you send
-// and receive raw KMIP requests and responses.
+// There is also a partial implementation of a server, and a basic Client, which sends requests
+// to a server and handles the operations a server may push to its clients, like Notify and Put.
+// It is also simple to open a socket overwhich you send and receive raw KMIP requests and responses.
 package kmip
@@ -0,0 +1,623 @@
+package kmip
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// ErrKeyFormatTypeNotSupported is returned when a key can't be converted to or from a Key Format Type.  It carries
+// the Key Format Type Not Supported result reason.
+var ErrKeyFormatTypeNotSupported = errors.New("kmip: key format type not supported")
+
+// ErrKeyCompressionTypeNotSupported is returned when a key can't be converted to a Key Compression Type.  It
+// carries the Key Compression Type Not Supported result reason.
+var ErrKeyCompressionTypeNotSupported = errors.New("kmip: key compression type not supported")
+
+func keyFormatTypeNotSupportedf(format string, args ...interface{}) error {
+	err := merry.WithUserMessagef(merry.Here(ErrKeyFormatTypeNotSupported), format, args...)
+
+	return WithResultReason(err, kmip14.ResultReasonKeyFormatTypeNotSupported)
+}
+
+func keyCompressionTypeNotSupportedf(format string, args ...interface{}) error {
+	err := merry.WithUserMessagef(merry.Here(ErrKeyCompressionTypeNotSupported), format, args...)
+
+	return WithResultReason(err, kmip14.ResultReasonKeyCompressionTypeNotSupported)
+}
+
+func invalidKeyMaterial(err error) error {
+	return WithResultReason(merry.Prepend(err, "invalid key material"), kmip14.ResultReasonCryptographicFailure)
+}
+
+// ConvertKeyBlock returns a copy of the Key Block of a key of the Object Type, with its Key Material in another Key
+// Format Type, and, for elliptic curve public keys, in another Key Compression Type.  A zero format or compression
+// leaves it unchanged.  The Key Value's attributes are kept.
+//
+// Symmetric Keys convert between Raw and Transparent Symmetric Key.  Private and Public Keys convert between the
+// formats of their algorithm:
+//
+//   - RSA private keys: PKCS#1, PKCS#8 and Transparent RSA Private Key.  Transparent keys must have the prime
+//     factors P and Q.
+//   - RSA public keys: PKCS#1, X.509 and Transparent RSA Public Key.
+//   - Elliptic curve private keys, on the P-224, P-256, P-384 and P-521 curves: PKCS#8, EC Private Key, and the
+//     Transparent EC, ECDSA, ECDH and ECMQV Private Key formats.
+//   - Elliptic curve public keys: X.509, and the Transparent EC, ECDSA, ECDH and ECMQV Public Key formats.  The
+//     Q String of the transparent formats may be uncompressed, or X9.62 compressed.  X.509 keys are uncompressed.
+//   - Ed25519 keys: PKCS#8 private keys and X.509 public keys.
+//
+// Wrapped keys can't be converted.  Other conversions fail with ErrKeyFormatTypeNotSupported or
+// ErrKeyCompressionTypeNotSupported.
+func ConvertKeyBlock(objectType kmip14.ObjectType, kb *KeyBlock, format kmip14.KeyFormatType, compression kmip14.KeyCompressionType) (*KeyBlock, error) {
+	if kb.KeyValue == nil {
+		return nil, WithResultReason(merry.UserError("the key has no Key Value"), kmip14.ResultReasonKeyValueNotPresent)
+	}
+
+	if kb.KeyWrappingData != nil {
+		return nil, keyFormatTypeNotSupportedf("wrapped keys can't be converted")
+	}
+
+	if format == 0 {
+		format = kb.KeyFormatType
+	}
+
+	out := *kb
+	out.KeyFormatType = format
+
+	if format == kb.KeyFormatType && (compression == 0 || compression == keyCompressionType(kb)) {
+		return &out, nil
+	}
+
+	if compression != 0 && objectType != kmip14.ObjectTypePublicKey {
+		return nil, keyCompressionTypeNotSupportedf("only elliptic curve public keys can be compressed")
+	}
+
+	var (
+		material interface{}
+		err      error
+	)
+
+	switch objectType {
+	case kmip14.ObjectTypeSymmetricKey:
+		material, err = convertSymmetricKey(kb, format)
+	case kmip14.ObjectTypePrivateKey:
+		var key crypto.PrivateKey
+
+		key, err = parsePrivateKey(kb)
+		if err == nil {
+			material, err = encodePrivateKey(key, format)
+		}
+	case kmip14.ObjectTypePublicKey:
+		var key crypto.PublicKey
+
+		key, err = parsePublicKey(kb)
+		if err == nil {
+			material, err = encodePublicKey(key, format, compression)
+		}
+
+		out.KeyCompressionType = 0
+		if isTransparentECPublicKey(format) {
+			out.KeyCompressionType = compression
+		}
+	default:
+		return nil, keyFormatTypeNotSupportedf("Object Type %s can't be converted", objectType.String())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	out.KeyValue = &KeyValue{KeyMaterial: material, Attribute: kb.KeyValue.Attribute}
+
+	return &out, nil
+}
+
+// keyCompressionType returns the Key Compression Type of a Key Block.  Elliptic curve public keys are uncompressed
+// by default.
+func keyCompressionType(kb *KeyBlock) kmip14.KeyCompressionType {
+	if kb.KeyCompressionType == 0 && isTransparentECPublicKey(kb.KeyFormatType) {
+		return kmip14.KeyCompressionTypeECPublicKeyTypeUncompressed
+	}
+
+	return kb.KeyCompressionType
+}
+
+func convertSymmetricKey(kb *KeyBlock, format kmip14.KeyFormatType) (interface{}, error) {
+	var key []byte
+
+	switch kb.KeyFormatType {
+	case kmip14.KeyFormatTypeRaw:
+		b, ok := kb.KeyValue.KeyMaterial.([]byte)
+		if !ok {
+			return nil, invalidKeyMaterial(merry.New("Raw Key Material must be a byte string"))
+		}
+
+		key = b
+	case kmip14.KeyFormatTypeTransparentSymmetricKey:
+		var t TransparentSymmetricKey
+		if err := DecodeAttributeValue(kb.KeyValue.KeyMaterial, &t); err != nil {
+			return nil, invalidKeyMaterial(err)
+		}
+
+		key = t.Key
+	default:
+		return nil, keyFormatTypeNotSupportedf("unsupported Key Format Type for symmetric keys: %s", kb.KeyFormatType.String())
+	}
+
+	switch format {
+	case kmip14.KeyFormatTypeRaw:
+		return key, nil
+	case kmip14.KeyFormatTypeTransparentSymmetricKey:
+		return &TransparentSymmetricKey{Key: key}, nil
+	}
+
+	return nil, keyFormatTypeNotSupportedf("symmetric keys can't be converted to %s", format.String())
+}
+
+// keyBytes returns the Key Material of an encoded key.
+func keyBytes(kb *KeyBlock) ([]byte, error) {
+	b, ok := kb.KeyValue.KeyMaterial.([]byte)
+	if !ok {
+		return nil, invalidKeyMaterial(merry.Errorf("%s Key Material must be a byte string", kb.KeyFormatType.String()))
+	}
+
+	return b, nil
+}
+
+// parsePrivateKey returns the private key of a Key Block, as an *rsa.PrivateKey, *ecdsa.PrivateKey or
+// ed25519.PrivateKey.
+func parsePrivateKey(kb *KeyBlock) (crypto.PrivateKey, error) {
+	var (
+		key crypto.PrivateKey
+		err error
+	)
+
+	switch kb.KeyFormatType {
+	case kmip14.KeyFormatTypePKCS_1, kmip14.KeyFormatTypePKCS_8, kmip14.KeyFormatTypeECPrivateKey:
+		der, derr := keyBytes(kb)
+		if derr != nil {
+			return nil, derr
+		}
+
+		switch kb.KeyFormatType {
+		case kmip14.KeyFormatTypePKCS_1:
+			key, err = x509.ParsePKCS1PrivateKey(der)
+		case kmip14.KeyFormatTypePKCS_8:
+			key, err = x509.ParsePKCS8PrivateKey(der)
+		default:
+			key, err = x509.ParseECPrivateKey(der)
+		}
+	case kmip14.KeyFormatTypeTransparentRSAPrivateKey:
+		var t TransparentRSAPrivateKey
+		if err := DecodeAttributeValue(kb.KeyValue.KeyMaterial, &t); err != nil {
+			return nil, invalidKeyMaterial(err)
+		}
+
+		return rsaPrivateKey(&t)
+	case kmip14.KeyFormatTypeTransparentECPrivateKey, kmip14.KeyFormatTypeTransparentECDSAPrivateKey,
+		kmip14.KeyFormatTypeTransparentECDHPrivateKey, kmip14.KeyFormatTypeTransparentECMQVPrivateKey:
+		var t TransparentECPrivateKey
+		if err := DecodeAttributeValue(kb.KeyValue.KeyMaterial, &t); err != nil {
+			return nil, invalidKeyMaterial(err)
+		}
+
+		return ecPrivateKey(t.RecommendedCurve, t.D)
+	default:
+		return nil, keyFormatTypeNotSupportedf("unsupported Key Format Type for private keys: %s", kb.KeyFormatType.String())
+	}
+
+	if err != nil {
+		return nil, invalidKeyMaterial(err)
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+
+	return nil, keyFormatTypeNotSupportedf("unsupported private key type: %T", key)
+}
+
+// parsePublicKey returns the public key of a Key Block, as an *rsa.PublicKey, *ecdsa.PublicKey or
+// ed25519.PublicKey.
+func parsePublicKey(kb *KeyBlock) (crypto.PublicKey, error) {
+	var (
+		key crypto.PublicKey
+		err error
+	)
+
+	switch kb.KeyFormatType {
+	case kmip14.KeyFormatTypePKCS_1, kmip14.KeyFormatTypeX_509:
+		der, derr := keyBytes(kb)
+		if derr != nil {
+			return nil, derr
+		}
+
+		if kb.KeyFormatType == kmip14.KeyFormatTypePKCS_1 {
+			key, err = x509.ParsePKCS1PublicKey(der)
+		} else {
+			key, err = x509.ParsePKIXPublicKey(der)
+		}
+	case kmip14.KeyFormatTypeTransparentRSAPublicKey:
+		var t TransparentRSAPublicKey
+		if err := DecodeAttributeValue(kb.KeyValue.KeyMaterial, &t); err != nil {
+			return nil, invalidKeyMaterial(err)
+		}
+
+		return rsaPublicKey(t.Modulus, t.PublicExponent)
+	case kmip14.KeyFormatTypeTransparentECPublicKey, kmip14.KeyFormatTypeTransparentECDSAPublicKey,
+		kmip14.KeyFormatTypeTransparentECDHPublicKey, kmip14.KeyFormatTypeTransparentECMQVPublicKey:
+		var t TransparentECPublicKey
+		if err := DecodeAttributeValue(kb.KeyValue.KeyMaterial, &t); err != nil {
+			return nil, invalidKeyMaterial(err)
+		}
+
+		return ecPublicKey(t.RecommendedCurve, t.QString)
+	default:
+		return nil, keyFormatTypeNotSupportedf("unsupported Key Format Type for public keys: %s", kb.KeyFormatType.String())
+	}
+
+	if err != nil {
+		return nil, invalidKeyMaterial(err)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+
+	return nil, keyFormatTypeNotSupportedf("unsupported public key type: %T", key)
+}
+
+// encodePrivateKey returns the Key Material of a private key in the Key Format Type.
+func encodePrivateKey(key crypto.PrivateKey, format kmip14.KeyFormatType) (interface{}, error) {
+	switch format {
+	case kmip14.KeyFormatTypePKCS_8:
+		b, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, keyFormatTypeNotSupportedf("%T can't be encoded as PKCS#8: %v", key, err)
+		}
+
+		return b, nil
+	case kmip14.KeyFormatTypePKCS_1, kmip14.KeyFormatTypeTransparentRSAPrivateKey:
+		k, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			break
+		}
+
+		if format == kmip14.KeyFormatTypePKCS_1 {
+			return x509.MarshalPKCS1PrivateKey(k), nil
+		}
+
+		return transparentRSAPrivateKey(k)
+	case kmip14.KeyFormatTypeECPrivateKey, kmip14.KeyFormatTypeTransparentECPrivateKey, kmip14.KeyFormatTypeTransparentECDSAPrivateKey,
+		kmip14.KeyFormatTypeTransparentECDHPrivateKey, kmip14.KeyFormatTypeTransparentECMQVPrivateKey:
+		k, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			break
+		}
+
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, keyFormatTypeNotSupportedf("unsupported elliptic curve: %v", err)
+		}
+
+		if format == kmip14.KeyFormatTypeECPrivateKey {
+			return der, nil
+		}
+
+		return transparentECPrivateKey(k, der, format)
+	}
+
+	return nil, keyFormatTypeNotSupportedf("%T can't be converted to %s", key, format.String())
+}
+
+// encodePublicKey returns the Key Material of a public key in the Key Format Type and Key Compression Type.
+func encodePublicKey(key crypto.PublicKey, format kmip14.KeyFormatType, compression kmip14.KeyCompressionType) (interface{}, error) {
+	_, isEC := key.(*ecdsa.PublicKey)
+
+	switch {
+	case compression == 0:
+	case !isEC:
+		return nil, keyCompressionTypeNotSupportedf("only elliptic curve public keys can be compressed")
+	case compression == kmip14.KeyCompressionTypeECPublicKeyTypeUncompressed:
+	case compression == kmip14.KeyCompressionTypeECPublicKeyTypeX9_62CompressedPrime && isTransparentECPublicKey(format):
+	default:
+		return nil, keyCompressionTypeNotSupportedf("unsupported Key Compression Type for %s: %s", format.String(), compression.String())
+	}
+
+	switch format {
+	case kmip14.KeyFormatTypeX_509:
+		b, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return nil, keyFormatTypeNotSupportedf("%T can't be encoded as X.509: %v", key, err)
+		}
+
+		return b, nil
+	case kmip14.KeyFormatTypePKCS_1, kmip14.KeyFormatTypeTransparentRSAPublicKey:
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			break
+		}
+
+		if format == kmip14.KeyFormatTypePKCS_1 {
+			return x509.MarshalPKCS1PublicKey(k), nil
+		}
+
+		return &TransparentRSAPublicKey{Modulus: new(big.Int).Set(k.N), PublicExponent: big.NewInt(int64(k.E))}, nil
+	case kmip14.KeyFormatTypeTransparentECPublicKey, kmip14.KeyFormatTypeTransparentECDSAPublicKey,
+		kmip14.KeyFormatTypeTransparentECDHPublicKey, kmip14.KeyFormatTypeTransparentECMQVPublicKey:
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			break
+		}
+
+		return transparentECPublicKey(k, format, compression == kmip14.KeyCompressionTypeECPublicKeyTypeX9_62CompressedPrime)
+	}
+
+	return nil, keyFormatTypeNotSupportedf("%T can't be converted to %s", key, format.String())
+}
+
+func isTransparentECPublicKey(format kmip14.KeyFormatType) bool {
+	switch format {
+	case kmip14.KeyFormatTypeTransparentECPublicKey, kmip14.KeyFormatTypeTransparentECDSAPublicKey,
+		kmip14.KeyFormatTypeTransparentECDHPublicKey, kmip14.KeyFormatTypeTransparentECMQVPublicKey:
+		return true
+	}
+
+	return false
+}
+
+func rsaPublicKey(modulus, exponent *big.Int) (*rsa.PublicKey, error) {
+	if modulus == nil || exponent == nil || modulus.Sign() <= 0 || !exponent.IsInt64() || exponent.Int64() < 2 || exponent.Int64() > 1<<31-1 {
+		return nil, invalidKeyMaterial(merry.New("invalid RSA modulus or public exponent"))
+	}
+
+	return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+}
+
+func rsaPrivateKey(t *TransparentRSAPrivateKey) (*rsa.PrivateKey, error) {
+	if t.P == nil || t.Q == nil {
+		return nil, keyFormatTypeNotSupportedf("Transparent RSA Private Keys without the prime factors P and Q aren't supported")
+	}
+
+	pub, err := rsaPublicKey(t.Modulus, t.PublicExponent)
+	if err != nil {
+		return nil, err
+	}
+
+	d := t.PrivateExponent
+	if d == nil {
+		one := big.NewInt(1)
+		phi := new(big.Int).Mul(new(big.Int).Sub(t.P, one), new(big.Int).Sub(t.Q, one))
+
+		d = new(big.Int).ModInverse(big.NewInt(int64(pub.E)), phi)
+		if d == nil {
+			return nil, invalidKeyMaterial(merry.New("the public exponent has no inverse"))
+		}
+	}
+
+	key := &rsa.PrivateKey{PublicKey: *pub, D: d, Primes: []*big.Int{t.P, t.Q}}
+	if err := key.Validate(); err != nil {
+		return nil, invalidKeyMaterial(err)
+	}
+
+	key.Precompute()
+
+	return key, nil
+}
+
+func transparentRSAPrivateKey(key *rsa.PrivateKey) (*TransparentRSAPrivateKey, error) {
+	if len(key.Primes) != 2 {
+		return nil, keyFormatTypeNotSupportedf("multi-prime RSA keys can't be converted to %s", kmip14.KeyFormatTypeTransparentRSAPrivateKey.String())
+	}
+
+	one := big.NewInt(1)
+	p, q := key.Primes[0], key.Primes[1]
+
+	return &TransparentRSAPrivateKey{
+		Modulus:         new(big.Int).Set(key.N),
+		PrivateExponent: new(big.Int).Set(key.D),
+		PublicExponent:  big.NewInt(int64(key.E)),
+		P:               new(big.Int).Set(p),
+		Q:               new(big.Int).Set(q),
+		PrimeExponentP:  new(big.Int).Mod(key.D, new(big.Int).Sub(p, one)),
+		PrimeExponentQ:  new(big.Int).Mod(key.D, new(big.Int).Sub(q, one)),
+		CRTCoefficient:  new(big.Int).ModInverse(q, p),
+	}, nil
+}
+
+// ecCurves are the elliptic curves supported by the conversions, with their object identifiers.
+var ecCurves = []struct {
+	recommended kmip14.RecommendedCurve
+	curve       elliptic.Curve
+	oid         asn1.ObjectIdentifier
+}{
+	{kmip14.RecommendedCurveP_224, elliptic.P224(), asn1.ObjectIdentifier{1, 3, 132, 0, 33}},
+	{kmip14.RecommendedCurveP_256, elliptic.P256(), asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}},
+	{kmip14.RecommendedCurveP_384, elliptic.P384(), asn1.ObjectIdentifier{1, 3, 132, 0, 34}},
+	{kmip14.RecommendedCurveP_521, elliptic.P521(), asn1.ObjectIdentifier{1, 3, 132, 0, 35}},
+}
+
+var oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+// ecPrivateKeyASN1 is the SEC 1 encoding of an elliptic curve private key, as used by the EC Private Key format.
+type ecPrivateKeyASN1 struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// subjectPublicKeyInfo is the X.509 encoding of a public key.
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// ecCurve returns the Recommended Curve's elliptic curve and object identifier.
+func ecCurve(recommended kmip14.RecommendedCurve) (elliptic.Curve, asn1.ObjectIdentifier, error) {
+	for _, c := range ecCurves {
+		if c.recommended == recommended {
+			return c.curve, c.oid, nil
+		}
+	}
+
+	return nil, nil, keyFormatTypeNotSupportedf("unsupported Recommended Curve: %s", recommended.String())
+}
+
+// recommendedCurve returns the Recommended Curve of an elliptic curve.
+func recommendedCurve(curve elliptic.Curve) (kmip14.RecommendedCurve, error) {
+	for _, c := range ecCurves {
+		if c.curve == curve {
+			return c.recommended, nil
+		}
+	}
+
+	return 0, keyFormatTypeNotSupportedf("unsupported elliptic curve: %s", curve.Params().Name)
+}
+
+// ecPrivateKey returns the private key with the scalar d.  The key is built from its SEC 1 encoding, so the
+// public key is derived by crypto/x509.
+func ecPrivateKey(recommended kmip14.RecommendedCurve, d *big.Int) (*ecdsa.PrivateKey, error) {
+	curve, oid, err := ecCurve(recommended)
+	if err != nil {
+		return nil, err
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if d == nil || d.Sign() <= 0 || d.BitLen() > size*8 {
+		return nil, invalidKeyMaterial(merry.New("invalid elliptic curve private key"))
+	}
+
+	der, err := asn1.Marshal(ecPrivateKeyASN1{Version: 1, PrivateKey: d.FillBytes(make([]byte, size)), NamedCurveOID: oid})
+	if err != nil {
+		return nil, merry.Wrap(err)
+	}
+
+	key, err := x509.ParseECPrivateKey(der)
+	if err != nil {
+		return nil, invalidKeyMaterial(err)
+	}
+
+	return key, nil
+}
+
+func transparentECPrivateKey(key *ecdsa.PrivateKey, der []byte, format kmip14.KeyFormatType) (interface{}, error) {
+	recommended, err := recommendedCurve(key.Curve)
+	if err != nil {
+		return nil, err
+	}
+
+	var sec1 ecPrivateKeyASN1
+	if _, err := asn1.Unmarshal(der, &sec1); err != nil {
+		return nil, merry.Wrap(err)
+	}
+
+	t := TransparentECPrivateKey{RecommendedCurve: recommended, D: new(big.Int).SetBytes(sec1.PrivateKey)}
+
+	switch format {
+	case kmip14.KeyFormatTypeTransparentECDSAPrivateKey:
+		return &TransparentECDSAPrivateKey{RecommendedCurve: t.RecommendedCurve, D: t.D}, nil
+	case kmip14.KeyFormatTypeTransparentECDHPrivateKey:
+		return (*TransparentECDHPrivateKey)(&t), nil
+	case kmip14.KeyFormatTypeTransparentECMQVPrivateKey:
+		return (*TransparentECMQVPrivateKey)(&t), nil
+	}
+
+	return &t, nil
+}
+
+// ecPublicKey returns the public key with the point q, which may be uncompressed or compressed.  The key is
+// built from its X.509 encoding, so the point is validated by crypto/x509.
+func ecPublicKey(recommended kmip14.RecommendedCurve, q []byte) (*ecdsa.PublicKey, error) {
+	curve, oid, err := ecCurve(recommended)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(q) > 0 && (q[0] == 2 || q[0] == 3) {
+		x, y := elliptic.UnmarshalCompressed(curve, q)
+		if x == nil {
+			return nil, invalidKeyMaterial(merry.New("invalid compressed elliptic curve point"))
+		}
+
+		size := (curve.Params().BitSize + 7) / 8
+		q = make([]byte, 1+2*size)
+		q[0] = 4
+		x.FillBytes(q[1 : 1+size])
+		y.FillBytes(q[1+size:])
+	}
+
+	params, err := asn1.Marshal(oid)
+	if err != nil {
+		return nil, merry.Wrap(err)
+	}
+
+	der, err := asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+		PublicKey: asn1.BitString{Bytes: q, BitLength: 8 * len(q)},
+	})
+	if err != nil {
+		return nil, merry.Wrap(err)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, invalidKeyMaterial(err)
+	}
+
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, invalidKeyMaterial(merry.New("not an elliptic curve public key"))
+	}
+
+	return ecKey, nil
+}
+
+func transparentECPublicKey(key *ecdsa.PublicKey, format kmip14.KeyFormatType, compressed bool) (interface{}, error) {
+	recommended, err := recommendedCurve(key.Curve)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, keyFormatTypeNotSupportedf("unsupported elliptic curve: %v", err)
+	}
+
+	var spki subjectPublicKeyInfo
+	if _, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, merry.Wrap(err)
+	}
+
+	q := spki.PublicKey.Bytes
+
+	if compressed {
+		size := (len(q) - 1) / 2
+		q = elliptic.MarshalCompressed(key.Curve, new(big.Int).SetBytes(q[1:1+size]), new(big.Int).SetBytes(q[1+size:]))
+	}
+
+	t := TransparentECPublicKey{RecommendedCurve: recommended, QString: q}
+
+	switch format {
+	case kmip14.KeyFormatTypeTransparentECDSAPublicKey:
+		return &TransparentECDSAPublicKey{RecommendedCurve: t.RecommendedCurve, QString: t.QString}, nil
+	case kmip14.KeyFormatTypeTransparentECDHPublicKey:
+		return (*TransparentECDHPublicKey)(&t), nil
+	case kmip14.KeyFormatTypeTransparentECMQVPublicKey:
+		return (*TransparentECMQVPublicKey)(&t), nil
+	}
+
+	return &t, nil
+}
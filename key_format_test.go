@@ -0,0 +1,190 @@
+package kmip
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"math/big"
+	"testing"
+
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertKeyBlock(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der := func(b []byte, err error) []byte {
+		require.NoError(t, err)
+		return b
+	}
+
+	keyBlock := func(format kmip14.KeyFormatType, material []byte) *KeyBlock {
+		return &KeyBlock{KeyFormatType: format, KeyValue: &KeyValue{KeyMaterial: material}}
+	}
+
+	tests := []struct {
+		name       string
+		objectType kmip14.ObjectType
+		kb         *KeyBlock
+		formats    []kmip14.KeyFormatType
+	}{
+		{
+			name:       "symmetric",
+			objectType: kmip14.ObjectTypeSymmetricKey,
+			kb:         keyBlock(kmip14.KeyFormatTypeRaw, RandomBytes(32)),
+			formats:    []kmip14.KeyFormatType{kmip14.KeyFormatTypeTransparentSymmetricKey},
+		},
+		{
+			name:       "RSA private",
+			objectType: kmip14.ObjectTypePrivateKey,
+			kb:         keyBlock(kmip14.KeyFormatTypePKCS_1, x509.MarshalPKCS1PrivateKey(rsaKey)),
+			formats:    []kmip14.KeyFormatType{kmip14.KeyFormatTypePKCS_8, kmip14.KeyFormatTypeTransparentRSAPrivateKey},
+		},
+		{
+			name:       "RSA public",
+			objectType: kmip14.ObjectTypePublicKey,
+			kb:         keyBlock(kmip14.KeyFormatTypePKCS_1, x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)),
+			formats:    []kmip14.KeyFormatType{kmip14.KeyFormatTypeX_509, kmip14.KeyFormatTypeTransparentRSAPublicKey},
+		},
+		{
+			name:       "EC private",
+			objectType: kmip14.ObjectTypePrivateKey,
+			kb:         keyBlock(kmip14.KeyFormatTypePKCS_8, der(x509.MarshalPKCS8PrivateKey(ecKey))),
+			formats: []kmip14.KeyFormatType{
+				kmip14.KeyFormatTypeECPrivateKey,
+				kmip14.KeyFormatTypeTransparentECPrivateKey,
+				kmip14.KeyFormatTypeTransparentECDSAPrivateKey,
+				kmip14.KeyFormatTypeTransparentECDHPrivateKey,
+				kmip14.KeyFormatTypeTransparentECMQVPrivateKey,
+			},
+		},
+		{
+			name:       "EC public",
+			objectType: kmip14.ObjectTypePublicKey,
+			kb:         keyBlock(kmip14.KeyFormatTypeX_509, der(x509.MarshalPKIXPublicKey(&ecKey.PublicKey))),
+			formats: []kmip14.KeyFormatType{
+				kmip14.KeyFormatTypeTransparentECPublicKey,
+				kmip14.KeyFormatTypeTransparentECDSAPublicKey,
+				kmip14.KeyFormatTypeTransparentECDHPublicKey,
+				kmip14.KeyFormatTypeTransparentECMQVPublicKey,
+			},
+		},
+		{
+			name:       "Ed25519 private",
+			objectType: kmip14.ObjectTypePrivateKey,
+			kb:         keyBlock(kmip14.KeyFormatTypePKCS_8, der(x509.MarshalPKCS8PrivateKey(edPriv))),
+		},
+		{
+			name:       "Ed25519 public",
+			objectType: kmip14.ObjectTypePublicKey,
+			kb:         keyBlock(kmip14.KeyFormatTypeX_509, der(x509.MarshalPKIXPublicKey(edPub))),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, format := range test.formats {
+				converted, err := ConvertKeyBlock(test.objectType, test.kb, format, 0)
+				require.NoError(t, err, format.String())
+				assert.Equal(t, format, converted.KeyFormatType)
+
+				// the converted key survives encoding, as it would in a Get response
+				b, err := ttlv.Marshal(converted)
+				require.NoError(t, err)
+
+				var decoded KeyBlock
+				require.NoError(t, ttlv.Unmarshal(b, &decoded))
+
+				back, err := ConvertKeyBlock(test.objectType, &decoded, test.kb.KeyFormatType, 0)
+				require.NoError(t, err, format.String())
+				assert.Equal(t, test.kb.KeyValue.KeyMaterial, back.KeyValue.KeyMaterial, format.String())
+			}
+
+			// converting to the same format is a copy
+			same, err := ConvertKeyBlock(test.objectType, test.kb, 0, 0)
+			require.NoError(t, err)
+			assert.Equal(t, test.kb, same)
+
+			_, err = ConvertKeyBlock(test.objectType, test.kb, kmip14.KeyFormatTypeOpaque, 0)
+			require.ErrorIs(t, err, ErrKeyFormatTypeNotSupported)
+			assert.Equal(t, kmip14.ResultReasonKeyFormatTypeNotSupported, GetResultReason(err))
+		})
+	}
+
+	t.Run("compression", func(t *testing.T) {
+		kb := keyBlock(kmip14.KeyFormatTypeX_509, der(x509.MarshalPKIXPublicKey(&ecKey.PublicKey)))
+
+		compressed, err := ConvertKeyBlock(kmip14.ObjectTypePublicKey, kb, kmip14.KeyFormatTypeTransparentECPublicKey, kmip14.KeyCompressionTypeECPublicKeyTypeX9_62CompressedPrime)
+		require.NoError(t, err)
+		assert.Equal(t, kmip14.KeyCompressionTypeECPublicKeyTypeX9_62CompressedPrime, compressed.KeyCompressionType)
+
+		q := compressed.KeyValue.KeyMaterial.(*TransparentECPublicKey).QString
+		assert.Len(t, q, 49)
+		assert.Contains(t, []byte{2, 3}, q[0])
+
+		uncompressed, err := ConvertKeyBlock(kmip14.ObjectTypePublicKey, compressed, 0, kmip14.KeyCompressionTypeECPublicKeyTypeUncompressed)
+		require.NoError(t, err)
+		assert.Len(t, uncompressed.KeyValue.KeyMaterial.(*TransparentECPublicKey).QString, 97)
+
+		back, err := ConvertKeyBlock(kmip14.ObjectTypePublicKey, compressed, kmip14.KeyFormatTypeX_509, 0)
+		require.NoError(t, err)
+		assert.Equal(t, kb.KeyValue.KeyMaterial, back.KeyValue.KeyMaterial)
+		assert.Zero(t, back.KeyCompressionType)
+
+		_, err = ConvertKeyBlock(kmip14.ObjectTypePublicKey, kb, 0, kmip14.KeyCompressionTypeECPublicKeyTypeX9_62CompressedPrime)
+		require.ErrorIs(t, err, ErrKeyCompressionTypeNotSupported)
+		assert.Equal(t, kmip14.ResultReasonKeyCompressionTypeNotSupported, GetResultReason(err))
+
+		rsaPub := keyBlock(kmip14.KeyFormatTypePKCS_1, x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))
+		_, err = ConvertKeyBlock(kmip14.ObjectTypePublicKey, rsaPub, kmip14.KeyFormatTypeX_509, kmip14.KeyCompressionTypeECPublicKeyTypeUncompressed)
+		require.ErrorIs(t, err, ErrKeyCompressionTypeNotSupported)
+	})
+
+	t.Run("transparent RSA private key without private exponent", func(t *testing.T) {
+		kb := &KeyBlock{
+			KeyFormatType: kmip14.KeyFormatTypeTransparentRSAPrivateKey,
+			KeyValue: &KeyValue{KeyMaterial: TransparentRSAPrivateKey{
+				Modulus:        rsaKey.N,
+				PublicExponent: big.NewInt(int64(rsaKey.E)),
+				P:              rsaKey.Primes[0],
+				Q:              rsaKey.Primes[1],
+			}},
+		}
+
+		converted, err := ConvertKeyBlock(kmip14.ObjectTypePrivateKey, kb, kmip14.KeyFormatTypePKCS_8, 0)
+		require.NoError(t, err)
+
+		key, err := x509.ParsePKCS8PrivateKey(converted.KeyValue.KeyMaterial.([]byte))
+		require.NoError(t, err)
+		assert.True(t, rsaKey.PublicKey.Equal(key.(*rsa.PrivateKey).Public()))
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := ConvertKeyBlock(kmip14.ObjectTypePrivateKey, keyBlock(kmip14.KeyFormatTypePKCS_8, der(x509.MarshalPKCS8PrivateKey(ecKey))), kmip14.KeyFormatTypePKCS_1, 0)
+		require.ErrorIs(t, err, ErrKeyFormatTypeNotSupported)
+
+		_, err = ConvertKeyBlock(kmip14.ObjectTypeSymmetricKey, keyBlock(kmip14.KeyFormatTypeRaw, RandomBytes(16)), kmip14.KeyFormatTypeRaw, kmip14.KeyCompressionTypeECPublicKeyTypeUncompressed)
+		require.ErrorIs(t, err, ErrKeyCompressionTypeNotSupported)
+
+		wrapped := keyBlock(kmip14.KeyFormatTypeRaw, RandomBytes(24))
+		wrapped.KeyWrappingData = &KeyWrappingData{WrappingMethod: kmip14.WrappingMethodEncrypt}
+		_, err = ConvertKeyBlock(kmip14.ObjectTypeSymmetricKey, wrapped, kmip14.KeyFormatTypeTransparentSymmetricKey, 0)
+		require.ErrorIs(t, err, ErrKeyFormatTypeNotSupported)
+
+		_, err = ConvertKeyBlock(kmip14.ObjectTypeSecretData, keyBlock(kmip14.KeyFormatTypeOpaque, RandomBytes(16)), kmip14.KeyFormatTypeRaw, 0)
+		require.ErrorIs(t, err, ErrKeyFormatTypeNotSupported)
+	})
+}
@@ -0,0 +1,85 @@
+package kmip
+
+import (
+	"sync"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// KeyMaterialFunc returns a pointer to a new value to decode the structured Key Material of a Key Block into.
+// It is passed the Key Block's Cryptographic Algorithm.  It may return nil, in which case the Key Material is
+// left as a ttlv.TTLV.
+type KeyMaterialFunc func(alg kmip14.CryptographicAlgorithm) interface{}
+
+var (
+	keyMaterialTypesMu sync.RWMutex
+	keyMaterialTypes   = map[kmip14.KeyFormatType]KeyMaterialFunc{
+		kmip14.KeyFormatTypeTransparentSymmetricKey:    func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentSymmetricKey{} },
+		kmip14.KeyFormatTypeTransparentDSAPrivateKey:   func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentDSAPrivateKey{} },
+		kmip14.KeyFormatTypeTransparentDSAPublicKey:    func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentDSAPublicKey{} },
+		kmip14.KeyFormatTypeTransparentRSAPrivateKey:   func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentRSAPrivateKey{} },
+		kmip14.KeyFormatTypeTransparentRSAPublicKey:    func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentRSAPublicKey{} },
+		kmip14.KeyFormatTypeTransparentDHPrivateKey:    func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentDHPrivateKey{} },
+		kmip14.KeyFormatTypeTransparentDHPublicKey:     func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentDHPublicKey{} },
+		kmip14.KeyFormatTypeTransparentECDSAPrivateKey: func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentECDSAPrivateKey{} },
+		kmip14.KeyFormatTypeTransparentECDSAPublicKey:  func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentECDSAPublicKey{} },
+		kmip14.KeyFormatTypeTransparentECDHPrivateKey:  func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentECDHPrivateKey{} },
+		kmip14.KeyFormatTypeTransparentECDHPublicKey:   func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentECDHPublicKey{} },
+		kmip14.KeyFormatTypeTransparentECMQVPrivateKey: func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentECMQVPrivateKey{} },
+		kmip14.KeyFormatTypeTransparentECMQVPublicKey:  func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentECMQVPublicKey{} },
+		kmip14.KeyFormatTypeTransparentECPrivateKey:    func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentECPrivateKey{} },
+		kmip14.KeyFormatTypeTransparentECPublicKey:     func(kmip14.CryptographicAlgorithm) interface{} { return &TransparentECPublicKey{} },
+	}
+)
+
+// RegisterKeyMaterialType sets the function which returns the value the structured Key Material of a Key Format
+// Type is decoded into.  It is intended for vendor extension Key Format Types, and is typically called from an
+// init function.  Registering a standard Key Format Type replaces its default.  A nil fn removes the
+// registration.
+func RegisterKeyMaterialType(format kmip14.KeyFormatType, fn KeyMaterialFunc) {
+	keyMaterialTypesMu.Lock()
+	defer keyMaterialTypesMu.Unlock()
+
+	if fn == nil {
+		delete(keyMaterialTypes, format)
+		return
+	}
+
+	keyMaterialTypes[format] = fn
+}
+
+func keyMaterialType(format kmip14.KeyFormatType) KeyMaterialFunc {
+	keyMaterialTypesMu.RLock()
+	defer keyMaterialTypesMu.RUnlock()
+
+	return keyMaterialTypes[format]
+}
+
+// decodeKeyMaterial decodes structured Key Material into the type registered for the Key Format Type.  Byte
+// String Key Material, and Key Material of unregistered Key Format Types, is left unchanged.
+func (kb *KeyBlock) decodeKeyMaterial(d *ttlv.Decoder) error {
+	t, ok := kb.KeyValue.KeyMaterial.(ttlv.TTLV)
+	if !ok {
+		return nil
+	}
+
+	fn := keyMaterialType(kb.KeyFormatType)
+	if fn == nil {
+		return nil
+	}
+
+	v := fn(kb.CryptographicAlgorithm)
+	if v == nil {
+		return nil
+	}
+
+	if err := d.DecodeValue(v, t); err != nil {
+		return merry.Prependf(err, "decoding %s Key Material", kb.KeyFormatType.String())
+	}
+
+	kb.KeyValue.KeyMaterial = v
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package kmip
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyBlock_UnmarshalTTLV_keyMaterial(t *testing.T) {
+	roundTrip := func(t *testing.T, kb KeyBlock) KeyBlock {
+		t.Helper()
+
+		b, err := ttlv.Marshal(kb)
+		require.NoError(t, err)
+
+		var decoded KeyBlock
+		require.NoError(t, ttlv.Unmarshal(b, &decoded))
+
+		return decoded
+	}
+
+	tests := []struct {
+		format   kmip14.KeyFormatType
+		material interface{}
+	}{
+		{kmip14.KeyFormatTypeRaw, []byte{1, 2, 3}},
+		{kmip14.KeyFormatTypeTransparentSymmetricKey, &TransparentSymmetricKey{Key: []byte{1, 2, 3}}},
+		{kmip14.KeyFormatTypeTransparentDSAPublicKey, &TransparentDSAPublicKey{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(4), Y: big.NewInt(8)}},
+		{kmip14.KeyFormatTypeTransparentRSAPublicKey, &TransparentRSAPublicKey{Modulus: big.NewInt(3233), PublicExponent: big.NewInt(17)}},
+		{kmip14.KeyFormatTypeTransparentDHPrivateKey, &TransparentDHPrivateKey{P: big.NewInt(23), G: big.NewInt(5), X: big.NewInt(6)}},
+		{kmip14.KeyFormatTypeTransparentECDSAPublicKey, &TransparentECDSAPublicKey{RecommendedCurve: kmip14.RecommendedCurveP_256, QString: []byte{4, 1, 2}}},
+		{kmip14.KeyFormatTypeTransparentECDHPrivateKey, &TransparentECDHPrivateKey{RecommendedCurve: kmip14.RecommendedCurveP_256, D: big.NewInt(7)}},
+		{kmip14.KeyFormatTypeTransparentECPublicKey, &TransparentECPublicKey{RecommendedCurve: kmip14.RecommendedCurveP_384, QString: []byte{4, 1, 2}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format.String(), func(t *testing.T) {
+			kb := KeyBlock{KeyFormatType: test.format, KeyValue: &KeyValue{KeyMaterial: test.material}}
+
+			decoded := roundTrip(t, kb)
+			assert.Equal(t, test.material, decoded.KeyValue.KeyMaterial)
+		})
+	}
+
+	t.Run("extension", func(t *testing.T) {
+		type vendorKey struct {
+			Key       []byte
+			Algorithm kmip14.CryptographicAlgorithm
+		}
+
+		format := kmip14.KeyFormatType(0x80000042)
+
+		kb := KeyBlock{
+			KeyFormatType:          format,
+			CryptographicAlgorithm: kmip14.CryptographicAlgorithmAES,
+			KeyValue: &KeyValue{KeyMaterial: ttlv.Value{Tag: kmip14.TagKeyMaterial, Value: ttlv.Values{
+				ttlv.Value{Tag: kmip14.TagKey, Value: []byte{1, 2, 3}},
+			}}},
+		}
+
+		// unregistered extensions are left undecoded
+		_, ok := roundTrip(t, kb).KeyValue.KeyMaterial.(ttlv.TTLV)
+		assert.True(t, ok)
+
+		RegisterKeyMaterialType(format, func(alg kmip14.CryptographicAlgorithm) interface{} {
+			return &vendorKey{Algorithm: alg}
+		})
+		defer RegisterKeyMaterialType(format, nil)
+
+		assert.Equal(t, &vendorKey{Key: []byte{1, 2, 3}, Algorithm: kmip14.CryptographicAlgorithmAES}, roundTrip(t, kb).KeyValue.KeyMaterial)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		kb := KeyBlock{
+			KeyFormatType: kmip14.KeyFormatTypeTransparentRSAPublicKey,
+			KeyValue: &KeyValue{KeyMaterial: ttlv.Value{Tag: kmip14.TagKeyMaterial, Value: ttlv.Values{
+				ttlv.Value{Tag: kmip14.TagModulus, Value: "not an integer"},
+			}}},
+		}
+
+		b, err := ttlv.Marshal(kb)
+		require.NoError(t, err)
+
+		var decoded KeyBlock
+		require.Error(t, ttlv.Unmarshal(b, &decoded))
+	})
+}
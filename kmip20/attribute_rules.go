@@ -0,0 +1,53 @@
+package kmip20
+
+import (
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// 4 Object Attributes
+//
+// KMIP 2.0 removed the deprecated 1.x certificate attributes, the Operation Policy Name, and Custom Attributes,
+// which are replaced by Vendor Attributes.  It added attributes, like the Short Unique Identifier and the
+// Protection Storage Masks.  The rules for the attributes common to both versions are unchanged.
+
+// AttributeRules are the attribute rules from section 4 of the 2.0 spec.  Use them as the AttributeRules of
+// kmip.StoreHandlers serving 2.0 requests.
+var AttributeRules = newAttributeRules()
+
+func newAttributeRules() kmip.AttributeRules {
+	rules := kmip.AttributeRules{}
+
+	for tag, rule := range kmip.AttributeRules14 {
+		rules[tag] = rule
+	}
+
+	for _, tag := range []ttlv.Tag{
+		kmip14.TagCertificateIdentifier,
+		kmip14.TagCertificateSubject,
+		kmip14.TagCertificateIssuer,
+		kmip14.TagOperationPolicyName,
+		kmip14.TagCustomAttribute,
+	} {
+		delete(rules, tag)
+	}
+
+	keys := []kmip14.ObjectType{
+		kmip14.ObjectTypeSymmetricKey,
+		kmip14.ObjectTypePublicKey,
+		kmip14.ObjectTypePrivateKey,
+		kmip14.ObjectTypeSplitKey,
+		kmip14.ObjectTypePGPKey,
+	}
+
+	rules[TagShortUniqueIdentifier] = kmip.AttributeRule{ServerOnly: true}
+	rules[kmip14.TagKeyFormatType] = kmip.AttributeRule{ServerOnly: true, ObjectTypes: keys}
+	rules[TagNISTKeyType] = kmip.AttributeRule{ObjectTypes: keys}
+	rules[TagProtectionLevel] = kmip.AttributeRule{ClientModifiable: true, ClientDeletable: true}
+	rules[TagProtectionPeriod] = kmip.AttributeRule{ClientModifiable: true, ClientDeletable: true}
+	rules[TagQuantumSafe] = kmip.AttributeRule{}
+	rules[TagProtectionStorageMasks] = kmip.AttributeRule{ServerOnly: true}
+
+	return rules
+}
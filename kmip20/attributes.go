@@ -0,0 +1,131 @@
+package kmip20
+
+import (
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// KMIP 2.0 replaced the Attribute structure, which held the name, index and value of an attribute, with
+// attributes encoded directly as tagged values in an Attributes structure.  DecodeAttributes and EncodeAttributes
+// convert between the 2.0 form and the []kmip.Attribute form used by the kmip package, so that code which
+// manages attributes can be shared by both protocol versions.
+
+// DecodeAttributes converts a 2.0 Attributes structure to a list of attributes.  v is the value of an
+// Attributes field decoded into an interface{}, i.e. a ttlv.TTLV.  A nil v returns no attributes.
+//
+// Attribute Indexes are assigned in the order attributes with the same tag appear in the structure.  Attribute
+// values which are structures are held as ttlv.TTLV, as they would be when decoded from a 1.x Attribute.
+func DecodeAttributes(v interface{}) ([]kmip.Attribute, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	t, ok := v.(ttlv.TTLV)
+	if !ok {
+		var err error
+
+		t, err = ttlv.Marshal(ttlv.Value{Tag: TagAttributes, Value: v})
+		if err != nil {
+			return nil, merry.Prepend(err, "encoding attributes")
+		}
+	}
+
+	if t.Type() != ttlv.TypeStructure {
+		return nil, merry.Errorf("invalid type for Attributes: %s", t.Type().String())
+	}
+
+	var attrs []kmip.Attribute
+
+	indexes := map[ttlv.Tag]int{}
+
+	for child := t.ValueStructure(); len(child) > 0; child = child.Next() {
+		if err := child.Valid(); err != nil {
+			return nil, merry.Prepend(err, "invalid attribute")
+		}
+
+		tag := child.Tag()
+
+		var value interface{}
+		if child.Type() == ttlv.TypeStructure {
+			value = retag(child, kmip14.TagAttributeValue)
+		} else {
+			value = child.Value()
+		}
+
+		attrs = append(attrs, kmip.NewAttributeFromTag(tag, indexes[tag], value))
+		indexes[tag]++
+	}
+
+	return attrs, nil
+}
+
+// EncodeAttributes converts a list of attributes to a 2.0 Attributes structure.  Attributes are encoded in
+// the order of the list.  Attribute names must be the canonical names of registered tags.
+func EncodeAttributes(attrs []kmip.Attribute) (ttlv.Value, error) {
+	values := make(ttlv.Values, 0, len(attrs))
+
+	for _, attr := range attrs {
+		tag, err := ttlv.DefaultRegistry.ParseTag(attr.AttributeName)
+		if err != nil {
+			return ttlv.Value{}, merry.Prependf(err, "unknown attribute %q", attr.AttributeName)
+		}
+
+		value := attr.AttributeValue
+		if t, ok := value.(ttlv.TTLV); ok {
+			value = retag(t, tag)
+		}
+
+		values = append(values, ttlv.Value{Tag: tag, Value: value})
+	}
+
+	return ttlv.Value{Tag: TagAttributes, Value: values}, nil
+}
+
+// DecodeAttribute converts a structure holding a single attribute, like a New Attribute or Current Attribute,
+// to an attribute.  It returns an error if the structure doesn't hold exactly one attribute.
+func DecodeAttribute(v interface{}) (kmip.Attribute, error) {
+	attrs, err := DecodeAttributes(v)
+	if err != nil {
+		return kmip.Attribute{}, err
+	}
+
+	if len(attrs) != 1 {
+		return kmip.Attribute{}, merry.Errorf("expected a single attribute, got %d", len(attrs))
+	}
+
+	return attrs[0], nil
+}
+
+// EncodeAttribute converts an attribute to a structure with the tag, like TagNewAttribute, holding the
+// attribute's value.
+func EncodeAttribute(tag ttlv.Tag, attr kmip.Attribute) (ttlv.Value, error) {
+	v, err := EncodeAttributes([]kmip.Attribute{attr})
+	if err != nil {
+		return ttlv.Value{}, err
+	}
+
+	v.Tag = tag
+
+	return v, nil
+}
+
+// retag returns a copy of the TTLV value with a different tag.
+func retag(t ttlv.TTLV, tag ttlv.Tag) ttlv.TTLV {
+	b := make(ttlv.TTLV, t.FullLen())
+	copy(b, t)
+	b[0], b[1], b[2] = byte(tag>>16), byte(tag>>8), byte(tag)
+
+	return b
+}
+
+// resolveUniqueIdentifier substitutes the ID Placeholder for a Unique Identifier which was omitted from
+// a request, or which refers to the ID Placeholder.
+func resolveUniqueIdentifier(u *UniqueIdentifierValue, req *kmip.Request) *UniqueIdentifierValue {
+	if u == nil || (u.Text == "" && u.Index == 0 && (u.Enum == 0 || u.Enum == UniqueIdentifierIDPlaceholder)) {
+		return &UniqueIdentifierValue{Text: req.IDPlaceholder}
+	}
+
+	return u
+}
@@ -0,0 +1,38 @@
+package kmip20
+
+import (
+	"errors"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// ErrorHandler maps errors to result reasons, like kmip.DefaultErrorHandler, but uses the result reasons added
+// in 2.0 where the kmip package falls back on a 1.x reason.  Use it as the ErrorHandler of OperationMuxes
+// serving 2.0 requests.
+var ErrorHandler = kmip.ErrorHandlerFunc(func(err error) *kmip.ResponseBatchItem {
+	switch {
+	case errors.Is(err, kmip.ErrWrongKeyLifecycleState):
+		err = kmip.WithResultReason(err, kmip14.ResultReason(ResultReasonWrongKeyLifecycleState))
+	case errors.Is(err, kmip.ErrIncompatibleCryptographicUsageMask):
+		err = kmip.WithResultReason(err, kmip14.ResultReason(ResultReasonIncompatibleCryptographicUsageMask))
+	case errors.Is(err, kmip.ErrInvalidCorrelationValue):
+		err = kmip.WithResultReason(err, kmip14.ResultReason(ResultReasonInvalidCorrelationValue))
+	case errors.Is(err, kmip.ErrTooManyStreams), errors.Is(err, kmip.ErrStreamBufferFull):
+		err = kmip.WithResultReason(err, kmip14.ResultReason(ResultReasonServerLimitExceeded))
+	case errors.Is(err, kmip.ErrUnsupportedAttribute):
+		err = kmip.WithResultReason(err, kmip14.ResultReason(ResultReasonUnsupportedAttribute))
+	case errors.Is(err, kmip.ErrAttributeNotApplicable):
+		err = kmip.WithResultReason(err, kmip14.ResultReason(ResultReasonInvalidAttribute))
+	case errors.Is(err, kmip.ErrAttributeReadOnly):
+		err = kmip.WithResultReason(err, kmip14.ResultReason(ResultReasonAttributeReadOnly))
+	case errors.Is(err, kmip.ErrAttributeSingleValued):
+		err = kmip.WithResultReason(err, kmip14.ResultReason(ResultReasonAttributeSingleValued))
+	case errors.Is(err, kmip.ErrAttributeNotFound):
+		err = kmip.WithResultReason(err, kmip14.ResultReason(ResultReasonAttributeNotFound))
+	case errors.Is(err, kmip.ErrAttributeInstanceNotFound):
+		err = kmip.WithResultReason(err, kmip14.ResultReason(ResultReasonAttributeInstanceNotFound))
+	}
+
+	return kmip.DefaultErrorHandler.HandleError(err)
+})
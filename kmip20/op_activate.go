@@ -33,6 +33,8 @@ func (h *ActivateHandler) HandleItem(ctx context.Context, req *kmip.Request) (*k
 		return nil, err
 	}
 
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
 	respPayload, err := h.Activate(ctx, &payload)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,48 @@
+//nolint:dupl
+package kmip20
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go"
+)
+
+// 6.1.2 Add Attribute
+//
+// This operation requests the server to add a new attribute instance to be associated with a Managed Object
+// and set its value.  The New Attribute is a structure holding the attribute, e.g.:
+//
+//	payload.NewAttribute, err = kmip20.EncodeAttribute(kmip20.TagNewAttribute, attr)
+
+type AddAttributeRequestPayload struct {
+	UniqueIdentifier *UniqueIdentifierValue
+	NewAttribute     interface{}
+}
+
+type AddAttributeResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type AddAttributeHandler struct {
+	AddAttribute func(ctx context.Context, payload *AddAttributeRequestPayload) (*AddAttributeResponsePayload, error)
+}
+
+func (h *AddAttributeHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload AddAttributeRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
+	respPayload, err := h.AddAttribute(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
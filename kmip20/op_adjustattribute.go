@@ -0,0 +1,172 @@
+//nolint:dupl
+package kmip20
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// 6.1.3 Adjust Attribute
+//
+// This operation requests the server to adjust the value of a single instance attribute associated with a
+// Managed Object, by incrementing, decrementing, or negating it.  See AdjustAttributeValue.
+
+type AdjustAttributeRequestPayload struct {
+	UniqueIdentifier   *UniqueIdentifierValue
+	AttributeReference AttributeReference
+	AdjustmentType     AdjustmentType
+	AdjustmentValue    interface{} `ttlv:",omitempty"`
+}
+
+type AdjustAttributeResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type AdjustAttributeHandler struct {
+	AdjustAttribute func(ctx context.Context, payload *AdjustAttributeRequestPayload) (*AdjustAttributeResponsePayload, error)
+}
+
+func (h *AdjustAttributeHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload AdjustAttributeRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
+	respPayload, err := h.AdjustAttribute(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
+
+// AdjustAttributeValue returns the result of adjusting an attribute value.  Integer, Long Integer, Big Integer
+// and Interval values may be incremented, decremented, or negated.  Date-Time values may be incremented or
+// decremented.  The adjustment value is an integer or an interval; date-times and intervals are adjusted by
+// that many seconds.  If the adjustment value is nil, values are incremented or decremented by 1.
+//
+// The result has the same type as the value as it would be decoded from TTLV, e.g. an int32 for an Integer.
+// Returns an error with the Invalid Data Type result reason if the value or adjustment value can't be
+// adjusted, or with the Numeric Range result reason if the result is out of range for the value's type.
+func AdjustAttributeValue(value interface{}, adjustmentType AdjustmentType, adjustmentValue interface{}) (interface{}, error) {
+	t, err := ttlv.Marshal(ttlv.Value{Tag: kmip14.TagAttributeValue, Value: value})
+	if err != nil {
+		return nil, kmip.WithResultReason(merry.Prepend(err, "invalid attribute value"), kmip14.ResultReason(ResultReasonInvalidDataType))
+	}
+
+	amount := big.NewInt(1)
+
+	if adjustmentValue != nil {
+		amount, err = adjustmentAmount(adjustmentValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var n *big.Int
+
+	switch t.Type() {
+	case ttlv.TypeInteger:
+		n = big.NewInt(int64(t.ValueInteger()))
+	case ttlv.TypeLongInteger:
+		n = big.NewInt(t.ValueLongInteger())
+	case ttlv.TypeBigInteger:
+		n = t.ValueBigInteger()
+	case ttlv.TypeInterval:
+		n = big.NewInt(int64(t.ValueInterval() / time.Second))
+	case ttlv.TypeDateTime:
+		if adjustmentType == AdjustmentTypeNegate {
+			return nil, kmip.WithResultReason(merry.UserError("Date-Time values can't be negated"), kmip14.ResultReason(ResultReasonInvalidDataType))
+		}
+
+		n = big.NewInt(t.ValueDateTime().Unix())
+	default:
+		return nil, kmip.WithResultReason(merry.UserErrorf("%s values can't be adjusted", t.Type().String()), kmip14.ResultReason(ResultReasonInvalidDataType))
+	}
+
+	switch adjustmentType {
+	case AdjustmentTypeIncrement:
+		n.Add(n, amount)
+	case AdjustmentTypeDecrement:
+		n.Sub(n, amount)
+	case AdjustmentTypeNegate:
+		n.Neg(n)
+	default:
+		return nil, kmip.WithResultReason(merry.UserErrorf("unsupported Adjustment Type: %s", adjustmentType.String()), kmip14.ResultReasonInvalidField)
+	}
+
+	outOfRange := func(min, max int64) bool {
+		return n.Cmp(big.NewInt(min)) < 0 || n.Cmp(big.NewInt(max)) > 0
+	}
+
+	var result interface{}
+
+	switch t.Type() {
+	case ttlv.TypeInteger:
+		if outOfRange(math.MinInt32, math.MaxInt32) {
+			return nil, numericRangeError(n)
+		}
+
+		result = int32(n.Int64())
+	case ttlv.TypeLongInteger:
+		if !n.IsInt64() {
+			return nil, numericRangeError(n)
+		}
+
+		result = n.Int64()
+	case ttlv.TypeBigInteger:
+		result = n
+	case ttlv.TypeInterval:
+		if outOfRange(0, math.MaxUint32) {
+			return nil, numericRangeError(n)
+		}
+
+		result = time.Duration(n.Int64()) * time.Second
+	case ttlv.TypeDateTime:
+		if !n.IsInt64() {
+			return nil, numericRangeError(n)
+		}
+
+		result = time.Unix(n.Int64(), 0).In(t.ValueDateTime().Location())
+	}
+
+	return result, nil
+}
+
+// adjustmentAmount returns the amount of an Adjustment Value, which may be an integer or an interval.
+func adjustmentAmount(v interface{}) (*big.Int, error) {
+	t, err := ttlv.Marshal(ttlv.Value{Tag: TagAdjustmentValue, Value: v})
+	if err != nil {
+		return nil, kmip.WithResultReason(merry.Prepend(err, "invalid Adjustment Value"), kmip14.ResultReason(ResultReasonInvalidDataType))
+	}
+
+	switch t.Type() {
+	case ttlv.TypeInteger:
+		return big.NewInt(int64(t.ValueInteger())), nil
+	case ttlv.TypeLongInteger:
+		return big.NewInt(t.ValueLongInteger()), nil
+	case ttlv.TypeBigInteger:
+		return t.ValueBigInteger(), nil
+	case ttlv.TypeInterval:
+		return big.NewInt(int64(t.ValueInterval() / time.Second)), nil
+	}
+
+	return nil, kmip.WithResultReason(merry.UserErrorf("invalid type for Adjustment Value: %s", t.Type().String()), kmip14.ResultReason(ResultReasonInvalidDataType))
+}
+
+func numericRangeError(n *big.Int) error {
+	return kmip.WithResultReason(merry.UserErrorf("adjusted value %s is out of range", n.String()), kmip14.ResultReason(ResultReasonNumericRange))
+}
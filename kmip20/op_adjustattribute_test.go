@@ -0,0 +1,66 @@
+package kmip20
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustAttributeValue(t *testing.T) {
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		value           interface{}
+		adjustmentType  AdjustmentType
+		adjustmentValue interface{}
+		expected        interface{}
+		reason          ResultReason
+	}{
+		{name: "increment integer", value: 5, adjustmentType: AdjustmentTypeIncrement, expected: int32(6)},
+		{name: "decrement integer", value: int32(5), adjustmentType: AdjustmentTypeDecrement, adjustmentValue: 10, expected: int32(-5)},
+		{name: "negate long integer", value: int64(5), adjustmentType: AdjustmentTypeNegate, expected: int64(-5)},
+		{name: "increment big integer", value: big.NewInt(math.MaxInt64), adjustmentType: AdjustmentTypeIncrement, expected: new(big.Int).Add(big.NewInt(math.MaxInt64), big.NewInt(1))},
+		{name: "increment interval", value: time.Minute, adjustmentType: AdjustmentTypeIncrement, adjustmentValue: time.Minute, expected: 2 * time.Minute},
+		{name: "increment date", value: date, adjustmentType: AdjustmentTypeIncrement, adjustmentValue: time.Hour, expected: date.Add(time.Hour)},
+		{name: "decrement date", value: date, adjustmentType: AdjustmentTypeDecrement, adjustmentValue: 60, expected: date.Add(-time.Minute)},
+		{name: "negate date", value: date, adjustmentType: AdjustmentTypeNegate, reason: ResultReasonInvalidDataType},
+		{name: "negate interval", value: time.Minute, adjustmentType: AdjustmentTypeNegate, reason: ResultReasonNumericRange},
+		{name: "integer overflow", value: int32(math.MaxInt32), adjustmentType: AdjustmentTypeIncrement, reason: ResultReasonNumericRange},
+		{name: "text string", value: "text", adjustmentType: AdjustmentTypeIncrement, reason: ResultReasonInvalidDataType},
+		{name: "text adjustment value", value: 5, adjustmentType: AdjustmentTypeIncrement, adjustmentValue: "text", reason: ResultReasonInvalidDataType},
+		{name: "decoded integer", value: decoded(t, 5), adjustmentType: AdjustmentTypeIncrement, expected: int32(6)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := AdjustAttributeValue(test.value, test.adjustmentType, test.adjustmentValue)
+			if test.reason != 0 {
+				require.Error(t, err)
+				assert.Equal(t, kmip14.ResultReason(test.reason), kmip.GetResultReason(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+// decoded returns the value as it would be held after decoding it from a message.
+func decoded(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+
+	b, err := ttlv.Marshal(ttlv.NewValue(kmip14.TagAttributeValue, v))
+	require.NoError(t, err)
+
+	return ttlv.TTLV(b)
+}
@@ -0,0 +1,99 @@
+package kmip20
+
+import (
+	"context"
+	"time"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 6.1.6 Certify
+//
+// The Certificate Request is either passed by value, in the Certificate Request Value, or refers to a Certificate
+// Request object, by its Certificate Request Unique Identifier.  The Unique Identifier of the Public Key defaults
+// to the ID Placeholder, which is set to the certificate.
+
+type CertifyRequestPayload struct {
+	UniqueIdentifier                   *UniqueIdentifierValue        `ttlv:",omitempty"`
+	CertificateRequestUniqueIdentifier string                        `ttlv:",omitempty"`
+	CertificateRequestType             kmip14.CertificateRequestType `ttlv:",omitempty"`
+	CertificateRequestValue            []byte                        `ttlv:",omitempty"`
+	Attributes                         interface{}                   `ttlv:",omitempty"`
+	ProtectionStorageMasks             ProtectionStorageMask         `ttlv:",omitempty"`
+}
+
+type CertifyResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type CertifyHandler struct {
+	Certify func(ctx context.Context, payload *CertifyRequestPayload) (*CertifyResponsePayload, error)
+}
+
+func (h *CertifyHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload CertifyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
+	respPayload, err := h.Certify(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
+
+// 6.1.41 Re-certify
+//
+// The Unique Identifier of the existing certificate defaults to the ID Placeholder, which is set to the new
+// certificate.
+
+type ReCertifyRequestPayload struct {
+	UniqueIdentifier                   *UniqueIdentifierValue        `ttlv:",omitempty"`
+	CertificateRequestUniqueIdentifier string                        `ttlv:",omitempty"`
+	CertificateRequestType             kmip14.CertificateRequestType `ttlv:",omitempty"`
+	CertificateRequestValue            []byte                        `ttlv:",omitempty"`
+	Offset                             *time.Duration                `ttlv:",omitempty"`
+	Attributes                         interface{}                   `ttlv:",omitempty"`
+	ProtectionStorageMasks             ProtectionStorageMask         `ttlv:",omitempty"`
+}
+
+type ReCertifyResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type ReCertifyHandler struct {
+	ReCertify func(ctx context.Context, payload *ReCertifyRequestPayload) (*ReCertifyResponsePayload, error)
+}
+
+func (h *ReCertifyHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload ReCertifyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
+	respPayload, err := h.ReCertify(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package kmip20
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go"
+)
+
+// 6.1.8 Create
+//
+// The payloads are defined in payloads.go.
+
+type CreateHandler struct {
+	Create func(ctx context.Context, payload *CreateRequestPayload) (*CreateResponsePayload, error)
+}
+
+func (h *CreateHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload CreateRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.Create(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
+
+// 6.1.9 Create Key Pair
+//
+// The payloads are defined in payloads.go.
+
+type CreateKeyPairHandler struct {
+	CreateKeyPair func(ctx context.Context, payload *CreateKeyPairRequestPayload) (*CreateKeyPairResponsePayload, error)
+}
+
+func (h *CreateKeyPairHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload CreateKeyPairRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.CreateKeyPair(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ID Placeholder is set to the private key
+	req.IDPlaceholder = respPayload.PrivateKeyUniqueIdentifier
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
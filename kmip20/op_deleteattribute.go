@@ -0,0 +1,48 @@
+//nolint:dupl
+package kmip20
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go"
+)
+
+// 6.1.13 Delete Attribute
+//
+// This operation requests the server to delete an attribute associated with a Managed Object.  Either the
+// Current Attribute, which identifies a single instance by its value, or the Attribute Reference, which
+// identifies all the instances of the attribute, should be given.
+
+type DeleteAttributeRequestPayload struct {
+	UniqueIdentifier   *UniqueIdentifierValue
+	CurrentAttribute   interface{}         `ttlv:",omitempty"`
+	AttributeReference *AttributeReference `ttlv:",omitempty"`
+}
+
+type DeleteAttributeResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type DeleteAttributeHandler struct {
+	DeleteAttribute func(ctx context.Context, payload *DeleteAttributeRequestPayload) (*DeleteAttributeResponsePayload, error)
+}
+
+func (h *DeleteAttributeHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload DeleteAttributeRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
+	respPayload, err := h.DeleteAttribute(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
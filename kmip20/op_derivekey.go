@@ -0,0 +1,57 @@
+package kmip20
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go"
+)
+
+// 6.1.14 Derive Key
+//
+// The Attributes replace the Template-Attribute of 1.x.  The Unique Identifiers of the base objects default to the
+// ID Placeholder, which is set to the derived object.
+
+type DeriveKeyRequestPayload struct {
+	ObjectType             ObjectType
+	UniqueIdentifier       []UniqueIdentifierValue `ttlv:",omitempty"`
+	DerivationMethod       DerivationMethod
+	DerivationParameters   kmip.DerivationParameters
+	Attributes             interface{}
+	ProtectionStorageMasks ProtectionStorageMask `ttlv:",omitempty"`
+}
+
+type DeriveKeyResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type DeriveKeyHandler struct {
+	DeriveKey func(ctx context.Context, payload *DeriveKeyRequestPayload) (*DeriveKeyResponsePayload, error)
+}
+
+func (h *DeriveKeyHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload DeriveKeyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload.UniqueIdentifier) == 0 {
+		payload.UniqueIdentifier = []UniqueIdentifierValue{{}}
+	}
+
+	for i := range payload.UniqueIdentifier {
+		payload.UniqueIdentifier[i] = *resolveUniqueIdentifier(&payload.UniqueIdentifier[i], req)
+	}
+
+	respPayload, err := h.DeriveKey(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
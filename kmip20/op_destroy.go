@@ -33,6 +33,8 @@ func (h *DestroyHandler) HandleItem(ctx context.Context, req *kmip.Request) (*km
 		return nil, err
 	}
 
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
 	respPayload, err := h.Destroy(ctx, &payload)
 	if err != nil {
 		return nil, err
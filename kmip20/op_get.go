@@ -9,14 +9,29 @@ import (
 
 // GetRequestPayload ////////////////////////////////////////
 type GetRequestPayload struct {
-	UniqueIdentifier *UniqueIdentifierValue
+	UniqueIdentifier         *UniqueIdentifierValue
+	KeyFormatType            kmip14.KeyFormatType           `ttlv:",omitempty"`
+	KeyWrapType              kmip14.KeyWrapType             `ttlv:",omitempty"`
+	KeyCompressionType       kmip14.KeyCompressionType      `ttlv:",omitempty"`
+	KeyWrappingSpecification *kmip.KeyWrappingSpecification `ttlv:",omitempty"`
 }
 
 // GetResponsePayload
+//
+// The response holds the object in the field matching its Object Type.  These fields replace
+// the Key field of earlier versions of this package, which could only hold a symmetric key:
+// use SymmetricKey instead.
 type GetResponsePayload struct {
 	ObjectType       kmip14.ObjectType
 	UniqueIdentifier string
-	Key              kmip.SymmetricKey
+	Certificate      *kmip.Certificate
+	SymmetricKey     *kmip.SymmetricKey
+	PrivateKey       *kmip.PrivateKey
+	PublicKey        *kmip.PublicKey
+	SplitKey         *kmip.SplitKey
+	SecretData       *kmip.SecretData
+	OpaqueObject     *kmip.OpaqueObject
+	PGPKey           *kmip.PGPKey
 }
 
 type GetHandler struct {
@@ -31,6 +46,8 @@ func (h *GetHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.R
 		return nil, err
 	}
 
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
 	respPayload, err := h.Get(ctx, &payload)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,46 @@
+//nolint:dupl
+package kmip20
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go"
+)
+
+// 6.1.20 Get Attribute List
+//
+// This operation requests a list of the attribute names associated with a Managed Object.  In 2.0, the
+// attributes are identified by Attribute References rather than names.
+
+type GetAttributeListRequestPayload struct {
+	UniqueIdentifier *UniqueIdentifierValue
+}
+
+type GetAttributeListResponsePayload struct {
+	UniqueIdentifier   string
+	AttributeReference []AttributeReference
+}
+
+type GetAttributeListHandler struct {
+	GetAttributeList func(ctx context.Context, payload *GetAttributeListRequestPayload) (*GetAttributeListResponsePayload, error)
+}
+
+func (h *GetAttributeListHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload GetAttributeListRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
+	respPayload, err := h.GetAttributeList(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package kmip20
+
+import (
+	"context"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// 6.1.19 Get Attributes
+
+// AttributeReference identifies an attribute by its tag.  It's encoded as an Enumeration holding
+// the tag value.  The structure form of Attribute Reference, which names vendor attributes, isn't
+// supported.
+type AttributeReference ttlv.Tag
+
+func (a AttributeReference) MarshalTTLV(e *ttlv.Encoder, tag ttlv.Tag) error {
+	e.EncodeEnumeration(tag, uint32(a))
+	return nil
+}
+
+func (a *AttributeReference) UnmarshalTTLV(_ *ttlv.Decoder, v ttlv.TTLV) error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	if v.Type() != ttlv.TypeEnumeration {
+		return merry.Errorf("unsupported type for AttributeReference: %s", v.Type().String())
+	}
+
+	*a = AttributeReference(v.ValueEnumeration())
+
+	return nil
+}
+
+type GetAttributesRequestPayload struct {
+	UniqueIdentifier   *UniqueIdentifierValue
+	AttributeReference []AttributeReference
+}
+
+type GetAttributesResponsePayload struct {
+	UniqueIdentifier string
+	Attributes       interface{}
+}
+
+type GetAttributesHandler struct {
+	GetAttributes func(ctx context.Context, payload *GetAttributesRequestPayload) (*GetAttributesResponsePayload, error)
+}
+
+func (h *GetAttributesHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload GetAttributesRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
+	respPayload, err := h.GetAttributes(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -4,20 +4,30 @@ import (
 	"context"
 
 	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
 )
 
 // 6.1.27 Locate
 
+// StorageStatusMaskDestroyedStorage is the Storage Status Mask bit, added in 2.0, which selects objects which
+// have been destroyed, but whose attributes are retained.
+const StorageStatusMaskDestroyedStorage kmip14.StorageStatusMask = 0x00000004
+
 // Table 229
 
 type LocateRequestPayload struct {
-	Attributes interface{}
+	MaximumItems      int                      `ttlv:",omitempty"`
+	OffsetItems       int                      `ttlv:",omitempty"`
+	StorageStatusMask kmip14.StorageStatusMask `ttlv:",omitempty"`
+	ObjectGroupMember kmip14.ObjectGroupMember `ttlv:",omitempty"`
+	Attributes        interface{}
 }
 
 // Table 230
 
 type LocateResponsePayload struct {
-	UniqueIdentifier string
+	LocatedItems     int `ttlv:",omitempty"`
+	UniqueIdentifier []string
 }
 
 type LocateHandler struct {
@@ -37,6 +47,10 @@ func (h *LocateHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmi
 		return nil, err
 	}
 
+	if len(respPayload.UniqueIdentifier) == 1 {
+		req.IDPlaceholder = respPayload.UniqueIdentifier[0]
+	}
+
 	return &kmip.ResponseBatchItem{
 		ResponsePayload: respPayload,
 	}, nil
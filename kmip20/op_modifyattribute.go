@@ -0,0 +1,48 @@
+//nolint:dupl
+package kmip20
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go"
+)
+
+// 6.1.33 Modify Attribute
+//
+// This operation requests the server to modify the value of an existing attribute instance associated with a
+// Managed Object.  The Current Attribute, if present, identifies the instance to modify by its value.  If it's
+// omitted, the attribute must have a single instance.  The New Attribute holds the new value.
+
+type ModifyAttributeRequestPayload struct {
+	UniqueIdentifier *UniqueIdentifierValue
+	CurrentAttribute interface{} `ttlv:",omitempty"`
+	NewAttribute     interface{}
+}
+
+type ModifyAttributeResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type ModifyAttributeHandler struct {
+	ModifyAttribute func(ctx context.Context, payload *ModifyAttributeRequestPayload) (*ModifyAttributeResponsePayload, error)
+}
+
+func (h *ModifyAttributeHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload ModifyAttributeRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
+	respPayload, err := h.ModifyAttribute(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -59,13 +59,13 @@ type ObjectDefaults struct {
 // combination of the remaining fields within the RNG Parameters structure.
 type RNGParameters struct {
 	RNGAlgorithm           kmip14.RNGAlgorithm           // Required: Yes
-	CryptographicAlgorithm kmip14.CryptographicAlgorithm // Required: No
-	CryptographicLength    int                           // Required: No
-	HashingAlgorithm       kmip14.HashingAlgorithm       // Required: No
-	DRBGAlgorithm          kmip14.DRBGAlgorithm          // Required: No
-	RecommendedCurve       kmip14.RecommendedCurve       // Required: No
-	FIPS186Variation       kmip14.FIPS186Variation       // Required: No
-	PredictionResistance   bool                          // Required: No
+	CryptographicAlgorithm kmip14.CryptographicAlgorithm `ttlv:",omitempty"` // Required: No
+	CryptographicLength    int                           `ttlv:",omitempty"` // Required: No
+	HashingAlgorithm       kmip14.HashingAlgorithm       `ttlv:",omitempty"` // Required: No
+	DRBGAlgorithm          kmip14.DRBGAlgorithm          `ttlv:",omitempty"` // Required: No
+	RecommendedCurve       kmip14.RecommendedCurve       `ttlv:",omitempty"` // Required: No
+	FIPS186Variation       kmip14.FIPS186Variation       `ttlv:",omitempty"` // Required: No
+	PredictionResistance   bool                          `ttlv:",omitempty"` // Required: No
 }
 
 // 7.31 Server Information
@@ -89,8 +89,10 @@ type ServerInformation struct {
 
 // Table 259
 
+// QueryRequestPayload holds the Query Functions, which may be repeated.  QueryFunction was a single
+// value in earlier versions of this package.
 type QueryRequestPayload struct {
-	QueryFunction QueryFunction
+	QueryFunction []QueryFunction
 }
 
 // Table 260
@@ -98,16 +100,16 @@ type QueryRequestPayload struct {
 type QueryResponsePayload struct {
 	Operation                []kmip14.Operation
 	ObjectType               []ObjectType
-	VendorIdentification     string
+	VendorIdentification     string `ttlv:",omitempty"`
 	ServerInformation        []ServerInformation
 	ApplicationNamespace     []string
 	ExtensionInformation     []ExtensionInformation
-	AttestationType          kmip14.AttestationType
+	AttestationType          kmip14.AttestationType `ttlv:",omitempty"`
 	RNGParameters            []RNGParameters
 	ProfileInformation       []ProfileName
 	ValidationInformation    []kmip14.ValidationAuthorityType
 	CapabilityInformation    []CapabilityInformation
-	ClientRegistrationMethod kmip14.ClientRegistrationMethod
+	ClientRegistrationMethod kmip14.ClientRegistrationMethod `ttlv:",omitempty"`
 	DefaultsInformation      *DefaultsInformation
 	ProtectionStorageMasks   []ProtectionStorageMask
 }
@@ -0,0 +1,52 @@
+package kmip20
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go"
+)
+
+// 6.1.39 Register
+
+// RegisterRequestPayload holds the object being registered in the field matching its Object Type.
+type RegisterRequestPayload struct {
+	ObjectType             ObjectType
+	Attributes             interface{}
+	Certificate            *kmip.Certificate     `ttlv:",omitempty"`
+	SymmetricKey           *kmip.SymmetricKey    `ttlv:",omitempty"`
+	PrivateKey             *kmip.PrivateKey      `ttlv:",omitempty"`
+	PublicKey              *kmip.PublicKey       `ttlv:",omitempty"`
+	SplitKey               *kmip.SplitKey        `ttlv:",omitempty"`
+	SecretData             *kmip.SecretData      `ttlv:",omitempty"`
+	OpaqueObject           *kmip.OpaqueObject    `ttlv:",omitempty"`
+	PGPKey                 *kmip.PGPKey          `ttlv:",omitempty"`
+	ProtectionStorageMasks ProtectionStorageMask `ttlv:",omitempty"`
+}
+
+type RegisterResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type RegisterHandler struct {
+	Register func(ctx context.Context, payload *RegisterRequestPayload) (*RegisterResponsePayload, error)
+}
+
+func (h *RegisterHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload RegisterRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.Register(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package kmip20
+
+import (
+	"context"
+	"time"
+
+	"github.com/gemalto/kmip-go"
+)
+
+// 6.1.42 Re-key
+//
+// The Attributes replace the Template-Attribute of 1.x.  The Unique Identifier defaults to the ID Placeholder,
+// which is set to the replacement key.
+
+type ReKeyRequestPayload struct {
+	UniqueIdentifier       *UniqueIdentifierValue
+	Offset                 *time.Duration        `ttlv:",omitempty"`
+	Attributes             interface{}           `ttlv:",omitempty"`
+	ProtectionStorageMasks ProtectionStorageMask `ttlv:",omitempty"`
+}
+
+type ReKeyResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type ReKeyHandler struct {
+	ReKey func(ctx context.Context, payload *ReKeyRequestPayload) (*ReKeyResponsePayload, error)
+}
+
+func (h *ReKeyHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload ReKeyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
+	respPayload, err := h.ReKey(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
+
+// 6.1.43 Re-key Key Pair
+//
+// The Private Key Unique Identifier defaults to the ID Placeholder, which is set to the replacement private key.
+
+type ReKeyKeyPairRequestPayload struct {
+	PrivateKeyUniqueIdentifier    string                `ttlv:",omitempty"`
+	Offset                        *time.Duration        `ttlv:",omitempty"`
+	CommonAttributes              interface{}           `ttlv:",omitempty"`
+	PrivateKeyAttributes          interface{}           `ttlv:",omitempty"`
+	PublicKeyAttributes           interface{}           `ttlv:",omitempty"`
+	CommonProtectionStorageMasks  ProtectionStorageMask `ttlv:",omitempty"`
+	PrivateProtectionStorageMasks ProtectionStorageMask `ttlv:",omitempty"`
+	PublicProtectionStorageMasks  ProtectionStorageMask `ttlv:",omitempty"`
+}
+
+type ReKeyKeyPairResponsePayload struct {
+	PrivateKeyUniqueIdentifier string
+	PublicKeyUniqueIdentifier  string
+}
+
+type ReKeyKeyPairHandler struct {
+	ReKeyKeyPair func(ctx context.Context, payload *ReKeyKeyPairRequestPayload) (*ReKeyKeyPairResponsePayload, error)
+}
+
+func (h *ReKeyKeyPairHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload ReKeyKeyPairRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.PrivateKeyUniqueIdentifier == "" {
+		payload.PrivateKeyUniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.ReKeyKeyPair(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.PrivateKeyUniqueIdentifier
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
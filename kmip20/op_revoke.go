@@ -40,6 +40,8 @@ func (h *RevokeHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmi
 		return nil, err
 	}
 
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
 	respPayload, err := h.Revoke(ctx, &payload)
 	if err != nil {
 		return nil, err
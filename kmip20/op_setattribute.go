@@ -12,7 +12,7 @@ import (
 
 type SetAttributeRequestPayload struct {
 	UniqueIdentifier *UniqueIdentifierValue
-	NewAttribute     Attributes `ttlv:"DerivationData"`
+	NewAttribute     interface{}
 }
 
 // Table 297
@@ -33,6 +33,8 @@ func (h *SetAttributeHandler) HandleItem(ctx context.Context, req *kmip.Request)
 		return nil, err
 	}
 
+	payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+
 	respPayload, err := h.SetAttribute(ctx, &payload)
 	if err != nil {
 		return nil, err
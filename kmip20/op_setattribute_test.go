@@ -0,0 +1,40 @@
+package kmip20
+
+import (
+	"testing"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The New Attribute was tagged as Derivation Data by mistake.
+func TestSetAttributeRequestPayload(t *testing.T) {
+	attr, err := EncodeAttribute(TagNewAttribute, kmip.NewAttributeFromTag(kmip14.TagContactInformation, 0, "admin"))
+	require.NoError(t, err)
+
+	out, err := ttlv.Marshal(ttlv.Value{Tag: kmip14.TagRequestPayload, Value: &SetAttributeRequestPayload{
+		UniqueIdentifier: &UniqueIdentifierValue{Text: "key1"},
+		NewAttribute:     attr,
+	}})
+	require.NoError(t, err)
+
+	expected, err := ttlv.Marshal(s(kmip14.TagRequestPayload,
+		v(kmip14.TagUniqueIdentifier, "key1"),
+		s(TagNewAttribute,
+			v(kmip14.TagContactInformation, "admin"),
+		),
+	))
+	require.NoError(t, err)
+
+	require.Equal(t, expected, out)
+
+	var p SetAttributeRequestPayload
+	require.NoError(t, ttlv.Unmarshal(expected, &p))
+
+	decodedAttr, err := DecodeAttribute(p.NewAttribute)
+	require.NoError(t, err)
+	assert.Equal(t, kmip.NewAttributeFromTag(kmip14.TagContactInformation, 0, "admin"), decodedAttr)
+}
@@ -0,0 +1,66 @@
+package kmip20
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go"
+)
+
+// 6.1.48 Set Endpoint Role
+//
+// This operation requests the server to set the role of the client endpoint.  A client which sets
+// its role to Server is registering to receive server-to-client operations, like Notify and Put, on
+// the connection.
+
+// Table 298
+
+type SetEndpointRoleRequestPayload struct {
+	EndpointRole EndpointRole
+}
+
+// Table 299
+
+type SetEndpointRoleResponsePayload struct {
+	EndpointRole EndpointRole
+}
+
+// SetEndpointRoleHandler handles Set Endpoint Role requests.  The endpoint role in the response is
+// recorded on the request's connection: if it is EndpointRoleServer, the server may send
+// server-to-client operations to the client (see kmip.Server.RequireEndpointRole).
+//
+// If SetEndpointRole is nil, the handler accepts the requested role.  Otherwise, the function may
+// reject the request by returning an error, or respond with a different role.  If it returns a nil
+// payload, the requested role is accepted.
+type SetEndpointRoleHandler struct {
+	SetEndpointRole func(ctx context.Context, payload *SetEndpointRoleRequestPayload) (*SetEndpointRoleResponsePayload, error)
+}
+
+func (h *SetEndpointRoleHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload SetEndpointRoleRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload := &SetEndpointRoleResponsePayload{EndpointRole: payload.EndpointRole}
+
+	if h.SetEndpointRole != nil {
+		respPayload, err = h.SetEndpointRole(ctx, &payload)
+		if err != nil {
+			return nil, err
+		}
+
+		if respPayload == nil {
+			respPayload = &SetEndpointRoleResponsePayload{EndpointRole: payload.EndpointRole}
+		}
+	}
+
+	if req.Conn != nil {
+		req.Conn.SetEndpointRole(uint32(respPayload.EndpointRole))
+	}
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
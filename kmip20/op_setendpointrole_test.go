@@ -0,0 +1,82 @@
+package kmip20
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetEndpointRoleHandler(t *testing.T) {
+	conns := make(chan *kmip.ServerConn, 1)
+
+	mux := &kmip.OperationMux{}
+	mux.Handle(kmip14.Operation(OperationSetEndpointRole), &SetEndpointRoleHandler{
+		SetEndpointRole: func(_ context.Context, _ *SetEndpointRoleRequestPayload) (*SetEndpointRoleResponsePayload, error) {
+			// a nil payload accepts the requested role
+			return nil, nil //nolint:nilnil
+		},
+	})
+	mux.Handle(kmip14.OperationDiscoverVersions, kmip.ItemHandlerFunc(func(_ context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+		conns <- req.Conn
+		return &kmip.ResponseBatchItem{ResponsePayload: kmip.DiscoverVersionsResponsePayload{}}, nil
+	}))
+
+	srv := &kmip.Server{
+		Handler: &kmip.StandardProtocolHandler{
+			MessageHandler:  mux,
+			ProtocolVersion: kmip.ProtocolVersion{ProtocolVersionMajor: 2},
+		},
+		RequireEndpointRole: true,
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	defer srv.Close()
+
+	client, err := kmip.Dial("tcp", l.Addr().String(), nil)
+	require.NoError(t, err)
+
+	defer client.Close()
+
+	client.ProtocolVersion = kmip.ProtocolVersion{ProtocolVersionMajor: 2}
+	client.Handle(kmip14.OperationNotify, &kmip.NotifyHandler{
+		Notify: func(_ context.Context, _ *kmip.NotifyRequestPayload) (*kmip.NotifyResponsePayload, error) {
+			return &kmip.NotifyResponsePayload{}, nil
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, client.Do(ctx, kmip14.OperationDiscoverVersions, kmip.DiscoverVersionsRequestPayload{}, nil))
+
+	sc := <-conns
+	require.NotNil(t, sc)
+
+	// clients must register before the server may send them operations
+	assert.False(t, sc.AcceptsServerOperations())
+	require.ErrorIs(t, sc.Notify(ctx, &kmip.NotifyRequestPayload{UniqueIdentifier: "key1"}), kmip.ErrEndpointRoleNotServer)
+
+	var resp SetEndpointRoleResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.Operation(OperationSetEndpointRole), SetEndpointRoleRequestPayload{EndpointRole: EndpointRoleServer}, &resp))
+	assert.Equal(t, EndpointRoleServer, resp.EndpointRole)
+	assert.True(t, sc.AcceptsServerOperations())
+	assert.Equal(t, uint32(EndpointRoleServer), sc.EndpointRole())
+	require.NoError(t, sc.Notify(ctx, &kmip.NotifyRequestPayload{UniqueIdentifier: "key1"}))
+
+	// switching back to the client role unregisters
+	require.NoError(t, client.Do(ctx, kmip14.Operation(OperationSetEndpointRole), SetEndpointRoleRequestPayload{EndpointRole: EndpointRoleClient}, &resp))
+	assert.False(t, sc.AcceptsServerOperations())
+	assert.Equal(t, uint32(EndpointRoleClient), sc.EndpointRole())
+}
@@ -0,0 +1,100 @@
+package kmip20
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 6.1.10 Create Split Key
+//
+// The Attributes replace the Template-Attribute of 1.x.  The Unique Identifier of the key to split is optional: if
+// it is omitted, a new key is generated, so it doesn't default to the ID Placeholder.  The ID Placeholder is left
+// unchanged.
+
+type CreateSplitKeyRequestPayload struct {
+	ObjectType             ObjectType
+	UniqueIdentifier       *UniqueIdentifierValue `ttlv:",omitempty"`
+	SplitKeyParts          int
+	SplitKeyThreshold      int
+	SplitKeyMethod         kmip14.SplitKeyMethod
+	PrimeFieldSize         *big.Int              `ttlv:",omitempty"`
+	Attributes             interface{}           `ttlv:",omitempty"`
+	ProtectionStorageMasks ProtectionStorageMask `ttlv:",omitempty"`
+}
+
+type CreateSplitKeyResponsePayload struct {
+	UniqueIdentifier []string
+}
+
+type CreateSplitKeyHandler struct {
+	CreateSplitKey func(ctx context.Context, payload *CreateSplitKeyRequestPayload) (*CreateSplitKeyResponsePayload, error)
+}
+
+func (h *CreateSplitKeyHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload CreateSplitKeyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.UniqueIdentifier != nil {
+		payload.UniqueIdentifier = resolveUniqueIdentifier(payload.UniqueIdentifier, req)
+	}
+
+	respPayload, err := h.CreateSplitKey(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
+
+// 6.1.26 Join Split Key
+//
+// The Attributes replace the Template-Attribute of 1.x.  The ID Placeholder is set to the joined object.
+
+type JoinSplitKeyRequestPayload struct {
+	ObjectType             ObjectType
+	UniqueIdentifier       []UniqueIdentifierValue
+	SecretDataType         kmip14.SecretDataType `ttlv:",omitempty"`
+	Attributes             interface{}           `ttlv:",omitempty"`
+	ProtectionStorageMasks ProtectionStorageMask `ttlv:",omitempty"`
+}
+
+type JoinSplitKeyResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type JoinSplitKeyHandler struct {
+	JoinSplitKey func(ctx context.Context, payload *JoinSplitKeyRequestPayload) (*JoinSplitKeyResponsePayload, error)
+}
+
+func (h *JoinSplitKeyHandler) HandleItem(ctx context.Context, req *kmip.Request) (*kmip.ResponseBatchItem, error) {
+	var payload JoinSplitKeyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range payload.UniqueIdentifier {
+		payload.UniqueIdentifier[i] = *resolveUniqueIdentifier(&payload.UniqueIdentifier[i], req)
+	}
+
+	respPayload, err := h.JoinSplitKey(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &kmip.ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
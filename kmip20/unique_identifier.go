@@ -22,7 +22,7 @@ func (u *UniqueIdentifierValue) UnmarshalTTLV(_ *ttlv.Decoder, v ttlv.TTLV) erro
 
 	switch v.Type() {
 	case ttlv.TypeTextString:
-		u.Text = v.String()
+		u.Text = v.ValueTextString()
 	case ttlv.TypeEnumeration:
 		u.Enum = UniqueIdentifier(v.ValueEnumeration())
 	case ttlv.TypeInteger:
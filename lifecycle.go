@@ -0,0 +1,291 @@
+package kmip
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// 3.22 State
+//
+// A Managed Cryptographic Object SHALL be in one of the following states: Pre-Active, Active, Deactivated,
+// Compromised, Destroyed, Destroyed Compromised.  The transitions between states are:
+//
+//	Pre-Active  -> Active                 Activate, or the Activation Date is reached
+//	Active      -> Deactivated            Revoke, or the Deactivation Date is reached
+//	Pre-Active  -> Destroyed              Destroy
+//	Pre-Active  -> Compromised            Revoke with a compromise reason
+//	Active      -> Compromised            Revoke with a compromise reason
+//	Deactivated -> Destroyed              Destroy
+//	Deactivated -> Compromised            Revoke with a compromise reason
+//	Compromised -> Destroyed Compromised  Destroy
+//	Destroyed   -> Destroyed Compromised  Revoke with a compromise reason
+
+// ErrWrongKeyLifecycleState is returned when an operation isn't permitted in the object's current state.
+// KMIP 1.x doesn't have a result reason for this, so it carries the Permission Denied result reason.  KMIP 2.0
+// servers should report it with the Wrong Key Lifecycle State result reason instead.
+var ErrWrongKeyLifecycleState = errors.New("kmip: wrong key lifecycle state")
+
+// ErrIncompatibleCryptographicUsageMask is returned when an object's Cryptographic Usage Mask doesn't permit an
+// operation.  Like ErrWrongKeyLifecycleState, it carries the Permission Denied result reason, and KMIP 2.0 servers
+// should report it with the Incompatible Cryptographic Usage Mask result reason instead.
+var ErrIncompatibleCryptographicUsageMask = errors.New("kmip: incompatible cryptographic usage mask")
+
+// Lifecycle implements the state machine for managed objects.  It validates state transitions, and maintains
+// the State attribute, and the date attributes which record the transitions.  The zero value is ready to use.
+//
+// Objects without a State attribute, like Templates, aren't subject to the state machine.
+type Lifecycle struct {
+	// Now returns the current time.  If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (l *Lifecycle) now() time.Time {
+	if l.Now != nil {
+		return l.Now().Truncate(time.Second)
+	}
+
+	return time.Now().Truncate(time.Second)
+}
+
+// ObjectState returns the value of the object's State attribute, or 0 if it doesn't have one.
+func ObjectState(obj *ManagedObject) kmip14.State {
+	var state kmip14.State
+
+	if attr := obj.GetAttributeTag(kmip14.TagState); attr != nil {
+		_ = DecodeAttributeValue(attr.AttributeValue, &state)
+	}
+
+	return state
+}
+
+// objectDate returns the value of a date attribute, or the zero time if it isn't set.
+func objectDate(obj *ManagedObject, tag ttlv.Tag) time.Time {
+	var t time.Time
+
+	if attr := obj.GetAttributeTag(tag); attr != nil {
+		_ = DecodeAttributeValue(attr.AttributeValue, &t)
+	}
+
+	return t
+}
+
+func wrongStateError(obj *ManagedObject, op string) error {
+	err := merry.WithUserMessagef(merry.Here(ErrWrongKeyLifecycleState), "%s is not permitted in the %s state", op, ObjectState(obj).String())
+
+	return WithResultReason(err, kmip14.ResultReasonPermissionDenied)
+}
+
+// Init sets the State of a new object to Pre-Active, and sets its Initial Date.  If the object was created
+// with an Activation Date which has already been reached, it's made Active immediately.
+func (l *Lifecycle) Init(obj *ManagedObject) {
+	now := l.now()
+
+	if obj.ObjectType != kmip14.ObjectTypeTemplate {
+		obj.SetAttributeTag(kmip14.TagState, kmip14.StatePreActive)
+	}
+
+	obj.SetAttributeTag(kmip14.TagInitialDate, now)
+	obj.SetAttributeTag(kmip14.TagLastChangeDate, now)
+
+	l.Apply(obj)
+}
+
+// Apply makes the transitions triggered by the object's date attributes: a Pre-Active object becomes Active
+// once its Activation Date is reached, and an Active object becomes Deactivated once its Deactivation Date
+// is reached.  The Process Start Date and Protect Stop Date don't change the State; they restrict the
+// operations the object may be used for (see CheckOperation).
+//
+// Returns true if the object's state changed.
+func (l *Lifecycle) Apply(obj *ManagedObject) bool {
+	now := l.now()
+	changed := false
+
+	if ObjectState(obj) == kmip14.StatePreActive {
+		if d := objectDate(obj, kmip14.TagActivationDate); !d.IsZero() && !d.After(now) {
+			obj.SetAttributeTag(kmip14.TagState, kmip14.StateActive)
+
+			changed = true
+		}
+	}
+
+	if ObjectState(obj) == kmip14.StateActive {
+		if d := objectDate(obj, kmip14.TagDeactivationDate); !d.IsZero() && !d.After(now) {
+			obj.SetAttributeTag(kmip14.TagState, kmip14.StateDeactivated)
+
+			changed = true
+		}
+	}
+
+	if changed {
+		obj.SetAttributeTag(kmip14.TagLastChangeDate, now)
+	}
+
+	return changed
+}
+
+// Activate moves a Pre-Active object to the Active state, and sets its Activation Date.
+func (l *Lifecycle) Activate(obj *ManagedObject) error {
+	l.Apply(obj)
+
+	if ObjectState(obj) != kmip14.StatePreActive {
+		return wrongStateError(obj, "Activate")
+	}
+
+	now := l.now()
+	obj.SetAttributeTag(kmip14.TagState, kmip14.StateActive)
+	obj.SetAttributeTag(kmip14.TagActivationDate, now)
+	obj.SetAttributeTag(kmip14.TagLastChangeDate, now)
+
+	return nil
+}
+
+// Revoke revokes an object.  If the reason is a key or CA compromise, the object moves to the Compromised
+// state, or from Destroyed to Destroyed Compromised, and its Compromise Date and Compromise Occurrence Date are
+// set.  If compromiseOccurrenceDate is nil, the occurrence date defaults to the object's Initial Date.  Objects
+// which are already compromised can't be revoked again, so their compromise dates are never overwritten.
+// Otherwise, an Active object moves to the Deactivated state, and its Deactivation Date is set.
+func (l *Lifecycle) Revoke(obj *ManagedObject, reason RevocationReason, compromiseOccurrenceDate *time.Time) error {
+	l.Apply(obj)
+
+	now := l.now()
+	state := ObjectState(obj)
+
+	switch reason.RevocationReasonCode {
+	case kmip14.RevocationReasonCodeKeyCompromise, kmip14.RevocationReasonCodeCACompromise:
+		switch state {
+		case kmip14.StatePreActive, kmip14.StateActive, kmip14.StateDeactivated:
+			obj.SetAttributeTag(kmip14.TagState, kmip14.StateCompromised)
+		case kmip14.StateDestroyed:
+			obj.SetAttributeTag(kmip14.TagState, kmip14.StateDestroyedCompromised)
+		default:
+			return wrongStateError(obj, "Revoke")
+		}
+
+		occurred := objectDate(obj, kmip14.TagInitialDate)
+		if compromiseOccurrenceDate != nil {
+			occurred = *compromiseOccurrenceDate
+		}
+
+		obj.SetAttributeTag(kmip14.TagCompromiseDate, now)
+		obj.SetAttributeTag(kmip14.TagCompromiseOccurrenceDate, occurred)
+	default:
+		if state != kmip14.StateActive {
+			return wrongStateError(obj, "Revoke")
+		}
+
+		obj.SetAttributeTag(kmip14.TagState, kmip14.StateDeactivated)
+		obj.SetAttributeTag(kmip14.TagDeactivationDate, now)
+	}
+
+	obj.SetAttributeTag(kmip14.TagRevocationReason, reason)
+	obj.SetAttributeTag(kmip14.TagLastChangeDate, now)
+
+	return nil
+}
+
+// Destroy moves an object to the Destroyed state, or from Compromised to Destroyed Compromised, sets its
+// Destroy Date, and removes its key material.  The object's attributes are retained.  Active objects must be
+// revoked before they can be destroyed.
+func (l *Lifecycle) Destroy(obj *ManagedObject) error {
+	l.Apply(obj)
+
+	switch ObjectState(obj) {
+	case kmip14.StatePreActive, kmip14.StateDeactivated:
+		obj.SetAttributeTag(kmip14.TagState, kmip14.StateDestroyed)
+	case kmip14.StateCompromised:
+		obj.SetAttributeTag(kmip14.TagState, kmip14.StateDestroyedCompromised)
+	default:
+		return wrongStateError(obj, "Destroy")
+	}
+
+	now := l.now()
+	obj.SetAttributeTag(kmip14.TagDestroyDate, now)
+	obj.SetAttributeTag(kmip14.TagLastChangeDate, now)
+
+	if kb := obj.KeyBlock(); kb != nil {
+		kb.KeyValue = nil
+	}
+
+	switch {
+	case obj.Certificate != nil:
+		obj.Certificate.CertificateValue = nil
+	case obj.OpaqueObject != nil:
+		obj.OpaqueObject.OpaqueDataValue = nil
+	}
+
+	return nil
+}
+
+// CheckOperation returns an error if the object may not be used for the operation in its current state:
+//
+//   - Get, Re-key and Re-key Key Pair require an object which hasn't been destroyed
+//   - operations which apply cryptographic protection, like Encrypt, Sign and MAC, and Derive Key, require an
+//     Active object, whose Protect Stop Date hasn't been reached
+//   - operations which process cryptographically protected information, like Decrypt, Signature Verify and
+//     MAC Verify, require an Active, Deactivated or Compromised object, whose Process Start Date has been
+//     reached
+//
+// Other operations are permitted in any state.  The date triggered transitions are applied to obj first.
+func (l *Lifecycle) CheckOperation(obj *ManagedObject, op kmip14.Operation) error {
+	l.Apply(obj)
+
+	state := ObjectState(obj)
+	if state == 0 {
+		return nil
+	}
+
+	now := l.now()
+
+	switch op {
+	case kmip14.OperationGet, kmip14.OperationReKey, kmip14.OperationReKeyKeyPair:
+		if state == kmip14.StateDestroyed || state == kmip14.StateDestroyedCompromised {
+			return wrongStateError(obj, op.String())
+		}
+	case kmip14.OperationEncrypt, kmip14.OperationSign, kmip14.OperationMAC, kmip14.OperationDeriveKey:
+		if state != kmip14.StateActive {
+			return wrongStateError(obj, op.String())
+		}
+
+		if d := objectDate(obj, kmip14.TagProtectStopDate); !d.IsZero() && !d.After(now) {
+			return wrongStateError(obj, op.String()+" after the Protect Stop Date")
+		}
+	case kmip14.OperationDecrypt, kmip14.OperationSignatureVerify, kmip14.OperationMACVerify:
+		switch state {
+		case kmip14.StateActive, kmip14.StateDeactivated, kmip14.StateCompromised:
+		default:
+			return wrongStateError(obj, op.String())
+		}
+
+		if d := objectDate(obj, kmip14.TagProcessStartDate); !d.IsZero() && d.After(now) {
+			return wrongStateError(obj, op.String()+" before the Process Start Date")
+		}
+	}
+
+	return nil
+}
+
+// CheckUsageMask returns an error if the object has a Cryptographic Usage Mask which doesn't include all the
+// bits in usage.  Objects without a Cryptographic Usage Mask may be used for anything.
+func CheckUsageMask(obj *ManagedObject, usage kmip14.CryptographicUsageMask) error {
+	attr := obj.GetAttributeTag(kmip14.TagCryptographicUsageMask)
+	if attr == nil {
+		return nil
+	}
+
+	var mask kmip14.CryptographicUsageMask
+	if err := DecodeAttributeValue(attr.AttributeValue, &mask); err != nil {
+		return merry.Prepend(err, "invalid Cryptographic Usage Mask")
+	}
+
+	if mask&usage != usage {
+		err := merry.WithUserMessagef(merry.Here(ErrIncompatibleCryptographicUsageMask), "the object's Cryptographic Usage Mask does not permit %s", usage.String())
+
+		return WithResultReason(err, kmip14.ResultReasonPermissionDenied)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package kmip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLifecycleObject(l *Lifecycle, state kmip14.State) *ManagedObject {
+	obj := newTestSecretData("secret")
+	l.Init(obj)
+
+	if state != 0 {
+		obj.SetAttributeTag(kmip14.TagState, state)
+	}
+
+	return obj
+}
+
+func TestLifecycle_transitions(t *testing.T) {
+	var l Lifecycle
+
+	compromise := RevocationReason{RevocationReasonCode: kmip14.RevocationReasonCodeKeyCompromise}
+	superseded := RevocationReason{RevocationReasonCode: kmip14.RevocationReasonCodeSuperseded}
+
+	tests := []struct {
+		name     string
+		from     kmip14.State
+		op       func(obj *ManagedObject) error
+		expected kmip14.State
+	}{
+		{"activate pre-active", kmip14.StatePreActive, l.Activate, kmip14.StateActive},
+		{"activate active", kmip14.StateActive, l.Activate, 0},
+		{"revoke active", kmip14.StateActive, func(obj *ManagedObject) error { return l.Revoke(obj, superseded, nil) }, kmip14.StateDeactivated},
+		{"revoke pre-active", kmip14.StatePreActive, func(obj *ManagedObject) error { return l.Revoke(obj, superseded, nil) }, 0},
+		{"compromise pre-active", kmip14.StatePreActive, func(obj *ManagedObject) error { return l.Revoke(obj, compromise, nil) }, kmip14.StateCompromised},
+		{"compromise active", kmip14.StateActive, func(obj *ManagedObject) error { return l.Revoke(obj, compromise, nil) }, kmip14.StateCompromised},
+		{"compromise deactivated", kmip14.StateDeactivated, func(obj *ManagedObject) error { return l.Revoke(obj, compromise, nil) }, kmip14.StateCompromised},
+		{"compromise destroyed", kmip14.StateDestroyed, func(obj *ManagedObject) error { return l.Revoke(obj, compromise, nil) }, kmip14.StateDestroyedCompromised},
+		{"compromise compromised", kmip14.StateCompromised, func(obj *ManagedObject) error { return l.Revoke(obj, compromise, nil) }, 0},
+		{"destroy pre-active", kmip14.StatePreActive, l.Destroy, kmip14.StateDestroyed},
+		{"destroy active", kmip14.StateActive, l.Destroy, 0},
+		{"destroy deactivated", kmip14.StateDeactivated, l.Destroy, kmip14.StateDestroyed},
+		{"destroy compromised", kmip14.StateCompromised, l.Destroy, kmip14.StateDestroyedCompromised},
+		{"destroy destroyed", kmip14.StateDestroyed, l.Destroy, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj := newTestLifecycleObject(&l, test.from)
+
+			err := test.op(obj)
+			if test.expected == 0 {
+				require.ErrorIs(t, err, ErrWrongKeyLifecycleState)
+				assert.Equal(t, kmip14.ResultReasonPermissionDenied, GetResultReason(err))
+				assert.Equal(t, test.from, ObjectState(obj))
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ObjectState(obj))
+		})
+	}
+}
+
+func TestLifecycle_Destroy(t *testing.T) {
+	var l Lifecycle
+
+	obj := newTestLifecycleObject(&l, kmip14.StatePreActive)
+	obj.SetAttributeTag(kmip14.TagObjectGroup, "group1")
+
+	require.NoError(t, l.Destroy(obj))
+
+	// the key material is destroyed, but the attributes are kept
+	assert.Nil(t, obj.SecretData.KeyBlock.KeyValue)
+	assert.NotNil(t, obj.GetAttributeTag(kmip14.TagDestroyDate))
+	assert.NotNil(t, obj.GetAttributeTag(kmip14.TagObjectGroup))
+
+	require.ErrorIs(t, l.CheckOperation(obj, kmip14.OperationGet), ErrWrongKeyLifecycleState)
+}
+
+func TestLifecycle_dates(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := Lifecycle{Now: func() time.Time { return now }}
+
+	obj := newTestSecretData("secret")
+	obj.SetAttributeTag(kmip14.TagActivationDate, now.Add(time.Hour))
+	obj.SetAttributeTag(kmip14.TagDeactivationDate, now.Add(2*time.Hour))
+	l.Init(obj)
+
+	assert.Equal(t, kmip14.StatePreActive, ObjectState(obj))
+	assert.False(t, l.Apply(obj))
+	require.ErrorIs(t, l.CheckOperation(obj, kmip14.OperationEncrypt), ErrWrongKeyLifecycleState)
+
+	now = now.Add(time.Hour)
+	assert.True(t, l.Apply(obj))
+	assert.Equal(t, kmip14.StateActive, ObjectState(obj))
+	require.NoError(t, l.CheckOperation(obj, kmip14.OperationEncrypt))
+
+	now = now.Add(time.Hour)
+	require.ErrorIs(t, l.CheckOperation(obj, kmip14.OperationEncrypt), ErrWrongKeyLifecycleState)
+	assert.Equal(t, kmip14.StateDeactivated, ObjectState(obj))
+
+	// deactivated objects can still process
+	require.NoError(t, l.CheckOperation(obj, kmip14.OperationDecrypt))
+
+	// an activation date in the past activates the object when it's created
+	obj = newTestSecretData("secret")
+	obj.SetAttributeTag(kmip14.TagActivationDate, now.Add(-time.Hour))
+	l.Init(obj)
+	assert.Equal(t, kmip14.StateActive, ObjectState(obj))
+
+	// the protect stop and process start dates restrict usage, without changing the state
+	obj.SetAttributeTag(kmip14.TagProtectStopDate, now)
+	obj.SetAttributeTag(kmip14.TagProcessStartDate, now.Add(time.Hour))
+	require.ErrorIs(t, l.CheckOperation(obj, kmip14.OperationSign), ErrWrongKeyLifecycleState)
+	require.ErrorIs(t, l.CheckOperation(obj, kmip14.OperationSignatureVerify), ErrWrongKeyLifecycleState)
+	require.NoError(t, l.CheckOperation(obj, kmip14.OperationGet))
+	assert.Equal(t, kmip14.StateActive, ObjectState(obj))
+}
@@ -0,0 +1,156 @@
+package kmip
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// ObjectMatcher matches objects against the attributes in a Locate request.  An object matches if it
+// matches every attribute in the request:
+//
+//   - a date attribute, like Initial Date or Activation Date, may be given once, to match the object's date
+//     exactly, or twice, to match dates in a range: the first instance is the start of the range, and the
+//     second is the end, inclusive
+//   - a Name matches if the object has a Name with the same Name Value, and the same Name Type, if one is given
+//   - any other attribute matches if the object has an instance of it with an equal value
+//
+// An attribute given more than once, other than a date range, matches objects which match every instance.
+type ObjectMatcher struct {
+	criteria []matchCriterion
+}
+
+type matchCriterion struct {
+	name string
+
+	// value is the encoded value, for equality matches
+	value ttlv.TTLV
+
+	// start and end are set for date ranges
+	start, end time.Time
+
+	// nameValue and nameType are set for Name matches
+	nameValue string
+	nameType  kmip14.NameType
+	isName    bool
+}
+
+// NewObjectMatcher returns a matcher for the attributes in a Locate request.  It returns an error with the
+// Invalid Field result reason if the attributes can't be encoded, or a date attribute is given more than twice.
+func NewObjectMatcher(attrs []Attribute) (*ObjectMatcher, error) {
+	m := &ObjectMatcher{}
+	// dates holds the index of the criterion for each date attribute, and dateCount the number of instances
+	dates := map[string]int{}
+	dateCount := map[string]int{}
+
+	for _, attr := range attrs {
+		if attr.AttributeName == kmip14.TagName.CanonicalName() {
+			var name Name
+			if err := DecodeAttributeValue(attr.AttributeValue, &name); err != nil {
+				return nil, WithResultReason(merry.Prepend(err, "invalid Name"), kmip14.ResultReasonInvalidField)
+			}
+
+			m.criteria = append(m.criteria, matchCriterion{name: attr.AttributeName, nameValue: name.NameValue, nameType: name.NameType, isName: true})
+
+			continue
+		}
+
+		value, err := encodeAttributeValue(attr.AttributeValue)
+		if err != nil {
+			return nil, WithResultReason(merry.Prependf(err, "invalid %s", attr.AttributeName), kmip14.ResultReasonInvalidField)
+		}
+
+		if value.Type() != ttlv.TypeDateTime {
+			m.criteria = append(m.criteria, matchCriterion{name: attr.AttributeName, value: value})
+			continue
+		}
+
+		d := value.ValueDateTime()
+
+		dateCount[attr.AttributeName]++
+
+		if i, ok := dates[attr.AttributeName]; ok {
+			if dateCount[attr.AttributeName] > 2 {
+				return nil, WithResultReason(merry.UserErrorf("%s may be given at most twice", attr.AttributeName), kmip14.ResultReasonInvalidField)
+			}
+
+			m.criteria[i].end = d
+
+			continue
+		}
+
+		dates[attr.AttributeName] = len(m.criteria)
+		m.criteria = append(m.criteria, matchCriterion{name: attr.AttributeName, start: d, end: d})
+	}
+
+	return m, nil
+}
+
+// Match returns true if the object matches all the attributes.
+func (m *ObjectMatcher) Match(obj *ManagedObject) bool {
+	attrs := obj.Attributes()
+
+	for i := range m.criteria {
+		if !m.criteria[i].match(attrs) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *matchCriterion) match(attrs []Attribute) bool {
+	for _, attr := range attrs {
+		if attr.AttributeName != c.name {
+			continue
+		}
+
+		if c.isName {
+			var name Name
+			if DecodeAttributeValue(attr.AttributeValue, &name) == nil &&
+				name.NameValue == c.nameValue && (c.nameType == 0 || name.NameType == c.nameType) {
+				return true
+			}
+
+			continue
+		}
+
+		value, err := encodeAttributeValue(attr.AttributeValue)
+		if err != nil {
+			continue
+		}
+
+		if c.value == nil {
+			if value.Type() != ttlv.TypeDateTime {
+				continue
+			}
+
+			d := value.ValueDateTime()
+			if !d.Before(c.start) && !d.After(c.end) {
+				return true
+			}
+
+			continue
+		}
+
+		if bytes.Equal(value, c.value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// encodeAttributeValue encodes an attribute value, so that values held as go values and values decoded
+// from TTLV can be compared.
+func encodeAttributeValue(v interface{}) (ttlv.TTLV, error) {
+	t, err := ttlv.Marshal(ttlv.Value{Tag: kmip14.TagAttributeValue, Value: v})
+	if err != nil {
+		return nil, err
+	}
+
+	return t[:t.FullLen()], nil
+}
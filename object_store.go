@@ -0,0 +1,310 @@
+package kmip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// ErrObjectNotFound is returned by ObjectTx when the requested object doesn't exist.  It carries
+// the Item Not Found result reason.
+var ErrObjectNotFound = errors.New("kmip: object not found")
+
+// ErrObjectExists is returned by ObjectTx.Create if an object with the same Unique Identifier
+// already exists.
+var ErrObjectExists = errors.New("kmip: object already exists")
+
+// ManagedObject is a Managed Object, together with its attributes, as it is held by an ObjectStore.
+//
+// Exactly one of the object fields should be set, matching ObjectType.  The Unique Identifier and Object Type
+// are held in their own fields, and are not repeated in Attribute.
+type ManagedObject struct {
+	UniqueIdentifier string
+	ObjectType       kmip14.ObjectType
+	Certificate      *Certificate  `ttlv:",omitempty"`
+	SymmetricKey     *SymmetricKey `ttlv:",omitempty"`
+	PrivateKey       *PrivateKey   `ttlv:",omitempty"`
+	PublicKey        *PublicKey    `ttlv:",omitempty"`
+	SplitKey         *SplitKey     `ttlv:",omitempty"`
+	Template         *Template     `ttlv:",omitempty"`
+	SecretData       *SecretData   `ttlv:",omitempty"`
+	OpaqueObject     *OpaqueObject `ttlv:",omitempty"`
+	PGPKey           *PGPKey       `ttlv:",omitempty"`
+	Attribute        []Attribute
+
+	// NextAttributeIndex records the next Attribute Index of attributes which have had instances
+	// removed, so that the indexes of removed instances aren't reused.
+	NextAttributeIndex []NextAttributeIndex `ttlv:"0x54ff07,omitempty"`
+}
+
+// NextAttributeIndex is the Attribute Index the next instance of an attribute will get.
+type NextAttributeIndex struct {
+	AttributeName  string
+	AttributeIndex int
+}
+
+// Object returns the object held by the ManagedObject, e.g. *SymmetricKey, or nil if none is set.
+func (mo *ManagedObject) Object() interface{} {
+	switch {
+	case mo.Certificate != nil:
+		return mo.Certificate
+	case mo.SymmetricKey != nil:
+		return mo.SymmetricKey
+	case mo.PrivateKey != nil:
+		return mo.PrivateKey
+	case mo.PublicKey != nil:
+		return mo.PublicKey
+	case mo.SplitKey != nil:
+		return mo.SplitKey
+	case mo.Template != nil:
+		return mo.Template
+	case mo.SecretData != nil:
+		return mo.SecretData
+	case mo.OpaqueObject != nil:
+		return mo.OpaqueObject
+	case mo.PGPKey != nil:
+		return mo.PGPKey
+	}
+
+	return nil
+}
+
+// SetObject sets the object held by the ManagedObject, and its ObjectType.  v must be a pointer to
+// one of the managed object types, e.g. *SymmetricKey.  Any previously held object is cleared.
+func (mo *ManagedObject) SetObject(v interface{}) error {
+	mo.Certificate, mo.SymmetricKey, mo.PrivateKey, mo.PublicKey = nil, nil, nil, nil
+	mo.SplitKey, mo.Template, mo.SecretData, mo.OpaqueObject, mo.PGPKey = nil, nil, nil, nil, nil
+
+	switch t := v.(type) {
+	case *Certificate:
+		mo.ObjectType, mo.Certificate = kmip14.ObjectTypeCertificate, t
+	case *SymmetricKey:
+		mo.ObjectType, mo.SymmetricKey = kmip14.ObjectTypeSymmetricKey, t
+	case *PrivateKey:
+		mo.ObjectType, mo.PrivateKey = kmip14.ObjectTypePrivateKey, t
+	case *PublicKey:
+		mo.ObjectType, mo.PublicKey = kmip14.ObjectTypePublicKey, t
+	case *SplitKey:
+		mo.ObjectType, mo.SplitKey = kmip14.ObjectTypeSplitKey, t
+	case *Template:
+		mo.ObjectType, mo.Template = kmip14.ObjectTypeTemplate, t
+	case *SecretData:
+		mo.ObjectType, mo.SecretData = kmip14.ObjectTypeSecretData, t
+	case *OpaqueObject:
+		mo.ObjectType, mo.OpaqueObject = kmip14.ObjectTypeOpaqueObject, t
+	case *PGPKey:
+		mo.ObjectType, mo.PGPKey = kmip14.ObjectTypePGPKey, t
+	default:
+		return merry.Errorf("%T is not a managed object type", v)
+	}
+
+	return nil
+}
+
+// KeyBlock returns the Key Block of the object, or nil if the object doesn't have one.
+func (mo *ManagedObject) KeyBlock() *KeyBlock {
+	switch {
+	case mo.SymmetricKey != nil:
+		return &mo.SymmetricKey.KeyBlock
+	case mo.PrivateKey != nil:
+		return &mo.PrivateKey.KeyBlock
+	case mo.PublicKey != nil:
+		return &mo.PublicKey.KeyBlock
+	case mo.SplitKey != nil:
+		return &mo.SplitKey.KeyBlock
+	case mo.SecretData != nil:
+		return &mo.SecretData.KeyBlock
+	case mo.PGPKey != nil:
+		return &mo.PGPKey.KeyBlock
+	}
+
+	return nil
+}
+
+// GetAttribute returns a reference to the first attribute with the given name, or nil.
+func (mo *ManagedObject) GetAttribute(name string) *Attribute {
+	for i := range mo.Attribute {
+		if mo.Attribute[i].AttributeName == name {
+			return &mo.Attribute[i]
+		}
+	}
+
+	return nil
+}
+
+// GetAttributeTag returns a reference to the first attribute matching the tag, or nil.
+func (mo *ManagedObject) GetAttributeTag(tag ttlv.Tag) *Attribute {
+	return mo.GetAttribute(tag.CanonicalName())
+}
+
+// FindAttributeValue returns a reference to the first instance of the named attribute whose value equals
+// value, or nil.  Values are compared by their TTLV encodings, so a value decoded from a message matches the
+// equivalent go value.
+func (mo *ManagedObject) FindAttributeValue(name string, value interface{}) *Attribute {
+	want, err := encodeAttributeValue(value)
+	if err != nil {
+		return nil
+	}
+
+	for i := range mo.Attribute {
+		if mo.Attribute[i].AttributeName != name {
+			continue
+		}
+
+		if v, err := encodeAttributeValue(mo.Attribute[i].AttributeValue); err == nil && bytes.Equal(v, want) {
+			return &mo.Attribute[i]
+		}
+	}
+
+	return nil
+}
+
+// SetAttributeTag sets the value of a single instance attribute, replacing the value of the
+// first attribute matching the tag, or adding the attribute if there is no match.
+func (mo *ManagedObject) SetAttributeTag(tag ttlv.Tag, value interface{}) {
+	if attr := mo.GetAttributeTag(tag); attr != nil {
+		attr.AttributeValue = value
+		return
+	}
+
+	mo.Attribute = append(mo.Attribute, NewAttributeFromTag(tag, 0, value))
+}
+
+// AddAttributeTag adds an instance of a multi-instance attribute, with the next unused Attribute Index.
+func (mo *ManagedObject) AddAttributeTag(tag ttlv.Tag, value interface{}) {
+	mo.Attribute = append(mo.Attribute, NewAttributeFromTag(tag, mo.nextAttributeIndex(tag.CanonicalName()), value))
+}
+
+// LinkedObjectIdentifier returns the Linked Object Identifier of the object's first Link attribute of the Link
+// Type, or "" if it has none.
+func (mo *ManagedObject) LinkedObjectIdentifier(linkType kmip14.LinkType) string {
+	for i := range mo.Attribute {
+		if mo.Attribute[i].AttributeName != kmip14.TagLink.CanonicalName() {
+			continue
+		}
+
+		var link Link
+		if err := DecodeAttributeValue(mo.Attribute[i].AttributeValue, &link); err == nil && link.LinkType == linkType {
+			return link.LinkedObjectIdentifier
+		}
+	}
+
+	return ""
+}
+
+// nextAttributeIndex returns the Attribute Index for a new instance of an attribute: one more than the highest
+// index the attribute has ever had on this object.
+func (mo *ManagedObject) nextAttributeIndex(name string) int {
+	idx := 0
+
+	for i := range mo.Attribute {
+		if mo.Attribute[i].AttributeName == name && mo.Attribute[i].AttributeIndex >= idx {
+			idx = mo.Attribute[i].AttributeIndex + 1
+		}
+	}
+
+	for _, next := range mo.NextAttributeIndex {
+		if next.AttributeName == name && next.AttributeIndex > idx {
+			idx = next.AttributeIndex
+		}
+	}
+
+	return idx
+}
+
+// Attributes returns all the object's attributes, including the Unique Identifier and Object Type.
+func (mo *ManagedObject) Attributes() []Attribute {
+	attrs := make([]Attribute, 0, len(mo.Attribute)+2)
+	attrs = append(attrs,
+		NewAttributeFromTag(kmip14.TagUniqueIdentifier, 0, mo.UniqueIdentifier),
+		NewAttributeFromTag(kmip14.TagObjectType, 0, mo.ObjectType),
+	)
+
+	return append(attrs, mo.Attribute...)
+}
+
+// RemoveAttribute removes the attribute instance with the given name and index.  Returns false if
+// there was no such attribute.  The index won't be given to instances added later.
+func (mo *ManagedObject) RemoveAttribute(name string, idx int) bool {
+	for i := range mo.Attribute {
+		if mo.Attribute[i].AttributeName == name && mo.Attribute[i].AttributeIndex == idx {
+			next := mo.nextAttributeIndex(name)
+			mo.Attribute = append(mo.Attribute[:i], mo.Attribute[i+1:]...)
+
+			if mo.nextAttributeIndex(name) < next {
+				mo.setNextAttributeIndex(name, next)
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (mo *ManagedObject) setNextAttributeIndex(name string, idx int) {
+	for i := range mo.NextAttributeIndex {
+		if mo.NextAttributeIndex[i].AttributeName == name {
+			mo.NextAttributeIndex[i].AttributeIndex = idx
+			return
+		}
+	}
+
+	mo.NextAttributeIndex = append(mo.NextAttributeIndex, NextAttributeIndex{AttributeName: name, AttributeIndex: idx})
+}
+
+// DecodeAttributeValue decodes an Attribute Value into v.  Attribute values may be held
+// as go values, e.g. a kmip14.State or a time.Time, or as they were decoded from a TTLV message,
+// e.g. a ttlv.TTLV structure, or a ttlv.EnumValue.  This function decodes either form into
+// the type the caller expects.
+func DecodeAttributeValue(value interface{}, v interface{}) error {
+	t, ok := value.(ttlv.TTLV)
+	if !ok {
+		var err error
+
+		t, err = ttlv.Marshal(ttlv.Value{Tag: kmip14.TagAttributeValue, Value: value})
+		if err != nil {
+			return merry.Prepend(err, "encoding attribute value")
+		}
+	}
+
+	return ttlv.Unmarshal(t, v)
+}
+
+// ObjectStore stores Managed Objects, together with their attributes.
+//
+// Objects are read and written within transactions.  Objects returned by a transaction are
+// copies: changes to them aren't saved until they are passed to ObjectTx.Put.
+type ObjectStore interface {
+	// View runs fn in a read-only transaction.
+	View(ctx context.Context, fn func(tx ObjectTx) error) error
+	// Update runs fn in a read-write transaction.  If fn returns an error, none of the changes
+	// made in the transaction are applied, and the error is returned.
+	Update(ctx context.Context, fn func(tx ObjectTx) error) error
+}
+
+// ObjectTx is a transaction on an ObjectStore.  It is only valid for the duration of the
+// function passed to View or Update.
+type ObjectTx interface {
+	// Get returns the object with the given Unique Identifier.  If there is no such object, the
+	// error wraps ErrObjectNotFound.
+	Get(id string) (*ManagedObject, error)
+	// Create adds a new object to the store.  If the object's UniqueIdentifier is empty, the
+	// store assigns one.  Returns the object's Unique Identifier.
+	Create(obj *ManagedObject) (string, error)
+	// Put replaces an existing object.
+	Put(obj *ManagedObject) error
+	// Delete removes an object.
+	Delete(id string) error
+	// ForEach calls fn for each object in the store, in the order they were created.  If fn returns
+	// an error, iteration stops, and the error is returned.
+	ForEach(fn func(obj *ManagedObject) error) error
+}
+
+func objectNotFoundError(id string) error {
+	return WithResultReason(merry.Prependf(ErrObjectNotFound, "unique identifier %q", id), kmip14.ResultReasonItemNotFound)
+}
@@ -0,0 +1,661 @@
+package kmip
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/flume"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// ErrObjectStoreClosed is returned by FileObjectStore.Update after the store is closed.
+var ErrObjectStoreClosed = errors.New("kmip: object store closed")
+
+// ErrWrongMasterKey is returned by OpenFileObjectStore if the file was encrypted with a different master key.
+var ErrWrongMasterKey = errors.New("kmip: wrong master key")
+
+// DefaultCompactionThreshold is the default value of FileObjectStoreOptions.CompactionThreshold.
+const DefaultCompactionThreshold = 1000
+
+// FileObjectStoreOptions configures a FileObjectStore.
+type FileObjectStoreOptions struct {
+	// MasterKey is a 256 bit AES key, which encrypts the stored objects with AES-GCM.  Each object's
+	// Unique Identifier is authenticated with it, so records can't be swapped between objects.
+	//
+	// If nil, objects are stored unencrypted.  A file created with a master key can only be opened
+	// with the same key, and a file created without one can only be opened without one.
+	MasterKey []byte
+
+	// NoSync disables the fsync after each update.  Updates are still written to the file before Update
+	// returns, so they survive the process crashing, but they may be lost if the machine crashes.
+	NoSync bool
+
+	// CompactionThreshold is the number of superseded records the log may hold before it's compacted.  The
+	// log is only compacted when it also holds at least as many superseded records as live objects.  If 0,
+	// DefaultCompactionThreshold is used.  If negative, the log is only compacted by calling Compact.
+	CompactionThreshold int
+}
+
+// FileObjectStore is an ObjectStore which persists objects to an append-only log file.  It's meant for
+// development and small deployments: all objects are also held in memory, and the file may only be
+// opened by one FileObjectStore at a time.
+//
+// Each update transaction appends one record to the log, holding the new encoding of every object the
+// transaction changed, or a tombstone for objects it deleted.  On open, the log is replayed to rebuild
+// the objects.  If the process or machine crashed while a record was being written, the incomplete final
+// record is discarded, together with the rest of its transaction.
+//
+// As objects change, the log accumulates superseded records.  Once there are enough of them (see
+// FileObjectStoreOptions.CompactionThreshold), the log is compacted by writing the live objects to a
+// new file, and atomically renaming it over the old one.
+type FileObjectStore struct {
+	mem  MemoryObjectStore
+	path string
+	opts FileObjectStoreOptions
+	aead cipher.AEAD
+
+	// fmu guards the file fields.  It's only acquired while holding mem.mu, or from Compact and Close.
+	fmu     sync.Mutex
+	f       *os.File
+	size    int64
+	records int
+}
+
+// OpenFileObjectStore opens the object store at path, creating the file if it doesn't exist or is empty.
+// Opening any other file which isn't an object store log fails, and leaves the file untouched.
+func OpenFileObjectStore(path string, opts FileObjectStoreOptions) (*FileObjectStore, error) {
+	s := &FileObjectStore{
+		path: path,
+		opts: opts,
+	}
+
+	if opts.MasterKey != nil {
+		block, err := aes.NewCipher(opts.MasterKey)
+		if err != nil {
+			return nil, merry.Prepend(err, "invalid master key")
+		}
+
+		if len(opts.MasterKey) != 32 {
+			return nil, merry.New("invalid master key: must be 32 bytes")
+		}
+
+		s.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, merry.Wrap(err)
+		}
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	if s.shouldCompact() {
+		if err := s.Compact(); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// ReadMasterKeyFile reads a master key for FileObjectStoreOptions.MasterKey from a file.  The file must hold
+// the 32 key bytes, either raw or hex encoded.  Leading and trailing whitespace around hex is ignored.
+func ReadMasterKeyFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, merry.Wrap(err)
+	}
+
+	if len(b) == 32 {
+		return b, nil
+	}
+
+	key, err := hex.DecodeString(string(bytes.TrimSpace(b)))
+	if err != nil || len(key) != 32 {
+		return nil, merry.Errorf("master key file %s must hold 32 bytes, raw or hex encoded", path)
+	}
+
+	return key, nil
+}
+
+// View implements ObjectStore.
+func (s *FileObjectStore) View(ctx context.Context, fn func(tx ObjectTx) error) error {
+	return s.mem.View(ctx, fn)
+}
+
+// Update implements ObjectStore.  The changes are appended to the log before they are applied.  If
+// writing the log fails, the changes are discarded, and the error is returned.
+//
+// Once the changes are applied, Update may compact the log.  A failed compaction doesn't fail the update,
+// since its changes are already stored: the error is logged, and compaction is retried after the next update.
+func (s *FileObjectStore) Update(ctx context.Context, fn func(tx ObjectTx) error) error {
+	err := s.mem.update(ctx, fn, s.persist)
+	if err != nil {
+		return err
+	}
+
+	if s.shouldCompact() {
+		if err := s.Compact(); err != nil {
+			flume.FromContext(ctx).Error("compacting object store", "path", s.path, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// Compact rewrites the log so it only holds the live objects.
+func (s *FileObjectStore) Compact() error {
+	s.mem.mu.RLock()
+	defer s.mem.mu.RUnlock()
+
+	s.fmu.Lock()
+	defer s.fmu.Unlock()
+
+	if s.f == nil {
+		return ErrObjectStoreClosed
+	}
+
+	tmpPath := s.path + ".compact"
+
+	f, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return merry.Wrap(err)
+	}
+
+	size, err := s.writeLog(f, s.mem.order, s.mem.objects)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmpPath)
+
+		return err
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmpPath)
+
+		return merry.Wrap(err)
+	}
+
+	_ = s.f.Close()
+	s.f, s.size, s.records = f, size, len(s.mem.order)
+
+	return syncDir(s.path)
+}
+
+// Close closes the log file.  Later updates fail with ErrObjectStoreClosed.  Objects can still be viewed.
+func (s *FileObjectStore) Close() error {
+	s.fmu.Lock()
+	defer s.fmu.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+
+	err := s.f.Close()
+	s.f = nil
+
+	return merry.Wrap(err)
+}
+
+func (s *FileObjectStore) shouldCompact() bool {
+	threshold := s.opts.CompactionThreshold
+	if threshold < 0 {
+		return false
+	}
+
+	if threshold == 0 {
+		threshold = DefaultCompactionThreshold
+	}
+
+	s.mem.mu.RLock()
+	live := len(s.mem.objects)
+	s.mem.mu.RUnlock()
+
+	s.fmu.Lock()
+	superseded := s.records - live
+	s.fmu.Unlock()
+
+	return superseded >= threshold && superseded >= live
+}
+
+// persist appends the changes made by tx to the log.  It's called with the memory store locked.
+func (s *FileObjectStore) persist(tx *memoryObjectTx) error {
+	if len(tx.changes) == 0 {
+		return nil
+	}
+
+	s.fmu.Lock()
+	defer s.fmu.Unlock()
+
+	if s.f == nil {
+		return ErrObjectStoreClosed
+	}
+
+	// write new objects in the order they were created, so replaying the log restores the order
+	ids := make([]string, 0, len(tx.changes))
+	seen := make(map[string]bool, len(tx.changes))
+
+	for _, id := range tx.created {
+		if _, ok := tx.changes[id]; ok && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var rest []string
+
+	for id := range tx.changes {
+		if !seen[id] {
+			rest = append(rest, id)
+		}
+	}
+
+	sort.Strings(rest)
+	ids = append(ids, rest...)
+
+	rec, err := s.encodeRecord(ids, tx.changes)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.f.Write(rec); err != nil {
+		s.discardTail()
+		return merry.Prepend(err, "writing object store log")
+	}
+
+	if !s.opts.NoSync {
+		if err := s.f.Sync(); err != nil {
+			// the transaction is discarded, so its record must be too
+			s.discardTail()
+			return merry.Prepend(err, "syncing object store log")
+		}
+	}
+
+	s.size += int64(len(rec))
+	s.records += len(ids)
+
+	return nil
+}
+
+// discardTail removes anything written to the log after the last complete record, so later records
+// aren't appended after it.  If that fails, the log no longer matches the objects in memory, so the
+// file is closed, and later updates fail with ErrObjectStoreClosed.
+func (s *FileObjectStore) discardTail() {
+	err := s.f.Truncate(s.size)
+	if err == nil {
+		_, err = s.f.Seek(s.size, io.SeekStart)
+	}
+
+	if err != nil {
+		_ = s.f.Close()
+		s.f = nil
+	}
+}
+
+// load replays the log into memory, creating the file if necessary.  An incomplete final record is
+// discarded, and truncated from the file.
+func (s *FileObjectStore) load() error {
+	b, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return merry.Wrap(err)
+	}
+
+	if len(b) == 0 {
+		// a new file, or an empty one
+		return s.create()
+	}
+
+	rest, err := s.readHeader(b)
+	if err != nil {
+		return err
+	}
+
+	changes := map[string]ttlv.TTLV{}
+	offset := len(b) - len(rest)
+
+	for len(rest) > 0 {
+		rec, recErr := nextRecord(rest)
+
+		var ids []string
+		if recErr == nil {
+			ids, recErr = s.decodeRecord(rec, changes)
+		}
+
+		if recErr != nil {
+			if len(rec) < len(rest) && !allZero(rest) {
+				// not the final record, so it wasn't torn by a crash.  A crash may also leave
+				// a zero-filled tail, if the file's size was updated before its data.
+				return merry.Prependf(recErr, "object store log %s is corrupt at offset %d", s.path, offset)
+			}
+
+			break
+		}
+
+		var created []string
+
+		for _, id := range ids {
+			if _, ok := s.mem.objects[id]; !ok && changes[id] != nil {
+				created = append(created, id)
+			}
+		}
+
+		s.mem.apply(changes, created)
+
+		for id := range changes {
+			delete(changes, id)
+		}
+
+		s.records += len(ids)
+		offset += len(rec)
+		rest = rest[len(rec):]
+	}
+
+	f, err := os.OpenFile(s.path, os.O_RDWR, 0o600)
+	if err != nil {
+		return merry.Wrap(err)
+	}
+
+	if offset < len(b) {
+		if err := f.Truncate(int64(offset)); err != nil {
+			_ = f.Close()
+			return merry.Prepend(err, "truncating incomplete record")
+		}
+	}
+
+	if _, err := f.Seek(int64(offset), io.SeekStart); err != nil {
+		_ = f.Close()
+		return merry.Wrap(err)
+	}
+
+	s.f, s.size = f, int64(offset)
+
+	return nil
+}
+
+// create writes a new, empty log.  It's written to a temporary file, which is renamed over the
+// path, so a crash can't leave a log with an incomplete header.
+func (s *FileObjectStore) create() error {
+	tmpPath := s.path + ".new"
+
+	f, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return merry.Wrap(err)
+	}
+
+	size, err := s.writeLog(f, nil, nil)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if err == nil {
+		err = merry.Wrap(os.Rename(tmpPath, s.path))
+	}
+
+	if err == nil {
+		err = syncDir(s.path)
+	}
+
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmpPath)
+
+		return err
+	}
+
+	s.f, s.size = f, size
+
+	return nil
+}
+
+// writeLog writes a header, followed by a record for each object, to f.  Returns the number of bytes written.
+func (s *FileObjectStore) writeLog(f *os.File, order []string, objects map[string]ttlv.TTLV) (int64, error) {
+	header, err := s.encodeHeader()
+	if err != nil {
+		return 0, err
+	}
+
+	buf := bytes.NewBuffer(header)
+
+	for _, id := range order {
+		rec, err := s.encodeRecord([]string{id}, objects)
+		if err != nil {
+			return 0, err
+		}
+
+		buf.Write(rec)
+	}
+
+	n, err := f.Write(buf.Bytes())
+	if err != nil {
+		return 0, merry.Prepend(err, "writing object store log")
+	}
+
+	return int64(n), nil
+}
+
+// The log is a sequence of TTLV values: a header, followed by records.  The spec doesn't define these
+// structures, so their tags are taken from the range reserved for extensions.
+const (
+	tagFileStoreHeader   ttlv.Tag = 0x54ff01
+	tagFileStoreVersion  ttlv.Tag = 0x54ff02
+	tagFileStoreKeyCheck ttlv.Tag = 0x54ff03
+	tagFileStoreRecord   ttlv.Tag = 0x54ff04
+	tagFileStoreEntry    ttlv.Tag = 0x54ff05
+	tagFileStoreObject   ttlv.Tag = 0x54ff06
+)
+
+const fileStoreVersion = 1
+
+// fileStoreKeyCheckAAD is authenticated by the header's key check value, which is used to detect
+// opening an encrypted log with the wrong master key.
+var fileStoreKeyCheckAAD = []byte("kmip-go file object store")
+
+type fileStoreHeader struct {
+	TTLVTag  struct{} `ttlv:"0x54ff01"`
+	Version  int      `ttlv:"0x54ff02"`
+	KeyCheck []byte   `ttlv:"0x54ff03,omitempty"`
+}
+
+// fileStoreEntry holds the encoding of an object, which may be encrypted.  An entry with no Object deletes
+// the object.
+type fileStoreEntry struct {
+	UniqueIdentifier string
+	Object           []byte `ttlv:"0x54ff06,omitempty"`
+}
+
+type fileStoreRecord struct {
+	TTLVTag struct{}         `ttlv:"0x54ff04"`
+	Entry   []fileStoreEntry `ttlv:"0x54ff05"`
+}
+
+func (s *FileObjectStore) encodeHeader() ([]byte, error) {
+	h := fileStoreHeader{Version: fileStoreVersion}
+	if s.aead != nil {
+		h.KeyCheck = s.seal(nil, fileStoreKeyCheckAAD)
+	}
+
+	b, err := ttlv.Marshal(&h)
+	if err != nil {
+		return nil, merry.Prepend(err, "encoding object store header")
+	}
+
+	return b, nil
+}
+
+// readHeader checks the log's header, and returns the rest of the log.
+func (s *FileObjectStore) readHeader(b []byte) ([]byte, error) {
+	t, err := nextRecord(b)
+
+	var h fileStoreHeader
+	if err != nil || t.Tag() != tagFileStoreHeader || ttlv.Unmarshal(t, &h) != nil {
+		return nil, merry.Errorf("%s is not an object store log", s.path)
+	}
+
+	if h.Version != fileStoreVersion {
+		return nil, merry.Errorf("unsupported object store log version %d", h.Version)
+	}
+
+	switch {
+	case s.aead == nil && h.KeyCheck != nil:
+		return nil, merry.Errorf("object store log %s is encrypted, and no master key was provided", s.path)
+	case s.aead != nil && h.KeyCheck == nil:
+		return nil, merry.Errorf("object store log %s is not encrypted", s.path)
+	case s.aead != nil:
+		if _, err := s.open(h.KeyCheck, fileStoreKeyCheckAAD); err != nil {
+			return nil, merry.Here(ErrWrongMasterKey)
+		}
+	}
+
+	return b[len(t):], nil
+}
+
+func (s *FileObjectStore) encodeRecord(ids []string, objects map[string]ttlv.TTLV) ([]byte, error) {
+	rec := fileStoreRecord{Entry: make([]fileStoreEntry, len(ids))}
+
+	for i, id := range ids {
+		rec.Entry[i].UniqueIdentifier = id
+
+		if obj := objects[id]; obj != nil {
+			rec.Entry[i].Object = obj
+			if s.aead != nil {
+				rec.Entry[i].Object = s.seal(obj, []byte(id))
+			}
+		}
+	}
+
+	b, err := ttlv.Marshal(&rec)
+	if err != nil {
+		return nil, merry.Prepend(err, "encoding object store record")
+	}
+
+	return b, nil
+}
+
+// decodeRecord adds the entries in the record to changes, and returns their ids, in order.
+func (s *FileObjectStore) decodeRecord(t ttlv.TTLV, changes map[string]ttlv.TTLV) ([]string, error) {
+	var rec fileStoreRecord
+	if t.Tag() != tagFileStoreRecord {
+		return nil, merry.Errorf("unexpected tag %s", t.Tag().String())
+	}
+
+	if err := ttlv.Unmarshal(t, &rec); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(rec.Entry))
+
+	for i, e := range rec.Entry {
+		ids[i] = e.UniqueIdentifier
+
+		if e.Object == nil {
+			changes[e.UniqueIdentifier] = nil
+			continue
+		}
+
+		obj := e.Object
+
+		if s.aead != nil {
+			var err error
+
+			obj, err = s.open(obj, []byte(e.UniqueIdentifier))
+			if err != nil {
+				return nil, merry.Prependf(err, "decrypting object %q", e.UniqueIdentifier)
+			}
+		}
+
+		if err := ttlv.TTLV(obj).Valid(); err != nil {
+			return nil, merry.Prependf(err, "invalid object %q", e.UniqueIdentifier)
+		}
+
+		changes[e.UniqueIdentifier] = obj
+	}
+
+	return ids, nil
+}
+
+// seal encrypts plaintext, and returns the nonce followed by the ciphertext.
+func (s *FileObjectStore) seal(plaintext, additionalData []byte) []byte {
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(plaintext)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+
+	return s.aead.Seal(nonce, nonce, plaintext, additionalData)
+}
+
+func (s *FileObjectStore) open(ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < s.aead.NonceSize() {
+		return nil, merry.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := ciphertext[:s.aead.NonceSize()], ciphertext[s.aead.NonceSize():]
+
+	b, err := s.aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, merry.Wrap(err)
+	}
+
+	return b, nil
+}
+
+// nextRecord returns the TTLV value at the start of b.  It's an error if b doesn't hold a complete,
+// valid value.
+func nextRecord(b []byte) (ttlv.TTLV, error) {
+	t := ttlv.TTLV(b)
+	if len(t) < 8 {
+		return t, merry.New("incomplete record")
+	}
+
+	if err := t.ValidHeader(); err != nil {
+		return t, err
+	}
+
+	if t.FullLen() > len(t) {
+		return t, merry.New("incomplete record")
+	}
+
+	t = t[:t.FullLen()]
+
+	return t, t.Valid()
+}
+
+func allZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// syncDir fsyncs the directory containing path, so a created or renamed file is durable.
+func syncDir(path string) error {
+	d, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return merry.Wrap(err)
+	}
+	defer d.Close()
+
+	// some platforms don't support syncing directories
+	_ = d.Sync()
+
+	return nil
+}
@@ -0,0 +1,306 @@
+package kmip
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storedSecrets returns the key material of the objects in the store, in order.
+func storedSecrets(t *testing.T, store ObjectStore) []string {
+	t.Helper()
+
+	var secrets []string
+
+	require.NoError(t, store.View(context.Background(), func(tx ObjectTx) error {
+		return tx.ForEach(func(obj *ManagedObject) error {
+			secrets = append(secrets, string(obj.SecretData.KeyBlock.KeyValue.KeyMaterial.([]byte)))
+			return nil
+		})
+	}))
+
+	return secrets
+}
+
+func createSecrets(t *testing.T, store ObjectStore, secrets ...string) []string {
+	t.Helper()
+
+	var ids []string
+
+	require.NoError(t, store.Update(context.Background(), func(tx ObjectTx) error {
+		for _, secret := range secrets {
+			id, err := tx.Create(newTestSecretData(secret))
+			if err != nil {
+				return err
+			}
+
+			ids = append(ids, id)
+		}
+
+		return nil
+	}))
+
+	return ids
+}
+
+func TestFileObjectStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "objects.log")
+
+	store, err := OpenFileObjectStore(path, FileObjectStoreOptions{})
+	require.NoError(t, err)
+
+	ids := createSecrets(t, store, "one", "two", "three")
+
+	require.NoError(t, store.Update(ctx, func(tx ObjectTx) error {
+		obj, err := tx.Get(ids[0])
+		require.NoError(t, err)
+		obj.SetAttributeTag(kmip14.TagObjectGroup, "group1")
+		require.NoError(t, tx.Put(obj))
+
+		return tx.Delete(ids[1])
+	}))
+
+	require.NoError(t, store.Close())
+	require.ErrorIs(t, store.Update(ctx, func(tx ObjectTx) error {
+		return tx.Delete(ids[0])
+	}), ErrObjectStoreClosed)
+
+	// the log is replayed on open
+	store, err = OpenFileObjectStore(path, FileObjectStoreOptions{})
+	require.NoError(t, err)
+
+	defer store.Close()
+
+	assert.Equal(t, []string{"one", "three"}, storedSecrets(t, store))
+
+	require.NoError(t, store.View(ctx, func(tx ObjectTx) error {
+		obj, err := tx.Get(ids[0])
+		require.NoError(t, err)
+		assert.Equal(t, "group1", obj.GetAttributeTag(kmip14.TagObjectGroup).AttributeValue)
+
+		_, err = tx.Get(ids[1])
+		assert.ErrorIs(t, err, ErrObjectNotFound)
+
+		return nil
+	}))
+}
+
+func TestFileObjectStore_truncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "objects.log")
+
+	store, err := OpenFileObjectStore(path, FileObjectStoreOptions{NoSync: true})
+	require.NoError(t, err)
+
+	createSecrets(t, store, "one")
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+
+	createSecrets(t, store, "two", "three")
+	require.NoError(t, store.Close())
+
+	// simulate a crash part way through writing the last record
+	require.NoError(t, os.Truncate(path, fi.Size()+20))
+
+	store, err = OpenFileObjectStore(path, FileObjectStoreOptions{NoSync: true})
+	require.NoError(t, err)
+
+	// the whole transaction in the torn record is discarded
+	assert.Equal(t, []string{"one"}, storedSecrets(t, store))
+
+	// the torn record is removed, so new records can be appended
+	createSecrets(t, store, "four")
+	require.NoError(t, store.Close())
+
+	store, err = OpenFileObjectStore(path, FileObjectStoreOptions{NoSync: true})
+	require.NoError(t, err)
+
+	defer store.Close()
+
+	assert.Equal(t, []string{"one", "four"}, storedSecrets(t, store))
+}
+
+func TestFileObjectStore_notALog(t *testing.T) {
+	dir := t.TempDir()
+
+	// an empty file is a new log
+	path := filepath.Join(dir, "empty.log")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	store, err := OpenFileObjectStore(path, FileObjectStoreOptions{})
+	require.NoError(t, err)
+	createSecrets(t, store, "one")
+	require.NoError(t, store.Close())
+
+	header, err := (&FileObjectStore{}).encodeHeader()
+	require.NoError(t, err)
+
+	for name, content := range map[string][]byte{
+		"text":           []byte("not a log\n"),
+		"partial header": header[:len(header)-1],
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "other.log")
+			require.NoError(t, os.WriteFile(path, content, 0o600))
+
+			_, err := OpenFileObjectStore(path, FileObjectStoreOptions{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "not an object store log")
+
+			// the file is left alone
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, content, b)
+		})
+	}
+}
+
+func TestFileObjectStore_discardTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "objects.log")
+
+	store, err := OpenFileObjectStore(path, FileObjectStoreOptions{NoSync: true})
+	require.NoError(t, err)
+
+	createSecrets(t, store, "one")
+
+	// simulate a failed write, which left part of a record behind
+	_, err = store.f.Write([]byte{0x54, 0xff, 0x04, 0x01})
+	require.NoError(t, err)
+	store.discardTail()
+
+	createSecrets(t, store, "two")
+	require.NoError(t, store.Close())
+
+	store, err = OpenFileObjectStore(path, FileObjectStoreOptions{NoSync: true})
+	require.NoError(t, err)
+
+	defer store.Close()
+
+	assert.Equal(t, []string{"one", "two"}, storedSecrets(t, store))
+}
+
+func TestFileObjectStore_failedCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "objects.log")
+
+	store, err := OpenFileObjectStore(path, FileObjectStoreOptions{NoSync: true, CompactionThreshold: 1})
+	require.NoError(t, err)
+
+	defer store.Close()
+
+	ids := createSecrets(t, store, "one")
+
+	// a directory in the way of the compacted log
+	require.NoError(t, os.Mkdir(path+".compact", 0o700))
+
+	update := func() error {
+		return store.Update(ctx, func(tx ObjectTx) error {
+			obj, err := tx.Get(ids[0])
+			require.NoError(t, err)
+			obj.SetAttributeTag(kmip14.TagObjectGroup, "group")
+
+			return tx.Put(obj)
+		})
+	}
+
+	// the updates are stored, though the log can't be compacted
+	require.NoError(t, update())
+	require.NoError(t, update())
+	assert.Greater(t, store.records, 2)
+
+	// compaction is retried after the next update
+	require.NoError(t, os.Remove(path+".compact"))
+	require.NoError(t, update())
+	assert.Equal(t, 1, store.records)
+}
+
+func TestFileObjectStore_encryption(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "objects.log")
+
+	key := bytes.Repeat([]byte{1}, 32)
+	keyPath := filepath.Join(dir, "master.key")
+	require.NoError(t, os.WriteFile(keyPath, []byte(hex.EncodeToString(key)+"\n"), 0o600))
+
+	readKey, err := ReadMasterKeyFile(keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, key, readKey)
+
+	store, err := OpenFileObjectStore(path, FileObjectStoreOptions{MasterKey: readKey})
+	require.NoError(t, err)
+
+	createSecrets(t, store, "supersecret")
+	require.NoError(t, store.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "supersecret")
+
+	_, err = OpenFileObjectStore(path, FileObjectStoreOptions{})
+	require.Error(t, err)
+
+	_, err = OpenFileObjectStore(path, FileObjectStoreOptions{MasterKey: bytes.Repeat([]byte{2}, 32)})
+	require.ErrorIs(t, err, ErrWrongMasterKey)
+
+	store, err = OpenFileObjectStore(path, FileObjectStoreOptions{MasterKey: key})
+	require.NoError(t, err)
+
+	defer store.Close()
+
+	assert.Equal(t, []string{"supersecret"}, storedSecrets(t, store))
+}
+
+func TestFileObjectStore_compaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "objects.log")
+	opts := FileObjectStoreOptions{NoSync: true, CompactionThreshold: 10}
+
+	store, err := OpenFileObjectStore(path, opts)
+	require.NoError(t, err)
+
+	ids := createSecrets(t, store, "one", "two")
+
+	var maxSize int64
+
+	for i := 0; i < 30; i++ {
+		require.NoError(t, store.Update(ctx, func(tx ObjectTx) error {
+			obj, err := tx.Get(ids[1])
+			require.NoError(t, err)
+			obj.SetAttributeTag(kmip14.TagObjectGroup, "group")
+
+			return tx.Put(obj)
+		}))
+
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+
+		if fi.Size() > maxSize {
+			maxSize = fi.Size()
+		}
+	}
+
+	require.NoError(t, store.Compact())
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, fi.Size(), maxSize)
+
+	// the compacted log can be appended to, and replayed
+	createSecrets(t, store, "three")
+	require.NoError(t, store.Close())
+
+	store, err = OpenFileObjectStore(path, opts)
+	require.NoError(t, err)
+
+	defer store.Close()
+
+	assert.Equal(t, []string{"one", "two", "three"}, storedSecrets(t, store))
+}
@@ -0,0 +1,235 @@
+package kmip
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/google/uuid"
+)
+
+// MemoryObjectStore is an ObjectStore which holds objects in memory.  The zero value is ready to use.
+//
+// Objects are held in their TTLV encoding, so objects returned by a transaction never share memory
+// with the store, or with objects returned by other transactions.  Update transactions are serialized.
+type MemoryObjectStore struct {
+	mu      sync.RWMutex
+	objects map[string]ttlv.TTLV
+	order   []string
+}
+
+// View implements ObjectStore.
+func (s *MemoryObjectStore) View(ctx context.Context, fn func(tx ObjectTx) error) error {
+	if err := ctx.Err(); err != nil {
+		return merry.Wrap(err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return fn(&memoryObjectTx{store: s})
+}
+
+// Update implements ObjectStore.
+func (s *MemoryObjectStore) Update(ctx context.Context, fn func(tx ObjectTx) error) error {
+	return s.update(ctx, fn, nil)
+}
+
+// update runs fn in a read-write transaction.  If persist is not nil, it's called with the transaction
+// after fn succeeds, and before the changes are applied.  If persist returns an error, the changes are
+// discarded.
+func (s *MemoryObjectStore) update(ctx context.Context, fn func(tx ObjectTx) error, persist func(tx *memoryObjectTx) error) error {
+	if err := ctx.Err(); err != nil {
+		return merry.Wrap(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memoryObjectTx{
+		store:    s,
+		writable: true,
+		changes:  map[string]ttlv.TTLV{},
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if persist != nil {
+		if err := persist(tx); err != nil {
+			return err
+		}
+	}
+
+	s.apply(tx.changes, tx.created)
+
+	return nil
+}
+
+// apply applies changes to the store.  A nil value in changes deletes the object.  created lists
+// new objects, in the order they were created.
+func (s *MemoryObjectStore) apply(changes map[string]ttlv.TTLV, created []string) {
+	if s.objects == nil {
+		s.objects = map[string]ttlv.TTLV{}
+	}
+
+	deleted := false
+
+	for id, b := range changes {
+		if b == nil {
+			delete(s.objects, id)
+
+			deleted = true
+		} else {
+			s.objects[id] = b
+		}
+	}
+
+	if len(created) > 0 || deleted {
+		order := make([]string, 0, len(s.objects))
+		seen := make(map[string]bool, len(s.objects))
+
+		for _, id := range append(s.order, created...) {
+			if _, ok := s.objects[id]; ok && !seen[id] {
+				seen[id] = true
+				order = append(order, id)
+			}
+		}
+
+		s.order = order
+	}
+}
+
+// memoryObjectTx holds the changes made in an update transaction until it commits.  A nil
+// value in changes marks a deleted object.
+type memoryObjectTx struct {
+	store    *MemoryObjectStore
+	writable bool
+	changes  map[string]ttlv.TTLV
+	created  []string
+}
+
+func (tx *memoryObjectTx) lookup(id string) (ttlv.TTLV, bool) {
+	if b, ok := tx.changes[id]; ok {
+		return b, b != nil
+	}
+
+	b, ok := tx.store.objects[id]
+
+	return b, ok
+}
+
+func (tx *memoryObjectTx) Get(id string) (*ManagedObject, error) {
+	b, ok := tx.lookup(id)
+	if !ok {
+		return nil, objectNotFoundError(id)
+	}
+
+	return decodeManagedObject(b)
+}
+
+func (tx *memoryObjectTx) Create(obj *ManagedObject) (string, error) {
+	if !tx.writable {
+		return "", merry.New("kmip: create in read-only transaction")
+	}
+
+	if obj.UniqueIdentifier == "" {
+		obj.UniqueIdentifier = uuid.New().String()
+	}
+
+	if _, ok := tx.lookup(obj.UniqueIdentifier); ok {
+		return "", merry.Prependf(ErrObjectExists, "unique identifier %q", obj.UniqueIdentifier)
+	}
+
+	b, err := encodeManagedObject(obj)
+	if err != nil {
+		return "", err
+	}
+
+	tx.changes[obj.UniqueIdentifier] = b
+	tx.created = append(tx.created, obj.UniqueIdentifier)
+
+	return obj.UniqueIdentifier, nil
+}
+
+func (tx *memoryObjectTx) Put(obj *ManagedObject) error {
+	if !tx.writable {
+		return merry.New("kmip: put in read-only transaction")
+	}
+
+	if _, ok := tx.lookup(obj.UniqueIdentifier); !ok {
+		return objectNotFoundError(obj.UniqueIdentifier)
+	}
+
+	b, err := encodeManagedObject(obj)
+	if err != nil {
+		return err
+	}
+
+	tx.changes[obj.UniqueIdentifier] = b
+
+	return nil
+}
+
+func (tx *memoryObjectTx) Delete(id string) error {
+	if !tx.writable {
+		return merry.New("kmip: delete in read-only transaction")
+	}
+
+	if _, ok := tx.lookup(id); !ok {
+		return objectNotFoundError(id)
+	}
+
+	tx.changes[id] = nil
+
+	return nil
+}
+
+func (tx *memoryObjectTx) ForEach(fn func(obj *ManagedObject) error) error {
+	ids := tx.store.order
+	if len(tx.created) > 0 {
+		ids = append(ids[:len(ids):len(ids)], tx.created...)
+	}
+
+	for _, id := range ids {
+		b, ok := tx.lookup(id)
+		if !ok {
+			continue
+		}
+
+		obj, err := decodeManagedObject(b)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(obj); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tagManagedObject is the tag used to encode a ManagedObject for storage.  The spec doesn't
+// define this structure, so the tag is taken from the range reserved for extensions.
+const tagManagedObject ttlv.Tag = 0x54ff00
+
+func encodeManagedObject(obj *ManagedObject) (ttlv.TTLV, error) {
+	b, err := ttlv.Marshal(ttlv.Value{Tag: tagManagedObject, Value: obj})
+	if err != nil {
+		return nil, merry.Prepend(err, "encoding managed object")
+	}
+
+	return b, nil
+}
+
+func decodeManagedObject(b ttlv.TTLV) (*ManagedObject, error) {
+	var obj ManagedObject
+	if err := ttlv.Unmarshal(b, &obj); err != nil {
+		return nil, merry.Prepend(err, "decoding managed object")
+	}
+
+	return &obj, nil
+}
@@ -0,0 +1,143 @@
+package kmip
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSecretData(secret string) *ManagedObject {
+	obj := &ManagedObject{}
+	_ = obj.SetObject(&SecretData{
+		SecretDataType: kmip14.SecretDataTypePassword,
+		KeyBlock: KeyBlock{
+			KeyFormatType: kmip14.KeyFormatTypeOpaque,
+			KeyValue:      &KeyValue{KeyMaterial: []byte(secret)},
+		},
+	})
+
+	return obj
+}
+
+func TestMemoryObjectStore(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryObjectStore{}
+
+	var id1, id2 string
+
+	require.NoError(t, store.Update(ctx, func(tx ObjectTx) error {
+		var err error
+
+		id1, err = tx.Create(newTestSecretData("one"))
+		require.NoError(t, err)
+
+		obj := newTestSecretData("two")
+		obj.UniqueIdentifier = "two"
+		obj.SetAttributeTag(kmip14.TagObjectGroup, "group1")
+		id2, err = tx.Create(obj)
+		require.NoError(t, err)
+
+		// objects created in the transaction are visible to it
+		_, err = tx.Get(id1)
+
+		return err
+	}))
+
+	assert.NotEmpty(t, id1)
+	assert.Equal(t, "two", id2)
+
+	// changes are discarded if the transaction fails
+	errRollback := errors.New("rollback")
+	err := store.Update(ctx, func(tx ObjectTx) error {
+		require.NoError(t, tx.Delete(id1))
+
+		obj, err := tx.Get(id2)
+		require.NoError(t, err)
+		obj.SetAttributeTag(kmip14.TagObjectGroup, "group2")
+		require.NoError(t, tx.Put(obj))
+
+		_, err = tx.Create(newTestSecretData("three"))
+		require.NoError(t, err)
+
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	var ids []string
+
+	require.NoError(t, store.View(ctx, func(tx ObjectTx) error {
+		obj, err := tx.Get(id2)
+		require.NoError(t, err)
+		assert.Equal(t, kmip14.ObjectTypeSecretData, obj.ObjectType)
+		assert.Equal(t, []byte("two"), obj.SecretData.KeyBlock.KeyValue.KeyMaterial)
+		assert.Equal(t, "group1", obj.GetAttributeTag(kmip14.TagObjectGroup).AttributeValue)
+
+		// objects are copies, and changes aren't saved without Put
+		obj.SetAttributeTag(kmip14.TagObjectGroup, "group3")
+
+		return tx.ForEach(func(obj *ManagedObject) error {
+			ids = append(ids, obj.UniqueIdentifier)
+			return nil
+		})
+	}))
+	assert.Equal(t, []string{id1, id2}, ids)
+
+	require.NoError(t, store.Update(ctx, func(tx ObjectTx) error {
+		obj, err := tx.Get(id2)
+		require.NoError(t, err)
+		assert.Equal(t, "group1", obj.GetAttributeTag(kmip14.TagObjectGroup).AttributeValue)
+
+		_, err = tx.Create(obj)
+		require.ErrorIs(t, err, ErrObjectExists)
+
+		return tx.Delete(id1)
+	}))
+
+	err = store.View(ctx, func(tx ObjectTx) error {
+		_, err := tx.Get(id1)
+		return err
+	})
+	require.ErrorIs(t, err, ErrObjectNotFound)
+	assert.Equal(t, kmip14.ResultReasonItemNotFound, GetResultReason(err))
+
+	err = store.View(ctx, func(tx ObjectTx) error {
+		_, err := tx.Create(newTestSecretData("four"))
+		return err
+	})
+	require.Error(t, err)
+}
+
+func TestDecodeAttributeValue(t *testing.T) {
+	var state kmip14.State
+
+	require.NoError(t, DecodeAttributeValue(kmip14.StateActive, &state))
+	assert.Equal(t, kmip14.StateActive, state)
+
+	obj := newTestSecretData("one")
+	obj.SetAttributeTag(kmip14.TagName, Name{NameValue: "key1", NameType: kmip14.NameTypeUninterpretedTextString})
+	obj.SetAttributeTag(kmip14.TagState, kmip14.StatePreActive)
+
+	// attributes read back from a store hold their TTLV decoded form
+	store := &MemoryObjectStore{}
+	require.NoError(t, store.Update(context.Background(), func(tx ObjectTx) error {
+		_, err := tx.Create(obj)
+		return err
+	}))
+	require.NoError(t, store.View(context.Background(), func(tx ObjectTx) error {
+		var err error
+		obj, err = tx.Get(obj.UniqueIdentifier)
+
+		return err
+	}))
+
+	var name Name
+
+	require.NoError(t, DecodeAttributeValue(obj.GetAttributeTag(kmip14.TagName).AttributeValue, &name))
+	assert.Equal(t, "key1", name.NameValue)
+	require.NoError(t, DecodeAttributeValue(obj.GetAttributeTag(kmip14.TagState).AttributeValue, &state))
+	assert.Equal(t, kmip14.StatePreActive, state)
+}
@@ -0,0 +1,48 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.19 Activate
+//
+// This operation requests the server to activate a Managed Cryptographic Object. The operation SHALL only
+// be performed on an object in the Pre-Active state and has the effect of changing its state to Active,
+// and setting its Activation Date to the current date and time.
+
+// ActivateRequestPayload 4.19
+type ActivateRequestPayload struct {
+	UniqueIdentifier string
+}
+
+// ActivateResponsePayload 4.19
+type ActivateResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type ActivateHandler struct {
+	Activate func(ctx context.Context, payload *ActivateRequestPayload) (*ActivateResponsePayload, error)
+}
+
+func (h *ActivateHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload ActivateRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.Activate(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
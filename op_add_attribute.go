@@ -0,0 +1,56 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.14 Add Attribute
+//
+// This operation requests the server to add a new attribute instance to be associated with a Managed Object
+// and set its value. The request contains the Unique Identifier of the Managed Object to which the attribute
+// pertains, along with the attribute name and value. For single-instance attributes, this is how the attribute
+// value is created. For multi-instance attributes, this is how the first and subsequent values are created.
+// Existing attribute values SHALL only be changed by the Modify Attribute operation. Read-Only attributes
+// SHALL NOT be added using the Add Attribute operation. The Attribute Index SHALL NOT be specified in the
+// request. The response returns a new Attribute Index and the Attribute Index MAY be omitted if the index of
+// the added attribute instance is 0. Multiple Add Attribute requests MAY be included in a single batched
+// request to add multiple attributes.
+
+// AddAttributeRequestPayload 4.14
+type AddAttributeRequestPayload struct {
+	UniqueIdentifier string
+	Attribute        Attribute
+}
+
+// AddAttributeResponsePayload 4.14
+type AddAttributeResponsePayload struct {
+	UniqueIdentifier string
+	Attribute        Attribute
+}
+
+type AddAttributeHandler struct {
+	AddAttribute func(ctx context.Context, payload *AddAttributeRequestPayload) (*AddAttributeResponsePayload, error)
+}
+
+func (h *AddAttributeHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload AddAttributeRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.AddAttribute(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
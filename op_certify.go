@@ -0,0 +1,68 @@
+package kmip
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 4.7 Certify
+//
+// This request is used to generate a Certificate object for a public key.  This request supports the
+// certification of a new public key, as well as the certification of a public key that has already been
+// certified (i.e., certificate update).  Only a single certificate SHALL be requested at a time.
+//
+// The Certificate Request is passed as a Byte String, which allows multiple certificate request types for
+// X.509 certificates (e.g., PKCS#10, PEM, etc.) to be submitted to the server.
+//
+// The generated Certificate object whose Unique Identifier is returned MAY be obtained by the client via a Get
+// operation in the same batch, using the ID Placeholder mechanism.
+//
+// As a result of Certify, the Link attribute of the Public Key is set to point to the generated certificate, and
+// the certificate's Link attribute is set to point to the Public Key.  The server SHALL copy the Unique
+// Identifier of the generated certificate returned by this operation into the ID Placeholder variable.
+
+// CertifyRequestPayload 4.7
+//
+// The Unique Identifier of the Public Key defaults to the ID Placeholder.
+type CertifyRequestPayload struct {
+	UniqueIdentifier       string                        `ttlv:",omitempty"`
+	CertificateRequestType kmip14.CertificateRequestType `ttlv:",omitempty"`
+	CertificateRequest     []byte                        `ttlv:",omitempty"`
+	TemplateAttribute      *TemplateAttribute            `ttlv:",omitempty"`
+}
+
+// CertifyResponsePayload 4.7
+type CertifyResponsePayload struct {
+	UniqueIdentifier  string
+	TemplateAttribute *TemplateAttribute `ttlv:",omitempty"`
+}
+
+type CertifyHandler struct {
+	Certify func(ctx context.Context, payload *CertifyRequestPayload) (*CertifyResponsePayload, error)
+}
+
+func (h *CertifyHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload CertifyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.Certify(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
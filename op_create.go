@@ -59,13 +59,21 @@ func (h *CreateHandler) HandleItem(ctx context.Context, req *Request) (*Response
 		return nil, err
 	}
 
-	var ok bool
+	req.IDPlaceholder = respPayload.UniqueIdentifier
 
-	idAttr := respPayload.TemplateAttribute.GetTag(kmip14.TagUniqueIdentifier)
+	if req.IDPlaceholder == "" {
+		// older handlers returned the unique identifier as an attribute
+		var ok bool
 
-	req.IDPlaceholder, ok = idAttr.AttributeValue.(string)
-	if !ok {
-		return nil, merry.Errorf("invalid response returned by CreateHandler: unique identifier tag in attributes should have been a string, was %t", idAttr.AttributeValue)
+		idAttr := respPayload.TemplateAttribute.GetTag(kmip14.TagUniqueIdentifier)
+		if idAttr == nil {
+			return nil, merry.New("invalid response returned by CreateHandler: missing unique identifier")
+		}
+
+		req.IDPlaceholder, ok = idAttr.AttributeValue.(string)
+		if !ok {
+			return nil, merry.Errorf("invalid response returned by CreateHandler: unique identifier tag in attributes should have been a string, was %t", idAttr.AttributeValue)
+		}
 	}
 
 	return &ResponseBatchItem{
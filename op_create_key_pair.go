@@ -1,5 +1,9 @@
 package kmip
 
+import (
+	"context"
+)
+
 // CreateKeyPairRequestPayload
 // 4.2 Create Key Pair
 // This operation requests the server to generate a new public/private key pair
@@ -36,3 +40,27 @@ type CreateKeyPairResponsePayload struct {
 	PrivateKeyTemplateAttribute *TemplateAttribute
 	PublicKeyTemplateAttribute  *TemplateAttribute
 }
+
+type CreateKeyPairHandler struct {
+	CreateKeyPair func(ctx context.Context, payload *CreateKeyPairRequestPayload) (*CreateKeyPairResponsePayload, error)
+}
+
+func (h *CreateKeyPairHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload CreateKeyPairRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.CreateKeyPair(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.PrivateKeyUniqueIdentifier
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package kmip
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 4.38 Create Split Key
+//
+// This operation requests the server to generate a new split key and register all the splits as individual new
+// Managed Cryptographic Objects.  The Unique Identifier of an existing key or Secret Data object MAY be given, in
+// which case that key is split, rather than a new one generated.  The Object Type is the type of the key which is
+// split.
+//
+// The Split Key Threshold is the minimum number of parts needed to reconstruct the key, and the Prime Field Size
+// is required for the Polynomial Sharing Prime Field method.  The response returns the Unique Identifiers of the
+// parts, in the order of their Key Part Identifiers.  The ID Placeholder is left unchanged.
+
+// CreateSplitKeyRequestPayload 4.38
+type CreateSplitKeyRequestPayload struct {
+	ObjectType        kmip14.ObjectType
+	UniqueIdentifier  string `ttlv:",omitempty"`
+	SplitKeyParts     int
+	SplitKeyThreshold int
+	SplitKeyMethod    kmip14.SplitKeyMethod
+	PrimeFieldSize    *big.Int `ttlv:",omitempty"`
+	TemplateAttribute TemplateAttribute
+}
+
+// CreateSplitKeyResponsePayload 4.38
+type CreateSplitKeyResponsePayload struct {
+	UniqueIdentifier []string
+}
+
+type CreateSplitKeyHandler struct {
+	CreateSplitKey func(ctx context.Context, payload *CreateSplitKeyRequestPayload) (*CreateSplitKeyResponsePayload, error)
+}
+
+func (h *CreateSplitKeyHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload CreateSplitKeyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.CreateSplitKey(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.30 Decrypt
+//
+// This operation requests the server to perform a decryption operation on the provided data using a Managed
+// Cryptographic Object as the key for the decryption operation.
+//
+// The request contains information about the cryptographic parameters (mode and padding method), the data to be
+// decrypted, and the IV/Counter/Nonce to use. The cryptographic parameters MAY be omitted from the request as
+// they can be specified as associated attributes of the Managed Cryptographic Object. The initialization vector/
+// counter/nonce MAY also be omitted from the request if the algorithm does not use an IV/Counter/Nonce.
+//
+// The response contains the Unique Identifier of the Managed Cryptographic Object used as the key and the result
+// of the decryption operation.
+//
+// The success or failure of the operation is indicated by the Result Status (and if failure the Result Reason)
+// in the response header.
+
+// DecryptRequestPayload 4.30
+type DecryptRequestPayload struct {
+	UniqueIdentifier                      string                   `ttlv:",omitempty"`
+	CryptographicParameters               *CryptographicParameters `ttlv:",omitempty"`
+	Data                                  []byte                   `ttlv:",omitempty"`
+	IVCounterNonce                        []byte                   `ttlv:",omitempty"`
+	CorrelationValue                      []byte                   `ttlv:",omitempty"`
+	InitIndicator                         bool                     `ttlv:",omitempty"`
+	FinalIndicator                        bool                     `ttlv:",omitempty"`
+	AuthenticatedEncryptionAdditionalData []byte                   `ttlv:",omitempty"`
+	AuthenticatedEncryptionTag            []byte                   `ttlv:",omitempty"`
+}
+
+// DecryptResponsePayload 4.30
+type DecryptResponsePayload struct {
+	UniqueIdentifier string
+	Data             []byte `ttlv:",omitempty"`
+	CorrelationValue []byte `ttlv:",omitempty"`
+}
+
+type DecryptHandler struct {
+	Decrypt func(ctx context.Context, payload *DecryptRequestPayload) (*DecryptResponsePayload, error)
+}
+
+func (h *DecryptHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload DecryptRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.Decrypt(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.16 Delete Attribute
+//
+// This operation requests the server to delete an attribute associated with a Managed Object. The request
+// contains the Unique Identifier of the Managed Object whose attribute is to be deleted, the attribute name,
+// and the OPTIONAL Attribute Index of the attribute. If no Attribute Index is specified in the request, then
+// the Attribute Index SHALL be assumed to be 0. Attributes that are always required to have a value SHALL
+// never be deleted by this operation. Attempting to delete a non-existent attribute or specifying an Attribute
+// Index for which there exists no Attribute Value SHALL result in an error. The response returns the deleted
+// Attribute.
+
+// DeleteAttributeRequestPayload 4.16
+type DeleteAttributeRequestPayload struct {
+	UniqueIdentifier string
+	AttributeName    string
+	AttributeIndex   int `ttlv:",omitempty"`
+}
+
+// DeleteAttributeResponsePayload 4.16
+type DeleteAttributeResponsePayload struct {
+	UniqueIdentifier string
+	Attribute        Attribute
+}
+
+type DeleteAttributeHandler struct {
+	DeleteAttribute func(ctx context.Context, payload *DeleteAttributeRequestPayload) (*DeleteAttributeResponsePayload, error)
+}
+
+func (h *DeleteAttributeHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload DeleteAttributeRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.DeleteAttribute(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package kmip
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 4.6 Derive Key
+//
+// This request is used to derive a Symmetric Key or Secret Data object from keys or Secret Data objects that are
+// already known to the key management system.  The request SHALL only apply to Managed Objects that have the
+// Derive Key bit set in the Cryptographic Usage Mask attribute of the specified Managed Object (i.e., are able to
+// be used for key derivation).  If the operation is issued for an object that does not have this bit set, then
+// the server SHALL return an error.
+//
+// The Unique Identifiers of the base objects MAY be omitted, in which case the ID Placeholder is used.  The length
+// of the derived key material is given by the Cryptographic Length attribute in the Template-Attribute.
+//
+// The derived object is linked to each of the base objects with a Derivation Base Object Link, and each base
+// object is linked to the derived object with a Derived Key Link.  The server SHALL copy the Unique Identifier
+// of the derived object into the ID Placeholder variable.
+
+// DerivationParameters 4.6
+//
+// The Derivation Parameters hold the parameters of the Derivation Method.  Which fields are required depends on
+// the method: PBKDF2 takes a Salt and an Iteration Count, and the other methods typically take Derivation Data.
+// The Initialization Vector is used by the ENCRYPT method, and as the initial feedback value by NIST800-108-F.
+type DerivationParameters struct {
+	CryptographicParameters *CryptographicParameters `ttlv:",omitempty"`
+	InitializationVector    []byte                   `ttlv:",omitempty"`
+	DerivationData          []byte                   `ttlv:",omitempty"`
+	Salt                    []byte                   `ttlv:",omitempty"`
+	IterationCount          int                      `ttlv:",omitempty"`
+}
+
+// DeriveKeyRequestPayload 4.6
+type DeriveKeyRequestPayload struct {
+	ObjectType           kmip14.ObjectType
+	UniqueIdentifier     []string `ttlv:",omitempty"`
+	DerivationMethod     kmip14.DerivationMethod
+	DerivationParameters DerivationParameters
+	TemplateAttribute    TemplateAttribute
+}
+
+// DeriveKeyResponsePayload 4.6
+type DeriveKeyResponsePayload struct {
+	UniqueIdentifier  string
+	TemplateAttribute *TemplateAttribute `ttlv:",omitempty"`
+}
+
+type DeriveKeyHandler struct {
+	DeriveKey func(ctx context.Context, payload *DeriveKeyRequestPayload) (*DeriveKeyResponsePayload, error)
+}
+
+func (h *DeriveKeyHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload DeriveKeyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the base object defaults to the ID Placeholder, set by a previous item in the batch
+	if len(payload.UniqueIdentifier) == 0 {
+		payload.UniqueIdentifier = []string{req.IDPlaceholder}
+	}
+
+	respPayload, err := h.DeriveKey(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
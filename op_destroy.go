@@ -26,6 +26,11 @@ func (h *DestroyHandler) HandleItem(ctx context.Context, req *Request) (*Respons
 		return nil, err
 	}
 
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
 	respPayload, err := h.Destroy(ctx, &payload)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,75 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.29 Encrypt
+//
+// This operation requests the server to perform an encryption operation on the provided data using a Managed
+// Cryptographic Object as the key for the encryption operation.
+//
+// The request contains information about the cryptographic parameters (mode and padding method), the data to be
+// encrypted, and the IV/Counter/Nonce to use. The cryptographic parameters MAY be omitted from the request as
+// they can be specified as associated attributes of the Managed Cryptographic Object.
+//
+// The IV/Counter/Nonce MAY also be omitted from the request if the cryptographic parameters indicate that the
+// server shall generate a Random IV on behalf of the client or the encryption algorithm does not need an
+// IV/Counter/Nonce. The server does not store or otherwise manage the IV/Counter/Nonce.
+//
+// If the Managed Cryptographic Object referenced has a Usage Limits attribute then the server SHALL obtain an
+// allocation from the current Usage Limits value prior to performing the encryption operation.
+//
+// The response contains the Unique Identifier of the Managed Cryptographic Object used as the key and the result
+// of the encryption operation.
+//
+// The success or failure of the operation is indicated by the Result Status (and if failure the Result Reason)
+// in the response header.
+
+// EncryptRequestPayload 4.29
+type EncryptRequestPayload struct {
+	UniqueIdentifier                      string                   `ttlv:",omitempty"`
+	CryptographicParameters               *CryptographicParameters `ttlv:",omitempty"`
+	Data                                  []byte                   `ttlv:",omitempty"`
+	IVCounterNonce                        []byte                   `ttlv:",omitempty"`
+	CorrelationValue                      []byte                   `ttlv:",omitempty"`
+	InitIndicator                         bool                     `ttlv:",omitempty"`
+	FinalIndicator                        bool                     `ttlv:",omitempty"`
+	AuthenticatedEncryptionAdditionalData []byte                   `ttlv:",omitempty"`
+}
+
+// EncryptResponsePayload 4.29
+type EncryptResponsePayload struct {
+	UniqueIdentifier           string
+	Data                       []byte `ttlv:",omitempty"`
+	IVCounterNonce             []byte `ttlv:",omitempty"`
+	CorrelationValue           []byte `ttlv:",omitempty"`
+	AuthenticatedEncryptionTag []byte `ttlv:",omitempty"`
+}
+
+type EncryptHandler struct {
+	Encrypt func(ctx context.Context, payload *EncryptRequestPayload) (*EncryptResponsePayload, error)
+}
+
+func (h *EncryptHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload EncryptRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.Encrypt(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -7,8 +7,15 @@ import (
 )
 
 // GetRequestPayload ////////////////////////////////////////
+//
+// The Key Format Type, Key Wrap Type, Key Compression Type and Key Wrapping Specification determine how the key
+// is returned.  They are omitted to return the key as it's stored.
 type GetRequestPayload struct {
-	UniqueIdentifier string
+	UniqueIdentifier         string
+	KeyFormatType            kmip14.KeyFormatType      `ttlv:",omitempty"`
+	KeyWrapType              kmip14.KeyWrapType        `ttlv:",omitempty"`
+	KeyCompressionType       kmip14.KeyCompressionType `ttlv:",omitempty"`
+	KeyWrappingSpecification *KeyWrappingSpecification `ttlv:",omitempty"`
 }
 
 // GetResponsePayload
@@ -37,6 +44,11 @@ func (h *GetHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBat
 		return nil, err
 	}
 
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
 	respPayload, err := h.Get(ctx, &payload)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,48 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.13 Get Attribute List
+//
+// This operation requests a list of the attribute names associated with a Managed Object. The object is
+// specified by its Unique Identifier.
+
+// GetAttributeListRequestPayload 4.13
+type GetAttributeListRequestPayload struct {
+	UniqueIdentifier string
+}
+
+// GetAttributeListResponsePayload 4.13
+type GetAttributeListResponsePayload struct {
+	UniqueIdentifier string
+	AttributeName    []string
+}
+
+type GetAttributeListHandler struct {
+	GetAttributeList func(ctx context.Context, payload *GetAttributeListRequestPayload) (*GetAttributeListResponsePayload, error)
+}
+
+func (h *GetAttributeListHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload GetAttributeListRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.GetAttributeList(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
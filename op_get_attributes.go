@@ -0,0 +1,53 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.12 Get Attributes
+//
+// This operation requests one or more attributes associated with a Managed Object. The object is specified
+// by its Unique Identifier, and the attributes are specified by their name in the request. If a specified
+// attribute has multiple instances, then all instances are returned. If a specified attribute does not exist
+// (i.e., has no value), then it SHALL NOT be present in the returned response. If no requested attributes
+// exist, then the response SHALL consist only of the Unique Identifier. If no attribute name is specified
+// in the request, all attributes SHALL be returned.
+
+// GetAttributesRequestPayload 4.12
+type GetAttributesRequestPayload struct {
+	UniqueIdentifier string
+	AttributeName    []string
+}
+
+// GetAttributesResponsePayload 4.12
+type GetAttributesResponsePayload struct {
+	UniqueIdentifier string
+	Attribute        []Attribute
+}
+
+type GetAttributesHandler struct {
+	GetAttributes func(ctx context.Context, payload *GetAttributesRequestPayload) (*GetAttributesResponsePayload, error)
+}
+
+func (h *GetAttributesHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload GetAttributesRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.GetAttributes(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
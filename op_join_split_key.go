@@ -0,0 +1,54 @@
+package kmip
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 4.39 Join Split Key
+//
+// This request is used to combine a list of Split Keys into a single Managed Cryptographic Object.  The number of
+// Unique Identifiers in the request SHALL be at least the value of the Split Key Threshold defined in the Split
+// Keys.  The Object Type is the type of the object to create, a Symmetric Key or Secret Data.  The Secret Data
+// Type is only used for Secret Data.
+//
+// The server SHALL copy the Unique Identifier of the newly created object into the ID Placeholder variable.
+
+// JoinSplitKeyRequestPayload 4.39
+type JoinSplitKeyRequestPayload struct {
+	ObjectType        kmip14.ObjectType
+	UniqueIdentifier  []string
+	SecretDataType    kmip14.SecretDataType `ttlv:",omitempty"`
+	TemplateAttribute TemplateAttribute
+}
+
+// JoinSplitKeyResponsePayload 4.39
+type JoinSplitKeyResponsePayload struct {
+	UniqueIdentifier  string
+	TemplateAttribute *TemplateAttribute `ttlv:",omitempty"`
+}
+
+type JoinSplitKeyHandler struct {
+	JoinSplitKey func(ctx context.Context, payload *JoinSplitKeyRequestPayload) (*JoinSplitKeyResponsePayload, error)
+}
+
+func (h *JoinSplitKeyHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload JoinSplitKeyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.JoinSplitKey(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package kmip
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 4.9 Locate
+//
+// This operation requests that the server search for one or more Managed Objects, depending on the
+// attributes specified in the request. All attributes are allowed to be used. The request MAY contain
+// a Maximum Items field, which specifies the maximum number of objects to be returned. If the Maximum
+// Items field is omitted, then the server MAY return all objects matched, or MAY impose an internal
+// maximum limit due to resource limitations.
+//
+// The Storage Status Mask field is used to indicate whether only on-line objects, only archived objects,
+// or both on-line and archived objects are to be searched.
+//
+// The server returns a list of Unique Identifiers of the found objects, which then MAY be retrieved using
+// the Get operation. If the objects are archived, then the Recover and Get operations are REQUIRED to be
+// used to obtain those objects. If a single Unique Identifier is returned to the client, then the server
+// SHALL copy the Unique Identifier returned by this operation into the ID Placeholder variable.
+
+// The Offset Items field indicates the number of object identifiers to skip that satisfy the identification
+// criteria specified in the request.  Together with Maximum Items, it allows a client to page through the
+// located objects.  The response MAY contain the Located Items field, which is the number of objects which
+// satisfy the identification criteria, regardless of Offset Items and Maximum Items.
+//
+// The Object Group Member field indicates how the server should return the members of the group named by an
+// Object Group attribute in the request: Group Member Fresh returns the members which haven't been returned
+// before, and Group Member Default returns the group's default member.
+
+// LocateRequestPayload 4.9
+type LocateRequestPayload struct {
+	MaximumItems      int                      `ttlv:",omitempty"`
+	OffsetItems       int                      `ttlv:",omitempty"`
+	StorageStatusMask kmip14.StorageStatusMask `ttlv:",omitempty"`
+	ObjectGroupMember kmip14.ObjectGroupMember `ttlv:",omitempty"`
+	Attribute         []Attribute
+}
+
+// LocateResponsePayload 4.9
+type LocateResponsePayload struct {
+	LocatedItems     int `ttlv:",omitempty"`
+	UniqueIdentifier []string
+}
+
+type LocateHandler struct {
+	Locate func(ctx context.Context, payload *LocateRequestPayload) (*LocateResponsePayload, error)
+}
+
+func (h *LocateHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload LocateRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.Locate(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(respPayload.UniqueIdentifier) == 1 {
+		req.IDPlaceholder = respPayload.UniqueIdentifier[0]
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.33 MAC
+//
+// This operation requests the server to perform message authentication code (MAC) operation on the provided data
+// using a Managed Cryptographic Object as the key for the MAC operation.
+//
+// The request contains information about the cryptographic parameters (cryptographic algorithm) and the data to
+// be MACed. The cryptographic parameters MAY be omitted from the request as they can be specified as associated
+// attributes of the Managed Cryptographic Object.
+//
+// If the Managed Cryptographic Object referenced has a Usage Limits attribute then the server SHALL obtain an
+// allocation from the current Usage Limits value prior to performing the MAC operation.
+//
+// The response contains the Unique Identifier of the Managed Cryptographic Object used as the key and the result
+// of the MAC operation.
+//
+// The success or failure of the operation is indicated by the Result Status (and if failure the Result Reason)
+// in the response header.
+
+// MACRequestPayload 4.33
+type MACRequestPayload struct {
+	UniqueIdentifier        string                   `ttlv:",omitempty"`
+	CryptographicParameters *CryptographicParameters `ttlv:",omitempty"`
+	Data                    []byte                   `ttlv:",omitempty"`
+	CorrelationValue        []byte                   `ttlv:",omitempty"`
+	InitIndicator           bool                     `ttlv:",omitempty"`
+	FinalIndicator          bool                     `ttlv:",omitempty"`
+}
+
+// MACResponsePayload 4.33
+type MACResponsePayload struct {
+	UniqueIdentifier string
+	MACData          []byte `ttlv:",omitempty"`
+	CorrelationValue []byte `ttlv:",omitempty"`
+}
+
+type MACHandler struct {
+	MAC func(ctx context.Context, payload *MACRequestPayload) (*MACResponsePayload, error)
+}
+
+func (h *MACHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload MACRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.MAC(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
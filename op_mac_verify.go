@@ -0,0 +1,68 @@
+package kmip
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 4.34 MAC Verify
+//
+// This operation requests the server to perform message authentication code (MAC) verify operation on the
+// provided data using a Managed Cryptographic Object as the key for the MAC verify operation.
+//
+// The request contains information about the cryptographic parameters (cryptographic algorithm) and the data to
+// be MAC verified and MAY contain the data that was passed to the MAC operation (for those algorithms which need
+// the original data to verify a MAC). The cryptographic parameters MAY be omitted from the request as they can be
+// specified as associated attributes of the Managed Cryptographic Object.
+//
+// The response contains the Unique Identifier of the Managed Cryptographic Object used as the key and the result
+// of the MAC verify operation. The validity of the MAC is indicated by the Validity Indicator field.
+//
+// The success or failure of the operation is indicated by the Result Status (and if failure the Result Reason)
+// in the response header.
+
+// MACVerifyRequestPayload 4.34
+type MACVerifyRequestPayload struct {
+	UniqueIdentifier        string                   `ttlv:",omitempty"`
+	CryptographicParameters *CryptographicParameters `ttlv:",omitempty"`
+	Data                    []byte                   `ttlv:",omitempty"`
+	MACData                 []byte                   `ttlv:",omitempty"`
+	CorrelationValue        []byte                   `ttlv:",omitempty"`
+	InitIndicator           bool                     `ttlv:",omitempty"`
+	FinalIndicator          bool                     `ttlv:",omitempty"`
+}
+
+// MACVerifyResponsePayload 4.34
+type MACVerifyResponsePayload struct {
+	UniqueIdentifier  string
+	ValidityIndicator kmip14.ValidityIndicator
+	CorrelationValue  []byte `ttlv:",omitempty"`
+}
+
+type MACVerifyHandler struct {
+	MACVerify func(ctx context.Context, payload *MACVerifyRequestPayload) (*MACVerifyResponsePayload, error)
+}
+
+func (h *MACVerifyHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload MACVerifyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.MACVerify(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.15 Modify Attribute
+//
+// This operation requests the server to modify the value of an existing attribute instance associated with a
+// Managed Object. The request contains the Unique Identifier of the Managed Object whose attribute is to be
+// modified, the attribute name, the OPTIONAL Attribute Index, and the new value. If no Attribute Index is
+// specified in the request, then the Attribute Index SHALL be assumed to be 0. Only existing attributes MAY be
+// changed via this operation. New attributes SHALL only be added by the Add Attribute operation. Read-Only
+// attributes SHALL NOT be changed using this operation.
+
+// ModifyAttributeRequestPayload 4.15
+type ModifyAttributeRequestPayload struct {
+	UniqueIdentifier string
+	Attribute        Attribute
+}
+
+// ModifyAttributeResponsePayload 4.15
+type ModifyAttributeResponsePayload struct {
+	UniqueIdentifier string
+	Attribute        Attribute
+}
+
+type ModifyAttributeHandler struct {
+	ModifyAttribute func(ctx context.Context, payload *ModifyAttributeRequestPayload) (*ModifyAttributeResponsePayload, error)
+}
+
+func (h *ModifyAttributeHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload ModifyAttributeRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.ModifyAttribute(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 5.1 Notify
+//
+// This operation is used to notify a client of events that resulted in changes to attributes of an object.
+// This operation is only ever sent by a server to a client via the Server.Conns() handles, and is handled
+// by the client with a handler registered with Client.Handle().
+
+// NotifyRequestPayload 5.1
+//
+// The request contains the Unique Identifier of the object, and the attributes which changed.  If an
+// attribute was deleted, it is sent with only its Attribute Name (and Attribute Index, if non-zero).
+type NotifyRequestPayload struct {
+	UniqueIdentifier string
+	Attribute        []Attribute
+}
+
+// NotifyResponsePayload 5.1
+//
+// The response payload is empty.
+type NotifyResponsePayload struct{}
+
+type NotifyHandler struct {
+	Notify func(ctx context.Context, payload *NotifyRequestPayload) (*NotifyResponsePayload, error)
+}
+
+func (h *NotifyHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload NotifyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.Notify(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package kmip
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 5.2 Put
+//
+// This operation is used to "push" Managed Objects to clients.  This operation is only ever sent by a
+// server to a client via the Server.Conns() handles, and is handled by the client with a handler
+// registered with Client.Handle().
+//
+// The Put Function indicates whether the object being pushed is a new object, or is a replacement for
+// an object already known to the client (e.g., when pushing a certificate to replace one that is about
+// to expire, or a rotated key).  In the latter case, Replaced Unique Identifier identifies the object
+// being replaced.
+
+// PutRequestPayload 5.2
+type PutRequestPayload struct {
+	UniqueIdentifier         string
+	PutFunction              kmip14.PutFunction
+	ReplacedUniqueIdentifier string `ttlv:",omitempty"`
+	Certificate              *Certificate
+	SymmetricKey             *SymmetricKey
+	PrivateKey               *PrivateKey
+	PublicKey                *PublicKey
+	SplitKey                 *SplitKey
+	Template                 *Template
+	SecretData               *SecretData
+	OpaqueObject             *OpaqueObject
+	Attribute                []Attribute
+}
+
+// PutResponsePayload 5.2
+//
+// The response payload is empty.
+type PutResponsePayload struct{}
+
+type PutHandler struct {
+	Put func(ctx context.Context, payload *PutRequestPayload) (*PutResponsePayload, error)
+}
+
+func (h *PutHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload PutRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.Put(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package kmip
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 4.25 Query
+//
+// This operation is used by the client to interrogate the server to determine its capabilities and/or
+// protocol mechanisms. The Query operation SHOULD be invocable by unauthenticated clients to interrogate
+// server features and functions. The Query Function field in the request SHALL contain one or more of
+// the following items:
+//
+//   - Query Operations
+//   - Query Objects
+//   - Query Server Information
+//   - Query Application Namespaces
+//   - Query Extension List
+//   - Query Extension Map
+//   - Query Attestation Types
+//   - Query RNGs
+//   - Query Validations
+//   - Query Profiles
+//   - Query Capabilities
+//   - Query Client Registration Methods
+//
+// The Operation fields in the response contain Operation enumerated values, which SHALL list all the
+// operations that the server supports. If the request contains a Query Operations value in the Query
+// Function field, then these fields SHALL be returned in the response.
+//
+// The Object Type fields in the response contain Object Type enumerated values, which SHALL list all
+// the object types that the server supports. If the request contains a Query Objects value in the
+// Query Function field, then these fields SHALL be returned in the response.
+//
+// The RNG Parameters fields in the response describe the server's Random Number Generators.  If the request
+// contains a Query RNGs value in the Query Function field, then these fields SHALL be returned in the response.
+
+// QueryRequestPayload 4.25
+type QueryRequestPayload struct {
+	QueryFunction []kmip14.QueryFunction
+}
+
+// QueryResponsePayload 4.25
+type QueryResponsePayload struct {
+	Operation                []kmip14.Operation
+	ObjectType               []kmip14.ObjectType
+	VendorIdentification     string `ttlv:",omitempty"`
+	ApplicationNamespace     []string
+	AttestationType          []kmip14.AttestationType
+	RNGParameters            []RNGParameters
+	ClientRegistrationMethod []kmip14.ClientRegistrationMethod
+}
+
+// RNGParameters describes a Random Number Generator of the server, in the response to a Query RNGs query.  The
+// RNG Algorithm is required, and is Unspecified if the server doesn't disclose it.  The other fields describe
+// the RNG's building blocks, where they are known.  The 2.0 equivalent is kmip20.RNGParameters.
+type RNGParameters struct {
+	RNGAlgorithm           kmip14.RNGAlgorithm
+	CryptographicAlgorithm kmip14.CryptographicAlgorithm `ttlv:",omitempty"`
+	CryptographicLength    int                           `ttlv:",omitempty"`
+	HashingAlgorithm       kmip14.HashingAlgorithm       `ttlv:",omitempty"`
+	DRBGAlgorithm          kmip14.DRBGAlgorithm          `ttlv:",omitempty"`
+	RecommendedCurve       kmip14.RecommendedCurve       `ttlv:",omitempty"`
+	FIPS186Variation       kmip14.FIPS186Variation       `ttlv:",omitempty"`
+	PredictionResistance   bool                          `ttlv:",omitempty"`
+}
+
+type QueryHandler struct {
+	Query func(ctx context.Context, payload *QueryRequestPayload) (*QueryResponsePayload, error)
+}
+
+func (h *QueryHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload QueryRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.Query(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
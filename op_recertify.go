@@ -0,0 +1,65 @@
+package kmip
+
+import (
+	"context"
+	"time"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 4.8 Re-certify
+//
+// This request is used to renew an existing certificate for the same key pair.  Only a single certificate SHALL
+// be renewed at a time.  It is analogous to the Re-key operation: the attributes of the new certificate are
+// copied from the existing certificate, and its dates may be shifted by an Offset, see 4.4.
+//
+// As a result of Re-certify, the Link attribute of the existing certificate is set to point to the new
+// certificate, and vice versa, and the Public Key is linked to the new certificate.  The server SHALL copy the
+// Unique Identifier of the new certificate returned by this operation into the ID Placeholder variable.
+
+// ReCertifyRequestPayload 4.8
+//
+// The Unique Identifier of the existing certificate defaults to the ID Placeholder.  The Certificate Request may
+// be omitted, in which case the existing certificate is renewed as it is.
+type ReCertifyRequestPayload struct {
+	UniqueIdentifier       string                        `ttlv:",omitempty"`
+	CertificateRequestType kmip14.CertificateRequestType `ttlv:",omitempty"`
+	CertificateRequest     []byte                        `ttlv:",omitempty"`
+	Offset                 *time.Duration                `ttlv:",omitempty"`
+	TemplateAttribute      *TemplateAttribute            `ttlv:",omitempty"`
+}
+
+// ReCertifyResponsePayload 4.8
+type ReCertifyResponsePayload struct {
+	UniqueIdentifier  string
+	TemplateAttribute *TemplateAttribute `ttlv:",omitempty"`
+}
+
+type ReCertifyHandler struct {
+	ReCertify func(ctx context.Context, payload *ReCertifyRequestPayload) (*ReCertifyResponsePayload, error)
+}
+
+func (h *ReCertifyHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload ReCertifyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.ReCertify(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
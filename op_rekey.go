@@ -0,0 +1,72 @@
+package kmip
+
+import (
+	"context"
+	"time"
+)
+
+// 4.4 Re-key
+//
+// This request is used to generate a replacement key for an existing symmetric key. It is analogous to the Create
+// operation, except that attributes of the replacement key are copied from the existing key, with the exception
+// of the attributes which are set by the server for a new object, and the dates which are shifted by the Offset.
+//
+// As a result of Re-key, the Link attribute of the existing key is set to point to the replacement key and vice
+// versa.  The server SHALL copy the Unique Identifier of the replacement key returned by this operation into the
+// ID Placeholder variable.
+//
+// An Offset MAY be used to indicate the difference between the Initial Date and the Activation Date of the
+// replacement key.  If no Offset is specified, the Activation Date, Process Start Date, Protect Stop Date and
+// Deactivation Date values are copied from the existing key.  If Offset is set and dates exist for the existing
+// key, then the dates of the replacement key SHALL be set based on the dates of the existing key as follows:
+//
+//	Initial Date (IT1)        IT2 > IT1
+//	Activation Date (AT1)     AT2 = IT2 + Offset
+//	Process Start Date (CT1)  CT2 = CT1 + (AT2 - AT1)
+//	Protect Stop Date (TT1)   TT2 = TT1 + (AT2 - AT1)
+//	Deactivation Date (DT1)   DT2 = DT1 + (AT2 - AT1)
+
+// ReKeyRequestPayload 4.4
+//
+// The Unique Identifier defaults to the ID Placeholder.  The Template-Attribute holds attributes which are set
+// differently for the replacement key.
+type ReKeyRequestPayload struct {
+	UniqueIdentifier  string             `ttlv:",omitempty"`
+	Offset            *time.Duration     `ttlv:",omitempty"`
+	TemplateAttribute *TemplateAttribute `ttlv:",omitempty"`
+}
+
+// ReKeyResponsePayload 4.4
+type ReKeyResponsePayload struct {
+	UniqueIdentifier  string
+	TemplateAttribute *TemplateAttribute `ttlv:",omitempty"`
+}
+
+type ReKeyHandler struct {
+	ReKey func(ctx context.Context, payload *ReKeyRequestPayload) (*ReKeyResponsePayload, error)
+}
+
+func (h *ReKeyHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload ReKeyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.ReKey(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.UniqueIdentifier
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package kmip
+
+import (
+	"context"
+	"time"
+)
+
+// 4.5 Re-key Key Pair
+//
+// This request is used to generate a replacement key pair for an existing public/private key pair.  It is
+// analogous to the Create Key Pair operation, except that attributes of the replacement key pair are copied from
+// the existing key pair, with the exception of the attributes which are set by the server for new objects, and
+// the dates which are shifted by the Offset, as for Re-key.
+//
+// As a result of Re-key Key Pair, the Link attributes of the existing public key and private key are set to
+// point to the replacement public and private key, respectively, and vice versa.  The replacement keys are
+// linked to each other, as by Create Key Pair.  The ID Placeholder value SHALL be set to the Unique Identifier of
+// the replacement Private Key.
+
+// ReKeyKeyPairRequestPayload 4.5
+//
+// The Private Key Unique Identifier defaults to the ID Placeholder.  The existing public key is the one the
+// private key links to.
+type ReKeyKeyPairRequestPayload struct {
+	PrivateKeyUniqueIdentifier  string             `ttlv:",omitempty"`
+	Offset                      *time.Duration     `ttlv:",omitempty"`
+	CommonTemplateAttribute     *TemplateAttribute `ttlv:",omitempty"`
+	PrivateKeyTemplateAttribute *TemplateAttribute `ttlv:",omitempty"`
+	PublicKeyTemplateAttribute  *TemplateAttribute `ttlv:",omitempty"`
+}
+
+// ReKeyKeyPairResponsePayload 4.5
+type ReKeyKeyPairResponsePayload struct {
+	PrivateKeyUniqueIdentifier  string
+	PublicKeyUniqueIdentifier   string
+	PrivateKeyTemplateAttribute *TemplateAttribute `ttlv:",omitempty"`
+	PublicKeyTemplateAttribute  *TemplateAttribute `ttlv:",omitempty"`
+}
+
+type ReKeyKeyPairHandler struct {
+	ReKeyKeyPair func(ctx context.Context, payload *ReKeyKeyPairRequestPayload) (*ReKeyKeyPairResponsePayload, error)
+}
+
+func (h *ReKeyKeyPairHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload ReKeyKeyPairRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Private Key Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.PrivateKeyUniqueIdentifier == "" {
+		payload.PrivateKeyUniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.ReKeyKeyPair(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.IDPlaceholder = respPayload.PrivateKeyUniqueIdentifier
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package kmip
+
+import (
+	"context"
+	"time"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 4.20 Revoke
+//
+// This operation requests the server to revoke a Managed Cryptographic Object or an Opaque Object. The
+// request contains a reason for the revocation (e.g., "key compromise", "cessation of operation", etc.).
+// The operation has one of two effects. If the revocation reason is "key compromise" or "CA compromise",
+// then the object is placed into the "compromised" state; the Date is set to the current date and time; and
+// the Compromise Occurrence Date is set to the value (if provided) in the Revoke request and if a value is
+// not provided in the Revoke request then Compromise Occurrence Date SHOULD be set to the Initial Date for
+// the object. If the revocation reason is neither "key compromise" nor "CA compromise", the object is placed
+// into the "deactivated" state, and the Deactivation Date is set to the current date and time.
+
+// RevocationReason 3.31
+type RevocationReason struct {
+	RevocationReasonCode kmip14.RevocationReasonCode
+	RevocationMessage    string `ttlv:",omitempty"`
+}
+
+// RevokeRequestPayload 4.20
+type RevokeRequestPayload struct {
+	UniqueIdentifier         string
+	RevocationReason         RevocationReason
+	CompromiseOccurrenceDate *time.Time `ttlv:",omitempty"`
+}
+
+// RevokeResponsePayload 4.20
+type RevokeResponsePayload struct {
+	UniqueIdentifier string
+}
+
+type RevokeHandler struct {
+	Revoke func(ctx context.Context, payload *RevokeRequestPayload) (*RevokeResponsePayload, error)
+}
+
+func (h *RevokeHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload RevokeRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.Revoke(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.35 RNG Retrieve
+//
+// This operation requests the server to return output from a Random Number Generator (RNG).  The Data Length is
+// the number of bytes of random data requested, and the response returns that many bytes in the Data.  The ID
+// Placeholder is neither used nor changed.
+
+// RNGRetrieveRequestPayload 4.35
+type RNGRetrieveRequestPayload struct {
+	DataLength int
+}
+
+// RNGRetrieveResponsePayload 4.35
+type RNGRetrieveResponsePayload struct {
+	Data []byte
+}
+
+type RNGRetrieveHandler struct {
+	RNGRetrieve func(ctx context.Context, payload *RNGRetrieveRequestPayload) (*RNGRetrieveResponsePayload, error)
+}
+
+func (h *RNGRetrieveHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload RNGRetrieveRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.RNGRetrieve(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
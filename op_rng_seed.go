@@ -0,0 +1,43 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.36 RNG Seed
+//
+// This operation requests the server to seed a Random Number Generator.  The Data is the seed data, and the
+// response's Data Length is the amount of it the server accepted.  The server MAY accept less than all of the
+// seed data, or none of it.  The ID Placeholder is neither used nor changed.
+
+// RNGSeedRequestPayload 4.36
+type RNGSeedRequestPayload struct {
+	Data []byte
+}
+
+// RNGSeedResponsePayload 4.36
+type RNGSeedResponsePayload struct {
+	DataLength int
+}
+
+type RNGSeedHandler struct {
+	RNGSeed func(ctx context.Context, payload *RNGSeedRequestPayload) (*RNGSeedResponsePayload, error)
+}
+
+func (h *RNGSeedHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload RNGSeedRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	respPayload, err := h.RNGSeed(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package kmip
+
+import (
+	"context"
+)
+
+// 4.31 Sign
+//
+// This operation requests the server to perform a signature operation on the provided data using a Managed
+// Cryptographic Object as the key for the signature operation.
+//
+// The request contains information about the cryptographic parameters (digital signature algorithm or
+// cryptographic algorithm and hash algorithm) and the data to be signed. The cryptographic parameters MAY be
+// omitted from the request as they can be specified as associated attributes of the Managed Cryptographic Object.
+//
+// If the Managed Cryptographic Object referenced has a Usage Limits attribute then the server SHALL obtain an
+// allocation from the current Usage Limits value prior to performing the signing operation.
+//
+// The response contains the Unique Identifier of the Managed Cryptographic Object used as the key and the result
+// of the signature operation.
+//
+// The success or failure of the operation is indicated by the Result Status (and if failure the Result Reason)
+// in the response header.
+
+// SignRequestPayload 4.31
+type SignRequestPayload struct {
+	UniqueIdentifier        string                   `ttlv:",omitempty"`
+	CryptographicParameters *CryptographicParameters `ttlv:",omitempty"`
+	Data                    []byte                   `ttlv:",omitempty"`
+	DigestedData            []byte                   `ttlv:",omitempty"`
+	CorrelationValue        []byte                   `ttlv:",omitempty"`
+	InitIndicator           bool                     `ttlv:",omitempty"`
+	FinalIndicator          bool                     `ttlv:",omitempty"`
+}
+
+// SignResponsePayload 4.31
+type SignResponsePayload struct {
+	UniqueIdentifier string
+	SignatureData    []byte `ttlv:",omitempty"`
+	CorrelationValue []byte `ttlv:",omitempty"`
+}
+
+type SignHandler struct {
+	Sign func(ctx context.Context, payload *SignRequestPayload) (*SignResponsePayload, error)
+}
+
+func (h *SignHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload SignRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.Sign(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package kmip
+
+import (
+	"context"
+
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// 4.32 Signature Verify
+//
+// This operation requests the server to perform a signature verify operation on the provided data using a Managed
+// Cryptographic Object as the key for the signature verification operation.
+//
+// The request contains information about the cryptographic parameters (digital signature algorithm or
+// cryptographic algorithm and hash algorithm) and the signature to be verified and MAY contain the data that was
+// passed to the signing operation (for those algorithms which need the original data to verify a signature).
+//
+// The cryptographic parameters MAY be omitted from the request as they can be specified as associated attributes
+// of the Managed Cryptographic Object.
+//
+// The response contains the Unique Identifier of the Managed Cryptographic Object used as the key and the OPTIONAL
+// data recovered from the signature (for those signature algorithms where data recovery from the signature is
+// supported). The validity of the signature is indicated by the Validity Indicator field.
+//
+// The success or failure of the operation is indicated by the Result Status (and if failure the Result Reason)
+// in the response header.
+
+// SignatureVerifyRequestPayload 4.32
+type SignatureVerifyRequestPayload struct {
+	UniqueIdentifier        string                   `ttlv:",omitempty"`
+	CryptographicParameters *CryptographicParameters `ttlv:",omitempty"`
+	Data                    []byte                   `ttlv:",omitempty"`
+	DigestedData            []byte                   `ttlv:",omitempty"`
+	SignatureData           []byte                   `ttlv:",omitempty"`
+	CorrelationValue        []byte                   `ttlv:",omitempty"`
+	InitIndicator           bool                     `ttlv:",omitempty"`
+	FinalIndicator          bool                     `ttlv:",omitempty"`
+}
+
+// SignatureVerifyResponsePayload 4.32
+type SignatureVerifyResponsePayload struct {
+	UniqueIdentifier  string
+	ValidityIndicator kmip14.ValidityIndicator
+	Data              []byte `ttlv:",omitempty"`
+	CorrelationValue  []byte `ttlv:",omitempty"`
+}
+
+type SignatureVerifyHandler struct {
+	SignatureVerify func(ctx context.Context, payload *SignatureVerifyRequestPayload) (*SignatureVerifyResponsePayload, error)
+}
+
+func (h *SignatureVerifyHandler) HandleItem(ctx context.Context, req *Request) (*ResponseBatchItem, error) {
+	var payload SignatureVerifyRequestPayload
+
+	err := req.DecodePayload(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Unique Identifier defaults to the ID Placeholder, set by a previous item in the batch
+	if payload.UniqueIdentifier == "" {
+		payload.UniqueIdentifier = req.IDPlaceholder
+	}
+
+	respPayload, err := h.SignatureVerify(ctx, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseBatchItem{
+		ResponsePayload: respPayload,
+	}, nil
+}
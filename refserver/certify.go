@@ -0,0 +1,243 @@
+package refserver
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// DefaultCertificateValidity is the validity period of the certificates issued by a CertificateAuthority, if
+// its Validity is zero.
+const DefaultCertificateValidity = 365 * 24 * time.Hour
+
+// CertificateAuthority issues the certificates for Certify and Re-certify, signed with a CA key held in the Store.
+// Its IssueCertificate method has the signature of kmip.StoreHandlers' IssueCertificate.
+//
+// The CA is identified by its certificate.  The certificate is linked to the CA's Public Key, which is linked to
+// the CA's Private Key, as by Certify and Create Key Pair.  The Private Key signs the issued certificates: it must
+// be in a state which permits Sign, and its Cryptographic Usage Mask, if it has one, must include Certificate
+// Sign.
+type CertificateAuthority struct {
+	Store kmip.ObjectStore
+
+	// Lifecycle checks the CA key's state.  If nil, the zero Lifecycle is used.
+	Lifecycle *kmip.Lifecycle
+
+	// Clock dates the issued certificates.  If nil, SystemClock is used.
+	Clock Clock
+
+	// CertificateUniqueIdentifier identifies the CA's certificate.  If empty, Certify and Re-certify fail with
+	// Operation Not Supported.
+	CertificateUniqueIdentifier string
+
+	// Validity is the validity period of the issued certificates.  If zero, DefaultCertificateValidity is used.
+	Validity time.Duration
+}
+
+// IssueCertificate issues a certificate for a Certify or Re-certify request.  The request's Certificate Request
+// may be a DER encoded PKCS#10 request, the default, or a PEM encoded one.  The certificate gets the subject and
+// subject alternative names of the request, whose signature must be valid, and whose public key must be the
+// subject's public key.  Without a Certificate Request, which is only permitted for Re-certify, the certificate
+// gets the subject, subject alternative names, public key and key usages of the existing certificate.
+func (ca *CertificateAuthority) IssueCertificate(ctx context.Context, payload *kmip.CertifyRequestPayload, subject *kmip.ManagedObject) (*kmip.Certificate, error) {
+	if ca.CertificateUniqueIdentifier == "" {
+		return nil, kmip.WithResultReason(merry.UserError("no certificate authority is configured"), kmip14.ResultReasonOperationNotSupported)
+	}
+
+	template, pub, err := certificateTemplate(payload, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, signer, err := ca.issuer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	template.SerialNumber, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, merry.Prepend(err, "generating serial number")
+	}
+
+	clock := ca.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	validity := ca.Validity
+	if validity == 0 {
+		validity = DefaultCertificateValidity
+	}
+
+	template.NotBefore = clock.Now()
+	template.NotAfter = template.NotBefore.Add(validity)
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, pub, signer)
+	if err != nil {
+		return nil, kmip.WithResultReason(merry.Prepend(err, "issuing certificate"), kmip14.ResultReasonCryptographicFailure)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, merry.Prepend(err, "parsing issued certificate")
+	}
+
+	return kmip.NewCertificate(cert), nil
+}
+
+// issuer returns the CA's certificate and signing key.
+func (ca *CertificateAuthority) issuer(ctx context.Context) (*x509.Certificate, crypto.Signer, error) {
+	var certObj, privObj *kmip.ManagedObject
+
+	err := ca.Store.View(ctx, func(tx kmip.ObjectTx) error {
+		var err error
+
+		certObj, err = tx.Get(ca.CertificateUniqueIdentifier)
+		if err != nil {
+			return merry.Prepend(err, "getting the CA certificate")
+		}
+
+		pubID := certObj.LinkedObjectIdentifier(kmip14.LinkTypePublicKeyLink)
+		if pubID == "" {
+			return merry.New("the CA certificate isn't linked to a Public Key")
+		}
+
+		pub, err := tx.Get(pubID)
+		if err != nil {
+			return merry.Prepend(err, "getting the CA Public Key")
+		}
+
+		privID := pub.LinkedObjectIdentifier(kmip14.LinkTypePrivateKeyLink)
+		if privID == "" {
+			return merry.New("the CA Public Key isn't linked to a Private Key")
+		}
+
+		privObj, err = tx.Get(privID)
+		if err != nil {
+			return merry.Prepend(err, "getting the CA Private Key")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if certObj.Certificate == nil {
+		return nil, nil, merry.Errorf("the CA certificate is a %s", certObj.ObjectType.String())
+	}
+
+	lifecycle := ca.Lifecycle
+	if lifecycle == nil {
+		lifecycle = &kmip.Lifecycle{}
+	}
+
+	if err := lifecycle.CheckOperation(privObj, kmip14.OperationSign); err != nil {
+		return nil, nil, err
+	}
+
+	if err := kmip.CheckUsageMask(privObj, kmip14.CryptographicUsageMaskCertificateSign); err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := certObj.Certificate.X509Certificate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	priv, err := privateKey(privObj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, nil, merry.Errorf("the CA Private Key can't sign: %T", priv)
+	}
+
+	return cert, signer, nil
+}
+
+// certificateTemplate returns the template of the certificate to issue, and the public key to certify.
+func certificateTemplate(payload *kmip.CertifyRequestPayload, subject *kmip.ManagedObject) (*x509.Certificate, crypto.PublicKey, error) {
+	if payload.CertificateRequest == nil {
+		if subject == nil || subject.Certificate == nil {
+			return nil, nil, invalidFieldErrorf("a Certificate Request is required")
+		}
+
+		existing, err := subject.Certificate.X509Certificate()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &x509.Certificate{
+			Subject:        existing.Subject,
+			DNSNames:       existing.DNSNames,
+			EmailAddresses: existing.EmailAddresses,
+			IPAddresses:    existing.IPAddresses,
+			URIs:           existing.URIs,
+			KeyUsage:       existing.KeyUsage,
+			ExtKeyUsage:    existing.ExtKeyUsage,
+		}, existing.PublicKey, nil
+	}
+
+	csr, err := parseCertificateRequest(payload.CertificateRequestType, payload.CertificateRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if subject != nil {
+		pub, err := publicKey(subject)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if k, ok := pub.(interface{ Equal(x crypto.PublicKey) bool }); !ok || !k.Equal(csr.PublicKey) {
+			return nil, nil, invalidFieldErrorf("the Certificate Request's public key doesn't match the %s", subject.ObjectType.String())
+		}
+	}
+
+	return &x509.Certificate{
+		Subject:        csr.Subject,
+		DNSNames:       csr.DNSNames,
+		EmailAddresses: csr.EmailAddresses,
+		IPAddresses:    csr.IPAddresses,
+		URIs:           csr.URIs,
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+	}, csr.PublicKey, nil
+}
+
+// parseCertificateRequest parses a PKCS#10 Certificate Request, and checks its signature.
+func parseCertificateRequest(typ kmip14.CertificateRequestType, b []byte) (*x509.CertificateRequest, error) {
+	switch typ {
+	case 0, kmip14.CertificateRequestTypePKCS_10:
+	case kmip14.CertificateRequestTypePEM:
+		block, _ := pem.Decode(b)
+		if block == nil || (block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST") {
+			return nil, invalidFieldErrorf("the Certificate Request isn't a PEM encoded certificate request")
+		}
+
+		b = block.Bytes
+	default:
+		return nil, invalidFieldErrorf("unsupported Certificate Request Type: %s", typ.String())
+	}
+
+	csr, err := x509.ParseCertificateRequest(b)
+	if err != nil {
+		return nil, invalidFieldErrorf("invalid Certificate Request: %v", err)
+	}
+
+	if err := csr.CheckSignature(); err != nil {
+		return nil, invalidFieldErrorf("invalid Certificate Request signature: %v", err)
+	}
+
+	return csr, nil
+}
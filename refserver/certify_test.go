@@ -0,0 +1,187 @@
+package refserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/kmip20"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// putCA stores a self-signed CA certificate and its keys, linked to each other, and returns the certificate's
+// Unique Identifier.
+func putCA(t *testing.T, store kmip.ObjectStore) (string, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	privBlock, err := kmip.NewPrivateKeyBlock(key, 0)
+	require.NoError(t, err)
+
+	pubBlock, err := kmip.NewPublicKeyBlock(key.Public(), 0)
+	require.NoError(t, err)
+
+	privID := putKey(t, store, &kmip.PrivateKey{KeyBlock: *privBlock},
+		kmip.NewAttributeFromTag(kmip14.TagCryptographicUsageMask, 0, kmip14.CryptographicUsageMaskCertificateSign))
+	pubID := putKey(t, store, &kmip.PublicKey{KeyBlock: *pubBlock},
+		kmip.NewAttributeFromTag(kmip14.TagLink, 0, kmip.Link{LinkType: kmip14.LinkTypePrivateKeyLink, LinkedObjectIdentifier: privID}))
+	certID := putKey(t, store, kmip.NewCertificate(cert),
+		kmip.NewAttributeFromTag(kmip14.TagLink, 0, kmip.Link{LinkType: kmip14.LinkTypePublicKeyLink, LinkedObjectIdentifier: pubID}))
+
+	return certID, cert
+}
+
+func TestCertificateAuthority(t *testing.T) {
+	ctx := context.Background()
+	srv := New(nil)
+
+	get := func(id string) *kmip.ManagedObject {
+		t.Helper()
+
+		var obj *kmip.ManagedObject
+
+		require.NoError(t, srv.Store.View(ctx, func(tx kmip.ObjectTx) error {
+			var err error
+			obj, err = tx.Get(id)
+
+			return err
+		}))
+
+		return obj
+	}
+
+	links := func(obj *kmip.ManagedObject, linkType kmip14.LinkType) []string {
+		var ids []string
+
+		for _, attr := range obj.Attributes() {
+			var link kmip.Link
+			if attr.AttributeName == kmip14.TagLink.CanonicalName() {
+				require.NoError(t, kmip.DecodeAttributeValue(attr.AttributeValue, &link))
+
+				if link.LinkType == linkType {
+					ids = append(ids, link.LinkedObjectIdentifier)
+				}
+			}
+		}
+
+		return ids
+	}
+
+	// the subject's key pair, and a certificate request signed with it
+	pair := kmip.CreateKeyPairRequestPayload{CommonTemplateAttribute: &kmip.TemplateAttribute{}}
+	pair.CommonTemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmECDSA)
+	pair.CommonTemplateAttribute.Append(kmip14.TagCryptographicLength, 256)
+
+	pairResp, err := srv.Handlers.CreateKeyPair(ctx, &pair)
+	require.NoError(t, err)
+
+	priv, err := get(pairResp.PrivateKeyUniqueIdentifier).PrivateKey.KeyBlock.PrivateKey()
+	require.NoError(t, err)
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "client"},
+		DNSNames: []string{"client.example.com"},
+	}, priv)
+	require.NoError(t, err)
+
+	certify := kmip.CertifyRequestPayload{
+		UniqueIdentifier:       pairResp.PublicKeyUniqueIdentifier,
+		CertificateRequestType: kmip14.CertificateRequestTypePKCS_10,
+		CertificateRequest:     csr,
+	}
+
+	_, err = srv.Handlers.Certify(ctx, &certify)
+	assert.Equal(t, kmip14.ResultReasonOperationNotSupported, kmip.GetResultReason(err))
+
+	caID, caCert := putCA(t, srv.Store)
+	srv.CA.CertificateUniqueIdentifier = caID
+
+	resp, err := srv.Handlers.Certify(ctx, &certify)
+	require.NoError(t, err)
+
+	certObj := get(resp.UniqueIdentifier)
+	require.NotNil(t, certObj.Certificate)
+
+	cert, err := certObj.Certificate.X509Certificate()
+	require.NoError(t, err)
+	require.NoError(t, cert.CheckSignatureFrom(caCert))
+	assert.Equal(t, "client", cert.Subject.CommonName)
+	assert.Equal(t, []string{"client.example.com"}, cert.DNSNames)
+	assert.True(t, priv.(*ecdsa.PrivateKey).PublicKey.Equal(cert.PublicKey))
+
+	// the certificate and the public key are linked to each other
+	assert.Equal(t, []string{pairResp.PublicKeyUniqueIdentifier}, links(certObj, kmip14.LinkTypePublicKeyLink))
+	assert.Equal(t, []string{resp.UniqueIdentifier}, links(get(pairResp.PublicKeyUniqueIdentifier), kmip14.LinkTypeCertificateLink))
+
+	// PEM encoded requests are accepted too, and the request needn't identify a public key
+	_, err = srv.Handlers.Certify(ctx, &kmip.CertifyRequestPayload{
+		CertificateRequestType: kmip14.CertificateRequestTypePEM,
+		CertificateRequest:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+	})
+	require.NoError(t, err)
+
+	// the request's public key must be the identified public key
+	certify.UniqueIdentifier = get(caID).LinkedObjectIdentifier(kmip14.LinkTypePublicKeyLink)
+	_, err = srv.Handlers.Certify(ctx, &certify)
+	assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+
+	// 2.0 Re-certify, without a certificate request
+	offset := time.Hour
+	h20 := &handlers20{h: srv.Handlers20}
+
+	reResp, err := h20.reCertify(ctx, &kmip20.ReCertifyRequestPayload{
+		UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: resp.UniqueIdentifier},
+		Offset:           &offset,
+	})
+	require.NoError(t, err)
+
+	renewedObj := get(reResp.UniqueIdentifier)
+
+	renewed, err := renewedObj.Certificate.X509Certificate()
+	require.NoError(t, err)
+	require.NoError(t, renewed.CheckSignatureFrom(caCert))
+	assert.Equal(t, cert.Subject.String(), renewed.Subject.String())
+	assert.NotEqual(t, cert.SerialNumber, renewed.SerialNumber)
+	assert.True(t, priv.(*ecdsa.PrivateKey).PublicKey.Equal(renewed.PublicKey))
+
+	assert.Equal(t, []string{reResp.UniqueIdentifier}, links(get(resp.UniqueIdentifier), kmip14.LinkTypeReplacementObjectLink))
+	assert.Equal(t, []string{resp.UniqueIdentifier}, links(renewedObj, kmip14.LinkTypeReplacedObjectLink))
+	assert.Equal(t, []string{pairResp.PublicKeyUniqueIdentifier}, links(renewedObj, kmip14.LinkTypePublicKeyLink))
+	assert.Equal(t, []string{resp.UniqueIdentifier, reResp.UniqueIdentifier}, links(get(pairResp.PublicKeyUniqueIdentifier), kmip14.LinkTypeCertificateLink))
+
+	// Certificate Request objects aren't supported
+	_, err = h20.certify(ctx, &kmip20.CertifyRequestPayload{CertificateRequestUniqueIdentifier: "csr"})
+	assert.Equal(t, kmip14.ResultReasonFeatureNotSupported, kmip.GetResultReason(err))
+
+	// only certificates can be re-certified
+	_, err = srv.Handlers.ReCertify(ctx, &kmip.ReCertifyRequestPayload{UniqueIdentifier: pairResp.PublicKeyUniqueIdentifier})
+	assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+}
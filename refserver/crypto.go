@@ -0,0 +1,857 @@
+package refserver
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"hash"
+	"time"
+
+	// register the hashes used by the cryptographic operations
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// CryptoHandlers implements the cryptographic operations, Encrypt, Decrypt, Sign, Signature Verify, MAC and
+// MAC Verify, with keys held in an ObjectStore, using Go's crypto packages.  The key must be in a state which
+// permits the operation (see kmip.Lifecycle.CheckOperation), and its Cryptographic Usage Mask, if it has one, must
+// include the operation.
+//
+// The Cryptographic Parameters in the request take precedence over the key's Cryptographic Parameters attribute.
+// The supported algorithms are:
+//
+//   - Encrypt and Decrypt: AES and 3DES in GCM, CBC and CTR modes, and RSA with OAEP or PKCS#1 v1.5 padding.
+//     CBC mode supports PKCS#5 padding, or no padding.  If Random IV is set, Encrypt generates the
+//     IV/Counter/Nonce, and returns it.  For GCM, the tag is returned separately, as the Authenticated
+//     Encryption Tag.
+//   - Sign and Signature Verify: RSA with PKCS#1 v1.5 or PSS padding, ECDSA, and Ed25519.  The hash is given by
+//     the Digital Signature Algorithm, or by the Hashing Algorithm.  Data which has already been hashed may be
+//     passed as the Digested Data, except for Ed25519.
+//   - MAC and MAC Verify: HMAC with MD5, SHA-1 or SHA-2 hashes.
+//
+// All the operations may be streamed: a request with the Init Indicator, and without the Final Indicator, opens a
+// stream, and the response carries the stream's Correlation Value.  The data of later requests with the Correlation
+// Value is processed as if it followed the data of the earlier requests, until a request with the Final Indicator
+// closes the stream.  The key and Cryptographic Parameters are only taken from the first request.  Streams belong
+// to the connection they were opened on, and are closed when it closes.  When decrypting with GCM, the plaintext
+// is returned before the tag, which is sent with the final request, is checked.
+type CryptoHandlers struct {
+	Store kmip.ObjectStore
+
+	// Lifecycle checks the key's state.  If nil, the zero Lifecycle is used.
+	Lifecycle *kmip.Lifecycle
+
+	// MaxStreams limits the streams a connection may have open at once.  If zero, DefaultMaxStreams is used.
+	// Requests which weren't received by a kmip.Server share one set of streams.
+	MaxStreams int
+
+	// MaxStreamBuffer limits the data an open stream may buffer, in bytes, for algorithms which can't process
+	// data in parts, like RSA encryption and Ed25519 signatures.  If zero, DefaultMaxStreamBuffer is used.  Sending
+	// an open stream more data fails with kmip.ErrStreamBufferFull.
+	MaxStreamBuffer int
+
+	// StreamIdleTimeout is how long an open stream may go without a request before it's discarded.  If zero,
+	// DefaultStreamIdleTimeout is used.
+	StreamIdleTimeout time.Duration
+
+	streams streams
+}
+
+// Handle registers the handlers for the cryptographic operations with the mux.
+func (h *CryptoHandlers) Handle(mux *kmip.OperationMux) {
+	mux.Handle(kmip14.OperationEncrypt, &kmip.EncryptHandler{Encrypt: h.Encrypt})
+	mux.Handle(kmip14.OperationDecrypt, &kmip.DecryptHandler{Decrypt: h.Decrypt})
+	mux.Handle(kmip14.OperationSign, &kmip.SignHandler{Sign: h.Sign})
+	mux.Handle(kmip14.OperationSignatureVerify, &kmip.SignatureVerifyHandler{SignatureVerify: h.SignatureVerify})
+	mux.Handle(kmip14.OperationMAC, &kmip.MACHandler{MAC: h.MAC})
+	mux.Handle(kmip14.OperationMACVerify, &kmip.MACVerifyHandler{MACVerify: h.MACVerify})
+}
+
+// Encrypt encrypts the data with a symmetric key or a public key.
+func (h *CryptoHandlers) Encrypt(ctx context.Context, payload *kmip.EncryptRequestPayload) (*kmip.EncryptResponsePayload, error) {
+	var resp kmip.EncryptResponsePayload
+
+	s, err := h.begin(ctx, kmip14.OperationEncrypt, payload.CorrelationValue, payload.InitIndicator, payload.FinalIndicator, func() (*stream, error) {
+		obj, params, err := h.key(ctx, payload.UniqueIdentifier, kmip14.OperationEncrypt, kmip14.CryptographicUsageMaskEncrypt, payload.CryptographicParameters)
+		if err != nil {
+			return nil, err
+		}
+
+		c, iv, err := newEncrypter(obj, params, payload.IVCounterNonce, payload.AuthenticatedEncryptionAdditionalData)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.IVCounterNonce = iv
+
+		return &stream{uid: obj.UniqueIdentifier, cipher: c}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	final := s.isFinal(payload.FinalIndicator)
+	resp.UniqueIdentifier, resp.CorrelationValue = s.uid, s.correlationValue
+	resp.Data, resp.AuthenticatedEncryptionTag, err = s.crypt(payload.Data, final, nil)
+
+	h.end(ctx, s, final || err != nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Decrypt decrypts the data with a symmetric key or a private key.
+func (h *CryptoHandlers) Decrypt(ctx context.Context, payload *kmip.DecryptRequestPayload) (*kmip.DecryptResponsePayload, error) {
+	s, err := h.begin(ctx, kmip14.OperationDecrypt, payload.CorrelationValue, payload.InitIndicator, payload.FinalIndicator, func() (*stream, error) {
+		obj, params, err := h.key(ctx, payload.UniqueIdentifier, kmip14.OperationDecrypt, kmip14.CryptographicUsageMaskDecrypt, payload.CryptographicParameters)
+		if err != nil {
+			return nil, err
+		}
+
+		c, err := newDecrypter(obj, params, payload.IVCounterNonce, payload.AuthenticatedEncryptionAdditionalData)
+		if err != nil {
+			return nil, err
+		}
+
+		return &stream{uid: obj.UniqueIdentifier, cipher: c}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	final := s.isFinal(payload.FinalIndicator)
+	resp := kmip.DecryptResponsePayload{UniqueIdentifier: s.uid, CorrelationValue: s.correlationValue}
+	resp.Data, _, err = s.crypt(payload.Data, final, payload.AuthenticatedEncryptionTag)
+
+	h.end(ctx, s, final || err != nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Sign signs the data, or the digested data, with a private key.
+func (h *CryptoHandlers) Sign(ctx context.Context, payload *kmip.SignRequestPayload) (*kmip.SignResponsePayload, error) {
+	if err := checkDigestedData(payload.DigestedData, payload.CorrelationValue, payload.InitIndicator, payload.FinalIndicator); err != nil {
+		return nil, err
+	}
+
+	s, err := h.begin(ctx, kmip14.OperationSign, payload.CorrelationValue, payload.InitIndicator, payload.FinalIndicator, func() (*stream, error) {
+		obj, params, err := h.key(ctx, payload.UniqueIdentifier, kmip14.OperationSign, kmip14.CryptographicUsageMaskSign, payload.CryptographicParameters)
+		if err != nil {
+			return nil, err
+		}
+
+		priv, err := privateKey(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := newSignatureStream(priv, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return &stream{uid: obj.UniqueIdentifier, digest: d}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	final := s.isFinal(payload.FinalIndicator)
+	resp := kmip.SignResponsePayload{UniqueIdentifier: s.uid, CorrelationValue: s.correlationValue}
+	err = s.write(payload.Data)
+
+	if final && err == nil {
+		resp.SignatureData, err = s.digest.sum(payload.DigestedData)
+	}
+
+	h.end(ctx, s, final || err != nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// SignatureVerify verifies a signature of the data, or the digested data, with a public key, or the public
+// key of a certificate.
+func (h *CryptoHandlers) SignatureVerify(ctx context.Context, payload *kmip.SignatureVerifyRequestPayload) (*kmip.SignatureVerifyResponsePayload, error) {
+	if err := checkDigestedData(payload.DigestedData, payload.CorrelationValue, payload.InitIndicator, payload.FinalIndicator); err != nil {
+		return nil, err
+	}
+
+	s, err := h.begin(ctx, kmip14.OperationSignatureVerify, payload.CorrelationValue, payload.InitIndicator, payload.FinalIndicator, func() (*stream, error) {
+		obj, params, err := h.key(ctx, payload.UniqueIdentifier, kmip14.OperationSignatureVerify, kmip14.CryptographicUsageMaskVerify, payload.CryptographicParameters)
+		if err != nil {
+			return nil, err
+		}
+
+		pub, err := publicKey(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := newSignatureStream(pub, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return &stream{uid: obj.UniqueIdentifier, digest: d}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	final := s.isFinal(payload.FinalIndicator)
+	resp := kmip.SignatureVerifyResponsePayload{UniqueIdentifier: s.uid, CorrelationValue: s.correlationValue}
+	err = s.write(payload.Data)
+
+	if final && err == nil {
+		resp.ValidityIndicator, err = verify(s.digest, payload.DigestedData, payload.SignatureData, "Signature Data")
+	}
+
+	h.end(ctx, s, final || err != nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// MAC computes the HMAC of the data with a symmetric key.
+func (h *CryptoHandlers) MAC(ctx context.Context, payload *kmip.MACRequestPayload) (*kmip.MACResponsePayload, error) {
+	s, err := h.begin(ctx, kmip14.OperationMAC, payload.CorrelationValue, payload.InitIndicator, payload.FinalIndicator, func() (*stream, error) {
+		obj, params, err := h.key(ctx, payload.UniqueIdentifier, kmip14.OperationMAC, kmip14.CryptographicUsageMaskMACGenerate, payload.CryptographicParameters)
+		if err != nil {
+			return nil, err
+		}
+
+		mac, err := newHMAC(obj, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return &stream{uid: obj.UniqueIdentifier, digest: macStream{mac}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	final := s.isFinal(payload.FinalIndicator)
+	resp := kmip.MACResponsePayload{UniqueIdentifier: s.uid, CorrelationValue: s.correlationValue}
+	err = s.write(payload.Data)
+
+	if final && err == nil {
+		resp.MACData, err = s.digest.sum(nil)
+	}
+
+	h.end(ctx, s, final || err != nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// MACVerify verifies the HMAC of the data with a symmetric key.
+func (h *CryptoHandlers) MACVerify(ctx context.Context, payload *kmip.MACVerifyRequestPayload) (*kmip.MACVerifyResponsePayload, error) {
+	s, err := h.begin(ctx, kmip14.OperationMACVerify, payload.CorrelationValue, payload.InitIndicator, payload.FinalIndicator, func() (*stream, error) {
+		obj, params, err := h.key(ctx, payload.UniqueIdentifier, kmip14.OperationMACVerify, kmip14.CryptographicUsageMaskMACVerify, payload.CryptographicParameters)
+		if err != nil {
+			return nil, err
+		}
+
+		mac, err := newHMAC(obj, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return &stream{uid: obj.UniqueIdentifier, digest: macStream{mac}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	final := s.isFinal(payload.FinalIndicator)
+	resp := kmip.MACVerifyResponsePayload{UniqueIdentifier: s.uid, CorrelationValue: s.correlationValue}
+	err = s.write(payload.Data)
+
+	if final && err == nil {
+		resp.ValidityIndicator, err = verify(s.digest, nil, payload.MACData, "MAC Data")
+	}
+
+	h.end(ctx, s, final || err != nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// key returns the key for a cryptographic operation, after checking the key may be used for it, and the
+// Cryptographic Parameters for the operation.
+func (h *CryptoHandlers) key(ctx context.Context, id string, op kmip14.Operation, usage kmip14.CryptographicUsageMask, params *kmip.CryptographicParameters) (*kmip.ManagedObject, kmip.CryptographicParameters, error) {
+	var obj *kmip.ManagedObject
+
+	err := h.Store.View(ctx, func(tx kmip.ObjectTx) error {
+		var err error
+		obj, err = tx.Get(id)
+
+		return err
+	})
+	if err != nil {
+		return nil, kmip.CryptographicParameters{}, err
+	}
+
+	lifecycle := h.Lifecycle
+	if lifecycle == nil {
+		lifecycle = &kmip.Lifecycle{}
+	}
+
+	if err := lifecycle.CheckOperation(obj, op); err != nil {
+		return nil, kmip.CryptographicParameters{}, err
+	}
+
+	if err := kmip.CheckUsageMask(obj, usage); err != nil {
+		return nil, kmip.CryptographicParameters{}, err
+	}
+
+	if params != nil {
+		return obj, *params, nil
+	}
+
+	var stored kmip.CryptographicParameters
+
+	if attr := obj.GetAttributeTag(kmip14.TagCryptographicParameters); attr != nil {
+		if err := kmip.DecodeAttributeValue(attr.AttributeValue, &stored); err != nil {
+			return nil, stored, merry.Prepend(err, "invalid Cryptographic Parameters attribute")
+		}
+	}
+
+	return obj, stored, nil
+}
+
+// checkDigestedData returns an error if Digested Data is passed to a request which is part of a stream.
+func checkDigestedData(digestedData, correlationValue []byte, initIndicator, finalIndicator bool) error {
+	if digestedData != nil && (correlationValue != nil || (initIndicator && !finalIndicator)) {
+		return invalidFieldErrorf("Digested Data can't be streamed")
+	}
+
+	return nil
+}
+
+// verify verifies the signature or MAC of the data written to the stream, or of the digested data.
+func verify(d digestStream, digestedData, sig []byte, field string) (kmip14.ValidityIndicator, error) {
+	if sig == nil {
+		return 0, invalidFieldErrorf("the %s is required", field)
+	}
+
+	valid, err := d.verify(digestedData, sig)
+	if err != nil {
+		return 0, err
+	}
+
+	return validityIndicator(valid), nil
+}
+
+// newEncrypter returns the cipher stream which encrypts with a key.  If the Cryptographic Parameters set
+// Random IV, a random IV is generated, and returned, and the IV/Counter/Nonce must be nil.  Otherwise, the
+// IV/Counter/Nonce is required.
+func newEncrypter(obj *kmip.ManagedObject, params kmip.CryptographicParameters, iv, aad []byte) (cipherStream, []byte, error) {
+	if obj.PublicKey != nil {
+		pub, err := publicKey(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &bufferedCipher{fn: func(data []byte) ([]byte, error) {
+			return encryptRSA(pub, params, data)
+		}}, nil, nil
+	}
+
+	block, err := blockCipher(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var generated []byte
+
+	switch {
+	case params.RandomIV && iv != nil:
+		return nil, nil, invalidFieldErrorf("the IV/Counter/Nonce must be omitted when Random IV is set")
+	case iv == nil && !params.RandomIV:
+		return nil, nil, invalidFieldErrorf("the IV/Counter/Nonce is required, unless Random IV is set")
+	case iv == nil:
+		n, err := ivLength(block, params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		iv = make([]byte, n)
+		if _, err := rand.Read(iv); err != nil {
+			return nil, nil, merry.Prepend(err, "generating IV")
+		}
+
+		generated = iv
+	}
+
+	c, err := newBlockCipherStream(block, params, iv, aad, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c, generated, nil
+}
+
+// newDecrypter returns the cipher stream which decrypts with a key.
+func newDecrypter(obj *kmip.ManagedObject, params kmip.CryptographicParameters, iv, aad []byte) (cipherStream, error) {
+	if obj.PrivateKey != nil {
+		priv, err := privateKey(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		return &bufferedCipher{fn: func(data []byte) ([]byte, error) {
+			return decryptRSA(priv, params, data)
+		}}, nil
+	}
+
+	block, err := blockCipher(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBlockCipherStream(block, params, iv, aad, true)
+}
+
+// newBlockCipherStream returns the cipher stream for the Block Cipher Mode.
+func newBlockCipherStream(block cipher.Block, params kmip.CryptographicParameters, iv, aad []byte, decrypt bool) (cipherStream, error) {
+	if err := checkIVLength(block, params.BlockCipherMode, len(iv)); err != nil {
+		return nil, err
+	}
+
+	switch params.BlockCipherMode {
+	case kmip14.BlockCipherModeGCM:
+		tagSize := params.TagLength
+		if tagSize == 0 {
+			tagSize = 16
+		}
+
+		return newGCMStream(block, iv, aad, tagSize, decrypt)
+	case kmip14.BlockCipherModeCBC:
+		if decrypt {
+			return &cbcDecrypter{mode: cipher.NewCBCDecrypter(block, iv), padding: params.PaddingMethod}, nil
+		}
+
+		return &cbcEncrypter{mode: cipher.NewCBCEncrypter(block, iv), padding: params.PaddingMethod}, nil
+	case kmip14.BlockCipherModeCTR:
+		return ctrStream{cipher.NewCTR(block, iv)}, nil
+	}
+
+	return nil, unsupportedBlockCipherMode(params.BlockCipherMode)
+}
+
+// symmetricKeyMaterial returns the key material of a symmetric key in Raw or Transparent Symmetric Key format.
+func symmetricKeyMaterial(obj *kmip.ManagedObject) ([]byte, error) {
+	if obj.SymmetricKey == nil {
+		return nil, invalidFieldErrorf("the key must be a Symmetric Key, not a %s", obj.ObjectType.String())
+	}
+
+	kb := &obj.SymmetricKey.KeyBlock
+
+	if kb.KeyValue == nil || kb.KeyWrappingData != nil {
+		return nil, kmip.WithResultReason(merry.UserError("the key material isn't available"), kmip14.ResultReasonKeyValueNotPresent)
+	}
+
+	switch kb.KeyFormatType {
+	case kmip14.KeyFormatTypeRaw:
+		if material, ok := kb.KeyValue.KeyMaterial.([]byte); ok {
+			return material, nil
+		}
+	case kmip14.KeyFormatTypeTransparentSymmetricKey:
+		var t kmip.TransparentSymmetricKey
+		if err := kmip.DecodeAttributeValue(kb.KeyValue.KeyMaterial, &t); err == nil {
+			return t.Key, nil
+		}
+	}
+
+	return nil, kmip.WithResultReason(merry.UserErrorf("unsupported Key Format Type: %s", kb.KeyFormatType.String()), kmip14.ResultReasonKeyFormatTypeNotSupported)
+}
+
+// privateKey returns the private key of a Private Key object, see kmip.KeyBlock's PrivateKey.
+func privateKey(obj *kmip.ManagedObject) (crypto.PrivateKey, error) {
+	if obj.PrivateKey == nil {
+		return nil, invalidFieldErrorf("the key must be a Private Key, not a %s", obj.ObjectType.String())
+	}
+
+	return obj.PrivateKey.KeyBlock.PrivateKey()
+}
+
+// publicKey returns the public key of a Public Key object, see kmip.KeyBlock's PublicKey, or of a certificate.
+func publicKey(obj *kmip.ManagedObject) (crypto.PublicKey, error) {
+	if obj.Certificate != nil {
+		cert, err := obj.Certificate.X509Certificate()
+		if err != nil {
+			return nil, err
+		}
+
+		return cert.PublicKey, nil
+	}
+
+	if obj.PublicKey == nil {
+		return nil, invalidFieldErrorf("the key must be a Public Key or Certificate, not a %s", obj.ObjectType.String())
+	}
+
+	return obj.PublicKey.KeyBlock.PublicKey()
+}
+
+// blockCipher returns the block cipher for an AES or 3DES key.
+func blockCipher(obj *kmip.ManagedObject) (cipher.Block, error) {
+	material, err := symmetricKeyMaterial(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var block cipher.Block
+
+	switch alg := obj.SymmetricKey.KeyBlock.CryptographicAlgorithm; alg {
+	case kmip14.CryptographicAlgorithmAES:
+		block, err = aes.NewCipher(material)
+	case kmip14.CryptographicAlgorithmDES3:
+		block, err = des.NewTripleDESCipher(material)
+	default:
+		return nil, invalidFieldErrorf("unsupported Cryptographic Algorithm for Encrypt and Decrypt: %s", alg.String())
+	}
+
+	if err != nil {
+		return nil, kmip.WithResultReason(merry.Prepend(err, "invalid key"), kmip14.ResultReasonCryptographicFailure)
+	}
+
+	return block, nil
+}
+
+// maxGCMIVLength is the longest GCM IV accepted, in bytes.  GCM allows longer IVs, but they're hashed down
+// to a block, so they add nothing.
+const maxGCMIVLength = 128
+
+// checkIVLength checks the length in bytes of an IV for the Block Cipher Mode.  GCM IVs may be from 1 to
+// maxGCMIVLength bytes, and other modes take a block.
+func checkIVLength(block cipher.Block, mode kmip14.BlockCipherMode, n int) error {
+	if mode == kmip14.BlockCipherModeGCM {
+		if n < 1 || n > maxGCMIVLength {
+			return invalidFieldErrorf("the IV/Counter/Nonce must be from 1 to %d bytes", maxGCMIVLength)
+		}
+
+		return nil
+	}
+
+	if n != block.BlockSize() {
+		return invalidFieldErrorf("the IV/Counter/Nonce must be %d bytes", block.BlockSize())
+	}
+
+	return nil
+}
+
+// ivLength returns the length in bytes of a random IV: the IV Length, if set, otherwise 12 bytes for GCM, or a
+// block for other modes.  The IV Length must be a whole number of bytes, valid for the mode.
+func ivLength(block cipher.Block, params kmip.CryptographicParameters) (int, error) {
+	switch {
+	case params.IVLength != 0:
+		if params.IVLength < 0 || params.IVLength%8 != 0 {
+			return 0, invalidFieldErrorf("invalid IV Length: %d", params.IVLength)
+		}
+
+		n := params.IVLength / 8
+
+		return n, checkIVLength(block, params.BlockCipherMode, n)
+	case params.BlockCipherMode == kmip14.BlockCipherModeGCM:
+		return 12, nil
+	}
+
+	return block.BlockSize(), nil
+}
+
+// pad pads the data to a multiple of the block size with PKCS#5 padding.  Without a Padding Method, the data
+// must already be a multiple of the block size.
+func pad(method kmip14.PaddingMethod, data []byte, blockSize int) ([]byte, error) {
+	switch method {
+	case 0, kmip14.PaddingMethodNone:
+		if len(data)%blockSize != 0 {
+			return nil, invalidFieldErrorf("without padding, the data must be a multiple of %d bytes", blockSize)
+		}
+
+		return data, nil
+	case kmip14.PaddingMethodPKCS5:
+		n := blockSize - len(data)%blockSize
+
+		return append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(n)}, n)...), nil
+	}
+
+	return nil, invalidFieldErrorf("unsupported Padding Method: %s", method.String())
+}
+
+// unpad removes the padding added by pad.
+func unpad(method kmip14.PaddingMethod, data []byte, blockSize int) ([]byte, error) {
+	switch method {
+	case 0, kmip14.PaddingMethodNone:
+		return data, nil
+	case kmip14.PaddingMethodPKCS5:
+		if len(data) == 0 {
+			return nil, cryptographicFailuref("invalid padding")
+		}
+
+		n := int(data[len(data)-1])
+		if n == 0 || n > blockSize || n > len(data) || !bytes.Equal(data[len(data)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+			return nil, cryptographicFailuref("invalid padding")
+		}
+
+		return data[:len(data)-n], nil
+	}
+
+	return nil, invalidFieldErrorf("unsupported Padding Method: %s", method.String())
+}
+
+func encryptRSA(pub crypto.PublicKey, params kmip.CryptographicParameters, data []byte) ([]byte, error) {
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, invalidFieldErrorf("unsupported key type for Encrypt: %T", pub)
+	}
+
+	var (
+		out []byte
+		err error
+	)
+
+	switch params.PaddingMethod {
+	case kmip14.PaddingMethodOAEP:
+		h, herr := oaepHash(params)
+		if herr != nil {
+			return nil, herr
+		}
+
+		out, err = rsa.EncryptOAEP(h.New(), rand.Reader, key, data, params.PSource)
+	case kmip14.PaddingMethodPKCS1V1_5:
+		out, err = rsa.EncryptPKCS1v15(rand.Reader, key, data)
+	default:
+		return nil, invalidFieldErrorf("RSA encryption requires the OAEP or PKCS1 v1.5 Padding Method")
+	}
+
+	if err != nil {
+		return nil, kmip.WithResultReason(merry.Prepend(err, "encrypting"), kmip14.ResultReasonCryptographicFailure)
+	}
+
+	return out, nil
+}
+
+func decryptRSA(priv crypto.PrivateKey, params kmip.CryptographicParameters, data []byte) ([]byte, error) {
+	key, ok := priv.(*rsa.PrivateKey)
+	if !ok {
+		return nil, invalidFieldErrorf("unsupported key type for Decrypt: %T", priv)
+	}
+
+	var (
+		out []byte
+		err error
+	)
+
+	switch params.PaddingMethod {
+	case kmip14.PaddingMethodOAEP:
+		h, herr := oaepHash(params)
+		if herr != nil {
+			return nil, herr
+		}
+
+		out, err = rsa.DecryptOAEP(h.New(), rand.Reader, key, data, params.PSource)
+	case kmip14.PaddingMethodPKCS1V1_5:
+		out, err = rsa.DecryptPKCS1v15(rand.Reader, key, data)
+	default:
+		return nil, invalidFieldErrorf("RSA decryption requires the OAEP or PKCS1 v1.5 Padding Method")
+	}
+
+	if err != nil {
+		return nil, cryptographicFailuref("decryption failed")
+	}
+
+	return out, nil
+}
+
+// oaepHash returns the OAEP hash, which defaults to SHA-1.  Go uses the same hash for MGF1, so a different
+// Mask Generator Hashing Algorithm isn't supported.
+func oaepHash(params kmip.CryptographicParameters) (crypto.Hash, error) {
+	alg := params.HashingAlgorithm
+	if alg == 0 {
+		alg = kmip14.HashingAlgorithmSHA_1
+	}
+
+	if params.MaskGeneratorHashingAlgorithm != 0 && params.MaskGeneratorHashingAlgorithm != alg {
+		return 0, invalidFieldErrorf("the Mask Generator Hashing Algorithm must match the Hashing Algorithm")
+	}
+
+	return hashFunc(alg)
+}
+
+// hashes maps Hashing Algorithms to the Go hashes.
+var hashes = map[kmip14.HashingAlgorithm]crypto.Hash{
+	kmip14.HashingAlgorithmMD5:         crypto.MD5,
+	kmip14.HashingAlgorithmSHA_1:       crypto.SHA1,
+	kmip14.HashingAlgorithmSHA_224:     crypto.SHA224,
+	kmip14.HashingAlgorithmSHA_256:     crypto.SHA256,
+	kmip14.HashingAlgorithmSHA_384:     crypto.SHA384,
+	kmip14.HashingAlgorithmSHA_512:     crypto.SHA512,
+	kmip14.HashingAlgorithmSHA_512_224: crypto.SHA512_224,
+	kmip14.HashingAlgorithmSHA_512_256: crypto.SHA512_256,
+}
+
+func hashFunc(alg kmip14.HashingAlgorithm) (crypto.Hash, error) {
+	h, ok := hashes[alg]
+	if !ok || !h.Available() {
+		return 0, invalidFieldErrorf("unsupported Hashing Algorithm: %s", alg.String())
+	}
+
+	return h, nil
+}
+
+// signatureAlgorithms maps Digital Signature Algorithms to their hashes.
+var signatureAlgorithms = map[kmip14.DigitalSignatureAlgorithm]kmip14.HashingAlgorithm{
+	kmip14.DigitalSignatureAlgorithmMD5WithRSAEncryption:     kmip14.HashingAlgorithmMD5,
+	kmip14.DigitalSignatureAlgorithmSHA_1WithRSAEncryption:   kmip14.HashingAlgorithmSHA_1,
+	kmip14.DigitalSignatureAlgorithmSHA_224WithRSAEncryption: kmip14.HashingAlgorithmSHA_224,
+	kmip14.DigitalSignatureAlgorithmSHA_256WithRSAEncryption: kmip14.HashingAlgorithmSHA_256,
+	kmip14.DigitalSignatureAlgorithmSHA_384WithRSAEncryption: kmip14.HashingAlgorithmSHA_384,
+	kmip14.DigitalSignatureAlgorithmSHA_512WithRSAEncryption: kmip14.HashingAlgorithmSHA_512,
+	kmip14.DigitalSignatureAlgorithmECDSAWithSHA_1:           kmip14.HashingAlgorithmSHA_1,
+	kmip14.DigitalSignatureAlgorithmECDSAWithSHA224:          kmip14.HashingAlgorithmSHA_224,
+	kmip14.DigitalSignatureAlgorithmECDSAWithSHA256:          kmip14.HashingAlgorithmSHA_256,
+	kmip14.DigitalSignatureAlgorithmECDSAWithSHA384:          kmip14.HashingAlgorithmSHA_384,
+	kmip14.DigitalSignatureAlgorithmECDSAWithSHA512:          kmip14.HashingAlgorithmSHA_512,
+}
+
+// scheme describes how a signature is made: the hash, and, for RSA, whether PSS padding is used.
+type scheme struct {
+	hash       crypto.Hash
+	pss        bool
+	saltLength int
+}
+
+// signatureScheme returns the signature scheme for the Cryptographic Parameters.  The Digital Signature
+// Algorithm takes precedence over the Hashing Algorithm and Padding Method.  RSASSA-PSS takes its hash from
+// the Hashing Algorithm.
+func signatureScheme(params kmip.CryptographicParameters) (scheme, error) {
+	s := scheme{
+		pss:        params.PaddingMethod == kmip14.PaddingMethodPSS,
+		saltLength: params.SaltLength,
+	}
+
+	alg := params.HashingAlgorithm
+
+	switch dsa := params.DigitalSignatureAlgorithm; dsa {
+	case 0:
+	case kmip14.DigitalSignatureAlgorithmRSASSA_PSS:
+		s.pss = true
+	default:
+		var ok bool
+
+		alg, ok = signatureAlgorithms[dsa]
+		if !ok {
+			return s, invalidFieldErrorf("unsupported Digital Signature Algorithm: %s", dsa.String())
+		}
+	}
+
+	if alg != 0 {
+		h, err := hashFunc(alg)
+		if err != nil {
+			return s, err
+		}
+
+		s.hash = h
+	}
+
+	return s, nil
+}
+
+func (s scheme) pssOptions() *rsa.PSSOptions {
+	saltLength := s.saltLength
+	if saltLength == 0 {
+		saltLength = rsa.PSSSaltLengthEqualsHash
+	}
+
+	return &rsa.PSSOptions{SaltLength: saltLength, Hash: s.hash}
+}
+
+// hmacAlgorithms maps the HMAC Cryptographic Algorithms to their hashes.
+var hmacAlgorithms = map[kmip14.CryptographicAlgorithm]kmip14.HashingAlgorithm{
+	kmip14.CryptographicAlgorithmHMAC_MD5:    kmip14.HashingAlgorithmMD5,
+	kmip14.CryptographicAlgorithmHMAC_SHA1:   kmip14.HashingAlgorithmSHA_1,
+	kmip14.CryptographicAlgorithmHMAC_SHA224: kmip14.HashingAlgorithmSHA_224,
+	kmip14.CryptographicAlgorithmHMAC_SHA256: kmip14.HashingAlgorithmSHA_256,
+	kmip14.CryptographicAlgorithmHMAC_SHA384: kmip14.HashingAlgorithmSHA_384,
+	kmip14.CryptographicAlgorithmHMAC_SHA512: kmip14.HashingAlgorithmSHA_512,
+}
+
+// newHMAC returns the HMAC for a key.  The algorithm is the Cryptographic Algorithm of the Cryptographic
+// Parameters, or of the key.
+func newHMAC(obj *kmip.ManagedObject, params kmip.CryptographicParameters) (hash.Hash, error) {
+	material, err := symmetricKeyMaterial(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	alg := params.CryptographicAlgorithm
+	if alg == 0 {
+		alg = obj.SymmetricKey.KeyBlock.CryptographicAlgorithm
+	}
+
+	hashAlg, ok := hmacAlgorithms[alg]
+	if !ok {
+		return nil, invalidFieldErrorf("unsupported Cryptographic Algorithm for MAC: %s", alg.String())
+	}
+
+	h, err := hashFunc(hashAlg)
+	if err != nil {
+		return nil, err
+	}
+
+	return hmac.New(h.New, material), nil
+}
+
+func validityIndicator(valid bool) kmip14.ValidityIndicator {
+	if valid {
+		return kmip14.ValidityIndicatorValid
+	}
+
+	return kmip14.ValidityIndicatorInvalid
+}
+
+func unsupportedBlockCipherMode(mode kmip14.BlockCipherMode) error {
+	if mode == 0 {
+		return invalidFieldErrorf("a Block Cipher Mode is required")
+	}
+
+	return invalidFieldErrorf("unsupported Block Cipher Mode: %s", mode.String())
+}
+
+func cryptographicFailuref(format string, args ...interface{}) error {
+	return kmip.WithResultReason(merry.UserErrorf(format, args...), kmip14.ResultReasonCryptographicFailure)
+}
@@ -0,0 +1,545 @@
+package refserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"hash"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// DefaultMaxStreams is the number of streams a connection may have open at once, if CryptoHandlers.MaxStreams
+// isn't set.
+const DefaultMaxStreams = 16
+
+// DefaultMaxStreamBuffer is the data an open stream may buffer, in bytes, if CryptoHandlers.MaxStreamBuffer
+// isn't set.
+const DefaultMaxStreamBuffer = 1 << 20
+
+// DefaultStreamIdleTimeout is how long an open stream may go without a request, if
+// CryptoHandlers.StreamIdleTimeout isn't set.
+const DefaultStreamIdleTimeout = 5 * time.Minute
+
+// stream is a cryptographic operation in progress.  Requests which aren't part of a stream are processed as a
+// stream which is opened and finalized by the same request, and which has no Correlation Value.
+type stream struct {
+	mu sync.Mutex
+
+	op               kmip14.Operation
+	uid              string
+	correlationValue []byte
+	closed           bool
+
+	// cipher processes the data of Encrypt and Decrypt, and digest the data of the other operations.
+	cipher cipherStream
+	digest digestStream
+
+	// maxBuffer limits the data buffered by an open stream.  Zero means no limit.
+	maxBuffer int
+
+	// idle discards an open stream which hasn't been used since lastUsed for the idle timeout.
+	idle     *time.Timer
+	lastUsed time.Time
+}
+
+// bufferer is implemented by cipher and digest streams which buffer the data, rather than processing it as it
+// arrives.
+type bufferer interface {
+	// buffered returns the number of bytes buffered.
+	buffered() int
+}
+
+// checkBuffer checks that the stream may buffer n more bytes.
+func (s *stream) checkBuffer(n int) error {
+	if s.maxBuffer == 0 {
+		return nil
+	}
+
+	var b bufferer
+	if s.cipher != nil {
+		b, _ = s.cipher.(bufferer)
+	} else {
+		b, _ = s.digest.(bufferer)
+	}
+
+	if b != nil && b.buffered()+n > s.maxBuffer {
+		return kmip.WithResultReason(merry.Prependf(kmip.ErrStreamBufferFull, "the stream can't buffer more than %d bytes", s.maxBuffer), kmip14.ResultReasonPermissionDenied)
+	}
+
+	return nil
+}
+
+// write adds the next part of the data to the digest.
+func (s *stream) write(data []byte) error {
+	if err := s.checkBuffer(len(data)); err != nil {
+		return err
+	}
+
+	_, _ = s.digest.Write(data)
+
+	return nil
+}
+
+// isFinal returns true if the request with the Final Indicator finalizes the stream.
+func (s *stream) isFinal(finalIndicator bool) bool {
+	return finalIndicator || s.correlationValue == nil
+}
+
+// crypt encrypts or decrypts the next part of the data.  If final is true, the stream is finalized, with the
+// Authenticated Encryption Tag, when decrypting, and the tag is returned, when encrypting.
+func (s *stream) crypt(data []byte, final bool, tag []byte) ([]byte, []byte, error) {
+	if err := s.checkBuffer(len(data)); err != nil {
+		return nil, nil, err
+	}
+
+	out, err := s.cipher.update(data)
+	if err != nil || !final {
+		return out, nil, err
+	}
+
+	rest, tag, err := s.cipher.final(tag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return append(out, rest...), tag, nil
+}
+
+// begin returns the stream for a request, locked.  Requests with a Correlation Value continue the open stream
+// with that value, which must be for the same operation.  Other requests start a new stream, with create.  If the
+// request has the Init Indicator, and not the Final Indicator, the new stream is kept open, and given a
+// Correlation Value.  Open streams may only buffer MaxStreamBuffer bytes, and are discarded after
+// StreamIdleTimeout without a request.
+//
+// The caller must call end when it has processed the request.
+func (h *CryptoHandlers) begin(ctx context.Context, op kmip14.Operation, correlationValue []byte, initIndicator, finalIndicator bool, create func() (*stream, error)) (*stream, error) {
+	conn := kmip.ConnFromContext(ctx)
+
+	if correlationValue != nil {
+		if initIndicator {
+			return nil, invalidFieldErrorf("the Init Indicator can't be set with a Correlation Value")
+		}
+
+		return h.streams.get(conn, op, correlationValue)
+	}
+
+	s, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	s.op = op
+	s.mu.Lock()
+
+	if initIndicator && !finalIndicator {
+		maxStreams := h.MaxStreams
+		if maxStreams == 0 {
+			maxStreams = DefaultMaxStreams
+		}
+
+		if err := h.streams.add(conn, s, maxStreams); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+
+		s.maxBuffer = h.MaxStreamBuffer
+		if s.maxBuffer == 0 {
+			s.maxBuffer = DefaultMaxStreamBuffer
+		}
+	}
+
+	return s, nil
+}
+
+// end unlocks the stream after a request, and closes it if closeStream is true.  Otherwise, an open stream's
+// idle timer is restarted.
+func (h *CryptoHandlers) end(ctx context.Context, s *stream, closeStream bool) {
+	defer s.mu.Unlock()
+
+	if s.correlationValue == nil {
+		return
+	}
+
+	conn := kmip.ConnFromContext(ctx)
+
+	if closeStream {
+		h.streams.remove(conn, s)
+		s.closed = true
+
+		if s.idle != nil {
+			s.idle.Stop()
+		}
+
+		return
+	}
+
+	timeout := h.StreamIdleTimeout
+	if timeout == 0 {
+		timeout = DefaultStreamIdleTimeout
+	}
+
+	s.lastUsed = time.Now()
+
+	if s.idle == nil {
+		s.idle = time.AfterFunc(timeout, func() { h.expire(conn, s, timeout) })
+	}
+}
+
+// expire discards an open stream, unless it's been used within the timeout.
+func (h *CryptoHandlers) expire(conn *kmip.ServerConn, s *stream, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	if idle := time.Since(s.lastUsed); idle < timeout {
+		s.idle.Reset(timeout - idle)
+		return
+	}
+
+	h.streams.remove(conn, s)
+	s.closed = true
+}
+
+// streams holds the open streams of each connection.
+type streams struct {
+	mu   sync.Mutex
+	open map[*kmip.ServerConn]map[string]*stream
+}
+
+func (ss *streams) get(conn *kmip.ServerConn, op kmip14.Operation, correlationValue []byte) (*stream, error) {
+	ss.mu.Lock()
+	s := ss.open[conn][string(correlationValue)]
+	ss.mu.Unlock()
+
+	if s != nil && s.op == op {
+		s.mu.Lock()
+
+		// the stream may have been finalized while waiting for the lock
+		if !s.closed {
+			return s, nil
+		}
+
+		s.mu.Unlock()
+	}
+
+	return nil, kmip.WithResultReason(merry.Prependf(kmip.ErrInvalidCorrelationValue, "no open %s stream with the Correlation Value", op.String()), kmip14.ResultReasonItemNotFound)
+}
+
+// add opens a stream, giving it a new Correlation Value.  When the connection closes, its streams are discarded.
+func (ss *streams) add(conn *kmip.ServerConn, s *stream, maxStreams int) error {
+	correlationValue := make([]byte, 16)
+	if _, err := rand.Read(correlationValue); err != nil {
+		return merry.Prepend(err, "generating correlation value")
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if len(ss.open[conn]) >= maxStreams {
+		return kmip.WithResultReason(merry.Prependf(kmip.ErrTooManyStreams, "the connection has %d streams open", len(ss.open[conn])), kmip14.ResultReasonPermissionDenied)
+	}
+
+	if ss.open == nil {
+		ss.open = map[*kmip.ServerConn]map[string]*stream{}
+	}
+
+	if ss.open[conn] == nil {
+		ss.open[conn] = map[string]*stream{}
+
+		if conn != nil {
+			go func() {
+				<-conn.Done()
+				ss.mu.Lock()
+				delete(ss.open, conn)
+				ss.mu.Unlock()
+			}()
+		}
+	}
+
+	s.correlationValue = correlationValue
+	ss.open[conn][string(correlationValue)] = s
+
+	return nil
+}
+
+func (ss *streams) remove(conn *kmip.ServerConn, s *stream) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	delete(ss.open[conn], string(s.correlationValue))
+}
+
+// cipherStream encrypts or decrypts data in parts.
+type cipherStream interface {
+	// update processes the next part of the data, and returns the output which is ready.
+	update(data []byte) ([]byte, error)
+	// final returns the rest of the output.  When decrypting with an authenticated mode, it checks the tag.
+	// When encrypting with an authenticated mode, it returns the tag.
+	final(tag []byte) ([]byte, []byte, error)
+}
+
+// cbcEncrypter encrypts in CBC mode, holding back partial blocks until they're filled, or padded by final.
+type cbcEncrypter struct {
+	mode    cipher.BlockMode
+	padding kmip14.PaddingMethod
+	buf     []byte
+}
+
+func (c *cbcEncrypter) update(data []byte) ([]byte, error) {
+	c.buf = append(c.buf, data...)
+	n := len(c.buf) / c.mode.BlockSize() * c.mode.BlockSize()
+
+	return c.crypt(n), nil
+}
+
+func (c *cbcEncrypter) final([]byte) ([]byte, []byte, error) {
+	padded, err := pad(c.padding, c.buf, c.mode.BlockSize())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.buf = padded
+
+	return c.crypt(len(c.buf)), nil, nil
+}
+
+func (c *cbcEncrypter) crypt(n int) []byte {
+	out := make([]byte, n)
+	c.mode.CryptBlocks(out, c.buf[:n])
+	c.buf = append(c.buf[:0], c.buf[n:]...)
+
+	return out
+}
+
+// cbcDecrypter decrypts in CBC mode.  With padding, the last block is held back until final, so the padding can
+// be removed.
+type cbcDecrypter struct {
+	mode    cipher.BlockMode
+	padding kmip14.PaddingMethod
+	buf     []byte
+}
+
+func (c *cbcDecrypter) update(data []byte) ([]byte, error) {
+	c.buf = append(c.buf, data...)
+	n := len(c.buf) / c.mode.BlockSize() * c.mode.BlockSize()
+
+	if n == len(c.buf) && n > 0 && c.padding == kmip14.PaddingMethodPKCS5 {
+		n -= c.mode.BlockSize()
+	}
+
+	return c.crypt(n), nil
+}
+
+func (c *cbcDecrypter) final([]byte) ([]byte, []byte, error) {
+	if len(c.buf)%c.mode.BlockSize() != 0 {
+		return nil, nil, invalidFieldErrorf("the data must be a multiple of %d bytes", c.mode.BlockSize())
+	}
+
+	out, err := unpad(c.padding, c.crypt(len(c.buf)), c.mode.BlockSize())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return out, nil, nil
+}
+
+func (c *cbcDecrypter) crypt(n int) []byte {
+	out := make([]byte, n)
+	c.mode.CryptBlocks(out, c.buf[:n])
+	c.buf = append(c.buf[:0], c.buf[n:]...)
+
+	return out
+}
+
+// ctrStream encrypts and decrypts in CTR mode.
+type ctrStream struct {
+	cipher.Stream
+}
+
+func (c ctrStream) update(data []byte) ([]byte, error) {
+	out := make([]byte, len(data))
+	c.XORKeyStream(out, data)
+
+	return out, nil
+}
+
+func (c ctrStream) final([]byte) ([]byte, []byte, error) {
+	return nil, nil, nil
+}
+
+// bufferedCipher collects all the data, and processes it in final, for algorithms, like RSA, which can't process
+// data in parts.
+type bufferedCipher struct {
+	fn  func(data []byte) ([]byte, error)
+	buf []byte
+}
+
+func (c *bufferedCipher) update(data []byte) ([]byte, error) {
+	c.buf = append(c.buf, data...)
+	return nil, nil
+}
+
+func (c *bufferedCipher) buffered() int {
+	return len(c.buf)
+}
+
+func (c *bufferedCipher) final([]byte) ([]byte, []byte, error) {
+	out, err := c.fn(c.buf)
+	return out, nil, err
+}
+
+// digestStream collects the data to sign or MAC.
+type digestStream interface {
+	io.Writer
+	// sum returns the signature or MAC of the data, or of the digested data, if it isn't nil.
+	sum(digestedData []byte) ([]byte, error)
+	// verify reports whether sig is the signature or MAC of the data, or of the digested data.
+	verify(digestedData, sig []byte) (bool, error)
+}
+
+// signatureStream hashes the data to sign or verify.  Ed25519 signs the message itself, so the data is collected
+// instead.
+type signatureStream struct {
+	key    interface{}
+	scheme scheme
+	h      hash.Hash
+	msg    bytes.Buffer
+}
+
+// newSignatureStream returns the signature stream for a private key, to sign, or a public key, to verify.
+func newSignatureStream(key interface{}, params kmip.CryptographicParameters) (*signatureStream, error) {
+	sc, err := signatureScheme(params)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &signatureStream{key: key, scheme: sc}
+
+	switch key.(type) {
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return s, nil
+	case *rsa.PrivateKey, *rsa.PublicKey, *ecdsa.PrivateKey, *ecdsa.PublicKey:
+	default:
+		return nil, invalidFieldErrorf("unsupported key type for signatures: %T", key)
+	}
+
+	if sc.hash == 0 {
+		return nil, invalidFieldErrorf("a Hashing Algorithm or Digital Signature Algorithm is required")
+	}
+
+	s.h = sc.hash.New()
+
+	return s, nil
+}
+
+func (s *signatureStream) buffered() int {
+	return s.msg.Len()
+}
+
+func (s *signatureStream) Write(p []byte) (int, error) {
+	if s.h == nil {
+		return s.msg.Write(p)
+	}
+
+	return s.h.Write(p)
+}
+
+// digest returns the digest to sign: the digested data, if given, or the hash of the data.  For Ed25519, it's
+// the message.
+func (s *signatureStream) digest(digestedData []byte) ([]byte, error) {
+	switch {
+	case s.h == nil:
+		if digestedData != nil {
+			return nil, invalidFieldErrorf("Ed25519 signatures can't use Digested Data")
+		}
+
+		return s.msg.Bytes(), nil
+	case digestedData != nil:
+		if len(digestedData) != s.scheme.hash.Size() {
+			return nil, invalidFieldErrorf("the Digested Data must be %d bytes", s.scheme.hash.Size())
+		}
+
+		return digestedData, nil
+	}
+
+	return s.h.Sum(nil), nil
+}
+
+func (s *signatureStream) sum(digestedData []byte) ([]byte, error) {
+	digest, err := s.digest(digestedData)
+	if err != nil {
+		return nil, err
+	}
+
+	var sig []byte
+
+	switch k := s.key.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(k, digest), nil
+	case *rsa.PrivateKey:
+		if s.scheme.pss {
+			sig, err = rsa.SignPSS(rand.Reader, k, s.scheme.hash, digest, s.scheme.pssOptions())
+		} else {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, s.scheme.hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		sig, err = ecdsa.SignASN1(rand.Reader, k, digest)
+	default:
+		return nil, invalidFieldErrorf("a %T can't sign", s.key)
+	}
+
+	if err != nil {
+		return nil, kmip.WithResultReason(merry.Prepend(err, "signing"), kmip14.ResultReasonCryptographicFailure)
+	}
+
+	return sig, nil
+}
+
+func (s *signatureStream) verify(digestedData, sig []byte) (bool, error) {
+	digest, err := s.digest(digestedData)
+	if err != nil {
+		return false, err
+	}
+
+	switch k := s.key.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, digest, sig), nil
+	case *rsa.PublicKey:
+		if s.scheme.pss {
+			return rsa.VerifyPSS(k, s.scheme.hash, digest, sig, s.scheme.pssOptions()) == nil, nil
+		}
+
+		return rsa.VerifyPKCS1v15(k, s.scheme.hash, digest, sig) == nil, nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest, sig), nil
+	}
+
+	return false, invalidFieldErrorf("a %T can't verify signatures", s.key)
+}
+
+// macStream computes an HMAC.
+type macStream struct {
+	hash.Hash
+}
+
+func (m macStream) sum([]byte) ([]byte, error) {
+	return m.Sum(nil), nil
+}
+
+func (m macStream) verify(_, mac []byte) (bool, error) {
+	return hmac.Equal(m.Sum(nil), mac), nil
+}
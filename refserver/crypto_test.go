@@ -0,0 +1,572 @@
+package refserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// putKey stores an active key, with the attributes, and returns its Unique Identifier.
+func putKey(t *testing.T, store kmip.ObjectStore, key interface{}, attrs ...kmip.Attribute) string {
+	t.Helper()
+
+	obj := kmip.ManagedObject{Attribute: attrs}
+	require.NoError(t, obj.SetObject(key))
+	obj.SetAttributeTag(kmip14.TagState, kmip14.StateActive)
+
+	var id string
+
+	require.NoError(t, store.Update(context.Background(), func(tx kmip.ObjectTx) error {
+		var err error
+		id, err = tx.Create(&obj)
+
+		return err
+	}))
+
+	return id
+}
+
+func testSymmetricKey(t *testing.T, alg kmip14.CryptographicAlgorithm, length int) *kmip.SymmetricKey {
+	t.Helper()
+
+	payload := kmip.CreateRequestPayload{ObjectType: kmip14.ObjectTypeSymmetricKey}
+	payload.TemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, alg)
+	payload.TemplateAttribute.Append(kmip14.TagCryptographicLength, length)
+
+	key, err := GenerateSymmetricKey(context.Background(), &payload)
+	require.NoError(t, err)
+
+	return key
+}
+
+func testKeyPair(t *testing.T, alg kmip14.CryptographicAlgorithm, length int) (*kmip.PrivateKey, *kmip.PublicKey) {
+	t.Helper()
+
+	payload := kmip.CreateKeyPairRequestPayload{}
+	payload.CommonTemplateAttribute = &kmip.TemplateAttribute{}
+	payload.CommonTemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, alg)
+	payload.CommonTemplateAttribute.Append(kmip14.TagCryptographicLength, length)
+
+	priv, pub, err := GenerateKeyPair(context.Background(), &payload)
+	require.NoError(t, err)
+
+	return priv, pub
+}
+
+func TestCryptoHandlers_encryptDecrypt(t *testing.T) {
+	ctx := context.Background()
+	h := &CryptoHandlers{Store: &kmip.MemoryObjectStore{}}
+
+	aesID := putKey(t, h.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmAES, 256))
+	des3ID := putKey(t, h.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmDES3, 168))
+	priv, pub := testKeyPair(t, kmip14.CryptographicAlgorithmRSA, 2048)
+	privID := putKey(t, h.Store, priv)
+	pubID := putKey(t, h.Store, pub)
+
+	plaintext := []byte("the quick brown fox jumps over the lazy dog")
+
+	tests := []struct {
+		name          string
+		encryptID     string
+		decryptID     string
+		params        kmip.CryptographicParameters
+		aad           []byte
+		ivLength      int
+		sameLengthOut bool
+	}{
+		{
+			name:      "AES GCM",
+			encryptID: aesID,
+			params:    kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeGCM, RandomIV: true},
+			aad:       []byte("header"),
+			ivLength:  12,
+		},
+		{
+			name:      "AES CBC",
+			encryptID: aesID,
+			params: kmip.CryptographicParameters{
+				BlockCipherMode: kmip14.BlockCipherModeCBC,
+				PaddingMethod:   kmip14.PaddingMethodPKCS5,
+				RandomIV:        true,
+			},
+			ivLength: 16,
+		},
+		{
+			name:          "AES CTR",
+			encryptID:     aesID,
+			params:        kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeCTR, RandomIV: true},
+			ivLength:      16,
+			sameLengthOut: true,
+		},
+		{
+			name:      "3DES CBC",
+			encryptID: des3ID,
+			params: kmip.CryptographicParameters{
+				BlockCipherMode: kmip14.BlockCipherModeCBC,
+				PaddingMethod:   kmip14.PaddingMethodPKCS5,
+				RandomIV:        true,
+			},
+			ivLength: 8,
+		},
+		{
+			name:      "RSA OAEP",
+			encryptID: pubID,
+			decryptID: privID,
+			params: kmip.CryptographicParameters{
+				PaddingMethod:    kmip14.PaddingMethodOAEP,
+				HashingAlgorithm: kmip14.HashingAlgorithmSHA_256,
+			},
+		},
+		{
+			name:      "RSA PKCS1 v1.5",
+			encryptID: pubID,
+			decryptID: privID,
+			params:    kmip.CryptographicParameters{PaddingMethod: kmip14.PaddingMethodPKCS1V1_5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := test.params
+
+			enc, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{
+				UniqueIdentifier:                      test.encryptID,
+				CryptographicParameters:               &params,
+				Data:                                  plaintext,
+				AuthenticatedEncryptionAdditionalData: test.aad,
+			})
+			require.NoError(t, err)
+			assert.Len(t, enc.IVCounterNonce, test.ivLength)
+			assert.NotEqual(t, plaintext, enc.Data)
+
+			if test.sameLengthOut {
+				assert.Len(t, enc.Data, len(plaintext))
+			}
+
+			decryptID := test.decryptID
+			if decryptID == "" {
+				decryptID = test.encryptID
+			}
+
+			dec, err := h.Decrypt(ctx, &kmip.DecryptRequestPayload{
+				UniqueIdentifier:                      decryptID,
+				CryptographicParameters:               &params,
+				Data:                                  enc.Data,
+				IVCounterNonce:                        enc.IVCounterNonce,
+				AuthenticatedEncryptionAdditionalData: test.aad,
+				AuthenticatedEncryptionTag:            enc.AuthenticatedEncryptionTag,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, plaintext, dec.Data)
+		})
+	}
+
+	t.Run("GCM tag mismatch", func(t *testing.T) {
+		params := kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeGCM, RandomIV: true}
+
+		enc, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{UniqueIdentifier: aesID, CryptographicParameters: &params, Data: plaintext})
+		require.NoError(t, err)
+
+		enc.AuthenticatedEncryptionTag[0] ^= 1
+
+		_, err = h.Decrypt(ctx, &kmip.DecryptRequestPayload{
+			UniqueIdentifier:           aesID,
+			CryptographicParameters:    &params,
+			Data:                       enc.Data,
+			IVCounterNonce:             enc.IVCounterNonce,
+			AuthenticatedEncryptionTag: enc.AuthenticatedEncryptionTag,
+		})
+		require.Error(t, err)
+		assert.Equal(t, kmip14.ResultReasonCryptographicFailure, kmip.GetResultReason(err))
+	})
+
+	t.Run("IV required", func(t *testing.T) {
+		_, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{
+			UniqueIdentifier:        aesID,
+			CryptographicParameters: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeCTR},
+			Data:                    plaintext,
+		})
+		require.Error(t, err)
+		assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+	})
+
+	t.Run("invalid IVs", func(t *testing.T) {
+		for name, payload := range map[string]kmip.EncryptRequestPayload{
+			"client IV with Random IV": {
+				CryptographicParameters: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeCTR, RandomIV: true},
+				IVCounterNonce:          make([]byte, 16),
+			},
+			"IV Length too long": {
+				CryptographicParameters: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeGCM, RandomIV: true, IVLength: 1 << 30},
+			},
+			"IV Length for CBC": {
+				CryptographicParameters: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeCBC, RandomIV: true, IVLength: 64},
+			},
+			"IV Length not bytes": {
+				CryptographicParameters: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeGCM, RandomIV: true, IVLength: 95},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				payload.UniqueIdentifier = aesID
+				payload.Data = plaintext
+
+				_, err := h.Encrypt(ctx, &payload)
+				require.Error(t, err)
+				assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+			})
+		}
+	})
+
+	t.Run("stored parameters", func(t *testing.T) {
+		id := putKey(t, h.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmAES, 128),
+			kmip.NewAttributeFromTag(kmip14.TagCryptographicParameters, 0, kmip.CryptographicParameters{
+				BlockCipherMode: kmip14.BlockCipherModeGCM,
+				RandomIV:        true,
+			}),
+		)
+
+		enc, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{UniqueIdentifier: id, Data: plaintext})
+		require.NoError(t, err)
+		assert.Len(t, enc.IVCounterNonce, 12)
+		assert.Len(t, enc.AuthenticatedEncryptionTag, 16)
+	})
+
+	t.Run("usage mask", func(t *testing.T) {
+		id := putKey(t, h.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmAES, 128),
+			kmip.NewAttributeFromTag(kmip14.TagCryptographicUsageMask, 0, kmip14.CryptographicUsageMaskDecrypt),
+		)
+
+		_, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{
+			UniqueIdentifier:        id,
+			CryptographicParameters: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeGCM, RandomIV: true},
+			Data:                    plaintext,
+		})
+		require.Error(t, err)
+		require.ErrorIs(t, err, kmip.ErrIncompatibleCryptographicUsageMask)
+	})
+}
+
+func TestCryptoHandlers_signVerify(t *testing.T) {
+	ctx := context.Background()
+	h := &CryptoHandlers{Store: &kmip.MemoryObjectStore{}}
+
+	data := []byte("the quick brown fox jumps over the lazy dog")
+
+	tests := []struct {
+		name   string
+		alg    kmip14.CryptographicAlgorithm
+		length int
+		params kmip.CryptographicParameters
+	}{
+		{
+			name:   "RSA PKCS1 v1.5",
+			alg:    kmip14.CryptographicAlgorithmRSA,
+			length: 2048,
+			params: kmip.CryptographicParameters{DigitalSignatureAlgorithm: kmip14.DigitalSignatureAlgorithmSHA_256WithRSAEncryption},
+		},
+		{
+			name:   "RSA PSS",
+			alg:    kmip14.CryptographicAlgorithmRSA,
+			length: 2048,
+			params: kmip.CryptographicParameters{
+				PaddingMethod:    kmip14.PaddingMethodPSS,
+				HashingAlgorithm: kmip14.HashingAlgorithmSHA_384,
+			},
+		},
+		{
+			name:   "ECDSA",
+			alg:    kmip14.CryptographicAlgorithmECDSA,
+			length: 256,
+			params: kmip.CryptographicParameters{DigitalSignatureAlgorithm: kmip14.DigitalSignatureAlgorithmECDSAWithSHA256},
+		},
+		{
+			name:   "Ed25519",
+			alg:    cryptographicAlgorithmEd25519,
+			length: 256,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			priv, pub := testKeyPair(t, test.alg, test.length)
+			privID := putKey(t, h.Store, priv)
+			pubID := putKey(t, h.Store, pub)
+			params := test.params
+
+			sig, err := h.Sign(ctx, &kmip.SignRequestPayload{UniqueIdentifier: privID, CryptographicParameters: &params, Data: data})
+			require.NoError(t, err)
+
+			verify, err := h.SignatureVerify(ctx, &kmip.SignatureVerifyRequestPayload{
+				UniqueIdentifier:        pubID,
+				CryptographicParameters: &params,
+				Data:                    data,
+				SignatureData:           sig.SignatureData,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, kmip14.ValidityIndicatorValid, verify.ValidityIndicator)
+
+			verify, err = h.SignatureVerify(ctx, &kmip.SignatureVerifyRequestPayload{
+				UniqueIdentifier:        pubID,
+				CryptographicParameters: &params,
+				Data:                    []byte("something else"),
+				SignatureData:           sig.SignatureData,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, kmip14.ValidityIndicatorInvalid, verify.ValidityIndicator)
+		})
+	}
+
+	t.Run("digested data", func(t *testing.T) {
+		priv, pub := testKeyPair(t, kmip14.CryptographicAlgorithmECDSA, 256)
+		privID := putKey(t, h.Store, priv)
+		pubID := putKey(t, h.Store, pub)
+		params := kmip.CryptographicParameters{HashingAlgorithm: kmip14.HashingAlgorithmSHA_256}
+
+		digest := sha256.Sum256(data)
+
+		sig, err := h.Sign(ctx, &kmip.SignRequestPayload{UniqueIdentifier: privID, CryptographicParameters: &params, DigestedData: digest[:]})
+		require.NoError(t, err)
+
+		verify, err := h.SignatureVerify(ctx, &kmip.SignatureVerifyRequestPayload{
+			UniqueIdentifier:        pubID,
+			CryptographicParameters: &params,
+			Data:                    data,
+			SignatureData:           sig.SignatureData,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, kmip14.ValidityIndicatorValid, verify.ValidityIndicator)
+	})
+}
+
+func TestCryptoHandlers_mac(t *testing.T) {
+	ctx := context.Background()
+	h := &CryptoHandlers{Store: &kmip.MemoryObjectStore{}}
+
+	id := putKey(t, h.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmHMAC_SHA256, 256))
+	data := []byte("the quick brown fox jumps over the lazy dog")
+
+	mac, err := h.MAC(ctx, &kmip.MACRequestPayload{UniqueIdentifier: id, Data: data})
+	require.NoError(t, err)
+	assert.Len(t, mac.MACData, 32)
+
+	verify, err := h.MACVerify(ctx, &kmip.MACVerifyRequestPayload{UniqueIdentifier: id, Data: data, MACData: mac.MACData})
+	require.NoError(t, err)
+	assert.Equal(t, kmip14.ValidityIndicatorValid, verify.ValidityIndicator)
+
+	verify, err = h.MACVerify(ctx, &kmip.MACVerifyRequestPayload{UniqueIdentifier: id, Data: data[1:], MACData: mac.MACData})
+	require.NoError(t, err)
+	assert.Equal(t, kmip14.ValidityIndicatorInvalid, verify.ValidityIndicator)
+
+	// AES keys can't be used with HMAC
+	aesID := putKey(t, h.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmAES, 128))
+	_, err = h.MAC(ctx, &kmip.MACRequestPayload{UniqueIdentifier: aesID, Data: data})
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+}
+
+func TestCryptoHandlers_streaming(t *testing.T) {
+	ctx := context.Background()
+	h := &CryptoHandlers{Store: &kmip.MemoryObjectStore{}, MaxStreams: 2}
+
+	aesID := putKey(t, h.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmAES, 256))
+	data := []byte("the quick brown fox jumps over the lazy dog")
+
+	t.Run("encrypt", func(t *testing.T) {
+		for _, mode := range []kmip14.BlockCipherMode{kmip14.BlockCipherModeCBC, kmip14.BlockCipherModeCTR, kmip14.BlockCipherModeGCM} {
+			t.Run(mode.String(), func(t *testing.T) {
+				params := &kmip.CryptographicParameters{BlockCipherMode: mode, PaddingMethod: kmip14.PaddingMethodPKCS5}
+				iv := make([]byte, 16)
+
+				whole, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{
+					UniqueIdentifier:        aesID,
+					CryptographicParameters: params,
+					Data:                    data,
+					IVCounterNonce:          iv,
+				})
+				require.NoError(t, err)
+				assert.Nil(t, whole.CorrelationValue)
+
+				first, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{
+					UniqueIdentifier:        aesID,
+					CryptographicParameters: params,
+					Data:                    data[:5],
+					IVCounterNonce:          iv,
+					InitIndicator:           true,
+				})
+				require.NoError(t, err)
+				require.NotNil(t, first.CorrelationValue)
+				assert.Equal(t, aesID, first.UniqueIdentifier)
+
+				middle, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{CorrelationValue: first.CorrelationValue, Data: data[5:30]})
+				require.NoError(t, err)
+
+				last, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{CorrelationValue: first.CorrelationValue, Data: data[30:], FinalIndicator: true})
+				require.NoError(t, err)
+
+				var ciphertext []byte
+				ciphertext = append(ciphertext, first.Data...)
+				ciphertext = append(ciphertext, middle.Data...)
+				ciphertext = append(ciphertext, last.Data...)
+				assert.Equal(t, whole.Data, ciphertext)
+				assert.Equal(t, whole.AuthenticatedEncryptionTag, last.AuthenticatedEncryptionTag)
+
+				// the stream is closed by the final request
+				_, err = h.Encrypt(ctx, &kmip.EncryptRequestPayload{CorrelationValue: first.CorrelationValue, Data: data})
+				require.ErrorIs(t, err, kmip.ErrInvalidCorrelationValue)
+
+				// decrypt in two parts
+				first2, err := h.Decrypt(ctx, &kmip.DecryptRequestPayload{
+					UniqueIdentifier:        aesID,
+					CryptographicParameters: params,
+					Data:                    ciphertext[:20],
+					IVCounterNonce:          iv,
+					InitIndicator:           true,
+				})
+				require.NoError(t, err)
+
+				last2, err := h.Decrypt(ctx, &kmip.DecryptRequestPayload{
+					CorrelationValue:           first2.CorrelationValue,
+					Data:                       ciphertext[20:],
+					FinalIndicator:             true,
+					AuthenticatedEncryptionTag: last.AuthenticatedEncryptionTag,
+				})
+				require.NoError(t, err)
+				assert.Equal(t, data, append(first2.Data, last2.Data...))
+			})
+		}
+	})
+
+	t.Run("sign", func(t *testing.T) {
+		priv, pub := testKeyPair(t, kmip14.CryptographicAlgorithmECDSA, 256)
+		privID := putKey(t, h.Store, priv)
+		pubID := putKey(t, h.Store, pub)
+		params := &kmip.CryptographicParameters{DigitalSignatureAlgorithm: kmip14.DigitalSignatureAlgorithmECDSAWithSHA256}
+
+		first, err := h.Sign(ctx, &kmip.SignRequestPayload{UniqueIdentifier: privID, CryptographicParameters: params, Data: data[:10], InitIndicator: true})
+		require.NoError(t, err)
+		assert.Nil(t, first.SignatureData)
+
+		last, err := h.Sign(ctx, &kmip.SignRequestPayload{CorrelationValue: first.CorrelationValue, Data: data[10:], FinalIndicator: true})
+		require.NoError(t, err)
+		require.NotNil(t, last.SignatureData)
+
+		verify, err := h.SignatureVerify(ctx, &kmip.SignatureVerifyRequestPayload{
+			UniqueIdentifier:        pubID,
+			CryptographicParameters: params,
+			Data:                    data,
+			SignatureData:           last.SignatureData,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, kmip14.ValidityIndicatorValid, verify.ValidityIndicator)
+	})
+
+	t.Run("mac", func(t *testing.T) {
+		id := putKey(t, h.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmHMAC_SHA256, 256))
+
+		whole, err := h.MAC(ctx, &kmip.MACRequestPayload{UniqueIdentifier: id, Data: data})
+		require.NoError(t, err)
+
+		first, err := h.MAC(ctx, &kmip.MACRequestPayload{UniqueIdentifier: id, Data: data[:10], InitIndicator: true})
+		require.NoError(t, err)
+
+		last, err := h.MAC(ctx, &kmip.MACRequestPayload{CorrelationValue: first.CorrelationValue, Data: data[10:], FinalIndicator: true})
+		require.NoError(t, err)
+		assert.Equal(t, whole.MACData, last.MACData)
+	})
+
+	t.Run("wrong operation", func(t *testing.T) {
+		first, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{
+			UniqueIdentifier:        aesID,
+			CryptographicParameters: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeCTR, RandomIV: true},
+			InitIndicator:           true,
+		})
+		require.NoError(t, err)
+
+		_, err = h.Decrypt(ctx, &kmip.DecryptRequestPayload{CorrelationValue: first.CorrelationValue, Data: data})
+		require.ErrorIs(t, err, kmip.ErrInvalidCorrelationValue)
+		assert.Equal(t, kmip14.ResultReasonItemNotFound, kmip.GetResultReason(err))
+
+		_, err = h.Encrypt(ctx, &kmip.EncryptRequestPayload{CorrelationValue: first.CorrelationValue, FinalIndicator: true})
+		require.NoError(t, err)
+	})
+
+	t.Run("max streams", func(t *testing.T) {
+		req := &kmip.EncryptRequestPayload{
+			UniqueIdentifier:        aesID,
+			CryptographicParameters: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeCTR, RandomIV: true},
+			InitIndicator:           true,
+		}
+
+		for i := 0; i < 2; i++ {
+			_, err := h.Encrypt(ctx, req)
+			require.NoError(t, err)
+		}
+
+		_, err := h.Encrypt(ctx, req)
+		require.ErrorIs(t, err, kmip.ErrTooManyStreams)
+
+		// single requests don't open streams
+		req.InitIndicator = false
+		_, err = h.Encrypt(ctx, req)
+		require.NoError(t, err)
+	})
+
+	t.Run("digested data", func(t *testing.T) {
+		_, err := h.Sign(ctx, &kmip.SignRequestPayload{UniqueIdentifier: aesID, DigestedData: make([]byte, 32), InitIndicator: true})
+		require.Error(t, err)
+		assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+	})
+}
+
+func TestCryptoHandlers_streamLimits(t *testing.T) {
+	ctx := context.Background()
+	h := &CryptoHandlers{Store: &kmip.MemoryObjectStore{}, MaxStreamBuffer: 32, StreamIdleTimeout: 50 * time.Millisecond}
+
+	aesID := putKey(t, h.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmAES, 256))
+	_, pub := testKeyPair(t, kmip14.CryptographicAlgorithmRSA, 2048)
+	pubID := putKey(t, h.Store, pub)
+
+	t.Run("buffer", func(t *testing.T) {
+		params := &kmip.CryptographicParameters{PaddingMethod: kmip14.PaddingMethodPKCS1V1_5}
+
+		first, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{UniqueIdentifier: pubID, CryptographicParameters: params, Data: make([]byte, 20), InitIndicator: true})
+		require.NoError(t, err)
+
+		_, err = h.Encrypt(ctx, &kmip.EncryptRequestPayload{CorrelationValue: first.CorrelationValue, Data: make([]byte, 20)})
+		require.ErrorIs(t, err, kmip.ErrStreamBufferFull)
+		assert.NotErrorIs(t, err, kmip.ErrTooManyStreams)
+
+		// the stream is discarded
+		_, err = h.Encrypt(ctx, &kmip.EncryptRequestPayload{CorrelationValue: first.CorrelationValue, FinalIndicator: true})
+		require.ErrorIs(t, err, kmip.ErrInvalidCorrelationValue)
+
+		// single requests aren't limited
+		_, err = h.Encrypt(ctx, &kmip.EncryptRequestPayload{UniqueIdentifier: pubID, CryptographicParameters: params, Data: make([]byte, 40)})
+		require.NoError(t, err)
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		first, err := h.Encrypt(ctx, &kmip.EncryptRequestPayload{
+			UniqueIdentifier:        aesID,
+			CryptographicParameters: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeCTR, RandomIV: true},
+			InitIndicator:           true,
+		})
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			h.streams.mu.Lock()
+			defer h.streams.mu.Unlock()
+
+			return len(h.streams.open[nil]) == 0
+		}, time.Second, 10*time.Millisecond)
+
+		_, err = h.Encrypt(ctx, &kmip.EncryptRequestPayload{CorrelationValue: first.CorrelationValue, FinalIndicator: true})
+		require.ErrorIs(t, err, kmip.ErrInvalidCorrelationValue)
+	})
+}
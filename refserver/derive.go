@@ -0,0 +1,332 @@
+package refserver
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"encoding/binary"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/kmip20"
+)
+
+// MaxPBKDF2IterationCount is the largest Iteration Count DeriveKeyMaterial accepts for PBKDF2.
+const MaxPBKDF2IterationCount = 10_000_000
+
+// derivationMethodHKDF is the HKDF Derivation Method, added in 2.0.
+const derivationMethodHKDF = kmip14.DerivationMethod(kmip20.DerivationMethodHKDF)
+
+// DeriveKeyMaterial derives the key material for a Derive Key request.  It has the signature of
+// kmip.StoreHandlers' DeriveKeyMaterial.  The first base object is the key, a Symmetric Key or Secret Data.  The
+// key material of any further base objects is concatenated, and used as the Derivation Data, which then mustn't
+// be in the request.  The supported Derivation Methods are:
+//
+//   - PBKDF2 (RFC 8018), with HMAC as the PRF.  The Salt and Iteration Count are required, and the Iteration
+//     Count may be at most MaxPBKDF2IterationCount.
+//   - HASH: the hash of the key, followed by the Derivation Data.
+//   - HMAC: the HMAC of the Derivation Data, with the key.
+//   - ENCRYPT: the encryption of the Derivation Data with the key, an AES or 3DES key, as by Encrypt.  The
+//     Initialization Vector is the IV.
+//   - NIST800-108-C, NIST800-108-F and NIST800-108-DPI: the counter, feedback and double pipeline iteration
+//     modes of NIST SP 800-108, with HMAC as the PRF.  The Derivation Data is the fixed input data, which is
+//     preceded by a 32 bit, big endian, counter.  In feedback mode, the Initialization Vector is the initial
+//     feedback value.
+//   - HKDF (RFC 5869), added in 2.0.  The Salt is the HKDF salt, and the Derivation Data the HKDF info.
+//
+// The Cryptographic Parameters are those of the Derivation Parameters, or the key's Cryptographic Parameters
+// attribute.  The hash is their Hashing Algorithm, or, for an HMAC key, the hash of its Cryptographic
+// Algorithm.  PBKDF2 defaults to SHA-1.  HASH, HMAC and ENCRYPT fail if their output is shorter than the
+// requested key material, and truncate it otherwise.
+func DeriveKeyMaterial(ctx context.Context, payload *kmip.DeriveKeyRequestPayload, base []*kmip.ManagedObject, size int) ([]byte, error) {
+	if len(base) == 0 {
+		return nil, invalidFieldErrorf("a base object is required")
+	}
+
+	key, err := derivationKeyMaterial(base[0])
+	if err != nil {
+		return nil, err
+	}
+
+	dp := payload.DerivationParameters
+	data := dp.DerivationData
+
+	if len(base) > 1 {
+		if data != nil {
+			return nil, invalidFieldErrorf("the Derivation Data must not be given with more than one base object")
+		}
+
+		for _, obj := range base[1:] {
+			material, err := derivationKeyMaterial(obj)
+			if err != nil {
+				return nil, err
+			}
+
+			data = append(data, material...)
+		}
+	}
+
+	params, err := derivationCryptographicParameters(base[0], dp.CryptographicParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+
+	switch method := payload.DerivationMethod; method {
+	case kmip14.DerivationMethodPBKDF2:
+		if params.HashingAlgorithm == 0 {
+			params.HashingAlgorithm = kmip14.HashingAlgorithmSHA_1
+		}
+
+		h, err := derivationHash(base[0], params)
+		if err != nil {
+			return nil, err
+		}
+
+		if dp.Salt == nil || dp.IterationCount <= 0 {
+			return nil, invalidFieldErrorf("PBKDF2 requires a Salt and a positive Iteration Count")
+		}
+
+		if dp.IterationCount > MaxPBKDF2IterationCount {
+			return nil, invalidFieldErrorf("the Iteration Count must be at most %d", MaxPBKDF2IterationCount)
+		}
+
+		out, err = pbkdf2(ctx, h, key, dp.Salt, dp.IterationCount, size)
+		if err != nil {
+			return nil, err
+		}
+	case kmip14.DerivationMethodHASH:
+		h, err := derivationHash(base[0], params)
+		if err != nil {
+			return nil, err
+		}
+
+		d := h.New()
+		d.Write(key)
+		d.Write(data)
+		out = d.Sum(nil)
+	case kmip14.DerivationMethodHMAC:
+		h, err := derivationHash(base[0], params)
+		if err != nil {
+			return nil, err
+		}
+
+		mac := hmac.New(h.New, key)
+		mac.Write(data)
+		out = mac.Sum(nil)
+	case kmip14.DerivationMethodENCRYPT:
+		out, err = deriveEncrypt(base[0], params, dp.InitializationVector, data)
+		if err != nil {
+			return nil, err
+		}
+	case kmip14.DerivationMethodNIST800_108_C, kmip14.DerivationMethodNIST800_108_F, kmip14.DerivationMethodNIST800_108_DPI:
+		h, err := derivationHash(base[0], params)
+		if err != nil {
+			return nil, err
+		}
+
+		out = kdf108(method, h, key, data, dp.InitializationVector, size)
+	case derivationMethodHKDF:
+		h, err := derivationHash(base[0], params)
+		if err != nil {
+			return nil, err
+		}
+
+		if size > 255*h.Size() {
+			return nil, invalidFieldErrorf("HKDF with %s can't derive more than %d bytes", h.String(), 255*h.Size())
+		}
+
+		out = hkdf(h, key, dp.Salt, data, size)
+	default:
+		return nil, invalidFieldErrorf("unsupported Derivation Method: %s", method.String())
+	}
+
+	if len(out) < size {
+		return nil, invalidFieldErrorf("%s derives %d bytes, fewer than the %d requested", payload.DerivationMethod.String(), len(out), size)
+	}
+
+	return out[:size], nil
+}
+
+// derivationKeyMaterial returns the key material of a base object of Derive Key.
+func derivationKeyMaterial(obj *kmip.ManagedObject) ([]byte, error) {
+	if obj.SecretData == nil {
+		if obj.SymmetricKey == nil {
+			return nil, invalidFieldErrorf("the base object must be a Symmetric Key or Secret Data, not a %s", obj.ObjectType.String())
+		}
+
+		return symmetricKeyMaterial(obj)
+	}
+
+	kb := &obj.SecretData.KeyBlock
+
+	if kb.KeyValue == nil || kb.KeyWrappingData != nil {
+		return nil, kmip.WithResultReason(merry.UserError("the key material isn't available"), kmip14.ResultReasonKeyValueNotPresent)
+	}
+
+	material, ok := kb.KeyValue.KeyMaterial.([]byte)
+	if !ok {
+		return nil, kmip.WithResultReason(merry.UserErrorf("unsupported Key Format Type: %s", kb.KeyFormatType.String()), kmip14.ResultReasonKeyFormatTypeNotSupported)
+	}
+
+	return material, nil
+}
+
+// derivationCryptographicParameters returns the Cryptographic Parameters of the Derivation Parameters, or of the
+// key.
+func derivationCryptographicParameters(obj *kmip.ManagedObject, params *kmip.CryptographicParameters) (kmip.CryptographicParameters, error) {
+	if params != nil {
+		return *params, nil
+	}
+
+	var stored kmip.CryptographicParameters
+
+	if attr := obj.GetAttributeTag(kmip14.TagCryptographicParameters); attr != nil {
+		if err := kmip.DecodeAttributeValue(attr.AttributeValue, &stored); err != nil {
+			return stored, merry.Prepend(err, "invalid Cryptographic Parameters attribute")
+		}
+	}
+
+	return stored, nil
+}
+
+// derivationHash returns the Hashing Algorithm of the Cryptographic Parameters, or the hash of an HMAC key.
+func derivationHash(obj *kmip.ManagedObject, params kmip.CryptographicParameters) (crypto.Hash, error) {
+	alg := params.HashingAlgorithm
+
+	if alg == 0 && obj.SymmetricKey != nil {
+		alg = hmacAlgorithms[obj.SymmetricKey.KeyBlock.CryptographicAlgorithm]
+	}
+
+	if alg == 0 {
+		return 0, invalidFieldErrorf("a Hashing Algorithm is required")
+	}
+
+	return hashFunc(alg)
+}
+
+// deriveEncrypt encrypts the data with the key.
+func deriveEncrypt(obj *kmip.ManagedObject, params kmip.CryptographicParameters, iv, data []byte) ([]byte, error) {
+	if obj.SymmetricKey == nil {
+		return nil, invalidFieldErrorf("ENCRYPT requires a Symmetric Key, not a %s", obj.ObjectType.String())
+	}
+
+	c, _, err := newEncrypter(obj, params, iv, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := c.update(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rest, tag, err := c.final(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append(out, rest...), tag...), nil
+}
+
+// pbkdf2CheckInterval is the number of PBKDF2 iterations between checks for a canceled context.
+const pbkdf2CheckInterval = 1024
+
+// pbkdf2 implements PBKDF2, as defined in RFC 8018, with HMAC as the PRF.  It stops early if ctx is canceled.
+func pbkdf2(ctx context.Context, h crypto.Hash, password, salt []byte, iterations, size int) ([]byte, error) {
+	prf := hmac.New(h.New, password)
+
+	var out []byte
+
+	for block := uint32(1); len(out) < size; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, block))
+
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+
+		for n := 1; n < iterations; n++ {
+			if n%pbkdf2CheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, merry.Wrap(err)
+				}
+			}
+
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+
+		out = append(out, t...)
+	}
+
+	return out[:size], nil
+}
+
+// kdf108 implements the KDFs in counter, feedback and double pipeline iteration modes defined in NIST SP 800-108,
+// with HMAC as the PRF and a 32 bit counter.
+func kdf108(method kmip14.DerivationMethod, h crypto.Hash, key, fixed, iv []byte, size int) []byte {
+	prf := hmac.New(h.New, key)
+
+	var out []byte
+
+	k, a := iv, fixed
+
+	for i := uint32(1); len(out) < size; i++ {
+		counter := binary.BigEndian.AppendUint32(nil, i)
+
+		prf.Reset()
+
+		switch method {
+		case kmip14.DerivationMethodNIST800_108_F:
+			prf.Write(k)
+		case kmip14.DerivationMethodNIST800_108_DPI:
+			prf.Write(a)
+			a = prf.Sum(nil)
+
+			prf.Reset()
+			prf.Write(a)
+		}
+
+		prf.Write(counter)
+		prf.Write(fixed)
+
+		k = prf.Sum(nil)
+		out = append(out, k...)
+	}
+
+	return out[:size]
+}
+
+// hkdf implements HKDF, as defined in RFC 5869.
+func hkdf(h crypto.Hash, secret, salt, info []byte, size int) []byte {
+	if salt == nil {
+		salt = make([]byte, h.Size())
+	}
+
+	extract := hmac.New(h.New, salt)
+	extract.Write(secret)
+
+	expand := hmac.New(h.New, extract.Sum(nil))
+
+	var out, t []byte
+
+	for i := byte(1); len(out) < size; i++ {
+		expand.Reset()
+		expand.Write(t)
+		expand.Write(info)
+		expand.Write([]byte{i})
+
+		t = expand.Sum(nil)
+		out = append(out, t...)
+	}
+
+	return out[:size]
+}
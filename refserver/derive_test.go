@@ -0,0 +1,234 @@
+package refserver
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKeyMaterial(t *testing.T) {
+	object := func(t *testing.T, v interface{}) *kmip.ManagedObject {
+		t.Helper()
+
+		var obj kmip.ManagedObject
+		require.NoError(t, obj.SetObject(v))
+
+		return &obj
+	}
+
+	secret := func(t *testing.T, b []byte) *kmip.ManagedObject {
+		t.Helper()
+
+		return object(t, &kmip.SecretData{
+			SecretDataType: kmip14.SecretDataTypePassword,
+			KeyBlock: kmip.KeyBlock{
+				KeyFormatType: kmip14.KeyFormatTypeOpaque,
+				KeyValue:      &kmip.KeyValue{KeyMaterial: b},
+			},
+		})
+	}
+
+	unhex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		require.NoError(t, err)
+
+		return b
+	}
+
+	sha256Params := &kmip.CryptographicParameters{HashingAlgorithm: kmip14.HashingAlgorithmSHA_256}
+	key := []byte("0123456789abcdef")
+	data := []byte("derivation data")
+
+	hmacSHA256 := func(msg ...[]byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		for _, m := range msg {
+			mac.Write(m)
+		}
+
+		return mac.Sum(nil)
+	}
+
+	counter := func(i uint32) []byte {
+		return binary.BigEndian.AppendUint32(nil, i)
+	}
+
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+
+	iv := make([]byte, aes.BlockSize)
+	encrypted := make([]byte, 32)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, []byte("0123456789abcdef0123456789abcdef"))
+
+	hashed := sha256.Sum256(append(append([]byte(nil), key...), data...))
+
+	tests := []struct {
+		name     string
+		method   kmip14.DerivationMethod
+		params   kmip.DerivationParameters
+		base     []*kmip.ManagedObject
+		size     int
+		expected []byte
+	}{
+		{
+			// RFC 6070
+			name:     "PBKDF2",
+			method:   kmip14.DerivationMethodPBKDF2,
+			params:   kmip.DerivationParameters{Salt: []byte("salt"), IterationCount: 4096},
+			base:     []*kmip.ManagedObject{secret(t, []byte("password"))},
+			size:     20,
+			expected: unhex("4b007901b765489abead49d926f721d065a429c1"),
+		},
+		{
+			name:     "HASH",
+			method:   kmip14.DerivationMethodHASH,
+			params:   kmip.DerivationParameters{CryptographicParameters: sha256Params, DerivationData: data},
+			base:     []*kmip.ManagedObject{secret(t, key)},
+			size:     16,
+			expected: hashed[:16],
+		},
+		{
+			// the HMAC key's algorithm gives the hash
+			name:     "HMAC",
+			method:   kmip14.DerivationMethodHMAC,
+			params:   kmip.DerivationParameters{DerivationData: data},
+			base:     []*kmip.ManagedObject{object(t, &kmip.SymmetricKey{KeyBlock: keyBlock(kmip14.CryptographicAlgorithmHMAC_SHA256, 128, kmip14.KeyFormatTypeRaw, key)})},
+			size:     32,
+			expected: hmacSHA256(data),
+		},
+		{
+			// the key material of the second base object is the Derivation Data
+			name:     "HMAC with two base objects",
+			method:   kmip14.DerivationMethodHMAC,
+			params:   kmip.DerivationParameters{CryptographicParameters: sha256Params},
+			base:     []*kmip.ManagedObject{secret(t, key), secret(t, data)},
+			size:     32,
+			expected: hmacSHA256(data),
+		},
+		{
+			name:   "ENCRYPT",
+			method: kmip14.DerivationMethodENCRYPT,
+			params: kmip.DerivationParameters{
+				CryptographicParameters: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeCBC},
+				InitializationVector:    iv,
+				DerivationData:          []byte("0123456789abcdef0123456789abcdef"),
+			},
+			base:     []*kmip.ManagedObject{object(t, &kmip.SymmetricKey{KeyBlock: keyBlock(kmip14.CryptographicAlgorithmAES, 128, kmip14.KeyFormatTypeRaw, key)})},
+			size:     32,
+			expected: encrypted,
+		},
+		{
+			name:     "NIST800-108-C",
+			method:   kmip14.DerivationMethodNIST800_108_C,
+			params:   kmip.DerivationParameters{CryptographicParameters: sha256Params, DerivationData: data},
+			base:     []*kmip.ManagedObject{secret(t, key)},
+			size:     40,
+			expected: append(hmacSHA256(counter(1), data), hmacSHA256(counter(2), data)[:8]...),
+		},
+		{
+			name:     "NIST800-108-F",
+			method:   kmip14.DerivationMethodNIST800_108_F,
+			params:   kmip.DerivationParameters{CryptographicParameters: sha256Params, DerivationData: data, InitializationVector: iv},
+			base:     []*kmip.ManagedObject{secret(t, key)},
+			size:     40,
+			expected: append(hmacSHA256(iv, counter(1), data), hmacSHA256(hmacSHA256(iv, counter(1), data), counter(2), data)[:8]...),
+		},
+		{
+			name:     "NIST800-108-DPI",
+			method:   kmip14.DerivationMethodNIST800_108_DPI,
+			params:   kmip.DerivationParameters{CryptographicParameters: sha256Params, DerivationData: data},
+			base:     []*kmip.ManagedObject{secret(t, key)},
+			size:     16,
+			expected: hmacSHA256(hmacSHA256(data), counter(1), data)[:16],
+		},
+		{
+			// RFC 5869, test case 1
+			name:   "HKDF",
+			method: derivationMethodHKDF,
+			params: kmip.DerivationParameters{
+				CryptographicParameters: sha256Params,
+				Salt:                    unhex("000102030405060708090a0b0c"),
+				DerivationData:          unhex("f0f1f2f3f4f5f6f7f8f9"),
+			},
+			base:     []*kmip.ManagedObject{secret(t, unhex("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b"))},
+			size:     42,
+			expected: unhex("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"),
+		},
+		{
+			name:   "HASH output too short",
+			method: kmip14.DerivationMethodHASH,
+			params: kmip.DerivationParameters{CryptographicParameters: sha256Params},
+			base:   []*kmip.ManagedObject{secret(t, key)},
+			size:   33,
+		},
+		{
+			name:   "HMAC without a hash",
+			method: kmip14.DerivationMethodHMAC,
+			params: kmip.DerivationParameters{DerivationData: data},
+			base:   []*kmip.ManagedObject{secret(t, key)},
+			size:   16,
+		},
+		{
+			name:   "PBKDF2 without a salt",
+			method: kmip14.DerivationMethodPBKDF2,
+			params: kmip.DerivationParameters{IterationCount: 1},
+			base:   []*kmip.ManagedObject{secret(t, key)},
+			size:   16,
+		},
+		{
+			name:   "PBKDF2 Iteration Count too large",
+			method: kmip14.DerivationMethodPBKDF2,
+			params: kmip.DerivationParameters{Salt: []byte("salt"), IterationCount: MaxPBKDF2IterationCount + 1},
+			base:   []*kmip.ManagedObject{secret(t, key)},
+			size:   16,
+		},
+		{
+			name:   "Derivation Data with two base objects",
+			method: kmip14.DerivationMethodHMAC,
+			params: kmip.DerivationParameters{CryptographicParameters: sha256Params, DerivationData: data},
+			base:   []*kmip.ManagedObject{secret(t, key), secret(t, data)},
+			size:   16,
+		},
+		{
+			name:   "unsupported method",
+			method: kmip14.DerivationMethodAsymmetricKey,
+			base:   []*kmip.ManagedObject{secret(t, key)},
+			size:   16,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			material, err := DeriveKeyMaterial(context.Background(), &kmip.DeriveKeyRequestPayload{
+				DerivationMethod:     test.method,
+				DerivationParameters: test.params,
+			}, test.base, test.size)
+
+			if test.expected == nil {
+				assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, material)
+		})
+	}
+}
+
+func TestPBKDF2_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := pbkdf2(ctx, crypto.SHA256, []byte("password"), []byte("salt"), MaxPBKDF2IterationCount, 32)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,229 @@
+package refserver
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// gcmMaxData is the most data GCM may process with one key and IV: 2^32 - 2 blocks, see NIST SP 800-38D 5.2.1.1.
+const gcmMaxData = (1<<32 - 2) * 16
+
+// gcmStream implements GCM, as specified in NIST SP 800-38D, incrementally, so data can be encrypted and
+// decrypted in parts, across several requests.  Go's GCM implementation only processes whole messages.
+//
+// When decrypting, the plaintext of each part is returned before the tag has been checked.  Callers which
+// stream the decryption must discard the plaintext if the final part fails.
+type gcmStream struct {
+	block   cipher.Block
+	decrypt bool
+	tagSize int
+
+	// h is the hash subkey, and y the GHASH of the data so far.
+	h, y gcmFieldElement
+	// j0 is the pre-counter block, and counter the counter block of the next key stream block.
+	j0, counter [16]byte
+	// keyStream holds the current key stream block, of which keyStreamUsed bytes have been used.
+	keyStream     [16]byte
+	keyStreamUsed int
+
+	// partial holds ciphertext which doesn't fill a block, and hasn't been hashed yet.
+	partial       []byte
+	aadLen, ctLen uint64
+}
+
+// newGCMStream returns a GCM encrypter or decrypter, with the IV and additional authenticated data.  tagSize
+// is the size of the tag produced by encryption: decryption checks tags of any permitted size.
+func newGCMStream(block cipher.Block, iv, aad []byte, tagSize int, decrypt bool) (*gcmStream, error) {
+	if block.BlockSize() != 16 {
+		return nil, invalidFieldErrorf("GCM requires a 128 bit block cipher")
+	}
+
+	if len(iv) == 0 {
+		return nil, invalidFieldErrorf("GCM requires an IV")
+	}
+
+	if !validGCMTagSize(tagSize) {
+		return nil, invalidFieldErrorf("invalid GCM tag length: %d bytes", tagSize)
+	}
+
+	g := &gcmStream{block: block, decrypt: decrypt, tagSize: tagSize, keyStreamUsed: 16}
+
+	var hBlock [16]byte
+
+	block.Encrypt(hBlock[:], hBlock[:])
+	g.h = gcmFieldElementFromBytes(hBlock[:])
+
+	if len(iv) == 12 {
+		copy(g.j0[:], iv)
+		g.j0[15] = 1
+	} else {
+		// J0 = GHASH(IV || 0^s+64 || [len(IV)]64)
+		var j0 gcmFieldElement
+
+		j0 = g.ghash(j0, iv)
+		j0 = g.ghashLengths(j0, 0, uint64(len(iv)))
+		j0.putBytes(g.j0[:])
+	}
+
+	g.counter = g.j0
+	gcmInc32(&g.counter)
+
+	g.y = g.ghash(g.y, aad)
+	g.aadLen = uint64(len(aad))
+
+	return g, nil
+}
+
+func validGCMTagSize(n int) bool {
+	return n == 4 || n == 8 || (n >= 12 && n <= 16)
+}
+
+func (g *gcmStream) update(data []byte) ([]byte, error) {
+	if g.ctLen+uint64(len(data)) > gcmMaxData {
+		return nil, invalidFieldErrorf("too much data for one GCM IV")
+	}
+
+	out := make([]byte, len(data))
+
+	if g.decrypt {
+		g.hashCiphertext(data)
+		g.xorKeyStream(out, data)
+	} else {
+		g.xorKeyStream(out, data)
+		g.hashCiphertext(out)
+	}
+
+	g.ctLen += uint64(len(data))
+
+	return out, nil
+}
+
+// final returns the tag, when encrypting.  When decrypting, it checks the tag, and returns an error if it
+// doesn't match.
+func (g *gcmStream) final(tag []byte) ([]byte, []byte, error) {
+	if len(g.partial) > 0 {
+		g.y = g.ghash(g.y, g.partial)
+		g.partial = nil
+	}
+
+	y := g.ghashLengths(g.y, g.aadLen, g.ctLen)
+
+	var s, full [16]byte
+
+	y.putBytes(s[:])
+	g.block.Encrypt(full[:], g.j0[:])
+	subtle.XORBytes(full[:], full[:], s[:])
+
+	if !g.decrypt {
+		return nil, full[:g.tagSize], nil
+	}
+
+	if !validGCMTagSize(len(tag)) {
+		return nil, nil, invalidFieldErrorf("invalid GCM tag length: %d bytes", len(tag))
+	}
+
+	if subtle.ConstantTimeCompare(full[:len(tag)], tag) != 1 {
+		return nil, nil, cryptographicFailuref("decryption failed: the data or tag is invalid")
+	}
+
+	return nil, nil, nil
+}
+
+// xorKeyStream encrypts or decrypts src into dst with the GCTR function, which, unlike Go's CTR mode, only
+// increments the rightmost 32 bits of the counter block.
+func (g *gcmStream) xorKeyStream(dst, src []byte) {
+	for len(src) > 0 {
+		if g.keyStreamUsed == 16 {
+			g.block.Encrypt(g.keyStream[:], g.counter[:])
+			gcmInc32(&g.counter)
+			g.keyStreamUsed = 0
+		}
+
+		n := subtle.XORBytes(dst, src, g.keyStream[g.keyStreamUsed:])
+		g.keyStreamUsed += n
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+// hashCiphertext adds the ciphertext to the GHASH, holding back any part of a block until the block is filled, or
+// the stream is finalized.
+func (g *gcmStream) hashCiphertext(ct []byte) {
+	if len(g.partial) > 0 {
+		n := min(16-len(g.partial), len(ct))
+		g.partial = append(g.partial, ct[:n]...)
+		ct = ct[n:]
+
+		if len(g.partial) < 16 {
+			return
+		}
+
+		g.y = g.ghash(g.y, g.partial)
+		g.partial = g.partial[:0]
+	}
+
+	whole := len(ct) / 16 * 16
+	g.y = g.ghash(g.y, ct[:whole])
+	g.partial = append(g.partial, ct[whole:]...)
+}
+
+// ghash continues the GHASH y with the data, padded with zeros to a whole number of blocks.
+func (g *gcmStream) ghash(y gcmFieldElement, data []byte) gcmFieldElement {
+	for len(data) > 0 {
+		var block [16]byte
+
+		n := copy(block[:], data)
+		data = data[n:]
+
+		y = y.xor(gcmFieldElementFromBytes(block[:])).mul(g.h)
+	}
+
+	return y
+}
+
+// ghashLengths continues the GHASH y with the block holding the bit lengths of the additional data and ciphertext.
+func (g *gcmStream) ghashLengths(y gcmFieldElement, aadLen, ctLen uint64) gcmFieldElement {
+	return y.xor(gcmFieldElement{aadLen * 8, ctLen * 8}).mul(g.h)
+}
+
+func gcmInc32(counter *[16]byte) {
+	binary.BigEndian.PutUint32(counter[12:], binary.BigEndian.Uint32(counter[12:])+1)
+}
+
+// gcmFieldElement is an element of GF(2^128), as a big endian 128 bit block: the coefficient of x^0 is the most
+// significant bit of the first word.
+type gcmFieldElement [2]uint64
+
+func gcmFieldElementFromBytes(b []byte) gcmFieldElement {
+	return gcmFieldElement{binary.BigEndian.Uint64(b), binary.BigEndian.Uint64(b[8:])}
+}
+
+func (x gcmFieldElement) putBytes(b []byte) {
+	binary.BigEndian.PutUint64(b, x[0])
+	binary.BigEndian.PutUint64(b[8:], x[1])
+}
+
+func (x gcmFieldElement) xor(y gcmFieldElement) gcmFieldElement {
+	return gcmFieldElement{x[0] ^ y[0], x[1] ^ y[1]}
+}
+
+// mul multiplies two field elements, with algorithm 1 of NIST SP 800-38D 6.3.
+func (x gcmFieldElement) mul(y gcmFieldElement) gcmFieldElement {
+	var z gcmFieldElement
+
+	v := y
+
+	for i := 0; i < 128; i++ {
+		bit := (x[i/64] >> (63 - uint(i%64))) & 1
+		mask := -bit
+		z[0] ^= v[0] & mask
+		z[1] ^= v[1] & mask
+
+		// v = v * x: a right shift, reduced by R = 11100001 || 0^120 if a bit was shifted out
+		lsb := v[1] & 1
+		v[1] = v[1]>>1 | v[0]<<63
+		v[0] = v[0]>>1 ^ (0xe100000000000000 & -lsb)
+	}
+
+	return z
+}
@@ -0,0 +1,99 @@
+package refserver
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGCMStream(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+
+	data := make([]byte, 1000)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+
+	tests := []struct {
+		ivSize, tagSize, dataLen, chunk int
+		aad                             []byte
+	}{
+		{ivSize: 12, tagSize: 16, dataLen: 0, chunk: 1},
+		{ivSize: 12, tagSize: 16, dataLen: 64, chunk: 16, aad: []byte("header")},
+		{ivSize: 12, tagSize: 12, dataLen: 1000, chunk: 7},
+		{ivSize: 16, tagSize: 16, dataLen: 1000, chunk: 33, aad: make([]byte, 40)},
+		{ivSize: 8, tagSize: 16, dataLen: 17, chunk: 1000},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("iv %d tag %d data %d chunk %d", test.ivSize, test.tagSize, test.dataLen, test.chunk), func(t *testing.T) {
+			iv := make([]byte, test.ivSize)
+			_, err := rand.Read(iv)
+			require.NoError(t, err)
+
+			var aead cipher.AEAD
+			if test.ivSize == 12 {
+				aead, err = cipher.NewGCMWithTagSize(block, test.tagSize)
+			} else {
+				aead, err = cipher.NewGCMWithNonceSize(block, test.ivSize)
+			}
+
+			require.NoError(t, err)
+
+			plaintext := data[:test.dataLen]
+			sealed := aead.Seal(nil, iv, plaintext, test.aad)
+			wantCiphertext, wantTag := sealed[:test.dataLen], sealed[test.dataLen:]
+
+			crypt := func(g *gcmStream, in, tag []byte) ([]byte, []byte, error) {
+				var out []byte
+
+				for len(in) > 0 {
+					n := min(test.chunk, len(in))
+
+					part, err := g.update(in[:n])
+					require.NoError(t, err)
+
+					out = append(out, part...)
+					in = in[n:]
+				}
+
+				_, tag, err := g.final(tag)
+
+				return out, tag, err
+			}
+
+			enc, err := newGCMStream(block, iv, test.aad, test.tagSize, false)
+			require.NoError(t, err)
+
+			ciphertext, tag, err := crypt(enc, plaintext, nil)
+			require.NoError(t, err)
+			assert.Equal(t, string(wantCiphertext), string(ciphertext))
+			assert.Equal(t, wantTag, tag)
+
+			dec, err := newGCMStream(block, iv, test.aad, 16, true)
+			require.NoError(t, err)
+
+			decrypted, _, err := crypt(dec, ciphertext, tag)
+			require.NoError(t, err)
+			assert.Equal(t, string(plaintext), string(decrypted))
+
+			badTag := append([]byte(nil), tag...)
+			badTag[0] ^= 1
+
+			dec, err = newGCMStream(block, iv, test.aad, 16, true)
+			require.NoError(t, err)
+
+			_, _, err = crypt(dec, ciphertext, badTag)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package refserver_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/refserver"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// These tests run against the KMIP server at KMIP_SERVER_ADDR, e.g. the pykmip server started by
+// `make up`.  If KMIP_SERVER_ADDR isn't set, they run against an in-process reference server.
+
+var (
+	testServerOnce sync.Once
+	testServerAddr string
+	testServerErr  error
+)
+
+// kmipServerAddr returns the address of the test kmip server, starting the reference server if necessary.
+func kmipServerAddr(t *testing.T) string {
+	t.Helper()
+
+	if addr := os.Getenv("KMIP_SERVER_ADDR"); addr != "" {
+		return addr
+	}
+
+	testServerOnce.Do(func() {
+		var cert tls.Certificate
+
+		cert, testServerErr = tls.LoadX509KeyPair("../pykmip-server/server.cert", "../pykmip-server/server.key")
+		if testServerErr != nil {
+			return
+		}
+
+		var l net.Listener
+
+		l, testServerErr = net.Listen("tcp", "127.0.0.1:0")
+		if testServerErr != nil {
+			return
+		}
+
+		testServerAddr = l.Addr().String()
+
+		go func() {
+			_ = refserver.New(nil).Serve(l, &tls.Config{Certificates: []tls.Certificate{cert}}) //nolint:gosec
+		}()
+	})
+
+	require.NoError(t, testServerErr)
+
+	return testServerAddr
+}
+
+// clientConn returns a connection to the test kmip server.  Should be closed at end of test.
+func clientConn(t *testing.T) *tls.Conn {
+	t.Helper()
+
+	addr := kmipServerAddr(t)
+
+	cert, err := tls.LoadX509KeyPair("../pykmip-server/server.cert", "../pykmip-server/server.key")
+	require.NoError(t, err)
+
+	// the containerized pykmip we're using requires a very specific cipher suite, which isn't
+	// enabled by go by default.
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       []tls.Certificate{cert},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+		},
+	}
+
+	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	require.NoError(t, err)
+
+	return conn
+}
+
+func TestCreateKey(t *testing.T) {
+	conn := clientConn(t)
+	defer conn.Close()
+
+	biID := uuid.New()
+
+	payload := kmip.CreateRequestPayload{
+		ObjectType: kmip14.ObjectTypeSymmetricKey,
+	}
+
+	payload.TemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES)
+	payload.TemplateAttribute.Append(kmip14.TagCryptographicLength, 256)
+	payload.TemplateAttribute.Append(kmip14.TagCryptographicUsageMask, kmip14.CryptographicUsageMaskEncrypt|kmip14.CryptographicUsageMaskDecrypt)
+	payload.TemplateAttribute.Append(kmip14.TagName, kmip.Name{
+		NameValue: "Key1",
+		NameType:  kmip14.NameTypeUninterpretedTextString,
+	})
+
+	msg := kmip.RequestMessage{
+		RequestHeader: kmip.RequestHeader{
+			ProtocolVersion: kmip.ProtocolVersion{
+				ProtocolVersionMajor: 1,
+				ProtocolVersionMinor: 4,
+			},
+			BatchCount: 1,
+		},
+		BatchItem: []kmip.RequestBatchItem{
+			{
+				UniqueBatchItemID: biID[:],
+				Operation:         kmip14.OperationCreate,
+				RequestPayload:    &payload,
+			},
+		},
+	}
+
+	req, err := ttlv.Marshal(msg)
+	require.NoError(t, err)
+
+	t.Log(req)
+
+	_, err = conn.Write(req)
+	require.NoError(t, err)
+
+	decoder := ttlv.NewDecoder(bufio.NewReader(conn))
+	resp, err := decoder.NextTTLV()
+	require.NoError(t, err)
+
+	t.Log(resp)
+
+	var respMsg kmip.ResponseMessage
+	err = decoder.DecodeValue(&respMsg, resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, respMsg.ResponseHeader.BatchCount)
+	assert.Len(t, respMsg.BatchItem, 1)
+	bi := respMsg.BatchItem[0]
+	assert.Equal(t, kmip14.OperationCreate, bi.Operation)
+	assert.NotEmpty(t, bi.UniqueBatchItemID)
+	assert.Equal(t, kmip14.ResultStatusSuccess, bi.ResultStatus)
+
+	var respPayload kmip.CreateResponsePayload
+	err = decoder.DecodeValue(&respPayload, bi.ResponsePayload.(ttlv.TTLV))
+	require.NoError(t, err)
+
+	assert.Equal(t, kmip14.ObjectTypeSymmetricKey, respPayload.ObjectType)
+	assert.NotEmpty(t, respPayload.UniqueIdentifier)
+}
+
+func TestCreateKeyPair(t *testing.T) {
+	conn := clientConn(t)
+	defer conn.Close()
+
+	biID := uuid.New()
+
+	payload := kmip.CreateKeyPairRequestPayload{}
+	payload.CommonTemplateAttribute = &kmip.TemplateAttribute{}
+	payload.CommonTemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmRSA)
+	payload.CommonTemplateAttribute.Append(kmip14.TagCryptographicLength, 1024)
+	payload.CommonTemplateAttribute.Append(kmip14.TagCryptographicUsageMask, kmip14.CryptographicUsageMaskSign|kmip14.CryptographicUsageMaskVerify)
+
+	msg := kmip.RequestMessage{
+		RequestHeader: kmip.RequestHeader{
+			ProtocolVersion: kmip.ProtocolVersion{
+				ProtocolVersionMajor: 1,
+				ProtocolVersionMinor: 4,
+			},
+			BatchCount: 1,
+		},
+		BatchItem: []kmip.RequestBatchItem{
+			{
+				UniqueBatchItemID: biID[:],
+				Operation:         kmip14.OperationCreateKeyPair,
+				RequestPayload:    &payload,
+			},
+		},
+	}
+
+	req, err := ttlv.Marshal(msg)
+	require.NoError(t, err)
+
+	t.Log(req)
+
+	_, err = conn.Write(req)
+	require.NoError(t, err)
+
+	decoder := ttlv.NewDecoder(bufio.NewReader(conn))
+	resp, err := decoder.NextTTLV()
+	require.NoError(t, err)
+
+	t.Log(resp)
+
+	var respMsg kmip.ResponseMessage
+	err = decoder.DecodeValue(&respMsg, resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, respMsg.ResponseHeader.BatchCount)
+	assert.Len(t, respMsg.BatchItem, 1)
+	bi := respMsg.BatchItem[0]
+	assert.Equal(t, kmip14.OperationCreateKeyPair, bi.Operation)
+	assert.NotEmpty(t, bi.UniqueBatchItemID)
+	assert.Equal(t, kmip14.ResultStatusSuccess, bi.ResultStatus)
+
+	var respPayload kmip.CreateKeyPairResponsePayload
+	err = decoder.DecodeValue(&respPayload, bi.ResponsePayload.(ttlv.TTLV))
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, respPayload.PrivateKeyUniqueIdentifier)
+	assert.NotEmpty(t, respPayload.PublicKeyUniqueIdentifier)
+}
+
+func TestRequest(t *testing.T) {
+	conn := clientConn(t)
+	defer conn.Close()
+
+	biID := uuid.New()
+
+	msg := kmip.RequestMessage{
+		RequestHeader: kmip.RequestHeader{
+			ProtocolVersion: kmip.ProtocolVersion{
+				ProtocolVersionMajor: 1,
+				ProtocolVersionMinor: 2,
+			},
+			BatchCount: 1,
+		},
+		BatchItem: []kmip.RequestBatchItem{
+			{
+				UniqueBatchItemID: biID[:],
+				Operation:         kmip14.OperationDiscoverVersions,
+				RequestPayload: kmip.DiscoverVersionsRequestPayload{
+					ProtocolVersion: []kmip.ProtocolVersion{
+						{ProtocolVersionMajor: 1, ProtocolVersionMinor: 2},
+					},
+				},
+			},
+		},
+	}
+
+	req, err := ttlv.Marshal(msg)
+	require.NoError(t, err)
+
+	t.Log(req)
+
+	_, err = conn.Write(req)
+	require.NoError(t, err)
+
+	decoder := ttlv.NewDecoder(bufio.NewReader(conn))
+	resp, err := decoder.NextTTLV()
+	require.NoError(t, err)
+
+	t.Log(resp)
+
+	var respMsg kmip.ResponseMessage
+	err = decoder.DecodeValue(&respMsg, resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, respMsg.ResponseHeader.BatchCount)
+	assert.Len(t, respMsg.BatchItem, 1)
+	bi := respMsg.BatchItem[0]
+	assert.Equal(t, kmip14.OperationDiscoverVersions, bi.Operation)
+	assert.NotEmpty(t, bi.UniqueBatchItemID)
+	assert.Equal(t, kmip14.ResultStatusSuccess, bi.ResultStatus)
+
+	var discVerRespPayload struct {
+		ProtocolVersion kmip.ProtocolVersion
+	}
+	err = decoder.DecodeValue(&discVerRespPayload, bi.ResponsePayload.(ttlv.TTLV))
+	require.NoError(t, err)
+	assert.Equal(t, kmip.ProtocolVersion{
+		ProtocolVersionMajor: 1,
+		ProtocolVersionMinor: 2,
+	}, discVerRespPayload.ProtocolVersion)
+}
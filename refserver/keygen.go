@@ -0,0 +1,288 @@
+package refserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"math/bits"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/kmip20"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// MaxRSAKeyLength is the largest RSA modulus, in bits, which GenerateKeyPair generates.
+const MaxRSAKeyLength = 8192
+
+// MaxHMACKeyLength is the longest HMAC key, in bits, which GenerateSymmetricKey generates.
+const MaxHMACKeyLength = 1024 * 8
+
+// cryptographicAlgorithmEd25519 is the Ed25519 Cryptographic Algorithm, added in 2.0.
+const cryptographicAlgorithmEd25519 = kmip14.CryptographicAlgorithm(kmip20.CryptographicAlgorithmEd25519)
+
+// GenerateSymmetricKey generates random key material for a Create request.  The request must specify the
+// Cryptographic Algorithm and Cryptographic Length.  The supported algorithms and lengths are:
+//
+//   - AES: 128, 192 or 256 bits
+//   - 3DES: 112 bits, for two key 3DES, or 168 bits, for three key 3DES.  The key material is always 24
+//     bytes, with odd parity; a two key 3DES key repeats the first key as the third.
+//   - HMAC, with any of the SHA-1, SHA-2 or SHA-3 hashes, or MD5: any multiple of 8 bits, up to MaxHMACKeyLength
+//   - ChaCha20 and ChaCha20-Poly1305: 256 bits
+//
+// The key is returned in Raw format.
+func GenerateSymmetricKey(_ context.Context, payload *kmip.CreateRequestPayload) (*kmip.SymmetricKey, error) {
+	var alg kmip14.CryptographicAlgorithm
+	if err := templateAttributeValue(kmip14.TagCryptographicAlgorithm, &alg, &payload.TemplateAttribute); err != nil {
+		return nil, err
+	}
+
+	var length int
+	if err := templateAttributeValue(kmip14.TagCryptographicLength, &length, &payload.TemplateAttribute); err != nil {
+		return nil, err
+	}
+
+	size := length / 8
+
+	switch alg {
+	case kmip14.CryptographicAlgorithmAES:
+		if length != 128 && length != 192 && length != 256 {
+			return nil, invalidFieldErrorf("invalid Cryptographic Length for AES: %d", length)
+		}
+	case kmip14.CryptographicAlgorithmDES3:
+		if length != 112 && length != 168 {
+			return nil, invalidFieldErrorf("invalid Cryptographic Length for 3DES: %d", length)
+		}
+
+		size = 24
+	case kmip14.CryptographicAlgorithmHMAC_SHA1, kmip14.CryptographicAlgorithmHMAC_SHA224,
+		kmip14.CryptographicAlgorithmHMAC_SHA256, kmip14.CryptographicAlgorithmHMAC_SHA384,
+		kmip14.CryptographicAlgorithmHMAC_SHA512, kmip14.CryptographicAlgorithmHMAC_MD5,
+		kmip14.CryptographicAlgorithmHMAC_SHA3_224, kmip14.CryptographicAlgorithmHMAC_SHA3_256,
+		kmip14.CryptographicAlgorithmHMAC_SHA3_384, kmip14.CryptographicAlgorithmHMAC_SHA3_512:
+		if length <= 0 || length%8 != 0 || length > MaxHMACKeyLength {
+			return nil, invalidFieldErrorf("invalid Cryptographic Length for HMAC: %d", length)
+		}
+	case kmip14.CryptographicAlgorithmChaCha20, kmip14.CryptographicAlgorithmChaCha20Poly1305:
+		if length != 256 {
+			return nil, invalidFieldErrorf("invalid Cryptographic Length for %s: %d", alg.String(), length)
+		}
+	default:
+		return nil, invalidFieldErrorf("unsupported Cryptographic Algorithm for symmetric keys: %s", alg.String())
+	}
+
+	material := make([]byte, size)
+	if _, err := rand.Read(material); err != nil {
+		return nil, merry.Prepend(err, "generating key material")
+	}
+
+	if alg == kmip14.CryptographicAlgorithmDES3 {
+		if length == 112 {
+			copy(material[16:], material[:8])
+		}
+
+		setDESParity(material)
+	}
+
+	return &kmip.SymmetricKey{KeyBlock: keyBlock(alg, length, kmip14.KeyFormatTypeRaw, material)}, nil
+}
+
+// setDESParity sets the low bit of each byte of a DES key, so that each byte has odd parity.
+func setDESParity(key []byte) {
+	for i, b := range key {
+		b &^= 1
+		if bits.OnesCount8(b)%2 == 0 {
+			b |= 1
+		}
+
+		key[i] = b
+	}
+}
+
+// GenerateKeyPair generates a key pair for a Create Key Pair request.  The request must specify the Cryptographic
+// Algorithm, either in the common attributes or in the private key attributes.  The supported algorithms are:
+//
+//   - RSA: the Cryptographic Length is the modulus size, at least 1024 bits, and at most MaxRSAKeyLength.  The
+//     keys are returned in PKCS#1 format.
+//   - ECDSA, ECDH and EC: the curve is the Recommended Curve of the Cryptographic Domain Parameters, or,
+//     if that's omitted, the NIST P-curve with the Cryptographic Length.  P-224, P-256, P-384 and P-521 are
+//     supported.  The private key is returned in PKCS#8 format, and the public key in X.509 format.
+//   - Ed25519: the private key is returned in PKCS#8 format, and the public key in X.509 format.
+func GenerateKeyPair(_ context.Context, payload *kmip.CreateKeyPairRequestPayload) (*kmip.PrivateKey, *kmip.PublicKey, error) {
+	tas := []*kmip.TemplateAttribute{payload.PrivateKeyTemplateAttribute, payload.CommonTemplateAttribute}
+
+	var alg kmip14.CryptographicAlgorithm
+	if err := templateAttributeValue(kmip14.TagCryptographicAlgorithm, &alg, tas...); err != nil {
+		return nil, nil, err
+	}
+
+	switch alg {
+	case kmip14.CryptographicAlgorithmRSA:
+		return generateRSAKeyPair(tas)
+	case kmip14.CryptographicAlgorithmECDSA, kmip14.CryptographicAlgorithmECDH, kmip14.CryptographicAlgorithmEC:
+		return generateECKeyPair(alg, tas)
+	case cryptographicAlgorithmEd25519:
+		return generateEd25519KeyPair()
+	}
+
+	return nil, nil, invalidFieldErrorf("unsupported Cryptographic Algorithm for key pairs: %s", alg.String())
+}
+
+func generateRSAKeyPair(tas []*kmip.TemplateAttribute) (*kmip.PrivateKey, *kmip.PublicKey, error) {
+	var length int
+	if err := templateAttributeValue(kmip14.TagCryptographicLength, &length, tas...); err != nil {
+		return nil, nil, err
+	}
+
+	if length < 1024 || length%8 != 0 || length > MaxRSAKeyLength {
+		return nil, nil, invalidFieldErrorf("invalid Cryptographic Length for RSA: %d", length)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, length)
+	if err != nil {
+		return nil, nil, merry.Prepend(err, "generating RSA key")
+	}
+
+	return &kmip.PrivateKey{KeyBlock: keyBlock(kmip14.CryptographicAlgorithmRSA, length, kmip14.KeyFormatTypePKCS_1, x509.MarshalPKCS1PrivateKey(key))},
+		&kmip.PublicKey{KeyBlock: keyBlock(kmip14.CryptographicAlgorithmRSA, length, kmip14.KeyFormatTypePKCS_1, x509.MarshalPKCS1PublicKey(&key.PublicKey))},
+		nil
+}
+
+// curves are the elliptic curves supported by GenerateKeyPair.
+var curves = map[kmip14.RecommendedCurve]elliptic.Curve{
+	kmip14.RecommendedCurveP_224: elliptic.P224(),
+	kmip14.RecommendedCurveP_256: elliptic.P256(),
+	kmip14.RecommendedCurveP_384: elliptic.P384(),
+	kmip14.RecommendedCurveP_521: elliptic.P521(),
+}
+
+func generateECKeyPair(alg kmip14.CryptographicAlgorithm, tas []*kmip.TemplateAttribute) (*kmip.PrivateKey, *kmip.PublicKey, error) {
+	curve, err := templateCurve(tas)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, nil, merry.Prepend(err, "generating EC key")
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, merry.Prepend(err, "encoding EC private key")
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, nil, merry.Prepend(err, "encoding EC public key")
+	}
+
+	length := curve.Params().BitSize
+
+	return &kmip.PrivateKey{KeyBlock: keyBlock(alg, length, kmip14.KeyFormatTypePKCS_8, privDER)},
+		&kmip.PublicKey{KeyBlock: keyBlock(alg, length, kmip14.KeyFormatTypeX_509, pubDER)},
+		nil
+}
+
+// templateCurve returns the curve named by the Recommended Curve of the Cryptographic Domain Parameters, or the
+// P-curve with the Cryptographic Length.
+func templateCurve(tas []*kmip.TemplateAttribute) (elliptic.Curve, error) {
+	var params kmip.CryptographicDomainParameters
+	if hasTemplateAttribute(kmip14.TagCryptographicDomainParameters, tas...) {
+		if err := templateAttributeValue(kmip14.TagCryptographicDomainParameters, &params, tas...); err != nil {
+			return nil, err
+		}
+	}
+
+	if params.RecommendedCurve != 0 {
+		curve, ok := curves[params.RecommendedCurve]
+		if !ok {
+			return nil, invalidFieldErrorf("unsupported Recommended Curve: %s", params.RecommendedCurve.String())
+		}
+
+		return curve, nil
+	}
+
+	var length int
+	if err := templateAttributeValue(kmip14.TagCryptographicLength, &length, tas...); err != nil {
+		return nil, invalidFieldErrorf("Cryptographic Domain Parameters with a Recommended Curve, or a Cryptographic Length, is required")
+	}
+
+	for _, curve := range curves {
+		if curve.Params().BitSize == length {
+			return curve, nil
+		}
+	}
+
+	return nil, invalidFieldErrorf("invalid Cryptographic Length for EC: %d", length)
+}
+
+func generateEd25519KeyPair() (*kmip.PrivateKey, *kmip.PublicKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, merry.Prepend(err, "generating Ed25519 key")
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, merry.Prepend(err, "encoding Ed25519 private key")
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, nil, merry.Prepend(err, "encoding Ed25519 public key")
+	}
+
+	return &kmip.PrivateKey{KeyBlock: keyBlock(cryptographicAlgorithmEd25519, 256, kmip14.KeyFormatTypePKCS_8, privDER)},
+		&kmip.PublicKey{KeyBlock: keyBlock(cryptographicAlgorithmEd25519, 256, kmip14.KeyFormatTypeX_509, pubDER)},
+		nil
+}
+
+func keyBlock(alg kmip14.CryptographicAlgorithm, length int, format kmip14.KeyFormatType, material []byte) kmip.KeyBlock {
+	return kmip.KeyBlock{
+		KeyFormatType:          format,
+		KeyValue:               &kmip.KeyValue{KeyMaterial: material},
+		CryptographicAlgorithm: alg,
+		CryptographicLength:    length,
+	}
+}
+
+// hasTemplateAttribute returns true if any of the template attributes has the attribute.
+func hasTemplateAttribute(tag ttlv.Tag, tas ...*kmip.TemplateAttribute) bool {
+	for _, ta := range tas {
+		if ta != nil && ta.GetTag(tag) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// templateAttributeValue decodes the value of the first attribute matching tag into v.  The template
+// attributes are searched in order, skipping nils.  It's an error if no template attribute has the attribute.
+func templateAttributeValue(tag ttlv.Tag, v interface{}, tas ...*kmip.TemplateAttribute) error {
+	for _, ta := range tas {
+		if ta == nil {
+			continue
+		}
+
+		if attr := ta.GetTag(tag); attr != nil {
+			if err := kmip.DecodeAttributeValue(attr.AttributeValue, v); err != nil {
+				return invalidFieldErrorf("invalid %s: %v", tag.CanonicalName(), err)
+			}
+
+			return nil
+		}
+	}
+
+	return invalidFieldErrorf("%s is required", tag.CanonicalName())
+}
+
+func invalidFieldErrorf(format string, args ...interface{}) error {
+	return kmip.WithResultReason(merry.UserErrorf(format, args...), kmip14.ResultReasonInvalidField)
+}
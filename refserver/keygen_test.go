@@ -0,0 +1,171 @@
+package refserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"math/bits"
+	"testing"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSymmetricKey(t *testing.T) {
+	tests := []struct {
+		alg    kmip14.CryptographicAlgorithm
+		length int
+		size   int
+	}{
+		{alg: kmip14.CryptographicAlgorithmAES, length: 128, size: 16},
+		{alg: kmip14.CryptographicAlgorithmAES, length: 256, size: 32},
+		{alg: kmip14.CryptographicAlgorithmDES3, length: 112, size: 24},
+		{alg: kmip14.CryptographicAlgorithmDES3, length: 168, size: 24},
+		{alg: kmip14.CryptographicAlgorithmHMAC_SHA256, length: 512, size: 64},
+		{alg: kmip14.CryptographicAlgorithmChaCha20, length: 256, size: 32},
+		{alg: kmip14.CryptographicAlgorithmAES, length: 64},
+		{alg: kmip14.CryptographicAlgorithmDES3, length: 192},
+		{alg: kmip14.CryptographicAlgorithmChaCha20, length: 128},
+		{alg: kmip14.CryptographicAlgorithmHMAC_SHA256, length: MaxHMACKeyLength + 8},
+		{alg: kmip14.CryptographicAlgorithmRSA, length: 2048},
+	}
+
+	for _, test := range tests {
+		t.Run(test.alg.String(), func(t *testing.T) {
+			payload := kmip.CreateRequestPayload{ObjectType: kmip14.ObjectTypeSymmetricKey}
+			payload.TemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, test.alg)
+			payload.TemplateAttribute.Append(kmip14.TagCryptographicLength, test.length)
+
+			key, err := GenerateSymmetricKey(context.Background(), &payload)
+			if test.size == 0 {
+				require.Error(t, err)
+				assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.alg, key.KeyBlock.CryptographicAlgorithm)
+			assert.Equal(t, test.length, key.KeyBlock.CryptographicLength)
+
+			material, ok := key.KeyBlock.KeyValue.KeyMaterial.([]byte)
+			require.True(t, ok)
+			assert.Len(t, material, test.size)
+
+			if test.alg == kmip14.CryptographicAlgorithmDES3 {
+				for _, b := range material {
+					assert.Equal(t, 1, bits.OnesCount8(b)%2, "DES key bytes have odd parity")
+				}
+
+				if test.length == 112 {
+					assert.Equal(t, material[:8], material[16:])
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateKeyPair(t *testing.T) {
+	tests := []struct {
+		name   string
+		attrs  []kmip.Attribute
+		length int
+		check  func(t *testing.T, priv, pub interface{})
+	}{
+		{
+			name: "ECDSA length",
+			attrs: []kmip.Attribute{
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicAlgorithm, 0, kmip14.CryptographicAlgorithmECDSA),
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicLength, 0, 384),
+			},
+			length: 384,
+			check: func(t *testing.T, priv, pub interface{}) {
+				require.IsType(t, &ecdsa.PrivateKey{}, priv)
+				assert.Equal(t, "P-384", priv.(*ecdsa.PrivateKey).Curve.Params().Name)
+				assert.True(t, priv.(*ecdsa.PrivateKey).PublicKey.Equal(pub))
+			},
+		},
+		{
+			name: "ECDH curve",
+			attrs: []kmip.Attribute{
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicAlgorithm, 0, kmip14.CryptographicAlgorithmECDH),
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicDomainParameters, 0, kmip.CryptographicDomainParameters{
+					RecommendedCurve: kmip14.RecommendedCurveP_256,
+				}),
+			},
+			length: 256,
+			check: func(t *testing.T, priv, pub interface{}) {
+				require.IsType(t, &ecdsa.PrivateKey{}, priv)
+				assert.Equal(t, "P-256", priv.(*ecdsa.PrivateKey).Curve.Params().Name)
+				assert.True(t, priv.(*ecdsa.PrivateKey).PublicKey.Equal(pub))
+			},
+		},
+		{
+			name: "Ed25519",
+			attrs: []kmip.Attribute{
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicAlgorithm, 0, cryptographicAlgorithmEd25519),
+			},
+			length: 256,
+			check: func(t *testing.T, priv, pub interface{}) {
+				require.IsType(t, ed25519.PrivateKey{}, priv)
+				assert.True(t, priv.(ed25519.PrivateKey).Public().(ed25519.PublicKey).Equal(pub))
+			},
+		},
+		{
+			name: "unsupported curve",
+			attrs: []kmip.Attribute{
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicAlgorithm, 0, kmip14.CryptographicAlgorithmECDSA),
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicDomainParameters, 0, kmip.CryptographicDomainParameters{
+					RecommendedCurve: kmip14.RecommendedCurveK_163,
+				}),
+			},
+		},
+		{
+			name: "RSA too long",
+			attrs: []kmip.Attribute{
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicAlgorithm, 0, kmip14.CryptographicAlgorithmRSA),
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicLength, 0, MaxRSAKeyLength+8),
+			},
+		},
+		{
+			name: "unsupported algorithm",
+			attrs: []kmip.Attribute{
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicAlgorithm, 0, kmip14.CryptographicAlgorithmAES),
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicLength, 0, 256),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload := kmip.CreateKeyPairRequestPayload{
+				CommonTemplateAttribute: &kmip.TemplateAttribute{Attribute: test.attrs},
+			}
+
+			privKey, pubKey, err := GenerateKeyPair(context.Background(), &payload)
+			if test.check == nil {
+				require.Error(t, err)
+				assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, kmip14.KeyFormatTypePKCS_8, privKey.KeyBlock.KeyFormatType)
+			assert.Equal(t, kmip14.KeyFormatTypeX_509, pubKey.KeyBlock.KeyFormatType)
+			assert.Equal(t, test.length, privKey.KeyBlock.CryptographicLength)
+			assert.Equal(t, test.length, pubKey.KeyBlock.CryptographicLength)
+
+			priv, err := x509.ParsePKCS8PrivateKey(privKey.KeyBlock.KeyValue.KeyMaterial.([]byte))
+			require.NoError(t, err)
+
+			pub, err := x509.ParsePKIXPublicKey(pubKey.KeyBlock.KeyValue.KeyMaterial.([]byte))
+			require.NoError(t, err)
+
+			test.check(t, priv, pub)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package refserver
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// The AES key wrap algorithms: NIST Key Wrap, specified by RFC 3394, and AES Key Wrap with Padding, specified by
+// RFC 5649.  Both are described in NIST SP 800-38F, as KW and KWP.
+
+// kwIV is the default initial value of RFC 3394.
+var kwIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// kwpIVPrefix is the first half of the alternative initial value of RFC 5649.  The second half is the length of
+// the key data.
+var kwpIVPrefix = []byte{0xa6, 0x59, 0x59, 0xa6}
+
+// wrapKW wraps key data, which must be at least two semiblocks of 8 bytes, with RFC 3394.
+func wrapKW(block cipher.Block, data []byte) ([]byte, error) {
+	if len(data) < 16 || len(data)%8 != 0 {
+		return nil, invalidFieldErrorf("NIST Key Wrap requires a multiple of 8 bytes, at least 16 bytes, of key data")
+	}
+
+	return wrapSemiblocks(block, kwIV, data), nil
+}
+
+// unwrapKW unwraps key data wrapped with RFC 3394.
+func unwrapKW(block cipher.Block, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, cryptographicFailuref("invalid wrapped key length")
+	}
+
+	iv, data := unwrapSemiblocks(block, wrapped)
+	if subtle.ConstantTimeCompare(iv, kwIV) != 1 {
+		return nil, cryptographicFailuref("key unwrapping failed")
+	}
+
+	return data, nil
+}
+
+// wrapKWP wraps key data of any length with RFC 5649.
+func wrapKWP(block cipher.Block, data []byte) ([]byte, error) {
+	if len(data) == 0 || uint64(len(data)) > 1<<32-1 {
+		return nil, invalidFieldErrorf("invalid key data length for AES Key Wrap with Padding")
+	}
+
+	iv := make([]byte, 8, 8+len(data)+7)
+	copy(iv, kwpIVPrefix)
+	binary.BigEndian.PutUint32(iv[4:], uint32(len(data)))
+
+	padded := make([]byte, (len(data)+7)/8*8)
+	copy(padded, data)
+
+	if len(padded) == 8 {
+		out := append(iv, padded...)
+		block.Encrypt(out, out)
+
+		return out, nil
+	}
+
+	return wrapSemiblocks(block, iv, padded), nil
+}
+
+// unwrapKWP unwraps key data wrapped with RFC 5649.
+func unwrapKWP(block cipher.Block, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 16 || len(wrapped)%8 != 0 {
+		return nil, cryptographicFailuref("invalid wrapped key length")
+	}
+
+	var iv, padded []byte
+
+	if len(wrapped) == 16 {
+		out := make([]byte, 16)
+		block.Decrypt(out, wrapped)
+		iv, padded = out[:8], out[8:]
+	} else {
+		iv, padded = unwrapSemiblocks(block, wrapped)
+	}
+
+	n := int(binary.BigEndian.Uint32(iv[4:]))
+
+	if subtle.ConstantTimeCompare(iv[:4], kwpIVPrefix) != 1 || n <= len(padded)-8 || n > len(padded) ||
+		!bytes.Equal(padded[n:], make([]byte, len(padded)-n)) {
+		return nil, cryptographicFailuref("key unwrapping failed")
+	}
+
+	return padded[:n], nil
+}
+
+// wrapSemiblocks is the wrapping process W of SP 800-38F, with the initial value iv.
+func wrapSemiblocks(block cipher.Block, iv, data []byte) []byte {
+	n := len(data) / 8
+
+	out := make([]byte, 8+len(data))
+	copy(out, iv)
+	copy(out[8:], data)
+
+	b := make([]byte, 16)
+
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b, out[:8])
+			copy(b[8:], out[i*8:i*8+8])
+			block.Encrypt(b, b)
+
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(b[:8])^uint64(n*j+i))
+			copy(out[i*8:], b[8:])
+		}
+	}
+
+	return out
+}
+
+// unwrapSemiblocks is the unwrapping process W^-1 of SP 800-38F.  It returns the initial value, which the caller
+// must check, and the key data.
+func unwrapSemiblocks(block cipher.Block, wrapped []byte) ([]byte, []byte) {
+	n := len(wrapped)/8 - 1
+
+	out := append([]byte(nil), wrapped...)
+	b := make([]byte, 16)
+
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			binary.BigEndian.PutUint64(b[:8], binary.BigEndian.Uint64(out[:8])^uint64(n*j+i))
+			copy(b[8:], out[i*8:i*8+8])
+			block.Decrypt(b, b)
+
+			copy(out[:8], b[:8])
+			copy(out[i*8:], b[8:])
+		}
+	}
+
+	return out[:8], out[8:]
+}
@@ -0,0 +1,92 @@
+package refserver
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestKeyWrap(t *testing.T) {
+	tests := []struct {
+		name               string
+		kek, data, wrapped string
+		padding            bool
+	}{
+		// RFC 3394 4.1
+		{name: "KW 128 bit data with 128 bit KEK", kek: "000102030405060708090A0B0C0D0E0F", data: "00112233445566778899AABBCCDDEEFF", wrapped: "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"},
+		// RFC 3394 4.6
+		{name: "KW 256 bit data with 256 bit KEK", kek: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", data: "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F", wrapped: "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21"},
+		// RFC 5649 6
+		{name: "KWP 20 bytes", kek: "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8", data: "c37b7e6492584340bed12207808941155068f738", wrapped: "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a", padding: true},
+		{name: "KWP 7 bytes", kek: "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8", data: "466f7250617369", wrapped: "afbeb0f07dfbf5419200f2ccb50bb24f", padding: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			block, err := aes.NewCipher(unhex(t, test.kek))
+			require.NoError(t, err)
+
+			data, want := unhex(t, test.data), unhex(t, test.wrapped)
+
+			wrapKey, unwrapKey := wrapKW, unwrapKW
+			if test.padding {
+				wrapKey, unwrapKey = wrapKWP, unwrapKWP
+			}
+
+			wrapped, err := wrapKey(block, data)
+			require.NoError(t, err)
+			assert.Equal(t, want, wrapped)
+
+			unwrapped, err := unwrapKey(block, wrapped)
+			require.NoError(t, err)
+			assert.Equal(t, data, unwrapped)
+
+			wrapped[len(wrapped)-1] ^= 1
+			_, err = unwrapKey(block, wrapped)
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("KWP lengths", func(t *testing.T) {
+		block, err := aes.NewCipher(make([]byte, 16))
+		require.NoError(t, err)
+
+		for n := 1; n <= 33; n++ {
+			data := make([]byte, n)
+			for i := range data {
+				data[i] = byte(i + 1)
+			}
+
+			wrapped, err := wrapKWP(block, data)
+			require.NoError(t, err, fmt.Sprint(n))
+			assert.Len(t, wrapped, 8+(n+7)/8*8)
+
+			unwrapped, err := unwrapKWP(block, wrapped)
+			require.NoError(t, err, fmt.Sprint(n))
+			assert.Equal(t, data, unwrapped)
+		}
+	})
+
+	t.Run("KW length", func(t *testing.T) {
+		block, err := aes.NewCipher(make([]byte, 16))
+		require.NoError(t, err)
+
+		_, err = wrapKW(block, make([]byte, 20))
+		require.Error(t, err)
+		_, err = wrapKW(block, make([]byte, 8))
+		require.Error(t, err)
+	})
+}
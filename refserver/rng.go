@@ -0,0 +1,241 @@
+package refserver
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"io"
+	"sync"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// MaxRNGRetrieveLength is the most random data RNG Retrieve returns in one request.
+const MaxRNGRetrieveLength = 1 << 20
+
+// RNG is the server's Random Number Generator, used by RNG Retrieve and RNG Seed.  It isn't used to generate
+// keys, which always come from crypto/rand.
+type RNG interface {
+	// Read fills p with random data.
+	io.Reader
+	// Seed mixes seed data supplied by a client into the RNG.  It returns the number of bytes accepted, which
+	// may be zero if the RNG can't be seeded.
+	Seed(data []byte) (int, error)
+	// Parameters describes the RNG, for Query.
+	Parameters() kmip.RNGParameters
+}
+
+// SystemRNG is the RNG backed by crypto/rand.  It doesn't accept seed data, and its RNG Algorithm is
+// Unspecified, since it depends on the operating system.
+var SystemRNG RNG = systemRNG{}
+
+type systemRNG struct{}
+
+func (systemRNG) Read(p []byte) (int, error) {
+	return rand.Read(p)
+}
+
+func (systemRNG) Seed([]byte) (int, error) {
+	return 0, nil
+}
+
+func (systemRNG) Parameters() kmip.RNGParameters {
+	return kmip.RNGParameters{RNGAlgorithm: kmip14.RNGAlgorithmUnspecified}
+}
+
+// hmacDRBGMaxRequest is the most bytes HMAC_DRBG generates per request, 2^19 bits.
+const hmacDRBGMaxRequest = 1 << 16
+
+// hmacDRBGEntropyLength is the length of the entropy input, enough for the highest security strength, 256
+// bits.  The nonce is half as long.
+const hmacDRBGEntropyLength = 32
+
+// hmacDRBGReseedInterval is the number of requests after which HMAC_DRBG must be reseeded.
+const hmacDRBGReseedInterval = 1 << 48
+
+// HMACDRBG is the HMAC_DRBG defined in NIST SP 800-90A, without prediction resistance.  It is instantiated, and
+// reseeded after the reseed interval, with entropy read from its entropy source.  Seed reseeds it, with the seed
+// data as additional input.  It is safe for concurrent use.
+type HMACDRBG struct {
+	hash    crypto.Hash
+	entropy io.Reader
+
+	mu            sync.Mutex
+	k, v          []byte
+	reseedCounter uint64
+}
+
+// NewHMACDRBG instantiates an HMAC_DRBG with the hash, reading the entropy input and nonce from entropy, or from
+// crypto/rand if entropy is nil.  The personalization string is optional.  The entropy input is 256 bits, and
+// the nonce 128 bits, whatever the hash.
+func NewHMACDRBG(h crypto.Hash, entropy io.Reader, personalization []byte) (*HMACDRBG, error) {
+	if !h.Available() {
+		return nil, merry.Errorf("hash %s is not available", h.String())
+	}
+
+	if entropy == nil {
+		entropy = rand.Reader
+	}
+
+	d := &HMACDRBG{
+		hash:    h,
+		entropy: entropy,
+		k:       make([]byte, h.Size()),
+		v:       make([]byte, h.Size()),
+	}
+
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+
+	seed := make([]byte, hmacDRBGEntropyLength*3/2)
+	if _, err := io.ReadFull(entropy, seed); err != nil {
+		return nil, merry.Prepend(err, "reading entropy")
+	}
+
+	d.update(seed, personalization)
+	d.reseedCounter = 1
+
+	return d, nil
+}
+
+// update is the HMAC_DRBG Update function, with the provided data in parts.
+func (d *HMACDRBG) update(data ...[]byte) {
+	provided := false
+
+	for _, b := range data {
+		provided = provided || len(b) > 0
+	}
+
+	for _, sep := range []byte{0x00, 0x01} {
+		if sep == 0x01 && !provided {
+			return
+		}
+
+		mac := hmac.New(d.hash.New, d.k)
+		mac.Write(d.v)
+		mac.Write([]byte{sep})
+
+		for _, b := range data {
+			mac.Write(b)
+		}
+
+		d.k = mac.Sum(d.k[:0])
+
+		mac = hmac.New(d.hash.New, d.k)
+		mac.Write(d.v)
+		d.v = mac.Sum(d.v[:0])
+	}
+}
+
+// reseed reseeds the DRBG with fresh entropy and the additional input.
+func (d *HMACDRBG) reseed(additional []byte) error {
+	entropy := make([]byte, hmacDRBGEntropyLength)
+	if _, err := io.ReadFull(d.entropy, entropy); err != nil {
+		return merry.Prepend(err, "reading entropy")
+	}
+
+	d.update(entropy, additional)
+	d.reseedCounter = 1
+
+	return nil
+}
+
+// generate fills p, which must be at most hmacDRBGMaxRequest bytes, as one HMAC_DRBG generate request.
+func (d *HMACDRBG) generate(p, additional []byte) error {
+	if d.reseedCounter > hmacDRBGReseedInterval {
+		if err := d.reseed(additional); err != nil {
+			return err
+		}
+
+		additional = nil
+	}
+
+	if len(additional) > 0 {
+		d.update(additional)
+	}
+
+	mac := hmac.New(d.hash.New, d.k)
+
+	for n := 0; n < len(p); {
+		mac.Reset()
+		mac.Write(d.v)
+		d.v = mac.Sum(d.v[:0])
+		n += copy(p[n:], d.v)
+	}
+
+	d.update(additional)
+	d.reseedCounter++
+
+	return nil
+}
+
+// Read implements io.Reader, generating p in requests of up to 2^19 bits.
+func (d *HMACDRBG) Read(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for n := 0; n < len(p); n += hmacDRBGMaxRequest {
+		if err := d.generate(p[n:min(n+hmacDRBGMaxRequest, len(p))], nil); err != nil {
+			return n, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Seed reseeds the DRBG with fresh entropy, and the seed data as additional input.  All the seed data is
+// accepted.
+func (d *HMACDRBG) Seed(data []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.reseed(data); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+// Parameters implements RNG.
+func (d *HMACDRBG) Parameters() kmip.RNGParameters {
+	params := kmip.RNGParameters{
+		RNGAlgorithm:  kmip14.RNGAlgorithmDRBG,
+		DRBGAlgorithm: kmip14.DRBGAlgorithmHMAC,
+	}
+
+	for alg, h := range hashes {
+		if h == d.hash {
+			params.HashingAlgorithm = alg
+		}
+	}
+
+	return params
+}
+
+// rngRetrieve implements RNG Retrieve, for all protocol versions.
+func (s *Server) rngRetrieve(_ context.Context, payload *kmip.RNGRetrieveRequestPayload) (*kmip.RNGRetrieveResponsePayload, error) {
+	if payload.DataLength <= 0 || payload.DataLength > MaxRNGRetrieveLength {
+		return nil, invalidFieldErrorf("the Data Length must be between 1 and %d", MaxRNGRetrieveLength)
+	}
+
+	data := make([]byte, payload.DataLength)
+	if _, err := io.ReadFull(s.rng(), data); err != nil {
+		return nil, merry.Prepend(err, "reading RNG")
+	}
+
+	return &kmip.RNGRetrieveResponsePayload{Data: data}, nil
+}
+
+// rngSeed implements RNG Seed, for all protocol versions.
+func (s *Server) rngSeed(_ context.Context, payload *kmip.RNGSeedRequestPayload) (*kmip.RNGSeedResponsePayload, error) {
+	n, err := s.rng().Seed(payload.Data)
+	if err != nil {
+		return nil, merry.Prepend(err, "seeding RNG")
+	}
+
+	return &kmip.RNGSeedResponsePayload{DataLength: n}, nil
+}
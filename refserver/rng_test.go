@@ -0,0 +1,71 @@
+package refserver
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"testing"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACDRBG(t *testing.T) {
+	unhex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		require.NoError(t, err)
+
+		return b
+	}
+
+	// NIST CAVP HMAC_DRBG, SHA-256, no prediction resistance, no reseed, COUNT 0: the second of two 1024 bit
+	// generate calls is the returned bits.
+	entropy := unhex("ca851911349384bffe89de1cbdc46e6831e44d34a4fb935ee285dd14b71a7488" + "659ba96c601dc69fc902940805ec0ca8")
+	expected := unhex("e528e9abf2dece54d47c7e75e5fe302149f817ea9fb4bee6f4199697d04d5b89d54fbb978a15b5c443c9ec21036d2460" +
+		"b6f73ebad0dc2aba6e624abf07745bc107694bb7547bb0995f70de25d6b29e2d3011bb19d27676c07162c8b5ccde0668961df86803482cb37" +
+		"ed6d5c0bb8d50cf1f50d476aa0458bdaba806f48be9dcb8")
+
+	d, err := NewHMACDRBG(crypto.SHA256, bytes.NewReader(entropy), nil)
+	require.NoError(t, err)
+
+	out := make([]byte, 128)
+	_, err = d.Read(out)
+	require.NoError(t, err)
+	_, err = d.Read(out)
+	require.NoError(t, err)
+	assert.Equal(t, expected, out)
+
+	assert.Equal(t, kmip.RNGParameters{
+		RNGAlgorithm:     kmip14.RNGAlgorithmDRBG,
+		HashingAlgorithm: kmip14.HashingAlgorithmSHA_256,
+		DRBGAlgorithm:    kmip14.DRBGAlgorithmHMAC,
+	}, d.Parameters())
+
+	// reseeding needs more entropy
+	_, err = d.Seed([]byte("seed"))
+	require.Error(t, err)
+
+	// the same entropy and seeds give the same output, and different seeds different output
+	newDRBG := func(seed string) []byte {
+		t.Helper()
+
+		d, err := NewHMACDRBG(crypto.SHA256, bytes.NewReader(make([]byte, 1024)), []byte("personalization"))
+		require.NoError(t, err)
+
+		n, err := d.Seed([]byte(seed))
+		require.NoError(t, err)
+		assert.Equal(t, len(seed), n)
+
+		// longer than one generate request
+		out := make([]byte, hmacDRBGMaxRequest+10)
+		_, err = d.Read(out)
+		require.NoError(t, err)
+
+		return out
+	}
+
+	assert.Equal(t, newDRBG("a"), newDRBG("a"))
+	assert.NotEqual(t, newDRBG("a"), newDRBG("b"))
+}
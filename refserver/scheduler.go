@@ -0,0 +1,303 @@
+package refserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gemalto/flume"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// scheduledDates are the date attributes watched by the Scheduler.  The Activation Date and Deactivation
+// Date trigger state transitions.  The Protect Stop Date and Process Start Date don't change the State, but
+// are reported to OnDate, so the server can act on them, e.g. by notifying clients.
+var scheduledDates = []ttlv.Tag{
+	kmip14.TagActivationDate,
+	kmip14.TagDeactivationDate,
+	kmip14.TagProtectStopDate,
+	kmip14.TagProcessStartDate,
+}
+
+// The Scheduler retries a failed scan after schedulerMinBackoff, doubling the delay after each consecutive
+// failure, up to schedulerMaxBackoff.
+const (
+	schedulerMinBackoff = time.Second
+	schedulerMaxBackoff = time.Minute
+)
+
+// Scheduler applies the lifecycle transitions triggered by the objects' date attributes when the dates are
+// reached, so objects created with a future Activation Date or Deactivation Date change state on time, and
+// the new State is persisted in the store.  Without the scheduler, the transitions are only applied when an
+// object is used.
+//
+// The scheduler sleeps until the earliest future date held by any object.  Call Wake after objects are
+// created, or their watched dates are changed, so that new dates are picked up.  Stores returned by Watch
+// do this automatically.
+type Scheduler struct {
+	// Store holds the objects.
+	Store kmip.ObjectStore
+	// Lifecycle applies the transitions.  Its Now function should agree with Clock.
+	Lifecycle *kmip.Lifecycle
+	// Clock is the source of time.  If nil, SystemClock is used.
+	Clock Clock
+
+	// OnDate, if set, is called when one of an object's watched dates is reached, with the date's
+	// attribute tag, and the object with the transitions applied.  It's called after the changes
+	// are saved, and isn't called for dates which had already passed when Run started.
+	OnDate func(ctx context.Context, obj *kmip.ManagedObject, tag ttlv.Tag)
+
+	initOnce sync.Once
+	wake     chan struct{}
+}
+
+func (s *Scheduler) init() {
+	s.initOnce.Do(func() {
+		s.wake = make(chan struct{}, 1)
+	})
+}
+
+func (s *Scheduler) clock() Clock {
+	if s.Clock == nil {
+		return SystemClock
+	}
+
+	return s.Clock
+}
+
+// Wake causes a running scheduler to rescan the store.  It doesn't block.
+func (s *Scheduler) Wake() {
+	s.init()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Watch returns a store which wraps store, and wakes the scheduler after each update transaction which
+// creates an object with a watched date, or changes one of an object's watched dates.
+func (s *Scheduler) Watch(store kmip.ObjectStore) kmip.ObjectStore {
+	return &watchedStore{ObjectStore: store, s: s}
+}
+
+type watchedStore struct {
+	kmip.ObjectStore
+	s *Scheduler
+}
+
+func (w *watchedStore) Update(ctx context.Context, fn func(tx kmip.ObjectTx) error) error {
+	var wake bool
+
+	err := w.ObjectStore.Update(ctx, func(tx kmip.ObjectTx) error {
+		wake = false
+		return fn(&watchedTx{ObjectTx: tx, wake: &wake})
+	})
+	if err == nil && wake {
+		w.s.Wake()
+	}
+
+	return err
+}
+
+// watchedTx records whether a transaction changed any watched dates.
+type watchedTx struct {
+	kmip.ObjectTx
+	wake *bool
+}
+
+func (tx *watchedTx) Create(obj *kmip.ManagedObject) (string, error) {
+	id, err := tx.ObjectTx.Create(obj)
+	if err == nil && !*tx.wake {
+		for _, tag := range scheduledDates {
+			if _, ok := scheduledDate(obj, tag); ok {
+				*tx.wake = true
+				break
+			}
+		}
+	}
+
+	return id, err
+}
+
+func (tx *watchedTx) Put(obj *kmip.ManagedObject) error {
+	if !*tx.wake {
+		old, err := tx.ObjectTx.Get(obj.UniqueIdentifier)
+		if err != nil {
+			*tx.wake = true
+		} else {
+			for _, tag := range scheduledDates {
+				oldDate, oldOK := scheduledDate(old, tag)
+				newDate, newOK := scheduledDate(obj, tag)
+
+				if oldOK != newOK || !oldDate.Equal(newDate) {
+					*tx.wake = true
+					break
+				}
+			}
+		}
+	}
+
+	return tx.ObjectTx.Put(obj)
+}
+
+// scheduledDate returns the value of one of an object's date attributes.
+func scheduledDate(obj *kmip.ManagedObject, tag ttlv.Tag) (time.Time, bool) {
+	attr := obj.GetAttributeTag(tag)
+	if attr == nil {
+		return time.Time{}, false
+	}
+
+	var d time.Time
+	if err := kmip.DecodeAttributeValue(attr.AttributeValue, &d); err != nil {
+		return time.Time{}, false
+	}
+
+	return d, true
+}
+
+// Run applies transitions until ctx is canceled, and then returns ctx's error.  If the store returns an
+// error, it's logged to the logger in ctx, and the scan is retried after a delay, which doubles after each
+// consecutive failure, up to a minute.  Wake doesn't cut the delay short.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.init()
+
+	var backoff time.Duration
+
+	last := s.clock().Now()
+
+	for {
+		now := s.clock().Now()
+		wake := s.wake
+
+		next, err := s.run(ctx, last, now)
+
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			// the dates reached since last are reported after the retry succeeds
+			backoff = min(max(2*backoff, schedulerMinBackoff), schedulerMaxBackoff)
+			flume.FromContext(ctx).Error("applying scheduled transitions", "error", err, "retry", backoff)
+
+			next, wake = now.Add(backoff), nil
+		default:
+			backoff = 0
+			last = now
+		}
+
+		var (
+			timer Timer
+			fired <-chan time.Time
+		)
+
+		if !next.IsZero() {
+			timer = s.clock().NewTimer(next.Sub(now))
+			fired = timer.C()
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+
+			return ctx.Err()
+		case <-wake:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-fired:
+		}
+	}
+}
+
+type scheduledEvent struct {
+	obj *kmip.ManagedObject
+	tag ttlv.Tag
+}
+
+// run applies the transitions due at now, and reports the dates reached since last.  It returns the
+// earliest watched date after now, or the zero time if there isn't one.
+//
+// The objects are scanned in a read-only transaction.  Only the objects whose state changes are updated.
+func (s *Scheduler) run(ctx context.Context, last, now time.Time) (time.Time, error) {
+	var (
+		next   time.Time
+		events []scheduledEvent
+		due    []string
+	)
+
+	err := s.Store.View(ctx, func(tx kmip.ObjectTx) error {
+		next = time.Time{}
+		events, due = nil, nil
+
+		return tx.ForEach(func(obj *kmip.ManagedObject) error {
+			switch kmip.ObjectState(obj) {
+			case 0, kmip14.StateDestroyed, kmip14.StateDestroyedCompromised:
+				return nil
+			}
+
+			if s.Lifecycle.Apply(obj) {
+				due = append(due, obj.UniqueIdentifier)
+			}
+
+			for _, tag := range scheduledDates {
+				d, ok := scheduledDate(obj, tag)
+
+				switch {
+				case !ok:
+				case d.After(now):
+					if next.IsZero() || d.Before(next) {
+						next = d
+					}
+				case d.After(last):
+					events = append(events, scheduledEvent{obj: obj, tag: tag})
+				}
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(due) > 0 {
+		err = s.Store.Update(ctx, func(tx kmip.ObjectTx) error {
+			for _, id := range due {
+				// the object may have been changed or deleted since the scan
+				obj, err := tx.Get(id)
+				if errors.Is(err, kmip.ErrObjectNotFound) {
+					continue
+				}
+
+				if err != nil {
+					return err
+				}
+
+				if s.Lifecycle.Apply(obj) {
+					if err := tx.Put(obj); err != nil {
+						return err
+					}
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if s.OnDate != nil {
+		for _, e := range events {
+			s.OnDate(ctx, e.obj, e.tag)
+		}
+	}
+
+	return next, nil
+}
@@ -0,0 +1,424 @@
+package refserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a Clock which only moves when it's advanced.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	when  time.Time
+	c     chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, when: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+	}
+
+	return t
+}
+
+// Advance moves the clock forward, and fires the timers which are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	pending := c.timers[:0]
+
+	for _, t := range c.timers {
+		if t.when.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			t.c <- c.now
+		}
+	}
+
+	c.timers = pending
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// waitForTimer waits until a timer is pending on the clock.
+func (c *fakeClock) waitForTimer(t *testing.T) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		return len(c.timers) > 0
+	}, 5*time.Second, time.Millisecond)
+}
+
+func storedState(t *testing.T, store kmip.ObjectStore, id string) kmip14.State {
+	t.Helper()
+
+	var state kmip14.State
+
+	require.NoError(t, store.View(context.Background(), func(tx kmip.ObjectTx) error {
+		obj, err := tx.Get(id)
+		if err != nil {
+			return err
+		}
+
+		state = kmip.ObjectState(obj)
+
+		return nil
+	}))
+
+	return state
+}
+
+func TestScheduler(t *testing.T) {
+	clock := newFakeClock()
+	store := &kmip.MemoryObjectStore{}
+	lifecycle := &kmip.Lifecycle{Now: clock.Now}
+
+	start := clock.Now()
+
+	obj := &kmip.ManagedObject{}
+	require.NoError(t, obj.SetObject(&kmip.SecretData{
+		SecretDataType: kmip14.SecretDataTypePassword,
+		KeyBlock: kmip.KeyBlock{
+			KeyFormatType: kmip14.KeyFormatTypeOpaque,
+			KeyValue:      &kmip.KeyValue{KeyMaterial: []byte("secret")},
+		},
+	}))
+	obj.SetAttributeTag(kmip14.TagActivationDate, start.Add(time.Hour))
+	obj.SetAttributeTag(kmip14.TagProtectStopDate, start.Add(90*time.Minute))
+	obj.SetAttributeTag(kmip14.TagDeactivationDate, start.Add(2*time.Hour))
+	lifecycle.Init(obj)
+
+	var id string
+
+	require.NoError(t, store.Update(context.Background(), func(tx kmip.ObjectTx) error {
+		var err error
+		id, err = tx.Create(obj)
+
+		return err
+	}))
+
+	dates := make(chan ttlv.Tag, 10)
+
+	s := &Scheduler{
+		Store:     store,
+		Lifecycle: lifecycle,
+		Clock:     clock,
+		OnDate: func(_ context.Context, obj *kmip.ManagedObject, tag ttlv.Tag) {
+			assert.Equal(t, id, obj.UniqueIdentifier)
+			dates <- tag
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	assert.Equal(t, kmip14.StatePreActive, storedState(t, store, id))
+
+	clock.waitForTimer(t)
+	clock.Advance(time.Hour)
+	require.Equal(t, kmip14.TagActivationDate, <-dates)
+	assert.Equal(t, kmip14.StateActive, storedState(t, store, id))
+
+	// the protect stop date doesn't change the state, but it's reported
+	clock.waitForTimer(t)
+	clock.Advance(30 * time.Minute)
+	require.Equal(t, kmip14.TagProtectStopDate, <-dates)
+	assert.Equal(t, kmip14.StateActive, storedState(t, store, id))
+
+	clock.waitForTimer(t)
+	clock.Advance(30 * time.Minute)
+	require.Equal(t, kmip14.TagDeactivationDate, <-dates)
+	assert.Equal(t, kmip14.StateDeactivated, storedState(t, store, id))
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+// failingStore fails update transactions while fail is positive.
+type failingStore struct {
+	kmip.MemoryObjectStore
+
+	mu       sync.Mutex
+	fail     int
+	failures int
+}
+
+func (s *failingStore) Update(ctx context.Context, fn func(tx kmip.ObjectTx) error) error {
+	s.mu.Lock()
+	fail := s.fail > 0
+	if fail {
+		s.fail--
+		s.failures++
+	}
+	s.mu.Unlock()
+
+	if fail {
+		return errors.New("store unavailable")
+	}
+
+	return s.MemoryObjectStore.Update(ctx, fn)
+}
+
+func TestScheduler_retry(t *testing.T) {
+	clock := newFakeClock()
+	store := &failingStore{}
+	lifecycle := &kmip.Lifecycle{Now: clock.Now}
+
+	obj := &kmip.ManagedObject{}
+	require.NoError(t, obj.SetObject(&kmip.SecretData{
+		SecretDataType: kmip14.SecretDataTypePassword,
+		KeyBlock: kmip.KeyBlock{
+			KeyFormatType: kmip14.KeyFormatTypeOpaque,
+			KeyValue:      &kmip.KeyValue{KeyMaterial: []byte("secret")},
+		},
+	}))
+	obj.SetAttributeTag(kmip14.TagActivationDate, clock.Now().Add(time.Hour))
+	lifecycle.Init(obj)
+
+	var id string
+
+	require.NoError(t, store.Update(context.Background(), func(tx kmip.ObjectTx) error {
+		var err error
+		id, err = tx.Create(obj)
+
+		return err
+	}))
+
+	dates := make(chan ttlv.Tag, 10)
+
+	s := &Scheduler{
+		Store:     store,
+		Lifecycle: lifecycle,
+		Clock:     clock,
+		OnDate: func(_ context.Context, _ *kmip.ManagedObject, tag ttlv.Tag) {
+			dates <- tag
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	clock.waitForTimer(t)
+
+	store.mu.Lock()
+	store.fail = 2
+	store.mu.Unlock()
+
+	clock.Advance(time.Hour)
+
+	// the failed updates are retried after 1s, then 2s, and wakes don't cut the delay short
+	clock.waitForTimer(t)
+	s.Wake()
+	clock.Advance(time.Second)
+	clock.waitForTimer(t)
+	clock.Advance(time.Second)
+	assert.Equal(t, kmip14.StatePreActive, storedState(t, store, id))
+	assert.Empty(t, dates)
+
+	clock.Advance(time.Second)
+	require.Equal(t, kmip14.TagActivationDate, <-dates)
+	assert.Equal(t, kmip14.StateActive, storedState(t, store, id))
+
+	store.mu.Lock()
+	assert.Equal(t, 2, store.failures)
+	store.mu.Unlock()
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestScheduler_Watch(t *testing.T) {
+	ctx := context.Background()
+	s := &Scheduler{}
+	store := s.Watch(&kmip.MemoryObjectStore{})
+
+	woken := func() bool {
+		select {
+		case <-s.wake:
+			return true
+		default:
+			return false
+		}
+	}
+
+	newObject := func() *kmip.ManagedObject {
+		obj := &kmip.ManagedObject{}
+		require.NoError(t, obj.SetObject(&kmip.SecretData{
+			SecretDataType: kmip14.SecretDataTypePassword,
+			KeyBlock: kmip.KeyBlock{
+				KeyFormatType: kmip14.KeyFormatTypeOpaque,
+				KeyValue:      &kmip.KeyValue{KeyMaterial: []byte("secret")},
+			},
+		}))
+
+		return obj
+	}
+
+	update := func(fn func(tx kmip.ObjectTx) error) {
+		require.NoError(t, store.Update(ctx, fn))
+	}
+
+	s.init()
+
+	var id string
+
+	update(func(tx kmip.ObjectTx) error {
+		var err error
+		id, err = tx.Create(newObject())
+
+		return err
+	})
+	assert.False(t, woken(), "created without dates")
+
+	update(func(tx kmip.ObjectTx) error {
+		obj := newObject()
+		obj.SetAttributeTag(kmip14.TagActivationDate, time.Now().Add(time.Hour))
+		_, err := tx.Create(obj)
+
+		return err
+	})
+	assert.True(t, woken(), "created with a date")
+
+	update(func(tx kmip.ObjectTx) error {
+		obj, err := tx.Get(id)
+		require.NoError(t, err)
+		obj.SetAttributeTag(kmip14.TagObjectGroup, "group1")
+
+		return tx.Put(obj)
+	})
+	assert.False(t, woken(), "other attribute changed")
+
+	update(func(tx kmip.ObjectTx) error {
+		obj, err := tx.Get(id)
+		require.NoError(t, err)
+		obj.SetAttributeTag(kmip14.TagDeactivationDate, time.Now().Add(time.Hour))
+
+		return tx.Put(obj)
+	})
+	assert.True(t, woken(), "date changed")
+
+	// failed transactions don't wake the scheduler
+	require.Error(t, store.Update(ctx, func(tx kmip.ObjectTx) error {
+		obj := newObject()
+		obj.SetAttributeTag(kmip14.TagActivationDate, time.Now().Add(time.Hour))
+		if _, err := tx.Create(obj); err != nil {
+			return err
+		}
+
+		return errors.New("boom")
+	}))
+	assert.False(t, woken(), "failed transaction")
+}
+
+func TestServer_scheduledActivation(t *testing.T) {
+	clock := newFakeClock()
+
+	srv := New(nil)
+	srv.Clock = clock
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		_ = srv.Serve(l, nil)
+	}()
+
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+
+	client, err := kmip.Dial("tcp", l.Addr().String(), nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	client.ProtocolVersion = kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4}
+
+	var resp kmip.CreateResponsePayload
+
+	require.NoError(t, client.Do(testContext(t), kmip14.OperationCreate, &kmip.CreateRequestPayload{
+		ObjectType: kmip14.ObjectTypeSymmetricKey,
+		TemplateAttribute: kmip.TemplateAttribute{
+			Attribute: []kmip.Attribute{
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicAlgorithm, 0, kmip14.CryptographicAlgorithmAES),
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicLength, 0, 256),
+				kmip.NewAttributeFromTag(kmip14.TagActivationDate, 0, clock.Now().Add(time.Hour)),
+			},
+		},
+	}, &resp))
+
+	assert.Equal(t, kmip14.StatePreActive, storedState(t, srv.Store, resp.UniqueIdentifier))
+
+	// the scheduler is woken by the request, and sets a timer for the activation date
+	clock.waitForTimer(t)
+
+	clock.Advance(time.Hour)
+
+	assert.Eventually(t, func() bool {
+		return storedState(t, srv.Store, resp.UniqueIdentifier) == kmip14.StateActive
+	}, 5*time.Second, time.Millisecond)
+}
@@ -0,0 +1,419 @@
+// Package refserver implements a reference KMIP server, which holds its objects in a kmip.ObjectStore.
+//
+// The server speaks protocol versions 1.0 through 1.4, and 2.0.  It's intended for testing clients
+// and for exercising the server side of this module without an external KMIP server, not for protecting
+// real keys: by default, objects are held in memory, and are lost when the process exits.
+//
+// The server supports Create, Create Key Pair, Re-key, Re-key Key Pair, Derive Key, Certify, Re-certify,
+// Create Split Key, Join Split Key, Register, Get, Get Attributes, Get Attribute List, Add Attribute, Modify
+// Attribute, Delete Attribute, Locate, Activate, Revoke, Destroy, Encrypt, Decrypt, Sign, Signature Verify, MAC,
+// MAC Verify, RNG Retrieve, RNG Seed, Query and Discover Versions, and, for 2.0 requests, Adjust Attribute and
+// Set Attribute.  Other operations can be added by registering handlers with the muxes for each protocol
+// version:
+//
+//	srv := refserver.New(nil)
+//	srv.Mux14.Handle(kmip14.OperationCheck, myCheckHandler)
+//
+// The cryptographic operations may be streamed, see CryptoHandlers.  Get converts keys to the requested Key Format
+// Type, see kmip.ConvertKeyBlock, and returns wrapped keys when the request has a Key Wrapping Specification.
+// Register unwraps wrapped keys, see CryptoHandlers.WrapKey.  Certify and Re-certify issue certificates signed by
+// a CA whose keys are held by the server, see CertificateAuthority.  RNG Retrieve and RNG Seed are served by the
+// server's RNG, see HMACDRBG.
+//
+// Objects change state when their Activation Date or Deactivation Date is reached.  While the server is
+// serving, its Scheduler applies these transitions in the background.  Set Clock to control the server's
+// time in tests.
+package refserver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gemalto/flume"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/kmip20"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+var schedulerLog = flume.New("kmip_scheduler")
+
+// SupportedVersions are the protocol versions the server supports, most preferred first.
+var SupportedVersions = []kmip.ProtocolVersion{
+	{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0},
+	{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4},
+	{ProtocolVersionMajor: 1, ProtocolVersionMinor: 3},
+	{ProtocolVersionMajor: 1, ProtocolVersionMinor: 2},
+	{ProtocolVersionMajor: 1, ProtocolVersionMinor: 1},
+	{ProtocolVersionMajor: 1, ProtocolVersionMinor: 0},
+}
+
+// VendorIdentification is returned by Query, when server information is requested.
+const VendorIdentification = "kmip-go reference server"
+
+// operations are the operations registered by New, and returned by Query.
+var operations = []kmip14.Operation{
+	kmip14.OperationCreate,
+	kmip14.OperationCreateKeyPair,
+	kmip14.OperationReKey,
+	kmip14.OperationReKeyKeyPair,
+	kmip14.OperationDeriveKey,
+	kmip14.OperationCertify,
+	kmip14.OperationReCertify,
+	kmip14.OperationCreateSplitKey,
+	kmip14.OperationJoinSplitKey,
+	kmip14.OperationRegister,
+	kmip14.OperationGet,
+	kmip14.OperationGetAttributes,
+	kmip14.OperationGetAttributeList,
+	kmip14.OperationAddAttribute,
+	kmip14.OperationModifyAttribute,
+	kmip14.OperationDeleteAttribute,
+	kmip14.OperationLocate,
+	kmip14.OperationActivate,
+	kmip14.OperationRevoke,
+	kmip14.OperationDestroy,
+	kmip14.OperationEncrypt,
+	kmip14.OperationDecrypt,
+	kmip14.OperationSign,
+	kmip14.OperationSignatureVerify,
+	kmip14.OperationMAC,
+	kmip14.OperationMACVerify,
+	kmip14.OperationRNGRetrieve,
+	kmip14.OperationRNGSeed,
+	kmip14.OperationQuery,
+	kmip14.OperationDiscoverVersions,
+}
+
+// operations20 are the operations registered by New for 2.0 requests, and returned by Query.
+var operations20 = append(operations[:len(operations):len(operations)],
+	kmip14.Operation(kmip20.OperationAdjustAttribute),
+	kmip14.Operation(kmip20.OperationSetAttribute),
+	kmip14.Operation(kmip20.OperationSetEndpointRole),
+)
+
+// objectTypes are the object types returned by Query.
+var objectTypes = []kmip14.ObjectType{
+	kmip14.ObjectTypeCertificate,
+	kmip14.ObjectTypeSymmetricKey,
+	kmip14.ObjectTypePublicKey,
+	kmip14.ObjectTypePrivateKey,
+	kmip14.ObjectTypeSplitKey,
+	kmip14.ObjectTypeSecretData,
+	kmip14.ObjectTypeOpaqueObject,
+}
+
+// Server is a reference KMIP server.  Use New to create one.
+//
+// Server implements kmip.ProtocolHandler.  Each request is dispatched to the handler for the major
+// protocol version in its header.  Requests with a major version of 1 are handled by Mux14, and requests
+// with a major version of 2 by Mux20.  Responses carry protocol version 1.4 or 2.0, respectively.
+type Server struct {
+	// Store holds the server's objects.
+	Store kmip.ObjectStore
+
+	// Handlers implements the object management operations for 1.x requests.
+	Handlers *kmip.StoreHandlers
+	// Handlers20 implements the object management operations for 2.0 requests.  It shares the Store with
+	// Handlers, but validates attributes with the 2.0 attribute rules.
+	Handlers20 *kmip.StoreHandlers
+	// Crypto implements the cryptographic operations for all protocol versions.
+	Crypto *CryptoHandlers
+	// CA issues the certificates for Certify and Re-certify.  Set its CertificateUniqueIdentifier to enable them.
+	CA *CertificateAuthority
+
+	// Mux14 handles 1.x requests.
+	Mux14 *kmip.OperationMux
+	// Mux20 handles 2.0 requests.
+	Mux20 *kmip.OperationMux
+
+	// RNG serves RNG Retrieve and RNG Seed.  If nil, SystemRNG is used.  Set it to an HMACDRBG for an RNG which
+	// clients can seed.  It must be set before the server handles any requests.
+	RNG RNG
+
+	// Clock is the server's source of time.  If nil, SystemClock is used.  It must be set before the
+	// server handles any requests.
+	Clock Clock
+
+	// Scheduler applies the date triggered lifecycle transitions.  It's started by Serve, and stopped by
+	// Close.  When the server is used as a kmip.ProtocolHandler directly, the caller should run it.
+	Scheduler *Scheduler
+
+	handler14 kmip.ProtocolHandler
+	handler20 kmip.ProtocolHandler
+
+	srv kmip.Server
+
+	schedulerMu   sync.Mutex
+	stopScheduler func()
+	closed        bool
+}
+
+// New returns a Server which stores objects in store.  If store is nil, objects are stored in a
+// kmip.MemoryObjectStore.
+func New(store kmip.ObjectStore) *Server {
+	if store == nil {
+		store = &kmip.MemoryObjectStore{}
+	}
+
+	s := &Server{
+		Store: store,
+		Handlers: &kmip.StoreHandlers{
+			GenerateSymmetricKey: GenerateSymmetricKey,
+			GenerateKeyPair:      GenerateKeyPair,
+			DeriveKeyMaterial:    DeriveKeyMaterial,
+		},
+		Mux14: &kmip.OperationMux{},
+		Mux20: &kmip.OperationMux{ErrorHandler: kmip20.ErrorHandler},
+	}
+
+	s.Handlers.Lifecycle.Now = s.now
+
+	s.Scheduler = &Scheduler{
+		Store:     store,
+		Lifecycle: &s.Handlers.Lifecycle,
+		Clock:     serverClock{s},
+	}
+
+	// the handlers' updates wake the scheduler when they change any dates
+	watched := s.Scheduler.Watch(store)
+	s.Handlers.Store = watched
+
+	s.Crypto = &CryptoHandlers{
+		Store:     watched,
+		Lifecycle: &s.Handlers.Lifecycle,
+	}
+
+	s.Handlers.WrapKey = s.Crypto.WrapKey
+	s.Handlers.UnwrapKey = s.Crypto.UnwrapKey
+
+	s.CA = &CertificateAuthority{
+		Store:     watched,
+		Lifecycle: &s.Handlers.Lifecycle,
+		Clock:     serverClock{s},
+	}
+	s.Handlers.IssueCertificate = s.CA.IssueCertificate
+
+	h20 := *s.Handlers
+	h20.AttributeRules = kmip20.AttributeRules
+	s.Handlers20 = &h20
+
+	s.Handlers.Handle(s.Mux14)
+	s.Crypto.Handle(s.Mux14)
+	s.Mux14.Handle(kmip14.OperationRNGRetrieve, &kmip.RNGRetrieveHandler{RNGRetrieve: s.rngRetrieve})
+	s.Mux14.Handle(kmip14.OperationRNGSeed, &kmip.RNGSeedHandler{RNGSeed: s.rngSeed})
+	s.Mux14.Handle(kmip14.OperationQuery, &kmip.QueryHandler{Query: s.query14})
+	s.Mux14.Handle(kmip14.OperationDiscoverVersions, &kmip.DiscoverVersionsHandler{SupportedVersions: SupportedVersions})
+
+	(&handlers20{h: s.Handlers20}).handle(s.Mux20)
+	s.Crypto.Handle(s.Mux20)
+	s.Mux20.Handle(kmip14.OperationRNGRetrieve, &kmip.RNGRetrieveHandler{RNGRetrieve: s.rngRetrieve})
+	s.Mux20.Handle(kmip14.OperationRNGSeed, &kmip.RNGSeedHandler{RNGSeed: s.rngSeed})
+	s.Mux20.Handle(kmip14.Operation(kmip20.OperationSetEndpointRole), &kmip20.SetEndpointRoleHandler{})
+	s.Mux20.Handle(kmip14.OperationQuery, &kmip20.QueryHandler{Query: s.query20})
+	s.Mux20.Handle(kmip14.OperationDiscoverVersions, &kmip.DiscoverVersionsHandler{SupportedVersions: SupportedVersions})
+
+	s.handler14 = &kmip.StandardProtocolHandler{
+		ProtocolVersion: kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4},
+		MessageHandler:  s.Mux14,
+	}
+	s.handler20 = &kmip.StandardProtocolHandler{
+		ProtocolVersion: kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0},
+		MessageHandler:  s.Mux20,
+	}
+
+	return s
+}
+
+// ServeKMIP implements kmip.ProtocolHandler.
+func (s *Server) ServeKMIP(ctx context.Context, req *kmip.Request, w kmip.ResponseWriter) {
+	if protocolVersionMajor(req.TTLV) == 2 {
+		s.handler20.ServeKMIP(ctx, req, w)
+		return
+	}
+
+	// requests with other versions, or which can't be parsed, are rejected by the 1.4 handler
+	s.handler14.ServeKMIP(ctx, req, w)
+}
+
+// Serve accepts connections on l, and serves KMIP requests on them.  If tlsConfig is not nil, connections
+// are wrapped with TLS.  Serve always returns a non-nil error.  After Close, the error is kmip.ErrServerClosed.
+func (s *Server) Serve(l net.Listener, tlsConfig *tls.Config) error {
+	if tlsConfig != nil {
+		l = tls.NewListener(l, tlsConfig)
+	}
+
+	s.srv.Handler = s
+
+	if err := s.startScheduler(); err != nil {
+		_ = l.Close()
+		return err
+	}
+
+	return s.srv.Serve(l)
+}
+
+// startScheduler starts the scheduler, if it isn't already running.
+func (s *Server) startScheduler() error {
+	s.schedulerMu.Lock()
+	defer s.schedulerMu.Unlock()
+
+	if s.closed {
+		return kmip.ErrServerClosed
+	}
+
+	if s.stopScheduler != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(flume.WithLogger(context.Background(), schedulerLog))
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_ = s.Scheduler.Run(ctx)
+	}()
+
+	s.stopScheduler = func() {
+		cancel()
+		<-done
+	}
+
+	return nil
+}
+
+// ListenAndServe listens on the TCP address addr, and serves KMIP requests.  See Serve.
+func (s *Server) ListenAndServe(addr string, tlsConfig *tls.Config) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l, tlsConfig)
+}
+
+// Close closes the listeners and connections of a server started with Serve or ListenAndServe.
+func (s *Server) Close() error {
+	s.schedulerMu.Lock()
+	s.closed = true
+	stop := s.stopScheduler
+	s.schedulerMu.Unlock()
+
+	if stop != nil {
+		stop()
+	}
+
+	return s.srv.Close()
+}
+
+func (s *Server) clock() Clock {
+	if s.Clock == nil {
+		return SystemClock
+	}
+
+	return s.Clock
+}
+
+func (s *Server) rng() RNG {
+	if s.RNG == nil {
+		return SystemRNG
+	}
+
+	return s.RNG
+}
+
+func (s *Server) now() time.Time {
+	return s.clock().Now()
+}
+
+// serverClock is the Scheduler's and the CA's Clock.  It defers to the server's Clock, so the server, the
+// scheduler and the CA agree on the time, even if the server's Clock is set after New.
+type serverClock struct {
+	s *Server
+}
+
+func (c serverClock) Now() time.Time {
+	return c.s.clock().Now()
+}
+
+func (c serverClock) NewTimer(d time.Duration) Timer {
+	return c.s.clock().NewTimer(d)
+}
+
+// protocolVersionMajor returns the major protocol version from the header of a request message,
+// or 0 if it can't be found.
+func protocolVersionMajor(msg ttlv.TTLV) int {
+	path := []ttlv.Tag{kmip14.TagRequestMessage, kmip14.TagRequestHeader, kmip14.TagProtocolVersion, kmip14.TagProtocolVersionMajor}
+
+	v := msg
+	for i, tag := range path {
+		if v.Valid() != nil || v.Tag() != tag {
+			return 0
+		}
+
+		if i == len(path)-1 {
+			break
+		}
+
+		if v.Type() != ttlv.TypeStructure {
+			return 0
+		}
+
+		v = v.ValueStructure()
+	}
+
+	if v.Type() != ttlv.TypeInteger {
+		return 0
+	}
+
+	return int(v.ValueInteger())
+}
+
+func (s *Server) query14(_ context.Context, payload *kmip.QueryRequestPayload) (*kmip.QueryResponsePayload, error) {
+	var resp kmip.QueryResponsePayload
+
+	for _, f := range payload.QueryFunction {
+		switch f {
+		case kmip14.QueryFunctionQueryOperations:
+			resp.Operation = operations
+		case kmip14.QueryFunctionQueryObjects:
+			resp.ObjectType = objectTypes
+		case kmip14.QueryFunctionQueryServerInformation:
+			resp.VendorIdentification = VendorIdentification
+		case kmip14.QueryFunctionQueryRNGs:
+			resp.RNGParameters = []kmip.RNGParameters{s.rng().Parameters()}
+		}
+	}
+
+	return &resp, nil
+}
+
+func (s *Server) query20(_ context.Context, payload *kmip20.QueryRequestPayload) (*kmip20.QueryResponsePayload, error) {
+	var resp kmip20.QueryResponsePayload
+
+	for _, f := range payload.QueryFunction {
+		switch f {
+		case kmip20.QueryFunctionQueryOperations:
+			resp.Operation = operations20
+		case kmip20.QueryFunctionQueryObjects:
+			for _, t := range objectTypes {
+				resp.ObjectType = append(resp.ObjectType, kmip20.ObjectType(t))
+			}
+		case kmip20.QueryFunctionQueryServerInformation:
+			resp.VendorIdentification = VendorIdentification
+		case kmip20.QueryFunctionQueryRNGs:
+			resp.RNGParameters = []kmip20.RNGParameters{kmip20.RNGParameters(s.rng().Parameters())}
+		case kmip20.QueryFunctionQueryCapabilities:
+			resp.CapabilityInformation = []kmip20.CapabilityInformation{{StreamingCapability: true}}
+		case kmip20.QueryFunctionQueryClientRegistrationMethods:
+			// clients register themselves for server-to-client operations with Set Endpoint Role
+			resp.ClientRegistrationMethod = kmip14.ClientRegistrationMethodClientRegistered
+		}
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,981 @@
+package refserver
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/kmip20"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestServer starts a reference server on a local listener, and returns a client connected to it
+// with the given protocol version.  The server and client are closed when the test ends.
+func startTestServer(t *testing.T, v kmip.ProtocolVersion) *kmip.Client {
+	t.Helper()
+
+	srv := New(nil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		_ = srv.Serve(l, nil)
+	}()
+
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+
+	client, err := kmip.Dial("tcp", l.Addr().String(), nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	client.ProtocolVersion = v
+
+	return client
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+func TestServer_v14(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4})
+	ctx := testContext(t)
+
+	createReq := kmip.CreateRequestPayload{ObjectType: kmip14.ObjectTypeSymmetricKey}
+	createReq.TemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES)
+	createReq.TemplateAttribute.Append(kmip14.TagCryptographicLength, 256)
+	createReq.TemplateAttribute.Append(kmip14.TagName, kmip.Name{NameValue: "key1", NameType: kmip14.NameTypeUninterpretedTextString})
+
+	var createResp kmip.CreateResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreate, &createReq, &createResp))
+	require.NotEmpty(t, createResp.UniqueIdentifier)
+
+	id := createResp.UniqueIdentifier
+
+	var getResp kmip.GetResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGet, kmip.GetRequestPayload{UniqueIdentifier: id}, &getResp))
+	require.NotNil(t, getResp.SymmetricKey)
+	assert.Equal(t, kmip14.CryptographicAlgorithmAES, getResp.SymmetricKey.KeyBlock.CryptographicAlgorithm)
+	assert.Len(t, getResp.SymmetricKey.KeyBlock.KeyValue.KeyMaterial, 32)
+
+	var locateResp kmip.LocateResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationLocate, kmip.LocateRequestPayload{
+		Attribute: []kmip.Attribute{kmip.NewAttributeFromTag(kmip14.TagName, 0, kmip.Name{NameValue: "key1", NameType: kmip14.NameTypeUninterpretedTextString})},
+	}, &locateResp))
+	assert.Equal(t, []string{id}, locateResp.UniqueIdentifier)
+
+	require.NoError(t, client.Do(ctx, kmip14.OperationActivate, kmip.ActivateRequestPayload{UniqueIdentifier: id}, nil))
+
+	var attrsResp kmip.GetAttributesResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGetAttributes, kmip.GetAttributesRequestPayload{
+		UniqueIdentifier: id,
+		AttributeName:    []string{kmip14.TagState.CanonicalName()},
+	}, &attrsResp))
+	require.Len(t, attrsResp.Attribute, 1)
+
+	var state kmip14.State
+	require.NoError(t, kmip.DecodeAttributeValue(attrsResp.Attribute[0].AttributeValue, &state))
+	assert.Equal(t, kmip14.StateActive, state)
+
+	require.NoError(t, client.Do(ctx, kmip14.OperationRevoke, kmip.RevokeRequestPayload{
+		UniqueIdentifier: id,
+		RevocationReason: kmip.RevocationReason{RevocationReasonCode: kmip14.RevocationReasonCodeCessationOfOperation},
+	}, nil))
+
+	require.NoError(t, client.Do(ctx, kmip14.OperationDestroy, kmip.DestroyRequestPayload{UniqueIdentifier: id}, nil))
+
+	err := client.Do(ctx, kmip14.OperationGet, kmip.GetRequestPayload{UniqueIdentifier: id}, nil)
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReasonPermissionDenied, kmip.GetResultReason(err))
+
+	var queryResp kmip.QueryResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationQuery, kmip.QueryRequestPayload{
+		QueryFunction: []kmip14.QueryFunction{kmip14.QueryFunctionQueryOperations, kmip14.QueryFunctionQueryServerInformation},
+	}, &queryResp))
+	assert.Contains(t, queryResp.Operation, kmip14.OperationCreateKeyPair)
+	assert.Equal(t, VendorIdentification, queryResp.VendorIdentification)
+}
+
+func TestServer_v14CreateKeyPair(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4})
+	ctx := testContext(t)
+
+	req := kmip.CreateKeyPairRequestPayload{CommonTemplateAttribute: &kmip.TemplateAttribute{}}
+	req.CommonTemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmRSA)
+	req.CommonTemplateAttribute.Append(kmip14.TagCryptographicLength, 1024)
+
+	var resp kmip.CreateKeyPairResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreateKeyPair, &req, &resp))
+
+	var attrsResp kmip.GetAttributesResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGetAttributes, kmip.GetAttributesRequestPayload{
+		UniqueIdentifier: resp.PrivateKeyUniqueIdentifier,
+		AttributeName:    []string{kmip14.TagLink.CanonicalName()},
+	}, &attrsResp))
+	require.Len(t, attrsResp.Attribute, 1)
+
+	var link kmip.Link
+	require.NoError(t, kmip.DecodeAttributeValue(attrsResp.Attribute[0].AttributeValue, &link))
+	assert.Equal(t, kmip.Link{LinkType: kmip14.LinkTypePublicKeyLink, LinkedObjectIdentifier: resp.PublicKeyUniqueIdentifier}, link)
+
+	var getResp kmip.GetResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGet, kmip.GetRequestPayload{UniqueIdentifier: resp.PublicKeyUniqueIdentifier}, &getResp))
+	require.NotNil(t, getResp.PublicKey)
+	assert.Equal(t, kmip14.KeyFormatTypePKCS_1, getResp.PublicKey.KeyBlock.KeyFormatType)
+
+	// the public key links back to the private key
+	attrsResp = kmip.GetAttributesResponsePayload{}
+	require.NoError(t, client.Do(ctx, kmip14.OperationGetAttributes, kmip.GetAttributesRequestPayload{
+		UniqueIdentifier: resp.PublicKeyUniqueIdentifier,
+		AttributeName:    []string{kmip14.TagLink.CanonicalName()},
+	}, &attrsResp))
+	require.Len(t, attrsResp.Attribute, 1)
+	require.NoError(t, kmip.DecodeAttributeValue(attrsResp.Attribute[0].AttributeValue, &link))
+	assert.Equal(t, kmip.Link{LinkType: kmip14.LinkTypePrivateKeyLink, LinkedObjectIdentifier: resp.PrivateKeyUniqueIdentifier}, link)
+}
+
+func TestServer_rotateKey(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4})
+	ctx := testContext(t)
+
+	req := kmip.CreateRequestPayload{ObjectType: kmip14.ObjectTypeSymmetricKey}
+	req.TemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES)
+	req.TemplateAttribute.Append(kmip14.TagCryptographicLength, 128)
+
+	var createResp kmip.CreateResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreate, &req, &createResp))
+
+	id, err := client.RotateKey(ctx, createResp.UniqueIdentifier, time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, createResp.UniqueIdentifier, id)
+
+	var getResp kmip.GetResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGet, kmip.GetRequestPayload{UniqueIdentifier: id}, &getResp))
+	require.NotNil(t, getResp.SymmetricKey)
+	assert.Len(t, getResp.SymmetricKey.KeyBlock.KeyValue.KeyMaterial, 16)
+
+	var attrsResp kmip.GetAttributesResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGetAttributes, kmip.GetAttributesRequestPayload{
+		UniqueIdentifier: createResp.UniqueIdentifier,
+		AttributeName:    []string{kmip14.TagLink.CanonicalName()},
+	}, &attrsResp))
+	require.Len(t, attrsResp.Attribute, 1)
+
+	var link kmip.Link
+	require.NoError(t, kmip.DecodeAttributeValue(attrsResp.Attribute[0].AttributeValue, &link))
+	assert.Equal(t, kmip.Link{LinkType: kmip14.LinkTypeReplacementObjectLink, LinkedObjectIdentifier: id}, link)
+
+	pairReq := kmip.CreateKeyPairRequestPayload{CommonTemplateAttribute: &kmip.TemplateAttribute{}}
+	pairReq.CommonTemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmRSA)
+	pairReq.CommonTemplateAttribute.Append(kmip14.TagCryptographicLength, 1024)
+
+	var pairResp kmip.CreateKeyPairResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreateKeyPair, &pairReq, &pairResp))
+
+	privID, pubID, err := client.RotateKeyPair(ctx, pairResp.PrivateKeyUniqueIdentifier, 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, pairResp.PrivateKeyUniqueIdentifier, privID)
+	assert.NotEqual(t, pairResp.PublicKeyUniqueIdentifier, pubID)
+
+	// 2.0 Re-key, identifying the key with the ID Placeholder
+	client.ProtocolVersion = kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0}
+
+	resp, err := client.Send(ctx, &kmip.RequestMessage{
+		RequestHeader: kmip.RequestHeader{
+			ProtocolVersion: client.ProtocolVersion,
+			BatchCount:      2,
+		},
+		BatchItem: []kmip.RequestBatchItem{
+			{
+				Operation:      kmip14.OperationGet,
+				RequestPayload: kmip20.GetRequestPayload{UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: id}},
+			},
+			{
+				Operation:      kmip14.OperationReKey,
+				RequestPayload: kmip20.ReKeyRequestPayload{},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.BatchItem, 2)
+	require.Equal(t, kmip14.ResultStatusSuccess, resp.BatchItem[1].ResultStatus, resp.BatchItem[1].ResultMessage)
+
+	var rekeyResp kmip20.ReKeyResponsePayload
+	require.NoError(t, ttlv.Unmarshal(resp.BatchItem[1].ResponsePayload.(ttlv.TTLV), &rekeyResp))
+	assert.NotEmpty(t, rekeyResp.UniqueIdentifier)
+	assert.NotEqual(t, id, rekeyResp.UniqueIdentifier)
+}
+
+func TestServer_deriveKey(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4})
+	ctx := testContext(t)
+
+	req := kmip.CreateRequestPayload{ObjectType: kmip14.ObjectTypeSymmetricKey}
+	req.TemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmHMAC_SHA256)
+	req.TemplateAttribute.Append(kmip14.TagCryptographicLength, 256)
+	req.TemplateAttribute.Append(kmip14.TagCryptographicUsageMask, kmip14.CryptographicUsageMaskDeriveKey)
+
+	var createResp kmip.CreateResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreate, &req, &createResp))
+
+	baseID := createResp.UniqueIdentifier
+
+	deriveReq := kmip.DeriveKeyRequestPayload{
+		ObjectType:           kmip14.ObjectTypeSymmetricKey,
+		UniqueIdentifier:     []string{baseID},
+		DerivationMethod:     kmip14.DerivationMethodHMAC,
+		DerivationParameters: kmip.DerivationParameters{DerivationData: []byte("data")},
+	}
+	deriveReq.TemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES)
+	deriveReq.TemplateAttribute.Append(kmip14.TagCryptographicLength, 128)
+
+	// the base key must be Active
+	err := client.Do(ctx, kmip14.OperationDeriveKey, &deriveReq, nil)
+	assert.Equal(t, kmip14.ResultReasonPermissionDenied, kmip.GetResultReason(err))
+
+	require.NoError(t, client.Do(ctx, kmip14.OperationActivate, kmip.ActivateRequestPayload{UniqueIdentifier: baseID}, nil))
+
+	var deriveResp kmip.DeriveKeyResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationDeriveKey, &deriveReq, &deriveResp))
+
+	var getResp kmip.GetResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGet, kmip.GetRequestPayload{UniqueIdentifier: deriveResp.UniqueIdentifier}, &getResp))
+	require.NotNil(t, getResp.SymmetricKey)
+	assert.Equal(t, kmip14.CryptographicAlgorithmAES, getResp.SymmetricKey.KeyBlock.CryptographicAlgorithm)
+	assert.Len(t, getResp.SymmetricKey.KeyBlock.KeyValue.KeyMaterial, 16)
+
+	link := func(id string) kmip.Link {
+		t.Helper()
+
+		var attrsResp kmip.GetAttributesResponsePayload
+		require.NoError(t, client.Do(ctx, kmip14.OperationGetAttributes, kmip.GetAttributesRequestPayload{
+			UniqueIdentifier: id,
+			AttributeName:    []string{kmip14.TagLink.CanonicalName()},
+		}, &attrsResp))
+		require.Len(t, attrsResp.Attribute, 1)
+
+		var link kmip.Link
+		require.NoError(t, kmip.DecodeAttributeValue(attrsResp.Attribute[0].AttributeValue, &link))
+
+		return link
+	}
+
+	assert.Equal(t, kmip.Link{LinkType: kmip14.LinkTypeDerivationBaseObjectLink, LinkedObjectIdentifier: baseID}, link(deriveResp.UniqueIdentifier))
+	assert.Equal(t, kmip.Link{LinkType: kmip14.LinkTypeDerivedKeyLink, LinkedObjectIdentifier: deriveResp.UniqueIdentifier}, link(baseID))
+
+	// the derived key material is limited
+	tooLong := deriveReq
+	tooLong.TemplateAttribute = kmip.TemplateAttribute{}
+	tooLong.TemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmHMAC_SHA256)
+	tooLong.TemplateAttribute.Append(kmip14.TagCryptographicLength, kmip.MaxDerivedKeyLength+8)
+	err = client.Do(ctx, kmip14.OperationDeriveKey, &tooLong, nil)
+	assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+	assert.Contains(t, err.Error(), "can't derive more than")
+
+	// the derived key may not be used for derivation, since it's Pre-Active
+	deriveReq.UniqueIdentifier = []string{deriveResp.UniqueIdentifier}
+	err = client.Do(ctx, kmip14.OperationDeriveKey, &deriveReq, nil)
+	assert.Equal(t, kmip14.ResultReasonPermissionDenied, kmip.GetResultReason(err))
+
+	// 2.0 Derive Key of Secret Data
+	client.ProtocolVersion = kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0}
+
+	var deriveResp20 kmip20.DeriveKeyResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationDeriveKey, kmip20.DeriveKeyRequestPayload{
+		ObjectType:       kmip20.ObjectTypeSecretData,
+		UniqueIdentifier: []kmip20.UniqueIdentifierValue{{Text: baseID}},
+		DerivationMethod: kmip20.DerivationMethodHKDF,
+		DerivationParameters: kmip.DerivationParameters{
+			Salt:           []byte("salt"),
+			DerivationData: []byte("info"),
+		},
+		Attributes: ttlv.NewStruct(kmip20.TagAttributes,
+			ttlv.NewValue(kmip14.TagCryptographicLength, 256),
+		),
+	}, &deriveResp20))
+
+	var getResp20 kmip20.GetResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGet, kmip20.GetRequestPayload{
+		UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: deriveResp20.UniqueIdentifier},
+	}, &getResp20))
+	require.NotNil(t, getResp20.SecretData)
+	assert.Len(t, getResp20.SecretData.KeyBlock.KeyValue.KeyMaterial, 32)
+}
+
+func TestServer_splitKey(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4})
+	ctx := testContext(t)
+
+	req := kmip.CreateRequestPayload{ObjectType: kmip14.ObjectTypeSymmetricKey}
+	req.TemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES)
+	req.TemplateAttribute.Append(kmip14.TagCryptographicLength, 256)
+
+	var createResp kmip.CreateResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreate, &req, &createResp))
+
+	var keyResp kmip.GetResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGet, kmip.GetRequestPayload{UniqueIdentifier: createResp.UniqueIdentifier}, &keyResp))
+
+	// 2^521 - 1
+	prime := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 521), big.NewInt(1))
+
+	var splitResp kmip.CreateSplitKeyResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreateSplitKey, kmip.CreateSplitKeyRequestPayload{
+		ObjectType:        kmip14.ObjectTypeSymmetricKey,
+		UniqueIdentifier:  createResp.UniqueIdentifier,
+		SplitKeyParts:     3,
+		SplitKeyThreshold: 2,
+		SplitKeyMethod:    kmip14.SplitKeyMethodPolynomialSharingPrimeField,
+		PrimeFieldSize:    prime,
+	}, &splitResp))
+	require.Len(t, splitResp.UniqueIdentifier, 3)
+
+	var partResp kmip.GetResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGet, kmip.GetRequestPayload{UniqueIdentifier: splitResp.UniqueIdentifier[1]}, &partResp))
+	require.NotNil(t, partResp.SplitKey)
+	assert.Equal(t, 2, partResp.SplitKey.KeyPartIdentifier)
+	assert.Equal(t, 0, prime.Cmp(partResp.SplitKey.PrimeFieldSize))
+
+	var joinResp kmip.JoinSplitKeyResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationJoinSplitKey, kmip.JoinSplitKeyRequestPayload{
+		ObjectType:       kmip14.ObjectTypeSymmetricKey,
+		UniqueIdentifier: splitResp.UniqueIdentifier[1:],
+	}, &joinResp))
+
+	var joinedResp kmip.GetResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGet, kmip.GetRequestPayload{UniqueIdentifier: joinResp.UniqueIdentifier}, &joinedResp))
+	require.NotNil(t, joinedResp.SymmetricKey)
+	assert.Equal(t, kmip14.CryptographicAlgorithmAES, joinedResp.SymmetricKey.KeyBlock.CryptographicAlgorithm)
+	assert.Equal(t, keyResp.SymmetricKey.KeyBlock.KeyValue.KeyMaterial, joinedResp.SymmetricKey.KeyBlock.KeyValue.KeyMaterial)
+
+	// 2.0 Create Split Key of a generated key
+	client.ProtocolVersion = kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0}
+
+	var splitResp20 kmip20.CreateSplitKeyResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreateSplitKey, kmip20.CreateSplitKeyRequestPayload{
+		ObjectType:        kmip20.ObjectTypeSymmetricKey,
+		SplitKeyParts:     4,
+		SplitKeyThreshold: 3,
+		SplitKeyMethod:    kmip14.SplitKeyMethodPolynomialSharingGF2_16,
+		Attributes: ttlv.NewStruct(kmip20.TagAttributes,
+			ttlv.NewValue(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES),
+			ttlv.NewValue(kmip14.TagCryptographicLength, 128),
+		),
+	}, &splitResp20))
+	require.Len(t, splitResp20.UniqueIdentifier, 4)
+
+	join := func(ids ...string) (*kmip20.JoinSplitKeyResponsePayload, error) {
+		payload := kmip20.JoinSplitKeyRequestPayload{ObjectType: kmip20.ObjectTypeSymmetricKey}
+		for _, id := range ids {
+			payload.UniqueIdentifier = append(payload.UniqueIdentifier, kmip20.UniqueIdentifierValue{Text: id})
+		}
+
+		var resp kmip20.JoinSplitKeyResponsePayload
+
+		return &resp, client.Do(ctx, kmip14.OperationJoinSplitKey, payload, &resp)
+	}
+
+	get := func(id string) []byte {
+		t.Helper()
+
+		var resp kmip20.GetResponsePayload
+		require.NoError(t, client.Do(ctx, kmip14.OperationGet, kmip20.GetRequestPayload{
+			UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: id},
+		}, &resp))
+		require.NotNil(t, resp.SymmetricKey)
+
+		return resp.SymmetricKey.KeyBlock.KeyValue.KeyMaterial.([]byte)
+	}
+
+	ids := splitResp20.UniqueIdentifier
+
+	joinResp20, err := join(ids[0], ids[1], ids[2])
+	require.NoError(t, err)
+
+	key := get(joinResp20.UniqueIdentifier)
+	assert.Len(t, key, 16)
+
+	joinResp20, err = join(ids[3], ids[1], ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, key, get(joinResp20.UniqueIdentifier))
+
+	// fewer parts than the threshold
+	_, err = join(ids[0], ids[1])
+	assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+
+	// too many parts
+	err = client.Do(ctx, kmip14.OperationCreateSplitKey, kmip20.CreateSplitKeyRequestPayload{
+		ObjectType:        kmip20.ObjectTypeSymmetricKey,
+		SplitKeyParts:     math.MaxInt32,
+		SplitKeyThreshold: math.MaxInt32,
+		SplitKeyMethod:    kmip14.SplitKeyMethodXOR,
+		Attributes: ttlv.NewStruct(kmip20.TagAttributes,
+			ttlv.NewValue(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES),
+			ttlv.NewValue(kmip14.TagCryptographicLength, 128),
+		),
+	}, nil)
+	assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+}
+
+func TestServer_rng(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4})
+	ctx := testContext(t)
+
+	var retrieveResp kmip.RNGRetrieveResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationRNGRetrieve, kmip.RNGRetrieveRequestPayload{DataLength: 32}, &retrieveResp))
+	assert.Len(t, retrieveResp.Data, 32)
+
+	err := client.Do(ctx, kmip14.OperationRNGRetrieve, kmip.RNGRetrieveRequestPayload{DataLength: MaxRNGRetrieveLength + 1}, nil)
+	assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+
+	// the system RNG accepts no seed data
+	var seedResp kmip.RNGSeedResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationRNGSeed, kmip.RNGSeedRequestPayload{Data: []byte("seed")}, &seedResp))
+	assert.Equal(t, 0, seedResp.DataLength)
+
+	var queryResp kmip.QueryResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationQuery, kmip.QueryRequestPayload{
+		QueryFunction: []kmip14.QueryFunction{kmip14.QueryFunctionQueryOperations, kmip14.QueryFunctionQueryRNGs},
+	}, &queryResp))
+	assert.Contains(t, queryResp.Operation, kmip14.OperationRNGSeed)
+	assert.Equal(t, []kmip.RNGParameters{{RNGAlgorithm: kmip14.RNGAlgorithmUnspecified}}, queryResp.RNGParameters)
+
+	client.ProtocolVersion = kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0}
+
+	require.NoError(t, client.Do(ctx, kmip14.OperationRNGRetrieve, kmip.RNGRetrieveRequestPayload{DataLength: 16}, &retrieveResp))
+	assert.Len(t, retrieveResp.Data, 16)
+
+	var queryResp20 kmip20.QueryResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationQuery, kmip20.QueryRequestPayload{
+		QueryFunction: []kmip20.QueryFunction{kmip20.QueryFunctionQueryRNGs},
+	}, &queryResp20))
+	assert.Equal(t, []kmip20.RNGParameters{{RNGAlgorithm: kmip14.RNGAlgorithmUnspecified}}, queryResp20.RNGParameters)
+
+	// an HMAC_DRBG accepts seed data, and is described by Query
+	srv := New(nil)
+
+	srv.RNG, err = NewHMACDRBG(crypto.SHA256, nil, nil)
+	require.NoError(t, err)
+
+	seed, err := srv.rngSeed(ctx, &kmip.RNGSeedRequestPayload{Data: []byte("seed")})
+	require.NoError(t, err)
+	assert.Equal(t, 4, seed.DataLength)
+
+	retrieve, err := srv.rngRetrieve(ctx, &kmip.RNGRetrieveRequestPayload{DataLength: 100})
+	require.NoError(t, err)
+	assert.Len(t, retrieve.Data, 100)
+
+	query, err := srv.query20(ctx, &kmip20.QueryRequestPayload{QueryFunction: []kmip20.QueryFunction{kmip20.QueryFunctionQueryRNGs}})
+	require.NoError(t, err)
+	assert.Equal(t, []kmip20.RNGParameters{{
+		RNGAlgorithm:     kmip14.RNGAlgorithmDRBG,
+		HashingAlgorithm: kmip14.HashingAlgorithmSHA_256,
+		DRBGAlgorithm:    kmip14.DRBGAlgorithmHMAC,
+	}}, query.RNGParameters)
+}
+
+func TestServer_v14Encrypt(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4})
+	ctx := testContext(t)
+
+	createReq := kmip.CreateRequestPayload{ObjectType: kmip14.ObjectTypeSymmetricKey}
+	createReq.TemplateAttribute.Append(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES)
+	createReq.TemplateAttribute.Append(kmip14.TagCryptographicLength, 128)
+
+	var createResp kmip.CreateResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreate, &createReq, &createResp))
+
+	id := createResp.UniqueIdentifier
+	params := &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeGCM, RandomIV: true}
+
+	// keys must be active to encrypt
+	err := client.Do(ctx, kmip14.OperationEncrypt, kmip.EncryptRequestPayload{UniqueIdentifier: id, CryptographicParameters: params, Data: []byte("hello")}, nil)
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReasonPermissionDenied, kmip.GetResultReason(err))
+
+	require.NoError(t, client.Do(ctx, kmip14.OperationActivate, kmip.ActivateRequestPayload{UniqueIdentifier: id}, nil))
+
+	var encResp kmip.EncryptResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationEncrypt, kmip.EncryptRequestPayload{
+		UniqueIdentifier:        id,
+		CryptographicParameters: params,
+		Data:                    []byte("hello"),
+	}, &encResp))
+	assert.Len(t, encResp.IVCounterNonce, 12)
+
+	var decResp kmip.DecryptResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationDecrypt, kmip.DecryptRequestPayload{
+		UniqueIdentifier:           id,
+		CryptographicParameters:    params,
+		Data:                       encResp.Data,
+		IVCounterNonce:             encResp.IVCounterNonce,
+		AuthenticatedEncryptionTag: encResp.AuthenticatedEncryptionTag,
+	}, &decResp))
+	assert.Equal(t, []byte("hello"), decResp.Data)
+}
+
+func TestServer_v20(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0})
+	ctx := testContext(t)
+
+	var createResp kmip20.CreateResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreate, kmip20.CreateRequestPayload{
+		ObjectType: kmip20.ObjectTypeSymmetricKey,
+		Attributes: ttlv.NewStruct(kmip20.TagAttributes,
+			ttlv.NewValue(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES),
+			ttlv.NewValue(kmip14.TagCryptographicLength, 128),
+			ttlv.NewStruct(kmip14.TagName,
+				ttlv.NewValue(kmip14.TagNameValue, "key2"),
+				ttlv.NewValue(kmip14.TagNameType, kmip14.NameTypeUninterpretedTextString),
+			),
+		),
+	}, &createResp))
+	require.NotEmpty(t, createResp.UniqueIdentifier)
+
+	id := createResp.UniqueIdentifier
+
+	var getResp kmip20.GetResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGet, kmip20.GetRequestPayload{
+		UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: id},
+	}, &getResp))
+	require.NotNil(t, getResp.SymmetricKey)
+	assert.Len(t, getResp.SymmetricKey.KeyBlock.KeyValue.KeyMaterial, 16)
+
+	var locateResp kmip20.LocateResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationLocate, kmip20.LocateRequestPayload{
+		Attributes: ttlv.NewStruct(kmip20.TagAttributes,
+			ttlv.NewStruct(kmip14.TagName,
+				ttlv.NewValue(kmip14.TagNameValue, "key2"),
+				ttlv.NewValue(kmip14.TagNameType, kmip14.NameTypeUninterpretedTextString),
+			),
+		),
+	}, &locateResp))
+	assert.Equal(t, []string{id}, locateResp.UniqueIdentifier)
+	assert.Equal(t, 1, locateResp.LocatedItems)
+
+	// paging past the located objects returns no identifiers, but still counts them
+	locateResp = kmip20.LocateResponsePayload{}
+	require.NoError(t, client.Do(ctx, kmip14.OperationLocate, kmip20.LocateRequestPayload{
+		OffsetItems: 1,
+		Attributes: ttlv.NewStruct(kmip20.TagAttributes,
+			ttlv.NewValue(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES),
+		),
+	}, &locateResp))
+	assert.Empty(t, locateResp.UniqueIdentifier)
+	assert.Equal(t, 1, locateResp.LocatedItems)
+
+	require.NoError(t, client.Do(ctx, kmip14.OperationActivate, kmip20.ActivateRequestPayload{
+		UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: id},
+	}, nil))
+
+	var attrsResp struct {
+		UniqueIdentifier string
+		Attributes       struct {
+			State kmip14.State
+			Name  kmip.Name
+		}
+	}
+	require.NoError(t, client.Do(ctx, kmip14.OperationGetAttributes, kmip20.GetAttributesRequestPayload{
+		UniqueIdentifier:   &kmip20.UniqueIdentifierValue{Text: id},
+		AttributeReference: []kmip20.AttributeReference{kmip20.AttributeReference(kmip14.TagState), kmip20.AttributeReference(kmip14.TagName)},
+	}, &attrsResp))
+	assert.Equal(t, id, attrsResp.UniqueIdentifier)
+	assert.Equal(t, kmip14.StateActive, attrsResp.Attributes.State)
+	assert.Equal(t, "key2", attrsResp.Attributes.Name.NameValue)
+
+	// active objects must be revoked before they are destroyed
+	err := client.Do(ctx, kmip14.OperationDestroy, kmip20.DestroyRequestPayload{
+		UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: id},
+	}, nil)
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReason(kmip20.ResultReasonWrongKeyLifecycleState), kmip.GetResultReason(err))
+
+	require.NoError(t, client.Do(ctx, kmip14.OperationRevoke, kmip20.RevokeRequestPayload{
+		UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: id},
+		RevocationReason: kmip20.RevocationReason{RevocationReasonCode: kmip14.RevocationReasonCodeKeyCompromise},
+	}, nil))
+
+	require.NoError(t, client.Do(ctx, kmip14.OperationDestroy, kmip20.DestroyRequestPayload{
+		UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: id},
+	}, nil))
+
+	err = client.Do(ctx, kmip14.OperationGet, kmip20.GetRequestPayload{
+		UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: id},
+	}, nil)
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReason(kmip20.ResultReasonWrongKeyLifecycleState), kmip.GetResultReason(err))
+}
+
+func TestServer_v20IDPlaceholder(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0})
+	ctx := testContext(t)
+
+	msg := kmip.RequestMessage{
+		RequestHeader: kmip.RequestHeader{
+			ProtocolVersion: client.ProtocolVersion,
+			BatchCount:      2,
+		},
+		BatchItem: []kmip.RequestBatchItem{
+			{
+				Operation: kmip14.OperationRegister,
+				RequestPayload: kmip20.RegisterRequestPayload{
+					ObjectType: kmip20.ObjectTypeSecretData,
+					SecretData: &kmip.SecretData{
+						SecretDataType: kmip14.SecretDataTypePassword,
+						KeyBlock: kmip.KeyBlock{
+							KeyFormatType: kmip14.KeyFormatTypeOpaque,
+							KeyValue:      &kmip.KeyValue{KeyMaterial: []byte("secret")},
+						},
+					},
+				},
+			},
+			{
+				Operation:      kmip14.OperationGet,
+				RequestPayload: kmip20.GetRequestPayload{},
+			},
+		},
+	}
+
+	resp, err := client.Send(ctx, &msg)
+	require.NoError(t, err)
+	require.Len(t, resp.BatchItem, 2)
+
+	for _, bi := range resp.BatchItem {
+		require.Equal(t, kmip14.ResultStatusSuccess, bi.ResultStatus, bi.ResultMessage)
+	}
+
+	assert.Equal(t, kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0}, resp.ResponseHeader.ProtocolVersion)
+
+	var getResp kmip20.GetResponsePayload
+	require.NoError(t, ttlv.Unmarshal(resp.BatchItem[1].ResponsePayload.(ttlv.TTLV), &getResp))
+	require.NotNil(t, getResp.SecretData)
+	assert.Equal(t, []byte("secret"), getResp.SecretData.KeyBlock.KeyValue.KeyMaterial)
+}
+
+func TestServer_attributeRules(t *testing.T) {
+	secret := &kmip.SecretData{
+		SecretDataType: kmip14.SecretDataTypePassword,
+		KeyBlock: kmip.KeyBlock{
+			KeyFormatType: kmip14.KeyFormatTypeOpaque,
+			KeyValue:      &kmip.KeyValue{KeyMaterial: []byte("secret")},
+		},
+	}
+
+	t.Run("v14", func(t *testing.T) {
+		client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4})
+
+		err := client.Do(testContext(t), kmip14.OperationRegister, kmip.RegisterRequestPayload{
+			ObjectType: kmip14.ObjectTypeSecretData,
+			TemplateAttribute: kmip.TemplateAttribute{
+				Attribute: []kmip.Attribute{kmip.NewAttributeFromTag(kmip14.TagState, 0, kmip14.StateActive)},
+			},
+			SecretData: secret,
+		}, nil)
+		require.Error(t, err)
+		assert.Equal(t, kmip14.ResultReasonPermissionDenied, kmip.GetResultReason(err))
+	})
+
+	t.Run("v20", func(t *testing.T) {
+		client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0})
+
+		err := client.Do(testContext(t), kmip14.OperationRegister, kmip20.RegisterRequestPayload{
+			ObjectType: kmip20.ObjectTypeSecretData,
+			Attributes: ttlv.NewStruct(kmip20.TagAttributes,
+				ttlv.NewValue(kmip14.TagState, kmip14.StateActive),
+			),
+			SecretData: secret,
+		}, nil)
+		require.Error(t, err)
+		assert.Equal(t, kmip14.ResultReason(kmip20.ResultReasonAttributeReadOnly), kmip.GetResultReason(err))
+
+		// Operation Policy Name was removed in 2.0
+		err = client.Do(testContext(t), kmip14.OperationRegister, kmip20.RegisterRequestPayload{
+			ObjectType: kmip20.ObjectTypeSecretData,
+			Attributes: ttlv.NewStruct(kmip20.TagAttributes,
+				ttlv.NewValue(kmip14.TagOperationPolicyName, "default"),
+			),
+			SecretData: secret,
+		}, nil)
+		require.Error(t, err)
+		assert.Equal(t, kmip14.ResultReason(kmip20.ResultReasonUnsupportedAttribute), kmip.GetResultReason(err))
+	})
+}
+
+func TestServer_DiscoverVersions(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 2})
+	ctx := testContext(t)
+
+	var resp kmip.DiscoverVersionsResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationDiscoverVersions, kmip.DiscoverVersionsRequestPayload{}, &resp))
+	assert.Equal(t, SupportedVersions, resp.ProtocolVersion)
+}
+
+func TestServer_v14Attributes(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4})
+	ctx := testContext(t)
+
+	var createResp kmip.CreateResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreate, kmip.CreateRequestPayload{
+		ObjectType: kmip14.ObjectTypeSymmetricKey,
+		TemplateAttribute: kmip.TemplateAttribute{
+			Attribute: []kmip.Attribute{
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicAlgorithm, 0, kmip14.CryptographicAlgorithmAES),
+				kmip.NewAttributeFromTag(kmip14.TagCryptographicLength, 0, 128),
+			},
+		},
+	}, &createResp))
+
+	id := createResp.UniqueIdentifier
+
+	var addResp kmip.AddAttributeResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationAddAttribute, kmip.AddAttributeRequestPayload{
+		UniqueIdentifier: id,
+		Attribute:        kmip.NewAttributeFromTag(kmip14.TagContactInformation, 0, "admin"),
+	}, &addResp))
+	assert.Equal(t, "admin", addResp.Attribute.AttributeValue)
+
+	var modifyResp kmip.ModifyAttributeResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationModifyAttribute, kmip.ModifyAttributeRequestPayload{
+		UniqueIdentifier: id,
+		Attribute:        kmip.NewAttributeFromTag(kmip14.TagContactInformation, 0, "security"),
+	}, &modifyResp))
+	assert.Equal(t, "security", modifyResp.Attribute.AttributeValue)
+
+	var listResp kmip.GetAttributeListResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGetAttributeList, kmip.GetAttributeListRequestPayload{
+		UniqueIdentifier: id,
+	}, &listResp))
+	assert.Contains(t, listResp.AttributeName, kmip14.TagContactInformation.CanonicalName())
+
+	var deleteResp kmip.DeleteAttributeResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationDeleteAttribute, kmip.DeleteAttributeRequestPayload{
+		UniqueIdentifier: id,
+		AttributeName:    kmip14.TagContactInformation.CanonicalName(),
+	}, &deleteResp))
+	assert.Equal(t, "security", deleteResp.Attribute.AttributeValue)
+
+	err := client.Do(ctx, kmip14.OperationDeleteAttribute, kmip.DeleteAttributeRequestPayload{
+		UniqueIdentifier: id,
+		AttributeName:    kmip14.TagContactInformation.CanonicalName(),
+	}, nil)
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReasonItemNotFound, kmip.GetResultReason(err))
+}
+
+func TestServer_v20Streaming(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0})
+	ctx := testContext(t)
+
+	create := func(alg kmip14.CryptographicAlgorithm, length int) string {
+		var createResp kmip20.CreateResponsePayload
+		require.NoError(t, client.Do(ctx, kmip14.OperationCreate, kmip20.CreateRequestPayload{
+			ObjectType: kmip20.ObjectTypeSymmetricKey,
+			Attributes: ttlv.NewStruct(kmip20.TagAttributes,
+				ttlv.NewValue(kmip14.TagCryptographicAlgorithm, alg),
+				ttlv.NewValue(kmip14.TagCryptographicLength, length),
+			),
+		}, &createResp))
+		require.NoError(t, client.Do(ctx, kmip14.OperationActivate, kmip20.ActivateRequestPayload{
+			UniqueIdentifier: &kmip20.UniqueIdentifierValue{Text: createResp.UniqueIdentifier},
+		}, nil))
+
+		return createResp.UniqueIdentifier
+	}
+
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	aesID := create(kmip14.CryptographicAlgorithmAES, 256)
+	params := &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeGCM, RandomIV: true}
+
+	var ciphertext bytes.Buffer
+
+	ew := client.NewEncryptWriter(ctx, &ciphertext, kmip.EncryptRequestPayload{UniqueIdentifier: aesID, CryptographicParameters: params})
+	ew.ChunkSize = 300
+	_, err := ew.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, ew.Close())
+	assert.Len(t, ew.IVCounterNonce(), 12)
+	assert.Len(t, ew.AuthenticatedEncryptionTag(), 16)
+	assert.Equal(t, len(data), ciphertext.Len())
+
+	var plaintext bytes.Buffer
+
+	dw := client.NewDecryptWriter(ctx, &plaintext, kmip.DecryptRequestPayload{
+		UniqueIdentifier:           aesID,
+		CryptographicParameters:    params,
+		IVCounterNonce:             ew.IVCounterNonce(),
+		AuthenticatedEncryptionTag: ew.AuthenticatedEncryptionTag(),
+	})
+	dw.ChunkSize = 128
+	_, err = io.Copy(dw, &ciphertext)
+	require.NoError(t, err)
+	require.NoError(t, dw.Close())
+	assert.Equal(t, data, plaintext.Bytes())
+
+	macID := create(kmip14.CryptographicAlgorithmHMAC_SHA256, 256)
+
+	mw := client.NewMACWriter(ctx, kmip.MACRequestPayload{UniqueIdentifier: macID})
+	mw.ChunkSize = 100
+	_, err = mw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	var macResp kmip.MACResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationMAC, kmip.MACRequestPayload{UniqueIdentifier: macID, Data: data}, &macResp))
+	assert.Equal(t, macResp.MACData, mw.MAC())
+
+	err = client.Do(ctx, kmip14.OperationMAC, kmip.MACRequestPayload{CorrelationValue: []byte("unknown"), Data: data}, nil)
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReason(kmip20.ResultReasonInvalidCorrelationValue), kmip.GetResultReason(err))
+
+	var queryResp kmip20.QueryResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationQuery, kmip20.QueryRequestPayload{
+		QueryFunction: []kmip20.QueryFunction{kmip20.QueryFunctionQueryCapabilities},
+	}, &queryResp))
+	require.Len(t, queryResp.CapabilityInformation, 1)
+	assert.True(t, queryResp.CapabilityInformation[0].StreamingCapability)
+
+	queryResp = kmip20.QueryResponsePayload{}
+	require.NoError(t, client.Do(ctx, kmip14.OperationQuery, kmip20.QueryRequestPayload{
+		QueryFunction: []kmip20.QueryFunction{kmip20.QueryFunctionQueryClientRegistrationMethods},
+	}, &queryResp))
+	assert.Equal(t, kmip14.ClientRegistrationMethodClientRegistered, queryResp.ClientRegistrationMethod)
+}
+
+func TestServer_v20Attributes(t *testing.T) {
+	client := startTestServer(t, kmip.ProtocolVersion{ProtocolVersionMajor: 2, ProtocolVersionMinor: 0})
+	ctx := testContext(t)
+
+	var createResp kmip20.CreateResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationCreate, kmip20.CreateRequestPayload{
+		ObjectType: kmip20.ObjectTypeSymmetricKey,
+		Attributes: ttlv.NewStruct(kmip20.TagAttributes,
+			ttlv.NewValue(kmip14.TagCryptographicAlgorithm, kmip14.CryptographicAlgorithmAES),
+			ttlv.NewValue(kmip14.TagCryptographicLength, 128),
+			ttlv.NewValue(kmip14.TagObjectGroup, "group0"),
+			ttlv.NewValue(kmip14.TagObjectGroup, "group1"),
+		),
+	}, &createResp))
+
+	uid := &kmip20.UniqueIdentifierValue{Text: createResp.UniqueIdentifier}
+
+	getAttributes := func(refs ...ttlv.Tag) []kmip.Attribute {
+		req := kmip20.GetAttributesRequestPayload{UniqueIdentifier: uid}
+		for _, ref := range refs {
+			req.AttributeReference = append(req.AttributeReference, kmip20.AttributeReference(ref))
+		}
+
+		var resp kmip20.GetAttributesResponsePayload
+		require.NoError(t, client.Do(ctx, kmip14.OperationGetAttributes, req, &resp))
+
+		attrs, err := kmip20.DecodeAttributes(resp.Attributes)
+		require.NoError(t, err)
+
+		return attrs
+	}
+
+	doAttr := func(op kmip14.Operation, payload interface{}) error {
+		return client.Do(ctx, op, payload, nil)
+	}
+
+	// the attributes are sent in the New Attribute and Current Attribute structures
+	require.NoError(t, doAttr(kmip14.OperationAddAttribute, kmip20.AddAttributeRequestPayload{
+		UniqueIdentifier: uid,
+		NewAttribute:     ttlv.NewStruct(kmip20.TagNewAttribute, ttlv.NewValue(kmip14.TagObjectGroup, "group2")),
+	}))
+
+	require.NoError(t, doAttr(kmip14.OperationModifyAttribute, kmip20.ModifyAttributeRequestPayload{
+		UniqueIdentifier: uid,
+		CurrentAttribute: ttlv.NewStruct(kmip20.TagCurrentAttribute, ttlv.NewValue(kmip14.TagObjectGroup, "group1")),
+		NewAttribute:     ttlv.NewStruct(kmip20.TagNewAttribute, ttlv.NewValue(kmip14.TagObjectGroup, "group3")),
+	}))
+
+	require.NoError(t, doAttr(kmip14.OperationDeleteAttribute, kmip20.DeleteAttributeRequestPayload{
+		UniqueIdentifier: uid,
+		CurrentAttribute: ttlv.NewStruct(kmip20.TagCurrentAttribute, ttlv.NewValue(kmip14.TagObjectGroup, "group0")),
+	}))
+
+	var groups []interface{}
+	for _, attr := range getAttributes(kmip14.TagObjectGroup) {
+		groups = append(groups, attr.AttributeValue)
+	}
+
+	assert.Equal(t, []interface{}{"group3", "group2"}, groups)
+
+	// without a Current Attribute, a multi-valued attribute can't be modified
+	err := doAttr(kmip14.OperationModifyAttribute, kmip20.ModifyAttributeRequestPayload{
+		UniqueIdentifier: uid,
+		NewAttribute:     ttlv.NewStruct(kmip20.TagNewAttribute, ttlv.NewValue(kmip14.TagObjectGroup, "group4")),
+	})
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReason(kmip20.ResultReasonMultiValuedAttribute), kmip.GetResultReason(err))
+
+	err = doAttr(kmip14.OperationDeleteAttribute, kmip20.DeleteAttributeRequestPayload{
+		UniqueIdentifier: uid,
+		CurrentAttribute: ttlv.NewStruct(kmip20.TagCurrentAttribute, ttlv.NewValue(kmip14.TagObjectGroup, "group0")),
+	})
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReason(kmip20.ResultReasonAttributeInstanceNotFound), kmip.GetResultReason(err))
+
+	// an Attribute Reference deletes all the instances
+	ref := kmip20.AttributeReference(kmip14.TagObjectGroup)
+	require.NoError(t, doAttr(kmip14.OperationDeleteAttribute, kmip20.DeleteAttributeRequestPayload{
+		UniqueIdentifier:   uid,
+		AttributeReference: &ref,
+	}))
+	assert.Empty(t, getAttributes(kmip14.TagObjectGroup))
+
+	// Set Attribute adds or modifies a single valued attribute
+	for _, contact := range []string{"admin", "security"} {
+		require.NoError(t, doAttr(kmip14.Operation(kmip20.OperationSetAttribute), kmip20.SetAttributeRequestPayload{
+			UniqueIdentifier: uid,
+			NewAttribute:     ttlv.NewStruct(kmip20.TagNewAttribute, ttlv.NewValue(kmip14.TagContactInformation, contact)),
+		}))
+	}
+
+	assert.Equal(t, []kmip.Attribute{kmip.NewAttributeFromTag(kmip14.TagContactInformation, 0, "security")}, getAttributes(kmip14.TagContactInformation))
+
+	// the Activation Date can be adjusted while the object is Pre-Active
+	activation := time.Now().Add(time.Hour).Truncate(time.Second)
+	require.NoError(t, doAttr(kmip14.Operation(kmip20.OperationSetAttribute), kmip20.SetAttributeRequestPayload{
+		UniqueIdentifier: uid,
+		NewAttribute:     ttlv.NewStruct(kmip20.TagNewAttribute, ttlv.NewValue(kmip14.TagActivationDate, activation)),
+	}))
+	require.NoError(t, doAttr(kmip14.Operation(kmip20.OperationAdjustAttribute), kmip20.AdjustAttributeRequestPayload{
+		UniqueIdentifier:   uid,
+		AttributeReference: kmip20.AttributeReference(kmip14.TagActivationDate),
+		AdjustmentType:     kmip20.AdjustmentTypeIncrement,
+		AdjustmentValue:    time.Hour,
+	}))
+
+	dates := getAttributes(kmip14.TagActivationDate)
+	require.Len(t, dates, 1)
+	assert.True(t, activation.Add(time.Hour).Equal(dates[0].AttributeValue.(time.Time)))
+
+	var listResp kmip20.GetAttributeListResponsePayload
+	require.NoError(t, client.Do(ctx, kmip14.OperationGetAttributeList, kmip20.GetAttributeListRequestPayload{
+		UniqueIdentifier: uid,
+	}, &listResp))
+	assert.Contains(t, listResp.AttributeReference, kmip20.AttributeReference(kmip14.TagContactInformation))
+	assert.NotContains(t, listResp.AttributeReference, kmip20.AttributeReference(kmip14.TagObjectGroup))
+}
@@ -0,0 +1,775 @@
+package refserver
+
+import (
+	"context"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/kmip20"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// handlers20 adapts the 2.0 payloads to kmip.StoreHandlers, which implements the operations
+// with 1.x payloads.  Attributes are converted with kmip20.DecodeAttributes and kmip20.EncodeAttributes.
+type handlers20 struct {
+	h *kmip.StoreHandlers
+}
+
+func (a *handlers20) handle(mux *kmip.OperationMux) {
+	mux.Handle(kmip14.OperationCreate, &kmip20.CreateHandler{Create: a.create})
+	mux.Handle(kmip14.OperationCreateKeyPair, &kmip20.CreateKeyPairHandler{CreateKeyPair: a.createKeyPair})
+	mux.Handle(kmip14.OperationReKey, &kmip20.ReKeyHandler{ReKey: a.reKey})
+	mux.Handle(kmip14.OperationReKeyKeyPair, &kmip20.ReKeyKeyPairHandler{ReKeyKeyPair: a.reKeyKeyPair})
+	mux.Handle(kmip14.OperationDeriveKey, &kmip20.DeriveKeyHandler{DeriveKey: a.deriveKey})
+	mux.Handle(kmip14.OperationCertify, &kmip20.CertifyHandler{Certify: a.certify})
+	mux.Handle(kmip14.OperationReCertify, &kmip20.ReCertifyHandler{ReCertify: a.reCertify})
+	mux.Handle(kmip14.OperationCreateSplitKey, &kmip20.CreateSplitKeyHandler{CreateSplitKey: a.createSplitKey})
+	mux.Handle(kmip14.OperationJoinSplitKey, &kmip20.JoinSplitKeyHandler{JoinSplitKey: a.joinSplitKey})
+	mux.Handle(kmip14.OperationRegister, &kmip20.RegisterHandler{Register: a.register})
+	mux.Handle(kmip14.OperationGet, &kmip20.GetHandler{Get: a.get})
+	mux.Handle(kmip14.OperationGetAttributes, &kmip20.GetAttributesHandler{GetAttributes: a.getAttributes})
+	mux.Handle(kmip14.OperationGetAttributeList, &kmip20.GetAttributeListHandler{GetAttributeList: a.getAttributeList})
+	mux.Handle(kmip14.OperationAddAttribute, &kmip20.AddAttributeHandler{AddAttribute: a.addAttribute})
+	mux.Handle(kmip14.OperationModifyAttribute, &kmip20.ModifyAttributeHandler{ModifyAttribute: a.modifyAttribute})
+	mux.Handle(kmip14.OperationDeleteAttribute, &kmip20.DeleteAttributeHandler{DeleteAttribute: a.deleteAttribute})
+	mux.Handle(kmip14.Operation(kmip20.OperationAdjustAttribute), &kmip20.AdjustAttributeHandler{AdjustAttribute: a.adjustAttribute})
+	mux.Handle(kmip14.Operation(kmip20.OperationSetAttribute), &kmip20.SetAttributeHandler{SetAttribute: a.setAttribute})
+	mux.Handle(kmip14.OperationLocate, &kmip20.LocateHandler{Locate: a.locate})
+	mux.Handle(kmip14.OperationActivate, &kmip20.ActivateHandler{Activate: a.activate})
+	mux.Handle(kmip14.OperationRevoke, &kmip20.RevokeHandler{Revoke: a.revoke})
+	mux.Handle(kmip14.OperationDestroy, &kmip20.DestroyHandler{Destroy: a.destroy})
+}
+
+func (a *handlers20) create(ctx context.Context, payload *kmip20.CreateRequestPayload) (*kmip20.CreateResponsePayload, error) {
+	attrs, err := decodeAttributes(payload.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.Create(ctx, &kmip.CreateRequestPayload{
+		ObjectType:        kmip14.ObjectType(payload.ObjectType),
+		TemplateAttribute: kmip.TemplateAttribute{Attribute: attrs},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.CreateResponsePayload{
+		ObjectType:       kmip20.ObjectType(resp.ObjectType),
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) createKeyPair(ctx context.Context, payload *kmip20.CreateKeyPairRequestPayload) (*kmip20.CreateKeyPairResponsePayload, error) {
+	var tas [3]*kmip.TemplateAttribute
+
+	for i, v := range []interface{}{payload.CommonAttributes, payload.PrivateKeyAttributes, payload.PublicKeyAttributes} {
+		attrs, err := decodeAttributes(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if attrs != nil {
+			tas[i] = &kmip.TemplateAttribute{Attribute: attrs}
+		}
+	}
+
+	resp, err := a.h.CreateKeyPair(ctx, &kmip.CreateKeyPairRequestPayload{
+		CommonTemplateAttribute:     tas[0],
+		PrivateKeyTemplateAttribute: tas[1],
+		PublicKeyTemplateAttribute:  tas[2],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.CreateKeyPairResponsePayload{
+		PrivateKeyUniqueIdentifier: resp.PrivateKeyUniqueIdentifier,
+		PublicKeyUniqueIdentifier:  resp.PublicKeyUniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) reKey(ctx context.Context, payload *kmip20.ReKeyRequestPayload) (*kmip20.ReKeyResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := decodeAttributes(payload.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.ReKey(ctx, &kmip.ReKeyRequestPayload{
+		UniqueIdentifier:  id,
+		Offset:            payload.Offset,
+		TemplateAttribute: &kmip.TemplateAttribute{Attribute: attrs},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.ReKeyResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) reKeyKeyPair(ctx context.Context, payload *kmip20.ReKeyKeyPairRequestPayload) (*kmip20.ReKeyKeyPairResponsePayload, error) {
+	var tas [3]*kmip.TemplateAttribute
+
+	for i, v := range []interface{}{payload.CommonAttributes, payload.PrivateKeyAttributes, payload.PublicKeyAttributes} {
+		attrs, err := decodeAttributes(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if attrs != nil {
+			tas[i] = &kmip.TemplateAttribute{Attribute: attrs}
+		}
+	}
+
+	resp, err := a.h.ReKeyKeyPair(ctx, &kmip.ReKeyKeyPairRequestPayload{
+		PrivateKeyUniqueIdentifier:  payload.PrivateKeyUniqueIdentifier,
+		Offset:                      payload.Offset,
+		CommonTemplateAttribute:     tas[0],
+		PrivateKeyTemplateAttribute: tas[1],
+		PublicKeyTemplateAttribute:  tas[2],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.ReKeyKeyPairResponsePayload{
+		PrivateKeyUniqueIdentifier: resp.PrivateKeyUniqueIdentifier,
+		PublicKeyUniqueIdentifier:  resp.PublicKeyUniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) deriveKey(ctx context.Context, payload *kmip20.DeriveKeyRequestPayload) (*kmip20.DeriveKeyResponsePayload, error) {
+	ids := make([]string, len(payload.UniqueIdentifier))
+
+	for i := range payload.UniqueIdentifier {
+		id, err := uniqueIdentifier(&payload.UniqueIdentifier[i])
+		if err != nil {
+			return nil, err
+		}
+
+		ids[i] = id
+	}
+
+	attrs, err := decodeAttributes(payload.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.DeriveKey(ctx, &kmip.DeriveKeyRequestPayload{
+		ObjectType:           kmip14.ObjectType(payload.ObjectType),
+		UniqueIdentifier:     ids,
+		DerivationMethod:     kmip14.DerivationMethod(payload.DerivationMethod),
+		DerivationParameters: payload.DerivationParameters,
+		TemplateAttribute:    kmip.TemplateAttribute{Attribute: attrs},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.DeriveKeyResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) certify(ctx context.Context, payload *kmip20.CertifyRequestPayload) (*kmip20.CertifyResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkNoCertificateRequestObject(payload.CertificateRequestUniqueIdentifier); err != nil {
+		return nil, err
+	}
+
+	attrs, err := decodeAttributes(payload.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.Certify(ctx, &kmip.CertifyRequestPayload{
+		UniqueIdentifier:       id,
+		CertificateRequestType: payload.CertificateRequestType,
+		CertificateRequest:     payload.CertificateRequestValue,
+		TemplateAttribute:      &kmip.TemplateAttribute{Attribute: attrs},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.CertifyResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) reCertify(ctx context.Context, payload *kmip20.ReCertifyRequestPayload) (*kmip20.ReCertifyResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkNoCertificateRequestObject(payload.CertificateRequestUniqueIdentifier); err != nil {
+		return nil, err
+	}
+
+	attrs, err := decodeAttributes(payload.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.ReCertify(ctx, &kmip.ReCertifyRequestPayload{
+		UniqueIdentifier:       id,
+		CertificateRequestType: payload.CertificateRequestType,
+		CertificateRequest:     payload.CertificateRequestValue,
+		Offset:                 payload.Offset,
+		TemplateAttribute:      &kmip.TemplateAttribute{Attribute: attrs},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.ReCertifyResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) createSplitKey(ctx context.Context, payload *kmip20.CreateSplitKeyRequestPayload) (*kmip20.CreateSplitKeyResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := decodeAttributes(payload.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.CreateSplitKey(ctx, &kmip.CreateSplitKeyRequestPayload{
+		ObjectType:        kmip14.ObjectType(payload.ObjectType),
+		UniqueIdentifier:  id,
+		SplitKeyParts:     payload.SplitKeyParts,
+		SplitKeyThreshold: payload.SplitKeyThreshold,
+		SplitKeyMethod:    payload.SplitKeyMethod,
+		PrimeFieldSize:    payload.PrimeFieldSize,
+		TemplateAttribute: kmip.TemplateAttribute{Attribute: attrs},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.CreateSplitKeyResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) joinSplitKey(ctx context.Context, payload *kmip20.JoinSplitKeyRequestPayload) (*kmip20.JoinSplitKeyResponsePayload, error) {
+	ids := make([]string, len(payload.UniqueIdentifier))
+
+	for i := range payload.UniqueIdentifier {
+		id, err := uniqueIdentifier(&payload.UniqueIdentifier[i])
+		if err != nil {
+			return nil, err
+		}
+
+		ids[i] = id
+	}
+
+	attrs, err := decodeAttributes(payload.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.JoinSplitKey(ctx, &kmip.JoinSplitKeyRequestPayload{
+		ObjectType:        kmip14.ObjectType(payload.ObjectType),
+		UniqueIdentifier:  ids,
+		SecretDataType:    payload.SecretDataType,
+		TemplateAttribute: kmip.TemplateAttribute{Attribute: attrs},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.JoinSplitKeyResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+// checkNoCertificateRequestObject returns an error if a Certify or Re-certify request refers to a Certificate
+// Request object.  The server doesn't store Certificate Request objects, so the request must be passed by value.
+func checkNoCertificateRequestObject(id string) error {
+	if id != "" {
+		return kmip.WithResultReason(merry.UserError("Certificate Request objects are not supported, pass the Certificate Request Value"), kmip14.ResultReasonFeatureNotSupported)
+	}
+
+	return nil
+}
+
+func (a *handlers20) register(ctx context.Context, payload *kmip20.RegisterRequestPayload) (*kmip20.RegisterResponsePayload, error) {
+	attrs, err := decodeAttributes(payload.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.Register(ctx, &kmip.RegisterRequestPayload{
+		ObjectType:        kmip14.ObjectType(payload.ObjectType),
+		TemplateAttribute: kmip.TemplateAttribute{Attribute: attrs},
+		Certificate:       payload.Certificate,
+		SymmetricKey:      payload.SymmetricKey,
+		PrivateKey:        payload.PrivateKey,
+		PublicKey:         payload.PublicKey,
+		SplitKey:          payload.SplitKey,
+		SecretData:        payload.SecretData,
+		OpaqueObject:      payload.OpaqueObject,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.RegisterResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) get(ctx context.Context, payload *kmip20.GetRequestPayload) (*kmip20.GetResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.Get(ctx, &kmip.GetRequestPayload{
+		UniqueIdentifier:         id,
+		KeyFormatType:            payload.KeyFormatType,
+		KeyWrapType:              payload.KeyWrapType,
+		KeyCompressionType:       payload.KeyCompressionType,
+		KeyWrappingSpecification: payload.KeyWrappingSpecification,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.GetResponsePayload{
+		ObjectType:       resp.ObjectType,
+		UniqueIdentifier: resp.UniqueIdentifier,
+		Certificate:      resp.Certificate,
+		SymmetricKey:     resp.SymmetricKey,
+		PrivateKey:       resp.PrivateKey,
+		PublicKey:        resp.PublicKey,
+		SplitKey:         resp.SplitKey,
+		SecretData:       resp.SecretData,
+		OpaqueObject:     resp.OpaqueObject,
+	}, nil
+}
+
+func (a *handlers20) getAttributes(ctx context.Context, payload *kmip20.GetAttributesRequestPayload) (*kmip20.GetAttributesResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	req := kmip.GetAttributesRequestPayload{UniqueIdentifier: id}
+	for _, ref := range payload.AttributeReference {
+		req.AttributeName = append(req.AttributeName, ttlv.Tag(ref).CanonicalName())
+	}
+
+	resp, err := a.h.GetAttributes(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := kmip20.EncodeAttributes(resp.Attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.GetAttributesResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+		Attributes:       attrs,
+	}, nil
+}
+
+func (a *handlers20) getAttributeList(ctx context.Context, payload *kmip20.GetAttributeListRequestPayload) (*kmip20.GetAttributeListResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.GetAttributeList(ctx, &kmip.GetAttributeListRequestPayload{UniqueIdentifier: id})
+	if err != nil {
+		return nil, err
+	}
+
+	list := kmip20.GetAttributeListResponsePayload{UniqueIdentifier: resp.UniqueIdentifier}
+
+	for _, name := range resp.AttributeName {
+		// custom attributes have no tag, and can't be referenced
+		if tag, err := ttlv.DefaultRegistry.ParseTag(name); err == nil {
+			list.AttributeReference = append(list.AttributeReference, kmip20.AttributeReference(tag))
+		}
+	}
+
+	return &list, nil
+}
+
+// The 2.0 attribute operations identify attribute instances by value, rather than by index, so they're
+// implemented with StoreHandlers.UpdateObject and the 2.0 AttributeRules, rather than with the 1.x operations.
+
+func (a *handlers20) addAttribute(ctx context.Context, payload *kmip20.AddAttributeRequestPayload) (*kmip20.AddAttributeResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	attr, err := decodeAttribute(payload.NewAttribute)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.h.UpdateObject(ctx, id, func(obj *kmip.ManagedObject) error {
+		_, err := a.rules().AddAttribute(obj, attr)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.AddAttributeResponsePayload{UniqueIdentifier: id}, nil
+}
+
+func (a *handlers20) modifyAttribute(ctx context.Context, payload *kmip20.ModifyAttributeRequestPayload) (*kmip20.ModifyAttributeResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	attr, err := decodeAttribute(payload.NewAttribute)
+	if err != nil {
+		return nil, err
+	}
+
+	var current *kmip.Attribute
+
+	if payload.CurrentAttribute != nil {
+		c, err := decodeAttribute(payload.CurrentAttribute)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.AttributeName != attr.AttributeName {
+			return nil, kmip.WithResultReason(merry.UserError("the Current Attribute and New Attribute must be the same attribute"), kmip14.ResultReasonInvalidField)
+		}
+
+		current = &c
+	}
+
+	err = a.h.UpdateObject(ctx, id, func(obj *kmip.ManagedObject) error {
+		existing, err := findInstance(obj, attr.AttributeName, current)
+		if err != nil {
+			return err
+		}
+
+		attr.AttributeIndex = existing.AttributeIndex
+		_, err = a.rules().ModifyAttribute(obj, attr)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.ModifyAttributeResponsePayload{UniqueIdentifier: id}, nil
+}
+
+func (a *handlers20) deleteAttribute(ctx context.Context, payload *kmip20.DeleteAttributeRequestPayload) (*kmip20.DeleteAttributeResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var current *kmip.Attribute
+
+	var name string
+
+	switch {
+	case payload.CurrentAttribute != nil:
+		c, err := decodeAttribute(payload.CurrentAttribute)
+		if err != nil {
+			return nil, err
+		}
+
+		current, name = &c, c.AttributeName
+	case payload.AttributeReference != nil:
+		name = ttlv.Tag(*payload.AttributeReference).CanonicalName()
+	default:
+		return nil, kmip.WithResultReason(merry.UserError("either the Current Attribute or an Attribute Reference is required"), kmip14.ResultReasonInvalidField)
+	}
+
+	err = a.h.UpdateObject(ctx, id, func(obj *kmip.ManagedObject) error {
+		if current != nil {
+			existing, err := findInstance(obj, name, current)
+			if err != nil {
+				return err
+			}
+
+			_, err = a.rules().DeleteAttribute(obj, name, existing.AttributeIndex)
+
+			return err
+		}
+
+		// an Attribute Reference deletes every instance of the attribute
+		var indexes []int
+
+		for _, attr := range obj.Attribute {
+			if attr.AttributeName == name {
+				indexes = append(indexes, attr.AttributeIndex)
+			}
+		}
+
+		if len(indexes) == 0 {
+			_, err := a.rules().DeleteAttribute(obj, name, 0)
+			return err
+		}
+
+		for _, idx := range indexes {
+			if _, err := a.rules().DeleteAttribute(obj, name, idx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.DeleteAttributeResponsePayload{UniqueIdentifier: id}, nil
+}
+
+func (a *handlers20) adjustAttribute(ctx context.Context, payload *kmip20.AdjustAttributeRequestPayload) (*kmip20.AdjustAttributeResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	name := ttlv.Tag(payload.AttributeReference).CanonicalName()
+
+	err = a.h.UpdateObject(ctx, id, func(obj *kmip.ManagedObject) error {
+		existing, err := findInstance(obj, name, nil)
+		if err != nil {
+			return err
+		}
+
+		value, err := kmip20.AdjustAttributeValue(existing.AttributeValue, payload.AdjustmentType, payload.AdjustmentValue)
+		if err != nil {
+			return err
+		}
+
+		_, err = a.rules().ModifyAttribute(obj, kmip.Attribute{
+			AttributeName:  name,
+			AttributeIndex: existing.AttributeIndex,
+			AttributeValue: value,
+		})
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.AdjustAttributeResponsePayload{UniqueIdentifier: id}, nil
+}
+
+// setAttribute sets the value of a single instance attribute, adding the attribute if the object doesn't
+// have it, and modifying it if it does.
+func (a *handlers20) setAttribute(ctx context.Context, payload *kmip20.SetAttributeRequestPayload) (*kmip20.SetAttributeResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	attr, err := decodeAttribute(payload.NewAttribute)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.h.UpdateObject(ctx, id, func(obj *kmip.ManagedObject) error {
+		if rule, ok := a.rules().Rule(attr.AttributeName); ok && rule.MultiInstance {
+			return kmip.WithResultReason(merry.UserErrorf("Set Attribute may not be used with the multi-valued attribute %s", attr.AttributeName), kmip14.ResultReason(kmip20.ResultReasonMultiValuedAttribute))
+		}
+
+		if existing := obj.GetAttribute(attr.AttributeName); existing != nil {
+			attr.AttributeIndex = existing.AttributeIndex
+			_, err := a.rules().ModifyAttribute(obj, attr)
+
+			return err
+		}
+
+		_, err := a.rules().AddAttribute(obj, attr)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.SetAttributeResponsePayload{UniqueIdentifier: id}, nil
+}
+
+func (a *handlers20) locate(ctx context.Context, payload *kmip20.LocateRequestPayload) (*kmip20.LocateResponsePayload, error) {
+	attrs, err := decodeAttributes(payload.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.Locate(ctx, &kmip.LocateRequestPayload{
+		MaximumItems:      payload.MaximumItems,
+		OffsetItems:       payload.OffsetItems,
+		StorageStatusMask: payload.StorageStatusMask,
+		ObjectGroupMember: payload.ObjectGroupMember,
+		Attribute:         attrs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.LocateResponsePayload{
+		LocatedItems:     resp.LocatedItems,
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) activate(ctx context.Context, payload *kmip20.ActivateRequestPayload) (*kmip20.ActivateResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.Activate(ctx, &kmip.ActivateRequestPayload{UniqueIdentifier: id})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.ActivateResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) revoke(ctx context.Context, payload *kmip20.RevokeRequestPayload) (*kmip20.RevokeResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.Revoke(ctx, &kmip.RevokeRequestPayload{
+		UniqueIdentifier: id,
+		RevocationReason: kmip.RevocationReason{
+			RevocationReasonCode: payload.RevocationReason.RevocationReasonCode,
+		},
+		CompromiseOccurrenceDate: payload.CompromiseOccurrenceDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.RevokeResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+func (a *handlers20) destroy(ctx context.Context, payload *kmip20.DestroyRequestPayload) (*kmip20.DestroyResponsePayload, error) {
+	id, err := uniqueIdentifier(payload.UniqueIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.h.Destroy(ctx, &kmip.DestroyRequestPayload{UniqueIdentifier: id})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmip20.DestroyResponsePayload{
+		UniqueIdentifier: resp.UniqueIdentifier,
+	}, nil
+}
+
+// uniqueIdentifier returns the text value of a Unique Identifier.  The kmip20 handlers have already
+// replaced the ID Placeholder with its value, so any other enumeration, or an index, is not supported.
+func uniqueIdentifier(u *kmip20.UniqueIdentifierValue) (string, error) {
+	if u == nil {
+		return "", nil
+	}
+
+	if u.Text == "" && (u.Enum != 0 || u.Index != 0) {
+		return "", kmip.WithResultReason(merry.UserError("only the ID Placeholder may be used in place of a Unique Identifier"), kmip14.ResultReasonInvalidField)
+	}
+
+	return u.Text, nil
+}
+
+func decodeAttributes(v interface{}) ([]kmip.Attribute, error) {
+	attrs, err := kmip20.DecodeAttributes(v)
+	if err != nil {
+		return nil, kmip.WithResultReason(merry.WithUserMessage(err, "invalid attributes"), kmip14.ResultReasonInvalidMessage)
+	}
+
+	return attrs, nil
+}
+
+func decodeAttribute(v interface{}) (kmip.Attribute, error) {
+	attr, err := kmip20.DecodeAttribute(v)
+	if err != nil {
+		return attr, kmip.WithResultReason(merry.WithUserMessage(err, "invalid attribute"), kmip14.ResultReasonInvalidMessage)
+	}
+
+	return attr, nil
+}
+
+func (a *handlers20) rules() kmip.AttributeRules {
+	if a.h.AttributeRules == nil {
+		return kmip20.AttributeRules
+	}
+
+	return a.h.AttributeRules
+}
+
+// findInstance returns the instance of the named attribute with the same value as current.  If current is
+// nil, the attribute must have a single instance.
+func findInstance(obj *kmip.ManagedObject, name string, current *kmip.Attribute) (*kmip.Attribute, error) {
+	if current != nil {
+		if existing := obj.FindAttributeValue(name, current.AttributeValue); existing != nil {
+			return existing, nil
+		}
+
+		if obj.GetAttribute(name) != nil {
+			return nil, kmip.WithResultReason(merry.WithUserMessagef(merry.Here(kmip.ErrAttributeInstanceNotFound), "%s has no instance with the Current Attribute's value", name), kmip14.ResultReasonItemNotFound)
+		}
+
+		return nil, kmip.WithResultReason(merry.WithUserMessagef(merry.Here(kmip.ErrAttributeNotFound), "object has no %s attribute", name), kmip14.ResultReasonItemNotFound)
+	}
+
+	var found *kmip.Attribute
+
+	for i := range obj.Attribute {
+		if obj.Attribute[i].AttributeName != name {
+			continue
+		}
+
+		if found != nil {
+			return nil, kmip.WithResultReason(merry.UserErrorf("%s has more than one instance; the Current Attribute is required", name), kmip14.ResultReason(kmip20.ResultReasonMultiValuedAttribute))
+		}
+
+		found = &obj.Attribute[i]
+	}
+
+	if found == nil {
+		return nil, kmip.WithResultReason(merry.WithUserMessagef(merry.Here(kmip.ErrAttributeNotFound), "object has no %s attribute", name), kmip14.ResultReasonItemNotFound)
+	}
+
+	return found, nil
+}
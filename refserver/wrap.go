@@ -0,0 +1,267 @@
+package refserver
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// WrapKey wraps a Key Block returned by Get, as requested by a Key Wrapping Specification.  It has the signature
+// of kmip.StoreHandlers' WrapKey.
+//
+// Only the Encrypt Wrapping Method is supported.  The wrapping key must be in a state which permits Encrypt, and
+// its Cryptographic Usage Mask, if it has one, must include Wrap Key.  The Cryptographic Parameters in the
+// Encryption Key Information take precedence over the wrapping key's Cryptographic Parameters attribute.  The
+// supported wrapping algorithms are:
+//
+//   - AES with NIST Key Wrap (RFC 3394), the default Block Cipher Mode, or AES Key Wrap with Padding (RFC 5649).
+//   - AES with GCM.  The IV is returned as the IV/Counter/Nonce of the Key Wrapping Data, and the tag is
+//     appended to the wrapped key.
+//   - RSA with OAEP, the default Padding Method, or PKCS#1 v1.5 padding.  The wrapping key is a Public Key or
+//     a Certificate.
+//
+// With TTLV Encoding, the default, the whole TTLV encoded Key Value is wrapped.  With No Encoding, only the Key
+// Material, which must be a byte string, is wrapped.
+func (h *CryptoHandlers) WrapKey(ctx context.Context, kb *kmip.KeyBlock, spec *kmip.KeyWrappingSpecification) error {
+	eki, err := encryptionKeyInformation(spec.WrappingMethod, spec.EncryptionKeyInformation, spec.MACSignatureKeyInformation)
+	if err != nil {
+		return err
+	}
+
+	var plaintext []byte
+
+	if spec.EncodingOption == kmip14.EncodingOptionNoEncoding {
+		material, ok := kb.KeyValue.KeyMaterial.([]byte)
+		if !ok || len(kb.KeyValue.Attribute) > 0 {
+			return invalidFieldErrorf("No Encoding requires byte string Key Material, without attributes")
+		}
+
+		plaintext = material
+	} else {
+		plaintext, err = ttlv.Marshal(ttlv.Value{Tag: kmip14.TagKeyValue, Value: kb.KeyValue})
+		if err != nil {
+			return merry.Prepend(err, "encoding Key Value")
+		}
+	}
+
+	obj, params, err := h.key(ctx, eki.UniqueIdentifier, kmip14.OperationEncrypt, kmip14.CryptographicUsageMaskWrapKey, eki.CryptographicParameters)
+	if err != nil {
+		return err
+	}
+
+	wrapped, iv, err := wrap(obj, params, plaintext)
+	if err != nil {
+		return err
+	}
+
+	kb.KeyValue = &kmip.KeyValue{KeyMaterial: wrapped}
+	kb.KeyWrappingData = &kmip.KeyWrappingData{
+		WrappingMethod:           kmip14.WrappingMethodEncrypt,
+		EncryptionKeyInformation: eki,
+		IVCounterNonce:           iv,
+		EncodingOption:           spec.EncodingOption,
+	}
+
+	return nil
+}
+
+// UnwrapKey unwraps the Key Block of a key imported by Register.  It has the signature of kmip.StoreHandlers'
+// UnwrapKey.  It supports the algorithms supported by WrapKey.  The unwrapping key must be in a state which
+// permits Decrypt, and its Cryptographic Usage Mask, if it has one, must include Unwrap Key.  For RSA, the
+// unwrapping key is the Private Key.
+func (h *CryptoHandlers) UnwrapKey(ctx context.Context, kb *kmip.KeyBlock) error {
+	kwd := kb.KeyWrappingData
+
+	eki, err := encryptionKeyInformation(kwd.WrappingMethod, kwd.EncryptionKeyInformation, kwd.MACSignatureKeyInformation)
+	if err != nil {
+		return err
+	}
+
+	if kb.KeyValue == nil {
+		return kmip.WithResultReason(merry.UserError("the wrapped key has no Key Value"), kmip14.ResultReasonKeyValueNotPresent)
+	}
+
+	wrapped, ok := kb.KeyValue.KeyMaterial.([]byte)
+	if !ok {
+		return invalidFieldErrorf("the wrapped Key Material must be a byte string")
+	}
+
+	obj, params, err := h.key(ctx, eki.UniqueIdentifier, kmip14.OperationDecrypt, kmip14.CryptographicUsageMaskUnwrapKey, eki.CryptographicParameters)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := unwrap(obj, params, wrapped, kwd.IVCounterNonce)
+	if err != nil {
+		return err
+	}
+
+	kv := &kmip.KeyValue{KeyMaterial: plaintext, Attribute: kb.KeyValue.Attribute}
+
+	if kwd.EncodingOption != kmip14.EncodingOptionNoEncoding {
+		kv = &kmip.KeyValue{}
+
+		if ttlv.TTLV(plaintext).Valid() != nil || ttlv.TTLV(plaintext).Tag() != kmip14.TagKeyValue {
+			return cryptographicFailuref("the unwrapped key isn't a TTLV encoded Key Value")
+		}
+
+		if err := ttlv.Unmarshal(plaintext, kv); err != nil {
+			return kmip.WithResultReason(merry.Prepend(err, "decoding unwrapped Key Value"), kmip14.ResultReasonCryptographicFailure)
+		}
+	}
+
+	kb.KeyValue = kv
+	kb.KeyWrappingData = nil
+
+	return nil
+}
+
+// encryptionKeyInformation checks the Wrapping Method is supported, and returns the Encryption Key Information.
+func encryptionKeyInformation(method kmip14.WrappingMethod, eki *kmip.EncryptionKeyInformation, mski *kmip.MACSignatureKeyInformation) (*kmip.EncryptionKeyInformation, error) {
+	if method != kmip14.WrappingMethodEncrypt || mski != nil {
+		return nil, kmip.WithResultReason(merry.UserErrorf("unsupported Wrapping Method: %s", method.String()), kmip14.ResultReasonFeatureNotSupported)
+	}
+
+	if eki == nil || eki.UniqueIdentifier == "" {
+		return nil, invalidFieldErrorf("the Encryption Key Information is required")
+	}
+
+	return eki, nil
+}
+
+// wrap encrypts key data with a wrapping key.  For GCM, it returns the random IV.
+func wrap(obj *kmip.ManagedObject, params kmip.CryptographicParameters, data []byte) ([]byte, []byte, error) {
+	if obj.PublicKey != nil || obj.Certificate != nil {
+		pub, err := publicKey(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if params.PaddingMethod == 0 {
+			params.PaddingMethod = kmip14.PaddingMethodOAEP
+		}
+
+		out, err := encryptRSA(pub, params, data)
+
+		return out, nil, err
+	}
+
+	block, err := wrappingCipher(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch params.BlockCipherMode {
+	case 0, kmip14.BlockCipherModeNISTKeyWrap:
+		out, err := wrapKW(block, data)
+		return out, nil, err
+	case kmip14.BlockCipherModeAESKeyWrapPadding:
+		out, err := wrapKWP(block, data)
+		return out, nil, err
+	case kmip14.BlockCipherModeGCM:
+		n, err := ivLength(block, params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		iv := make([]byte, n)
+		if _, err := rand.Read(iv); err != nil {
+			return nil, nil, merry.Prepend(err, "generating IV")
+		}
+
+		c, err := newBlockCipherStream(block, params, iv, nil, false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		out, err := c.update(data)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rest, tag, err := c.final(nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return append(append(out, rest...), tag...), iv, nil
+	}
+
+	return nil, nil, unsupportedBlockCipherMode(params.BlockCipherMode)
+}
+
+// unwrap decrypts key data wrapped by wrap.
+func unwrap(obj *kmip.ManagedObject, params kmip.CryptographicParameters, wrapped, iv []byte) ([]byte, error) {
+	if obj.PrivateKey != nil {
+		priv, err := privateKey(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if params.PaddingMethod == 0 {
+			params.PaddingMethod = kmip14.PaddingMethodOAEP
+		}
+
+		return decryptRSA(priv, params, wrapped)
+	}
+
+	block, err := wrappingCipher(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	switch params.BlockCipherMode {
+	case 0, kmip14.BlockCipherModeNISTKeyWrap:
+		return unwrapKW(block, wrapped)
+	case kmip14.BlockCipherModeAESKeyWrapPadding:
+		return unwrapKWP(block, wrapped)
+	case kmip14.BlockCipherModeGCM:
+		tagSize := params.TagLength
+		if tagSize == 0 {
+			tagSize = 16
+		}
+
+		if len(wrapped) < tagSize {
+			return nil, cryptographicFailuref("invalid wrapped key length")
+		}
+
+		if len(iv) == 0 {
+			return nil, invalidFieldErrorf("the IV/Counter/Nonce is required for GCM")
+		}
+
+		c, err := newBlockCipherStream(block, params, iv, nil, true)
+		if err != nil {
+			return nil, err
+		}
+
+		n := len(wrapped) - tagSize
+
+		out, err := c.update(wrapped[:n])
+		if err != nil {
+			return nil, err
+		}
+
+		rest, _, err := c.final(wrapped[n:])
+		if err != nil {
+			return nil, err
+		}
+
+		return append(out, rest...), nil
+	}
+
+	return nil, unsupportedBlockCipherMode(params.BlockCipherMode)
+}
+
+// wrappingCipher returns the block cipher of an AES wrapping key.
+func wrappingCipher(obj *kmip.ManagedObject) (cipher.Block, error) {
+	if obj.SymmetricKey != nil && obj.SymmetricKey.KeyBlock.CryptographicAlgorithm != kmip14.CryptographicAlgorithmAES {
+		return nil, invalidFieldErrorf("unsupported Cryptographic Algorithm for key wrapping: %s", obj.SymmetricKey.KeyBlock.CryptographicAlgorithm.String())
+	}
+
+	return blockCipher(obj)
+}
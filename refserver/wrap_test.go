@@ -0,0 +1,147 @@
+package refserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gemalto/kmip-go"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCryptoHandlers_wrapKey(t *testing.T) {
+	ctx := context.Background()
+	s := New(nil)
+
+	aesID := putKey(t, s.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmAES, 256))
+	priv, pub := testKeyPair(t, kmip14.CryptographicAlgorithmRSA, 2048)
+	privID := putKey(t, s.Store, priv)
+	pubID := putKey(t, s.Store, pub)
+
+	key := testSymmetricKey(t, kmip14.CryptographicAlgorithmAES, 128)
+	keyID := putKey(t, s.Store, key, kmip.NewAttributeFromTag(kmip14.TagName, 0, kmip.Name{NameValue: "wrapped", NameType: kmip14.NameTypeUninterpretedTextString}))
+
+	tests := []struct {
+		name               string
+		wrapID, unwrapID   string
+		params             *kmip.CryptographicParameters
+		encoding           kmip14.EncodingOption
+		attributeNames     []string
+		wantIVCounterNonce bool
+	}{
+		{name: "NIST key wrap", wrapID: aesID, unwrapID: aesID},
+		{name: "NIST key wrap no encoding", wrapID: aesID, unwrapID: aesID, encoding: kmip14.EncodingOptionNoEncoding},
+		{name: "AES key wrap with padding", wrapID: aesID, unwrapID: aesID, params: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeAESKeyWrapPadding}, attributeNames: []string{"Name"}},
+		{name: "GCM", wrapID: aesID, unwrapID: aesID, params: &kmip.CryptographicParameters{BlockCipherMode: kmip14.BlockCipherModeGCM}, wantIVCounterNonce: true},
+		{name: "RSA OAEP", wrapID: pubID, unwrapID: privID, params: &kmip.CryptographicParameters{PaddingMethod: kmip14.PaddingMethodOAEP, HashingAlgorithm: kmip14.HashingAlgorithmSHA_256}},
+		{name: "RSA OAEP no encoding", wrapID: pubID, unwrapID: privID, encoding: kmip14.EncodingOptionNoEncoding},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := s.Handlers.Get(ctx, &kmip.GetRequestPayload{
+				UniqueIdentifier: keyID,
+				KeyWrappingSpecification: &kmip.KeyWrappingSpecification{
+					WrappingMethod: kmip14.WrappingMethodEncrypt,
+					EncryptionKeyInformation: &kmip.EncryptionKeyInformation{
+						UniqueIdentifier:        test.wrapID,
+						CryptographicParameters: test.params,
+					},
+					AttributeName:  test.attributeNames,
+					EncodingOption: test.encoding,
+				},
+			})
+			require.NoError(t, err)
+
+			kb := resp.SymmetricKey.KeyBlock
+			require.NotNil(t, kb.KeyWrappingData)
+			assert.Equal(t, kmip14.WrappingMethodEncrypt, kb.KeyWrappingData.WrappingMethod)
+			assert.Equal(t, test.wrapID, kb.KeyWrappingData.EncryptionKeyInformation.UniqueIdentifier)
+			assert.Equal(t, test.encoding, kb.KeyWrappingData.EncodingOption)
+			assert.Equal(t, test.wantIVCounterNonce, kb.KeyWrappingData.IVCounterNonce != nil)
+			assert.NotEqual(t, key.KeyBlock.KeyValue.KeyMaterial, kb.KeyValue.KeyMaterial)
+
+			// register the wrapped key with the unwrapping key, and get it back in the clear
+			kb.KeyWrappingData.EncryptionKeyInformation.UniqueIdentifier = test.unwrapID
+
+			regResp, err := s.Handlers.Register(ctx, &kmip.RegisterRequestPayload{
+				ObjectType:   kmip14.ObjectTypeSymmetricKey,
+				SymmetricKey: resp.SymmetricKey,
+			})
+			require.NoError(t, err)
+
+			getResp, err := s.Handlers.Get(ctx, &kmip.GetRequestPayload{UniqueIdentifier: regResp.UniqueIdentifier})
+			require.NoError(t, err)
+
+			kv := getResp.SymmetricKey.KeyBlock.KeyValue
+			assert.Nil(t, getResp.SymmetricKey.KeyBlock.KeyWrappingData)
+			assert.Equal(t, key.KeyBlock.KeyValue.KeyMaterial, kv.KeyMaterial)
+
+			if test.attributeNames != nil {
+				require.Len(t, kv.Attribute, 1)
+				assert.Equal(t, "Name", kv.Attribute[0].AttributeName)
+			} else {
+				assert.Empty(t, kv.Attribute)
+			}
+		})
+	}
+
+	t.Run("wrapped key round trips through the store", func(t *testing.T) {
+		resp, err := s.Handlers.Get(ctx, &kmip.GetRequestPayload{
+			UniqueIdentifier: keyID,
+			KeyWrappingSpecification: &kmip.KeyWrappingSpecification{
+				WrappingMethod:           kmip14.WrappingMethodEncrypt,
+				EncryptionKeyInformation: &kmip.EncryptionKeyInformation{UniqueIdentifier: aesID},
+			},
+		})
+		require.NoError(t, err)
+
+		// without UnwrapKey, the wrapped key is stored as it is
+		h := *s.Handlers
+		h.UnwrapKey = nil
+
+		regResp, err := h.Register(ctx, &kmip.RegisterRequestPayload{
+			ObjectType:   kmip14.ObjectTypeSymmetricKey,
+			SymmetricKey: resp.SymmetricKey,
+		})
+		require.NoError(t, err)
+
+		getResp, err := h.Get(ctx, &kmip.GetRequestPayload{UniqueIdentifier: regResp.UniqueIdentifier})
+		require.NoError(t, err)
+		assert.Equal(t, resp.SymmetricKey, getResp.SymmetricKey)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		macID := putKey(t, s.Store, testSymmetricKey(t, kmip14.CryptographicAlgorithmAES, 256),
+			kmip.NewAttributeFromTag(kmip14.TagCryptographicUsageMask, 0, kmip14.CryptographicUsageMaskEncrypt))
+
+		get := func(spec kmip.KeyWrappingSpecification) error {
+			_, err := s.Handlers.Get(ctx, &kmip.GetRequestPayload{UniqueIdentifier: keyID, KeyWrappingSpecification: &spec})
+			return err
+		}
+
+		err := get(kmip.KeyWrappingSpecification{
+			WrappingMethod:           kmip14.WrappingMethodEncrypt,
+			EncryptionKeyInformation: &kmip.EncryptionKeyInformation{UniqueIdentifier: macID},
+		})
+		require.ErrorIs(t, err, kmip.ErrIncompatibleCryptographicUsageMask)
+
+		err = get(kmip.KeyWrappingSpecification{
+			WrappingMethod:           kmip14.WrappingMethodMACSign,
+			EncryptionKeyInformation: &kmip.EncryptionKeyInformation{UniqueIdentifier: aesID},
+		})
+		assert.Equal(t, kmip14.ResultReasonFeatureNotSupported, kmip.GetResultReason(err))
+
+		err = get(kmip.KeyWrappingSpecification{WrappingMethod: kmip14.WrappingMethodEncrypt})
+		assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+
+		err = get(kmip.KeyWrappingSpecification{
+			WrappingMethod:           kmip14.WrappingMethodEncrypt,
+			EncryptionKeyInformation: &kmip.EncryptionKeyInformation{UniqueIdentifier: aesID},
+			AttributeName:            []string{"Name"},
+			EncodingOption:           kmip14.EncodingOptionNoEncoding,
+		})
+		assert.Equal(t, kmip14.ResultReasonInvalidField, kmip.GetResultReason(err))
+	})
+}
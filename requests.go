@@ -53,8 +53,22 @@ var serverLog = flume.New("kmip_server")
 type Server struct {
 	Handler ProtocolHandler
 
+	// HandlerTimeout is the maximum amount of time a single request is allowed to be
+	// handled.  Each request is handled with its own context, derived from the connection's
+	// context, which is cancelled when the timeout expires, or when the client closes the
+	// connection before the response has been written.  Zero means no timeout.
+	HandlerTimeout time.Duration
+
+	// RequireEndpointRole restricts server-to-client operations, like Notify and Put, to
+	// connections whose client has registered to receive them, by setting its endpoint role
+	// to Server with the Set Endpoint Role operation (see ServerConn.SetEndpointRole).
+	// Sending to other connections fails with ErrEndpointRoleNotServer.  If false, server-to-client
+	// operations may be sent to any connection.
+	RequireEndpointRole bool
+
 	mu         sync.Mutex
 	listeners  map[*net.Listener]struct{}
+	activeConn map[*conn]struct{}
 	inShutdown int32 // accessed atomically (non-zero means we're in Shutdown)
 }
 
@@ -109,7 +123,7 @@ func (srv *Server) Serve(l net.Listener) error {
 			return e
 		}
 		tempDelay = 0
-		c := &conn{server: srv, rwc: rw}
+		c := srv.newConn(rw)
 		// c.setState(c.rwc, StateNew) // before Serve can return
 		go c.serve(ctx)
 	}
@@ -130,10 +144,10 @@ func (srv *Server) Close() error {
 	defer srv.mu.Unlock()
 	// srv.closeDoneChanLocked()
 	err := srv.closeListenersLocked()
-	// for c := range srv.activeConn {
-	// 	c.rwc.Close()
-	// 	delete(srv.activeConn, c)
-	// }
+	for c := range srv.activeConn {
+		_ = c.rwc.Close()
+		delete(srv.activeConn, c)
+	}
 	return err
 }
 
@@ -234,6 +248,48 @@ func (srv *Server) shuttingDown() bool {
 	return atomic.LoadInt32(&srv.inShutdown) != 0
 }
 
+// trackConn adds or removes a connection to the set of active connections.
+func (srv *Server) trackConn(c *conn, add bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.activeConn == nil {
+		srv.activeConn = make(map[*conn]struct{})
+	}
+	if add {
+		srv.activeConn[c] = struct{}{}
+	} else {
+		delete(srv.activeConn, c)
+	}
+}
+
+// Conns returns handles to all the currently open client connections.
+func (srv *Server) Conns() []*ServerConn {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	conns := make([]*ServerConn, 0, len(srv.activeConn))
+	for c := range srv.activeConn {
+		conns = append(conns, c.handle)
+	}
+
+	return conns
+}
+
+// Conn returns the open client connection with the given ID, or nil if there
+// is no such connection.
+func (srv *Server) Conn(id string) *ServerConn {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	for c := range srv.activeConn {
+		if c.handle.id == id {
+			return c.handle
+		}
+	}
+
+	return nil
+}
+
 type conn struct {
 	rwc        net.Conn
 	remoteAddr string
@@ -246,12 +302,90 @@ type conn struct {
 	bufr *bufio.Reader
 	dec  *ttlv.Decoder
 
+	// bufw writes to rwc.  It is recycled for the whole life of the connection,
+	// and returned to bufioWriterPool when the connection is closed.  Responses and
+	// server-initiated messages are both written to it, so it is guarded by writeMu.
+	writeMu sync.Mutex
+	bufw    *bufio.Writer
+
+	// handle is the exported view of the connection, used to send
+	// server-initiated messages.
+	handle *ServerConn
+	// closed is closed when the connection is closed.
+	closed chan struct{}
+
 	server *Server
 }
 
+func (srv *Server) newConn(rwc net.Conn) *conn {
+	c := &conn{server: srv, rwc: rwc, closed: make(chan struct{})}
+	c.handle = newServerConn(c)
+
+	return c
+}
+
 func (c *conn) close() {
-	// TODO: http package has a buffered writer on the conn too, which is flushed here
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.bufw != nil {
+		_ = c.bufw.Flush()
+		putBufioWriter(c.bufw)
+		c.bufw = nil
+	}
+
 	_ = c.rwc.Close()
+	close(c.closed)
+}
+
+// writeMessage writes a single, complete message to the connection and flushes it.
+// It is safe to call concurrently with other writes.
+func (c *conn) writeMessage(b []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.bufw == nil {
+		return ErrConnClosed
+	}
+
+	if _, err := c.bufw.Write(b); err != nil {
+		return err
+	}
+
+	return c.bufw.Flush()
+}
+
+// connWriter is the ResponseWriter passed to the ProtocolHandler.  Each Write
+// is written and flushed as a single message, so responses aren't interleaved
+// with server-initiated messages.
+type connWriter struct {
+	c *conn
+}
+
+func (w connWriter) Write(p []byte) (int, error) {
+	if err := w.c.writeMessage(p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+var bufioWriterPool sync.Pool
+
+func newBufioWriter(w io.Writer) *bufio.Writer {
+	if v := bufioWriterPool.Get(); v != nil {
+		bw, _ := v.(*bufio.Writer)
+		bw.Reset(w)
+
+		return bw
+	}
+
+	return bufio.NewWriterSize(w, 4<<10)
+}
+
+func putBufioWriter(bw *bufio.Writer) {
+	bw.Reset(nil)
+	bufioWriterPool.Put(bw)
 }
 
 // Serve a new connection.
@@ -262,6 +396,9 @@ func (c *conn) serve(ctx context.Context) {
 	c.remoteAddr = c.rwc.RemoteAddr().String()
 	c.localAddr = c.rwc.LocalAddr().String()
 	// ctx = context.WithValue(ctx, LocalAddrContextKey, c.rwc.LocalAddr())
+	ctx = context.WithValue(ctx, connContextKey{}, c.handle)
+	c.server.trackConn(c, true)
+	defer c.server.trackConn(c, false)
 	defer func() {
 		if err := recover(); err != nil {
 			// if err := recover(); err != nil && err != ErrAbortHandler {
@@ -300,9 +437,9 @@ func (c *conn) serve(ctx context.Context) {
 	}
 
 	// TODO: do we really need instance pooling here?  We expect KMIP connections to be long lasting
-	c.dec = ttlv.NewDecoder(c.rwc)
 	c.bufr = bufio.NewReader(c.rwc)
-	// c.bufw = newBufioWriterSize(checkConnErrorWriter{c}, 4<<10)
+	c.dec = ttlv.NewDecoder(c.bufr)
+	c.bufw = newBufioWriter(c.rwc)
 
 	for {
 		w, err := c.readRequest(ctx)
@@ -344,6 +481,12 @@ func (c *conn) serve(ctx context.Context) {
 			// return
 		}
 
+		if w.TTLV.Tag() == kmip14.TagResponseMessage {
+			// the client is responding to a server-initiated message
+			c.handle.deliverResponse(w.TTLV)
+			continue
+		}
+
 		// Expect 100 Continue support
 		// req := w.req
 		// if req.expectsContinue() {
@@ -379,18 +522,12 @@ func (c *conn) serve(ctx context.Context) {
 
 		// var resp ResponseMessage
 		// err = c.server.MessageHandler.Handle(ctx, w, &resp)
-		// TODO: this cancelCtx() was created at the connection level, not the request level.  Need to
-		// figure out how to handle connection vs request timeouts and cancels.
-		// cancelCtx()
-
-		// TODO: use recycled buffered writer
-		writer := bufio.NewWriter(c.rwc)
-		h.ServeKMIP(ctx, w, writer)
-		err = writer.Flush()
-		if err != nil {
-			// TODO: handle error
-			panic(err)
-		}
+		reqCtx, cancelReq := c.requestContext(ctx)
+
+		stopBackgroundRead := c.startBackgroundRead(cancelReq)
+		h.ServeKMIP(reqCtx, w, connWriter{c: c})
+		stopBackgroundRead()
+		cancelReq()
 
 		// serverHandler{c.server}.ServeHTTP(w, w.req)
 		// w.cancelCtx()
@@ -425,6 +562,51 @@ func (c *conn) serve(ctx context.Context) {
 	}
 }
 
+// requestContext derives the context for a single request from the connection-level
+// context.  The request context is bounded by the server's HandlerTimeout, if set.
+func (c *conn) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d := c.server.HandlerTimeout; d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// aLongTimeAgo is a non-zero time, far in the past, used for
+// immediate cancellation of network operations.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// startBackgroundRead watches the connection for errors while a request is being handled.
+// KMIP clients wait for the response to a request before sending the next one, so if the
+// read fails, the client has most likely gone away, and there is no point in finishing the
+// request: cancel is called to abandon it.  If the client does send more data, it stays buffered
+// in bufr for the next call to readRequest.
+//
+// The returned func stops the background read, and must be called before the connection
+// is read from again.
+func (c *conn) startBackgroundRead(cancel context.CancelFunc) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_, err := c.bufr.Peek(1)
+
+		var ne net.Error
+		if err != nil && !(errors.As(err, &ne) && ne.Timeout()) {
+			cancel()
+		}
+	}()
+
+	return func() {
+		// unblock the Peek by expiring the read deadline, then clear the deadline
+		// so the next read isn't affected.
+		_ = c.rwc.SetReadDeadline(aLongTimeAgo)
+		<-done
+		_ = c.rwc.SetReadDeadline(time.Time{})
+	}
+}
+
 // Read next request from connection.
 func (c *conn) readRequest(_ context.Context) (w *Request, err error) {
 	// if c.hijacked() {
@@ -471,6 +653,7 @@ func (c *conn) readRequest(_ context.Context) (w *Request, err error) {
 		RemoteAddr: c.remoteAddr,
 		LocalAddr:  c.localAddr,
 		TLS:        c.tlsState,
+		Conn:       c.handle,
 	}
 
 	// c.r.setInfiniteReadLimit()
@@ -496,6 +679,11 @@ type Request struct {
 	RemoteAddr string
 	LocalAddr  string
 
+	// Conn is the connection this request was received on.  It can be retained to
+	// send server-initiated messages to the client later.  It is nil if the request
+	// wasn't received by a Server.
+	Conn *ServerConn
+
 	IDPlaceholder string
 
 	decoder *ttlv.Decoder
@@ -529,6 +717,16 @@ func (r *Request) Unmarshal(ttlv ttlv.TTLV, into interface{}) error {
 	return r.decoder.Decode(into)
 }
 
+// cloneForItem returns a shallow copy of the request, with its own decoder, so
+// the copy can be used to handle a batch item concurrently with other items.
+func (r *Request) cloneForItem() *Request {
+	c := *r
+	c.decoder = ttlv.NewDecoder(nil)
+	c.decoder.DisallowExtraValues = r.DisallowExtraValues
+
+	return &c
+}
+
 func (r *Request) DecodePayload(v interface{}) error {
 	if r.CurrentItem == nil {
 		return nil
@@ -680,6 +878,9 @@ func (r *Response) reset() {
 	r.buf.Reset()
 }
 
+// Bytes encodes the ResponseMessage, and returns the encoded TTLV.  Each call re-encodes
+// the message, so it reflects any changes made since the last call.  The returned
+// slice is only valid until the next call to Bytes, or until the Response is released.
 func (r *Response) Bytes() []byte {
 	r.buf.Reset()
 	err := r.enc.Encode(&r.ResponseMessage)
@@ -698,6 +899,7 @@ func (r *Response) errorResponse(reason kmip14.ResultReason, msg string) {
 			ResultMessage: msg,
 		},
 	}
+	r.ResponseHeader.BatchCount = len(r.BatchItem)
 }
 
 func (h *StandardProtocolHandler) handleRequest(ctx context.Context, req *Request, resp *Response) (logger flume.Logger) {
@@ -735,26 +937,40 @@ func (h *StandardProtocolHandler) handleRequest(ctx context.Context, req *Reques
 		return logger
 	}
 
+	if req.Conn != nil {
+		// server-initiated messages will use the client's protocol version
+		req.Conn.setProtocolVersion(req.Message.RequestHeader.ProtocolVersion)
+	}
+
 	// set a flag hinting to handlers that extra fields should not be tolerated when
 	// unmarshaling payloads.  According to spec, if server and client protocol version
 	// minor versions match, then extra fields should cause an error.  Not sure how to enforce
 	// this in this higher level handler, since we (the protocol/message handlers) don't unmarshal the payload.
 	// That's done by a particular item handler.
 	req.DisallowExtraValues = req.Message.RequestHeader.ProtocolVersion.ProtocolVersionMinor == h.ProtocolVersion.ProtocolVersionMinor
-	req.decoder = ttlv.NewDecoder(nil)
+	req.decoder = newDecoder()
 	req.decoder.DisallowExtraValues = req.DisallowExtraValues
 
 	h.MessageHandler.HandleMessage(ctx, req, resp)
 	resp.ResponseHeader.BatchCount = len(resp.BatchItem)
 
-	respTTLV := resp.Bytes()
+	return logger
+}
 
-	if req.Message.RequestHeader.MaximumResponseSize > 0 && len(respTTLV) > req.Message.RequestHeader.MaximumResponseSize {
-		// new error resp
-		resp.errorResponse(kmip14.ResultReasonResponseTooLarge, "")
+var decoderPool sync.Pool
+
+func newDecoder() *ttlv.Decoder {
+	if v := decoderPool.Get(); v != nil {
+		dec, _ := v.(*ttlv.Decoder)
+		return dec
 	}
 
-	return logger
+	return ttlv.NewDecoder(nil)
+}
+
+func releaseDecoder(dec *ttlv.Decoder) {
+	dec.Reset(nil)
+	decoderPool.Put(dec)
 }
 
 func (h *StandardProtocolHandler) ServeKMIP(ctx context.Context, req *Request, writer ResponseWriter) {
@@ -763,22 +979,32 @@ func (h *StandardProtocolHandler) ServeKMIP(ctx context.Context, req *Request, w
 	// for handlers to recalculate the response size after each batch item, which
 	// requires re-encoding the entire response. Seems inefficient.
 	resp := newResponse()
+	defer releaseResponse(resp)
+
 	logger := h.handleRequest(ctx, req, resp)
 
-	var err error
-	if h.LogTraffic {
-		ttlvV := resp.Bytes()
+	if req.decoder != nil {
+		releaseDecoder(req.decoder)
+		req.decoder = nil
+	}
 
-		logger.Debug("traffic log", "request", req.TTLV.String(), "response", ttlv.TTLV(ttlvV).String())
-		_, err = writer.Write(ttlvV)
-	} else {
-		_, err = resp.buf.WriteTo(writer)
+	// encode the response once.  The same bytes are used to enforce the maximum
+	// response size, to log the traffic, and to write the response.
+	respTTLV := resp.Bytes()
+
+	if req.Message != nil && req.Message.RequestHeader.MaximumResponseSize > 0 && len(respTTLV) > req.Message.RequestHeader.MaximumResponseSize {
+		resp.errorResponse(kmip14.ResultReasonResponseTooLarge, "")
+		respTTLV = resp.Bytes()
 	}
-	if err != nil {
-		panic(err)
+
+	if h.LogTraffic {
+		logger.Debug("traffic log", "request", req.TTLV.String(), "response", ttlv.TTLV(respTTLV).String())
 	}
 
-	releaseResponse(resp)
+	_, err := writer.Write(respTTLV)
+	if err != nil {
+		logger.Info("error writing response", "error", err)
+	}
 }
 
 // func (r *ResponseMessage) addFailure(reason kmip14.ResultReason, msg string) {
@@ -802,6 +1028,19 @@ type OperationMux struct {
 	handlers map[kmip14.Operation]ItemHandler
 	// ErrorHandler defaults to the DefaultErrorHandler.
 	ErrorHandler ErrorHandler
+
+	// MaxConcurrentItems enables concurrent handling of batch items.  If greater than 1, and the client
+	// has not set the Batch Order Option in the request header, the items in the batch are
+	// handled by a pool of up to MaxConcurrentItems goroutines.  The response items are always
+	// returned in the same order as the request items.
+	//
+	// Items which depend on the ID Placeholder are not reordered: they wait for all the preceding items
+	// to finish, and then see the ID Placeholder as it would have been if the batch had
+	// been handled serially.
+	//
+	// ItemHandlers must be safe for concurrent use if this is enabled.  Each concurrent item is
+	// handled with a shallow copy of the *Request.
+	MaxConcurrentItems int
 }
 
 // ErrorHandler converts a golang error into a *ResponseBatchItem (which should hold information
@@ -840,6 +1079,20 @@ func newFailedResponseBatchItem(reason kmip14.ResultReason, msg string) *Respons
 	}
 }
 
+// contextErrorResponseBatchItem converts errors caused by the request context
+// being cancelled or timing out into a failed *ResponseBatchItem.  Returns nil if
+// the error is unrelated to the context.
+func contextErrorResponseBatchItem(ctx context.Context, err error) *ResponseBatchItem {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return newFailedResponseBatchItem(kmip14.ResultReasonGeneralFailure, "request deadline exceeded")
+	case errors.Is(err, context.Canceled), ctx.Err() != nil:
+		return newFailedResponseBatchItem(kmip14.ResultReasonOperationCanceledByRequester, "request canceled")
+	default:
+		return nil
+	}
+}
+
 func (m *OperationMux) bi(ctx context.Context, req *Request, reqItem *RequestBatchItem) *ResponseBatchItem {
 	req.CurrentItem = reqItem
 	h := m.handlerForOp(reqItem.Operation)
@@ -847,8 +1100,17 @@ func (m *OperationMux) bi(ctx context.Context, req *Request, reqItem *RequestBat
 		return newFailedResponseBatchItem(kmip14.ResultReasonOperationNotSupported, "")
 	}
 
+	if err := ctx.Err(); err != nil {
+		// don't start new items once the request has been abandoned
+		return contextErrorResponseBatchItem(ctx, err)
+	}
+
 	resp, err := h.HandleItem(ctx, req)
 	if err != nil {
+		if resp := contextErrorResponseBatchItem(ctx, err); resp != nil {
+			return resp
+		}
+
 		eh := m.ErrorHandler
 		if eh == nil {
 			eh = DefaultErrorHandler
@@ -864,15 +1126,131 @@ func (m *OperationMux) bi(ctx context.Context, req *Request, reqItem *RequestBat
 }
 
 func (m *OperationMux) HandleMessage(ctx context.Context, req *Request, resp *Response) {
-	for i := range req.Message.BatchItem {
+	var respItems []*ResponseBatchItem
+	if m.MaxConcurrentItems > 1 && len(req.Message.BatchItem) > 1 && !req.Message.RequestHeader.BatchOrderOption {
+		respItems = m.handleItemsConcurrently(ctx, req)
+	} else {
+		respItems = make([]*ResponseBatchItem, len(req.Message.BatchItem))
+		for i := range req.Message.BatchItem {
+			respItems[i] = m.bi(ctx, req, &req.Message.BatchItem[i])
+		}
+	}
+
+	for i, respItem := range respItems {
 		reqItem := &req.Message.BatchItem[i]
-		respItem := m.bi(ctx, req, reqItem)
 		respItem.Operation = reqItem.Operation
 		respItem.UniqueBatchItemID = reqItem.UniqueBatchItemID
 		resp.BatchItem = append(resp.BatchItem, *respItem)
 	}
 }
 
+// handleItemsConcurrently handles the batch items on a bounded pool of goroutines.  Items
+// which use the ID Placeholder act as barriers: they are handled on the calling goroutine once
+// all the preceding items are finished.
+func (m *OperationMux) handleItemsConcurrently(ctx context.Context, req *Request) []*ResponseBatchItem {
+	items := req.Message.BatchItem
+	respItems := make([]*ResponseBatchItem, len(items))
+	// placeholders holds the ID Placeholder left by each concurrent item, if it set one
+	placeholders := make([]*string, len(items))
+	// panicVal holds the first panic raised by a concurrent item, so it can be
+	// re-raised on the calling goroutine
+	var panicOnce sync.Once
+	var panicVal interface{}
+
+	sem := make(chan struct{}, m.MaxConcurrentItems)
+	var wg sync.WaitGroup
+
+	// waitAll waits for the in-flight items, then updates the ID Placeholder as if
+	// the items had been handled in order.
+	waitFrom := 0
+	waitAll := func(end int) {
+		wg.Wait()
+		if panicVal != nil {
+			panic(panicVal)
+		}
+		for i := waitFrom; i < end; i++ {
+			if placeholders[i] != nil {
+				req.IDPlaceholder = *placeholders[i]
+			}
+		}
+		waitFrom = end
+	}
+
+	for i := range items {
+		reqItem := &items[i]
+		if usesIDPlaceholder(reqItem) {
+			waitAll(i)
+			respItems[i] = m.bi(ctx, req, reqItem)
+			waitFrom = i + 1
+			continue
+		}
+
+		itemReq := req.cloneForItem()
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				if r := recover(); r != nil {
+					panicOnce.Do(func() { panicVal = r })
+				}
+				<-sem
+				wg.Done()
+			}()
+
+			respItems[i] = m.bi(ctx, itemReq, reqItem)
+			if itemReq.IDPlaceholder != req.IDPlaceholder {
+				placeholders[i] = &itemReq.IDPlaceholder
+			}
+		}(i)
+	}
+
+	waitAll(len(items))
+
+	return respItems
+}
+
+// placeholderFreeOperations are operations whose request payloads never refer to an
+// existing object by Unique Identifier, so they never consume the ID Placeholder.
+var placeholderFreeOperations = map[kmip14.Operation]bool{
+	kmip14.OperationCreate:           true,
+	kmip14.OperationCreateKeyPair:    true,
+	kmip14.OperationRegister:         true,
+	kmip14.OperationLocate:           true,
+	kmip14.OperationQuery:            true,
+	kmip14.OperationDiscoverVersions: true,
+	kmip14.OperationRNGRetrieve:      true,
+	kmip14.OperationRNGSeed:          true,
+	kmip14.OperationHash:             true,
+}
+
+// usesIDPlaceholder reports whether a request item may depend on the ID Placeholder
+// set by a previous item in the batch.  That is the case if the request payload omits the
+// Unique Identifier (or leaves it blank), or, in KMIP 2.0, if the Unique Identifier is one of
+// the placeholder enumeration values.
+func usesIDPlaceholder(item *RequestBatchItem) bool {
+	payload, err := coerceToTTLV(item.RequestPayload)
+	if err != nil {
+		// can't tell, so play it safe
+		return true
+	}
+
+	if len(payload) == 0 {
+		return !placeholderFreeOperations[item.Operation]
+	}
+
+	if payload.Valid() != nil {
+		return true
+	}
+
+	for v := payload.ValueStructure(); len(v) > 0; v = v.Next() {
+		if v.Tag() == kmip14.TagUniqueIdentifier {
+			return v.Type() != ttlv.TypeTextString || v.Len() == 0
+		}
+	}
+
+	return !placeholderFreeOperations[item.Operation]
+}
+
 func (m *OperationMux) Handle(op kmip14.Operation, handler ItemHandler) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
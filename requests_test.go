@@ -0,0 +1,429 @@
+package kmip
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestServer serves mux on a local listener, and returns the address
+// of the listener.  The server is closed when the test ends.
+func startTestServer(t *testing.T, srv *Server, mux *OperationMux) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv.Handler = &StandardProtocolHandler{
+		MessageHandler: mux,
+		ProtocolVersion: ProtocolVersion{
+			ProtocolVersionMajor: 1,
+			ProtocolVersionMinor: 4,
+		},
+	}
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+
+	return l.Addr().String()
+}
+
+func newTestRequestMessage(items ...RequestBatchItem) RequestMessage {
+	return RequestMessage{
+		RequestHeader: RequestHeader{
+			ProtocolVersion: ProtocolVersion{
+				ProtocolVersionMajor: 1,
+				ProtocolVersionMinor: 4,
+			},
+			BatchCount: len(items),
+		},
+		BatchItem: items,
+	}
+}
+
+// roundTrip sends msg on conn, and decodes the response.
+func roundTrip(t *testing.T, conn net.Conn, msg RequestMessage) ResponseMessage {
+	t.Helper()
+
+	req, err := ttlv.Marshal(msg)
+	require.NoError(t, err)
+
+	_, err = conn.Write(req)
+	require.NoError(t, err)
+
+	var resp ResponseMessage
+	require.NoError(t, ttlv.NewDecoder(conn).Decode(&resp))
+
+	return resp
+}
+
+// newTestRequest returns a *Request as the StandardProtocolHandler would
+// pass it to the MessageHandler, with the payloads still encoded as TTLV.
+func newTestRequest(t *testing.T, msg RequestMessage) *Request {
+	t.Helper()
+
+	b, err := ttlv.Marshal(msg)
+	require.NoError(t, err)
+
+	req := Request{TTLV: b, decoder: ttlv.NewDecoder(nil)}
+	require.NoError(t, (&StandardProtocolHandler{}).parseMessage(context.Background(), &req))
+
+	return &req
+}
+
+func TestServer_HandlerTimeout(t *testing.T) {
+	mux := &OperationMux{}
+	mux.Handle(kmip14.OperationQuery, ItemHandlerFunc(func(ctx context.Context, _ *Request) (*ResponseBatchItem, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	mux.Handle(kmip14.OperationDiscoverVersions, &DiscoverVersionsHandler{
+		SupportedVersions: []ProtocolVersion{{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4}},
+	})
+
+	addr := startTestServer(t, &Server{HandlerTimeout: 50 * time.Millisecond}, mux)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	resp := roundTrip(t, conn, newTestRequestMessage(RequestBatchItem{Operation: kmip14.OperationQuery}))
+	require.Len(t, resp.BatchItem, 1)
+	assert.Equal(t, kmip14.ResultStatusOperationFailed, resp.BatchItem[0].ResultStatus)
+	assert.Equal(t, kmip14.ResultReasonGeneralFailure, resp.BatchItem[0].ResultReason)
+	assert.Equal(t, kmip14.OperationQuery, resp.BatchItem[0].Operation)
+
+	// the deadline applies per request, not per connection
+	resp = roundTrip(t, conn, newTestRequestMessage(RequestBatchItem{
+		Operation:      kmip14.OperationDiscoverVersions,
+		RequestPayload: DiscoverVersionsRequestPayload{},
+	}))
+	require.Len(t, resp.BatchItem, 1)
+	assert.Equal(t, kmip14.ResultStatusSuccess, resp.BatchItem[0].ResultStatus)
+}
+
+func TestServer_ClientDisconnectCancelsRequest(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan error, 1)
+
+	mux := &OperationMux{}
+	mux.Handle(kmip14.OperationQuery, ItemHandlerFunc(func(ctx context.Context, _ *Request) (*ResponseBatchItem, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			canceled <- ctx.Err()
+		case <-time.After(5 * time.Second):
+			canceled <- nil
+		}
+
+		return nil, ctx.Err()
+	}))
+
+	addr := startTestServer(t, &Server{}, mux)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+
+	req, err := ttlv.Marshal(newTestRequestMessage(RequestBatchItem{Operation: kmip14.OperationQuery}))
+	require.NoError(t, err)
+
+	_, err = conn.Write(req)
+	require.NoError(t, err)
+
+	<-started
+	require.NoError(t, conn.Close())
+
+	select {
+	case err := <-canceled:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not canceled")
+	}
+}
+
+func TestOperationMux_ContextError(t *testing.T) {
+	var called bool
+
+	mux := &OperationMux{}
+	mux.Handle(kmip14.OperationQuery, ItemHandlerFunc(func(_ context.Context, _ *Request) (*ResponseBatchItem, error) {
+		called = true
+		return &ResponseBatchItem{}, nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := Request{Message: &RequestMessage{BatchItem: []RequestBatchItem{{Operation: kmip14.OperationQuery}}}}
+
+	var resp Response
+	mux.HandleMessage(ctx, &req, &resp)
+
+	assert.False(t, called)
+	require.Len(t, resp.BatchItem, 1)
+	assert.Equal(t, kmip14.ResultStatusOperationFailed, resp.BatchItem[0].ResultStatus)
+	assert.Equal(t, kmip14.ResultReasonOperationCanceledByRequester, resp.BatchItem[0].ResultReason)
+}
+
+func TestOperationMux_ConcurrentItems(t *testing.T) {
+	const items = 4
+
+	// each Get handler blocks until all of them have started, so the batch only
+	// completes if the items are handled concurrently.
+	var started sync.WaitGroup
+	started.Add(items)
+
+	mux := &OperationMux{MaxConcurrentItems: items}
+	mux.Handle(kmip14.OperationGet, ItemHandlerFunc(func(_ context.Context, req *Request) (*ResponseBatchItem, error) {
+		var p GetRequestPayload
+		if err := req.DecodePayload(&p); err != nil {
+			return nil, err
+		}
+
+		started.Done()
+		started.Wait()
+
+		return &ResponseBatchItem{ResponsePayload: GetResponsePayload{UniqueIdentifier: p.UniqueIdentifier}}, nil
+	}))
+
+	var batch []RequestBatchItem
+	for i := 0; i < items; i++ {
+		batch = append(batch, RequestBatchItem{
+			Operation:         kmip14.OperationGet,
+			UniqueBatchItemID: []byte{byte(i)},
+			RequestPayload:    GetRequestPayload{UniqueIdentifier: strconv.Itoa(i)},
+		})
+	}
+
+	req := newTestRequest(t, newTestRequestMessage(batch...))
+
+	var resp Response
+
+	done := make(chan struct{})
+	go func() {
+		mux.HandleMessage(context.Background(), req, &resp)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch items were not handled concurrently")
+	}
+
+	require.Len(t, resp.BatchItem, items)
+
+	for i, bi := range resp.BatchItem {
+		assert.Equal(t, []byte{byte(i)}, bi.UniqueBatchItemID)
+		assert.Equal(t, strconv.Itoa(i), bi.ResponsePayload.(GetResponsePayload).UniqueIdentifier)
+	}
+}
+
+func TestOperationMux_ConcurrentItemsIDPlaceholder(t *testing.T) {
+	mux := &OperationMux{MaxConcurrentItems: 4}
+	mux.Handle(kmip14.OperationCreate, ItemHandlerFunc(func(_ context.Context, req *Request) (*ResponseBatchItem, error) {
+		var p CreateRequestPayload
+		if err := req.DecodePayload(&p); err != nil {
+			return nil, err
+		}
+
+		// finish the earlier items last, so a naive implementation would
+		// pick up the wrong placeholder
+		name := p.TemplateAttribute.Get(kmip14.TagObjectGroup.CanonicalName()).AttributeValue.(string)
+		if name == "first" {
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		req.IDPlaceholder = name
+
+		return &ResponseBatchItem{}, nil
+	}))
+	mux.Handle(kmip14.OperationGet, ItemHandlerFunc(func(_ context.Context, req *Request) (*ResponseBatchItem, error) {
+		var p GetRequestPayload
+		if err := req.DecodePayload(&p); err != nil {
+			return nil, err
+		}
+
+		if p.UniqueIdentifier == "" {
+			p.UniqueIdentifier = req.IDPlaceholder
+		}
+
+		return &ResponseBatchItem{ResponsePayload: GetResponsePayload{UniqueIdentifier: p.UniqueIdentifier}}, nil
+	}))
+
+	create := func(name string) RequestBatchItem {
+		var p CreateRequestPayload
+		p.TemplateAttribute.Append(kmip14.TagObjectGroup, name)
+
+		return RequestBatchItem{Operation: kmip14.OperationCreate, RequestPayload: p}
+	}
+
+	req := newTestRequest(t, newTestRequestMessage(
+		create("first"),
+		create("second"),
+		RequestBatchItem{Operation: kmip14.OperationGet, RequestPayload: GetRequestPayload{}},
+		create("third"),
+		RequestBatchItem{Operation: kmip14.OperationGet, RequestPayload: GetRequestPayload{UniqueIdentifier: "explicit"}},
+		RequestBatchItem{Operation: kmip14.OperationGet, RequestPayload: GetRequestPayload{}},
+	))
+
+	var resp Response
+	mux.HandleMessage(context.Background(), req, &resp)
+
+	require.Len(t, resp.BatchItem, 6)
+	assert.Equal(t, "second", resp.BatchItem[2].ResponsePayload.(GetResponsePayload).UniqueIdentifier)
+	assert.Equal(t, "explicit", resp.BatchItem[4].ResponsePayload.(GetResponsePayload).UniqueIdentifier)
+	assert.Equal(t, "third", resp.BatchItem[5].ResponsePayload.(GetResponsePayload).UniqueIdentifier)
+}
+
+func TestOperationMux_BatchOrderOption(t *testing.T) {
+	var active, maxActive int32
+
+	mux := &OperationMux{MaxConcurrentItems: 4}
+	mux.Handle(kmip14.OperationGet, ItemHandlerFunc(func(_ context.Context, _ *Request) (*ResponseBatchItem, error) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		return &ResponseBatchItem{}, nil
+	}))
+
+	get := RequestBatchItem{Operation: kmip14.OperationGet, RequestPayload: GetRequestPayload{UniqueIdentifier: "1"}}
+	msg := newTestRequestMessage(get, get, get)
+	msg.RequestHeader.BatchOrderOption = true
+	req := newTestRequest(t, msg)
+
+	var resp Response
+	mux.HandleMessage(context.Background(), req, &resp)
+
+	require.Len(t, resp.BatchItem, 3)
+	assert.EqualValues(t, 1, maxActive)
+}
+
+func TestStandardProtocolHandler_ResponseTooLarge(t *testing.T) {
+	h := benchmarkHandler()
+
+	msg := newTestRequestMessage(RequestBatchItem{
+		Operation:      kmip14.OperationDiscoverVersions,
+		RequestPayload: DiscoverVersionsRequestPayload{},
+	})
+	msg.RequestHeader.MaximumResponseSize = 10
+
+	reqTTLV, err := ttlv.Marshal(msg)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	h.ServeKMIP(context.Background(), &Request{TTLV: reqTTLV}, &buf)
+
+	var resp ResponseMessage
+	require.NoError(t, ttlv.Unmarshal(buf.Bytes(), &resp))
+	assert.Equal(t, 1, resp.ResponseHeader.BatchCount)
+	require.Len(t, resp.BatchItem, 1)
+	assert.Equal(t, kmip14.ResultReasonResponseTooLarge, resp.BatchItem[0].ResultReason)
+
+	// messages which fail to parse still get a complete response
+	buf.Reset()
+	h.ServeKMIP(context.Background(), &Request{TTLV: ttlv.TTLV{0x42, 0x00, 0x01, 0x01, 0, 0, 0, 0}}, &buf)
+
+	resp = ResponseMessage{}
+	require.NoError(t, ttlv.Unmarshal(buf.Bytes(), &resp))
+	require.Len(t, resp.BatchItem, 1)
+	assert.Equal(t, kmip14.ResultReasonInvalidMessage, resp.BatchItem[0].ResultReason)
+}
+
+func benchmarkHandler() *StandardProtocolHandler {
+	mux := &OperationMux{}
+	mux.Handle(kmip14.OperationDiscoverVersions, &DiscoverVersionsHandler{
+		SupportedVersions: []ProtocolVersion{{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4}},
+	})
+
+	return &StandardProtocolHandler{
+		MessageHandler:  mux,
+		ProtocolVersion: ProtocolVersion{ProtocolVersionMajor: 1, ProtocolVersionMinor: 4},
+	}
+}
+
+func benchmarkRequest(b *testing.B) ttlv.TTLV {
+	b.Helper()
+
+	req, err := ttlv.Marshal(newTestRequestMessage(RequestBatchItem{
+		Operation:      kmip14.OperationDiscoverVersions,
+		RequestPayload: DiscoverVersionsRequestPayload{},
+	}))
+	require.NoError(b, err)
+
+	return req
+}
+
+func benchmarkServeKMIP(b *testing.B, logTraffic bool) {
+	h := benchmarkHandler()
+	h.LogTraffic = logTraffic
+	reqTTLV := benchmarkRequest(b)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h.ServeKMIP(ctx, &Request{TTLV: reqTTLV}, io.Discard)
+	}
+}
+
+func BenchmarkStandardProtocolHandler_ServeKMIP(b *testing.B) {
+	benchmarkServeKMIP(b, false)
+}
+
+func BenchmarkStandardProtocolHandler_ServeKMIPLogTraffic(b *testing.B) {
+	benchmarkServeKMIP(b, true)
+}
+
+func BenchmarkServer_RoundTrip(b *testing.B) {
+	srv := &Server{Handler: benchmarkHandler()}
+	clientConn, serverConn := net.Pipe()
+
+	c := srv.newConn(serverConn)
+	go c.serve(context.Background())
+
+	defer clientConn.Close()
+
+	reqTTLV := benchmarkRequest(b)
+	dec := ttlv.NewDecoder(bufio.NewReader(clientConn))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := clientConn.Write(reqTTLV); err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := dec.NextTTLV(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
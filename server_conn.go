@@ -0,0 +1,193 @@
+package kmip
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/google/uuid"
+)
+
+// ErrEndpointRoleNotServer is returned when sending a server-to-client operation to a client which
+// has not registered to receive them, and the Server requires it.  See Server.RequireEndpointRole.
+var ErrEndpointRoleNotServer = errors.New("kmip: client has not set its endpoint role to server")
+
+// ServerConn is a handle to a client connection accepted by a Server.  Handlers can retain
+// the handle from Request.Conn, or look it up with Server.Conn, and use it to send
+// server-to-client operations, like Notify and Put, to the client.
+//
+// The client handles these operations like a server would, and responds on the same connection.
+// Because requests on a connection are processed serially, server-initiated messages must
+// not be sent from a handler which is handling a request on the same connection: the client's
+// response won't be read until the handler returns.
+type ServerConn struct {
+	c  *conn
+	id string
+
+	mu              sync.Mutex
+	protocolVersion ProtocolVersion
+	endpointRole    uint32
+
+	exchange *exchange
+}
+
+type connContextKey struct{}
+
+// ConnFromContext returns the connection a request was received on, from the context passed to the request's
+// handlers.  Returns nil if the request wasn't received by a Server.
+func ConnFromContext(ctx context.Context) *ServerConn {
+	sc, _ := ctx.Value(connContextKey{}).(*ServerConn)
+	return sc
+}
+
+func newServerConn(c *conn) *ServerConn {
+	return &ServerConn{
+		c:  c,
+		id: uuid.New().String(),
+		protocolVersion: ProtocolVersion{
+			ProtocolVersionMajor: 1,
+			ProtocolVersionMinor: 4,
+		},
+		endpointRole: endpointRoleClient,
+		exchange:     newExchange(),
+	}
+}
+
+// ID uniquely identifies the connection within the server.
+func (sc *ServerConn) ID() string {
+	return sc.id
+}
+
+// RemoteAddr returns the client's address.
+func (sc *ServerConn) RemoteAddr() string {
+	return sc.c.remoteAddr
+}
+
+// LocalAddr returns the address the client connected to.
+func (sc *ServerConn) LocalAddr() string {
+	return sc.c.localAddr
+}
+
+// TLS returns the TLS state of the connection, or nil if the connection doesn't use TLS.
+func (sc *ServerConn) TLS() *tls.ConnectionState {
+	return sc.c.tlsState
+}
+
+// Done returns a channel which is closed when the connection is closed.  Handlers which hold state for the
+// connection can use it to release the state.
+func (sc *ServerConn) Done() <-chan struct{} {
+	return sc.c.closed
+}
+
+// ProtocolVersion returns the protocol version of the last request the client sent on
+// this connection.  Server-initiated messages are sent with this version.  It defaults to 1.4.
+func (sc *ServerConn) ProtocolVersion() ProtocolVersion {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return sc.protocolVersion
+}
+
+func (sc *ServerConn) setProtocolVersion(v ProtocolVersion) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.protocolVersion = v
+}
+
+// The values of kmip20.EndpointRole, which this package can't import.
+const (
+	endpointRoleClient uint32 = 0x00000001
+	endpointRoleServer uint32 = 0x00000002
+)
+
+// EndpointRole returns the endpoint role of the client on this connection, as a kmip20.EndpointRole
+// value.  It defaults to Client.
+func (sc *ServerConn) EndpointRole() uint32 {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return sc.endpointRole
+}
+
+// SetEndpointRole records the endpoint role of the client on this connection, as a kmip20.EndpointRole
+// value.  In KMIP 2.0, a client registers to receive server-to-client operations by setting its endpoint
+// role to Server with the Set Endpoint Role operation: the kmip20.SetEndpointRoleHandler calls this
+// with the role it accepts.
+func (sc *ServerConn) SetEndpointRole(role uint32) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.endpointRole = role
+}
+
+// AcceptsServerOperations returns true if the client has registered to receive server-to-client
+// operations on this connection, that is, if its endpoint role is Server.
+func (sc *ServerConn) AcceptsServerOperations() bool {
+	return sc.EndpointRole() == endpointRoleServer
+}
+
+// SetAcceptsServerOperations records whether the client has registered to receive server-to-client
+// operations on this connection, by setting its endpoint role to Server or Client.  Servers using
+// registration methods other than Set Endpoint Role can call this from their own handlers.
+func (sc *ServerConn) SetAcceptsServerOperations(accepts bool) {
+	if accepts {
+		sc.SetEndpointRole(endpointRoleServer)
+	} else {
+		sc.SetEndpointRole(endpointRoleClient)
+	}
+}
+
+// Send sends a request message to the client, and waits for the client's response.
+//
+// If the Server requires it, the client must have registered to receive server-to-client operations,
+// or Send returns ErrEndpointRoleNotServer.
+func (sc *ServerConn) Send(ctx context.Context, msg *RequestMessage) (*ResponseMessage, error) {
+	if sc.c.server != nil && sc.c.server.RequireEndpointRole && !sc.AcceptsServerOperations() {
+		return nil, merry.Wrap(ErrEndpointRoleNotServer)
+	}
+
+	return sc.exchange.roundTrip(ctx, sc.c.closed, msg, sc.c.writeMessage)
+}
+
+// Notify sends a Notify operation to the client.
+func (sc *ServerConn) Notify(ctx context.Context, payload *NotifyRequestPayload) error {
+	return sc.do(ctx, kmip14.OperationNotify, payload)
+}
+
+// Put sends a Put operation to the client.
+func (sc *ServerConn) Put(ctx context.Context, payload *PutRequestPayload) error {
+	return sc.do(ctx, kmip14.OperationPut, payload)
+}
+
+func (sc *ServerConn) do(ctx context.Context, op kmip14.Operation, payload interface{}) error {
+	resp, err := sc.Send(ctx, newSingleItemRequest(sc.ProtocolVersion(), op, payload))
+	if err != nil {
+		return err
+	}
+
+	return decodeSingleItemResponse(resp, op, nil)
+}
+
+// deliverResponse passes a response message read off the connection to the pending
+// server-initiated request.
+func (sc *ServerConn) deliverResponse(msg ttlv.TTLV) {
+	var resp ResponseMessage
+	if err := ttlv.Unmarshal(msg, &resp); err != nil {
+		serverLog.Error("kmip: invalid response message", "remoteAddr", sc.c.remoteAddr, "error", err)
+
+		if !sc.exchange.deliver(nil, merry.Prepend(err, "decoding response")) {
+			serverLog.Info("kmip: discarding unexpected response message", "remoteAddr", sc.c.remoteAddr)
+		}
+
+		return
+	}
+
+	if !sc.exchange.deliver(&resp, nil) {
+		serverLog.Info("kmip: discarding unexpected response message", "remoteAddr", sc.c.remoteAddr)
+	}
+}
@@ -0,0 +1,448 @@
+package kmip
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"math/big"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+)
+
+// gf2_16Polynomial is the irreducible polynomial x^16 + x^5 + x^3 + x^2 + 1, which defines the field GF(2^16)
+// used by Polynomial Sharing GF(2^16).  KMIP doesn't specify the polynomial, so parts split by other
+// implementations with this method may not be compatible.
+const gf2_16Polynomial = 0x1002d
+
+// MaxSplitKeyParts is the largest number of Split Key Parts NewSplitKeys splits a key into, and JoinSplitKeys
+// accepts.
+const MaxSplitKeyParts = 255
+
+func invalidSplitKeyf(format string, args ...interface{}) error {
+	return WithResultReason(merry.UserErrorf(format, args...), kmip14.ResultReasonInvalidField)
+}
+
+// NewSplitKeys splits the key material of a Symmetric Key or Secret Data Key Block into parts, any threshold of
+// which can reconstruct it with JoinSplitKeys.  The supported Split Key Methods are:
+//
+//   - XOR: the parts are random, except the last, which is the XOR of the key material with the other parts.
+//     The threshold must be the number of parts.
+//   - Polynomial Sharing GF(2^16): Shamir's secret sharing over GF(2^16), applied to each 16 bit word of the key
+//     material.  Key material of an odd length is padded with a zero byte.
+//   - Polynomial Sharing Prime Field: Shamir's secret sharing over the prime field of the prime, the Prime Field
+//     Size, which must be greater than both the key material, as a big endian integer, and the number of parts.
+//     The parts are big endian integers, as long as the prime.
+//
+// There can be up to MaxSplitKeyParts parts.  For polynomial sharing, the Key Part Identifier of each part is the
+// x coordinate of its share.  The parts' Key Blocks hold their share in the Raw format, with the Cryptographic
+// Algorithm and Cryptographic Length of the key material.
+func NewSplitKeys(kb *KeyBlock, parts, threshold int, method kmip14.SplitKeyMethod, prime *big.Int) ([]*SplitKey, error) {
+	if parts < 1 || parts > MaxSplitKeyParts {
+		return nil, invalidSplitKeyf("the Split Key Parts must be between 1 and %d", MaxSplitKeyParts)
+	}
+
+	material, err := splitKeyMaterial(kb)
+	if err != nil {
+		return nil, err
+	}
+
+	length := kb.CryptographicLength
+	if length == 0 {
+		length = len(material) * 8
+	}
+
+	if length != len(material)*8 {
+		return nil, invalidSplitKeyf("the key material isn't %d bits long", length)
+	}
+
+	if threshold < 1 || threshold > parts {
+		return nil, invalidSplitKeyf("the Split Key Threshold must be between 1 and the number of Split Key Parts")
+	}
+
+	var shares [][]byte
+
+	switch method {
+	case kmip14.SplitKeyMethodXOR:
+		if threshold != parts {
+			return nil, invalidSplitKeyf("the Split Key Threshold of the XOR method must be the number of Split Key Parts")
+		}
+
+		shares, err = splitXOR(rand.Reader, material, parts)
+	case kmip14.SplitKeyMethodPolynomialSharingGF2_16:
+		if parts > 0xffff {
+			return nil, invalidSplitKeyf("Polynomial Sharing GF(2^16) supports up to %d Split Key Parts", 0xffff)
+		}
+
+		shares, err = splitGF2_16(rand.Reader, material, parts, threshold)
+	case kmip14.SplitKeyMethodPolynomialSharingPrimeField:
+		if prime == nil || !prime.ProbablyPrime(20) {
+			return nil, invalidSplitKeyf("Polynomial Sharing Prime Field requires a prime Prime Field Size")
+		}
+
+		if new(big.Int).SetBytes(material).Cmp(prime) >= 0 || big.NewInt(int64(parts)).Cmp(prime) >= 0 {
+			return nil, invalidSplitKeyf("the Prime Field Size must be greater than the key material and the number of Split Key Parts")
+		}
+
+		shares, err = splitPrimeField(rand.Reader, material, parts, threshold, prime)
+	default:
+		return nil, invalidSplitKeyf("unsupported Split Key Method: %s", method.String())
+	}
+
+	if err != nil {
+		return nil, merry.Prepend(err, "splitting key")
+	}
+
+	if method != kmip14.SplitKeyMethodPolynomialSharingPrimeField {
+		prime = nil
+	}
+
+	splitKeys := make([]*SplitKey, parts)
+
+	for i, share := range shares {
+		splitKeys[i] = &SplitKey{
+			SplitKeyParts:     parts,
+			KeyPartIdentifier: i + 1,
+			SplitKeyThreshold: threshold,
+			SplitKeyMethod:    method,
+			PrimeFieldSize:    prime,
+			KeyBlock: KeyBlock{
+				KeyFormatType:          kmip14.KeyFormatTypeRaw,
+				KeyValue:               &KeyValue{KeyMaterial: share},
+				CryptographicAlgorithm: kb.CryptographicAlgorithm,
+				CryptographicLength:    length,
+			},
+		}
+	}
+
+	return splitKeys, nil
+}
+
+// JoinSplitKeys reconstructs the key material split by NewSplitKeys from at least the threshold of its parts.
+// The parts must have been split together into at most MaxSplitKeyParts parts, and have distinct Key Part
+// Identifiers.  It returns a Raw Key Block, with the Cryptographic Algorithm and Cryptographic Length of the
+// parts.
+func JoinSplitKeys(parts []*SplitKey) (*KeyBlock, error) {
+	if len(parts) == 0 {
+		return nil, invalidSplitKeyf("no Split Keys to join")
+	}
+
+	first := parts[0]
+
+	if first.SplitKeyParts < 1 || first.SplitKeyParts > MaxSplitKeyParts {
+		return nil, invalidSplitKeyf("the Split Key Parts must be between 1 and %d", MaxSplitKeyParts)
+	}
+
+	if first.KeyBlock.CryptographicLength <= 0 || first.KeyBlock.CryptographicLength%8 != 0 {
+		return nil, invalidSplitKeyf("the Cryptographic Length of the Split Keys must be a positive multiple of 8")
+	}
+
+	size := first.KeyBlock.CryptographicLength / 8
+	seen := map[int]bool{}
+	xs := make([]int, len(parts))
+	shares := make([][]byte, len(parts))
+
+	for i, part := range parts {
+		if part.SplitKeyParts != first.SplitKeyParts ||
+			part.SplitKeyThreshold != first.SplitKeyThreshold ||
+			part.SplitKeyMethod != first.SplitKeyMethod ||
+			part.KeyBlock.CryptographicAlgorithm != first.KeyBlock.CryptographicAlgorithm ||
+			part.KeyBlock.CryptographicLength != first.KeyBlock.CryptographicLength ||
+			(part.PrimeFieldSize == nil) != (first.PrimeFieldSize == nil) ||
+			(part.PrimeFieldSize != nil && part.PrimeFieldSize.Cmp(first.PrimeFieldSize) != 0) {
+			return nil, invalidSplitKeyf("the Split Keys weren't split from the same key")
+		}
+
+		if part.KeyPartIdentifier < 1 || part.KeyPartIdentifier > part.SplitKeyParts || seen[part.KeyPartIdentifier] {
+			return nil, invalidSplitKeyf("invalid or duplicate Key Part Identifier: %d", part.KeyPartIdentifier)
+		}
+
+		seen[part.KeyPartIdentifier] = true
+
+		share, err := splitKeyMaterial(&part.KeyBlock)
+		if err != nil {
+			return nil, err
+		}
+
+		xs[i], shares[i] = part.KeyPartIdentifier, share
+	}
+
+	if len(parts) < first.SplitKeyThreshold {
+		return nil, invalidSplitKeyf("%d Split Keys are required, only %d were given", first.SplitKeyThreshold, len(parts))
+	}
+
+	var material []byte
+
+	switch first.SplitKeyMethod {
+	case kmip14.SplitKeyMethodXOR:
+		if first.SplitKeyThreshold != first.SplitKeyParts {
+			return nil, invalidSplitKeyf("the XOR method requires the Split Key Threshold to equal the Split Key Parts")
+		}
+
+		if len(parts) != first.SplitKeyParts {
+			return nil, invalidSplitKeyf("the XOR method requires all %d Split Keys", first.SplitKeyParts)
+		}
+
+		material = make([]byte, size)
+
+		for _, share := range shares {
+			if len(share) != size {
+				return nil, invalidSplitKeyf("the Split Key's key material must be %d bytes", size)
+			}
+
+			for i := range material {
+				material[i] ^= share[i]
+			}
+		}
+	case kmip14.SplitKeyMethodPolynomialSharingGF2_16:
+		// the Key Part Identifiers are the x coordinates, so must be field elements
+		if first.SplitKeyParts > 0xffff {
+			return nil, invalidSplitKeyf("Polynomial Sharing GF(2^16) supports up to %d Split Key Parts", 0xffff)
+		}
+
+		// polynomial sharing only needs the threshold of shares
+		xs, shares = xs[:first.SplitKeyThreshold], shares[:first.SplitKeyThreshold]
+
+		for _, share := range shares {
+			if len(share) != size+size%2 {
+				return nil, invalidSplitKeyf("the Split Key's key material must be %d bytes", size+size%2)
+			}
+		}
+
+		material = joinGF2_16(xs, shares)[:size]
+	case kmip14.SplitKeyMethodPolynomialSharingPrimeField:
+		if first.PrimeFieldSize == nil || !first.PrimeFieldSize.ProbablyPrime(20) {
+			return nil, invalidSplitKeyf("Polynomial Sharing Prime Field requires a prime Prime Field Size")
+		}
+
+		xs, shares = xs[:first.SplitKeyThreshold], shares[:first.SplitKeyThreshold]
+
+		secret, err := joinPrimeField(xs, shares, first.PrimeFieldSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if secret.BitLen() > size*8 {
+			return nil, invalidSplitKeyf("the joined key material is longer than the Cryptographic Length")
+		}
+
+		material = secret.FillBytes(make([]byte, size))
+	default:
+		return nil, invalidSplitKeyf("unsupported Split Key Method: %s", first.SplitKeyMethod.String())
+	}
+
+	return &KeyBlock{
+		KeyFormatType:          kmip14.KeyFormatTypeRaw,
+		KeyValue:               &KeyValue{KeyMaterial: material},
+		CryptographicAlgorithm: first.KeyBlock.CryptographicAlgorithm,
+		CryptographicLength:    first.KeyBlock.CryptographicLength,
+	}, nil
+}
+
+// splitKeyMaterial returns the key material of a Key Block, which must be a byte string, or a Transparent
+// Symmetric Key.
+func splitKeyMaterial(kb *KeyBlock) ([]byte, error) {
+	if err := kb.checkKeyValue(); err != nil {
+		return nil, err
+	}
+
+	if kb.KeyFormatType == kmip14.KeyFormatTypeTransparentSymmetricKey {
+		key, err := convertSymmetricKey(kb, kmip14.KeyFormatTypeRaw)
+		if err != nil {
+			return nil, err
+		}
+
+		return key.([]byte), nil
+	}
+
+	b, ok := kb.KeyValue.KeyMaterial.([]byte)
+	if !ok {
+		return nil, keyFormatTypeNotSupportedf("unsupported Key Format Type for splitting: %s", kb.KeyFormatType.String())
+	}
+
+	if len(b) == 0 {
+		return nil, invalidSplitKeyf("no key material to split")
+	}
+
+	return b, nil
+}
+
+func splitXOR(r io.Reader, material []byte, parts int) ([][]byte, error) {
+	shares := make([][]byte, parts)
+	last := append([]byte(nil), material...)
+
+	for i := 0; i < parts-1; i++ {
+		shares[i] = make([]byte, len(material))
+		if _, err := io.ReadFull(r, shares[i]); err != nil {
+			return nil, err
+		}
+
+		for j := range last {
+			last[j] ^= shares[i][j]
+		}
+	}
+
+	shares[parts-1] = last
+
+	return shares, nil
+}
+
+// gf2_16Mul multiplies two elements of GF(2^16).
+func gf2_16Mul(a, b uint16) uint16 {
+	var p uint32
+
+	x := uint32(a)
+
+	for ; b != 0; b >>= 1 {
+		if b&1 != 0 {
+			p ^= x
+		}
+
+		x <<= 1
+		if x&0x10000 != 0 {
+			x ^= gf2_16Polynomial
+		}
+	}
+
+	return uint16(p)
+}
+
+// gf2_16Inv returns the multiplicative inverse of a non-zero element of GF(2^16), a^(2^16-2).
+func gf2_16Inv(a uint16) uint16 {
+	result := uint16(1)
+
+	for e := 0xfffe; e != 0; e >>= 1 {
+		if e&1 != 0 {
+			result = gf2_16Mul(result, a)
+		}
+
+		a = gf2_16Mul(a, a)
+	}
+
+	return result
+}
+
+func splitGF2_16(r io.Reader, material []byte, parts, threshold int) ([][]byte, error) {
+	if len(material)%2 != 0 {
+		material = append(append([]byte(nil), material...), 0)
+	}
+
+	shares := make([][]byte, parts)
+	for i := range shares {
+		shares[i] = make([]byte, len(material))
+	}
+
+	random := make([]byte, 2*(threshold-1))
+	coefficients := make([]uint16, threshold)
+
+	for w := 0; w < len(material); w += 2 {
+		if _, err := io.ReadFull(r, random); err != nil {
+			return nil, err
+		}
+
+		coefficients[0] = binary.BigEndian.Uint16(material[w:])
+		for k := 1; k < threshold; k++ {
+			coefficients[k] = binary.BigEndian.Uint16(random[2*(k-1):])
+		}
+
+		for i, share := range shares {
+			x := uint16(i + 1)
+			y := coefficients[threshold-1]
+
+			for k := threshold - 2; k >= 0; k-- {
+				y = gf2_16Mul(y, x) ^ coefficients[k]
+			}
+
+			binary.BigEndian.PutUint16(share[w:], y)
+		}
+	}
+
+	return shares, nil
+}
+
+// joinGF2_16 interpolates the shares' polynomials at 0.
+func joinGF2_16(xs []int, shares [][]byte) []byte {
+	// the Lagrange basis polynomials at 0.  Subtraction is XOR.
+	basis := make([]uint16, len(xs))
+
+	for i := range xs {
+		basis[i] = 1
+
+		for j := range xs {
+			if j != i {
+				basis[i] = gf2_16Mul(basis[i], gf2_16Mul(uint16(xs[j]), gf2_16Inv(uint16(xs[j]^xs[i]))))
+			}
+		}
+	}
+
+	material := make([]byte, len(shares[0]))
+
+	for w := 0; w < len(material); w += 2 {
+		var secret uint16
+
+		for i, share := range shares {
+			secret ^= gf2_16Mul(basis[i], binary.BigEndian.Uint16(share[w:]))
+		}
+
+		binary.BigEndian.PutUint16(material[w:], secret)
+	}
+
+	return material
+}
+
+func splitPrimeField(r io.Reader, material []byte, parts, threshold int, prime *big.Int) ([][]byte, error) {
+	coefficients := make([]*big.Int, threshold)
+	coefficients[0] = new(big.Int).SetBytes(material)
+
+	for k := 1; k < threshold; k++ {
+		c, err := rand.Int(r, prime)
+		if err != nil {
+			return nil, err
+		}
+
+		coefficients[k] = c
+	}
+
+	size := (prime.BitLen() + 7) / 8
+	shares := make([][]byte, parts)
+
+	for i := range shares {
+		x := big.NewInt(int64(i + 1))
+		y := new(big.Int).Set(coefficients[threshold-1])
+
+		for k := threshold - 2; k >= 0; k-- {
+			y.Mul(y, x).Add(y, coefficients[k]).Mod(y, prime)
+		}
+
+		shares[i] = y.FillBytes(make([]byte, size))
+	}
+
+	return shares, nil
+}
+
+// joinPrimeField interpolates the shares' polynomial at 0.  It fails if two Key Part Identifiers are equal
+// modulo the prime, so the interpolation has no inverse.
+func joinPrimeField(xs []int, shares [][]byte, prime *big.Int) (*big.Int, error) {
+	secret := new(big.Int)
+
+	for i := range xs {
+		num, den := big.NewInt(1), big.NewInt(1)
+
+		for j := range xs {
+			if j != i {
+				num.Mul(num, big.NewInt(int64(xs[j])))
+				den.Mul(den, big.NewInt(int64(xs[j]-xs[i])))
+			}
+		}
+
+		if den.Mod(den, prime).ModInverse(den, prime) == nil {
+			return nil, invalidSplitKeyf("the Key Part Identifiers aren't distinct in the prime field")
+		}
+
+		term := new(big.Int).SetBytes(shares[i])
+		term.Mul(term, num).Mul(term, den)
+		secret.Add(secret, term).Mod(secret, prime)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,212 @@
+package kmip
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitKeys(t *testing.T) {
+	// 2^521 - 1
+	prime := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 521), big.NewInt(1))
+
+	tests := []struct {
+		name              string
+		kb                *KeyBlock
+		parts, threshold  int
+		method            kmip14.SplitKeyMethod
+		prime             *big.Int
+		subsets           [][]int
+		insufficientParts []int
+	}{
+		{
+			name:              "XOR",
+			kb:                &KeyBlock{KeyFormatType: kmip14.KeyFormatTypeRaw, KeyValue: &KeyValue{KeyMaterial: RandomBytes(32)}, CryptographicAlgorithm: kmip14.CryptographicAlgorithmAES, CryptographicLength: 256},
+			parts:             3,
+			threshold:         3,
+			method:            kmip14.SplitKeyMethodXOR,
+			subsets:           [][]int{{0, 1, 2}, {2, 0, 1}},
+			insufficientParts: []int{0, 1},
+		},
+		{
+			name:              "GF(2^16)",
+			kb:                &KeyBlock{KeyFormatType: kmip14.KeyFormatTypeOpaque, KeyValue: &KeyValue{KeyMaterial: RandomBytes(17)}},
+			parts:             5,
+			threshold:         3,
+			method:            kmip14.SplitKeyMethodPolynomialSharingGF2_16,
+			subsets:           [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}},
+			insufficientParts: []int{1, 3},
+		},
+		{
+			name:              "prime field",
+			kb:                &KeyBlock{KeyFormatType: kmip14.KeyFormatTypeTransparentSymmetricKey, KeyValue: &KeyValue{KeyMaterial: &TransparentSymmetricKey{Key: append([]byte{0}, RandomBytes(31)...)}}, CryptographicAlgorithm: kmip14.CryptographicAlgorithmAES, CryptographicLength: 256},
+			parts:             4,
+			threshold:         2,
+			method:            kmip14.SplitKeyMethodPolynomialSharingPrimeField,
+			prime:             prime,
+			subsets:           [][]int{{0, 1}, {3, 1}, {2, 0, 3}},
+			insufficientParts: []int{2},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			material, err := splitKeyMaterial(tc.kb)
+			require.NoError(t, err)
+
+			splitKeys, err := NewSplitKeys(tc.kb, tc.parts, tc.threshold, tc.method, tc.prime)
+			require.NoError(t, err)
+			require.Len(t, splitKeys, tc.parts)
+
+			for i, splitKey := range splitKeys {
+				assert.Equal(t, i+1, splitKey.KeyPartIdentifier)
+				assert.Equal(t, tc.parts, splitKey.SplitKeyParts)
+				assert.Equal(t, tc.threshold, splitKey.SplitKeyThreshold)
+				assert.Equal(t, tc.method, splitKey.SplitKeyMethod)
+				assert.Equal(t, tc.prime, splitKey.PrimeFieldSize)
+				assert.Equal(t, kmip14.KeyFormatTypeRaw, splitKey.KeyBlock.KeyFormatType)
+				assert.Equal(t, tc.kb.CryptographicAlgorithm, splitKey.KeyBlock.CryptographicAlgorithm)
+				assert.Equal(t, len(material)*8, splitKey.KeyBlock.CryptographicLength)
+				assert.NotEqual(t, material, splitKey.KeyBlock.KeyValue.KeyMaterial)
+			}
+
+			for _, subset := range tc.subsets {
+				var parts []*SplitKey
+				for _, i := range subset {
+					parts = append(parts, splitKeys[i])
+				}
+
+				kb, err := JoinSplitKeys(parts)
+				require.NoError(t, err, "parts %v", subset)
+				assert.Equal(t, material, kb.KeyValue.KeyMaterial, "parts %v", subset)
+				assert.Equal(t, kmip14.KeyFormatTypeRaw, kb.KeyFormatType)
+				assert.Equal(t, tc.kb.CryptographicAlgorithm, kb.CryptographicAlgorithm)
+				assert.Equal(t, len(material)*8, kb.CryptographicLength)
+			}
+
+			var parts []*SplitKey
+			for _, i := range tc.insufficientParts {
+				parts = append(parts, splitKeys[i])
+			}
+
+			_, err = JoinSplitKeys(parts)
+			require.Error(t, err)
+			assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+
+			_, err = JoinSplitKeys([]*SplitKey{splitKeys[0], splitKeys[0]})
+			require.Error(t, err)
+			assert.Contains(t, merry.UserMessage(err), "duplicate Key Part Identifier")
+		})
+	}
+
+	t.Run("mismatched parts", func(t *testing.T) {
+		kb := &KeyBlock{KeyFormatType: kmip14.KeyFormatTypeRaw, KeyValue: &KeyValue{KeyMaterial: RandomBytes(16)}}
+
+		a, err := NewSplitKeys(kb, 2, 2, kmip14.SplitKeyMethodPolynomialSharingGF2_16, nil)
+		require.NoError(t, err)
+
+		b, err := NewSplitKeys(kb, 3, 2, kmip14.SplitKeyMethodPolynomialSharingGF2_16, nil)
+		require.NoError(t, err)
+
+		_, err = JoinSplitKeys([]*SplitKey{a[0], b[1]})
+		require.Error(t, err)
+		assert.Contains(t, merry.UserMessage(err), "weren't split from the same key")
+	})
+
+	t.Run("XOR threshold below parts", func(t *testing.T) {
+		kb := &KeyBlock{KeyFormatType: kmip14.KeyFormatTypeRaw, KeyValue: &KeyValue{KeyMaterial: RandomBytes(16)}}
+
+		parts, err := NewSplitKeys(kb, 3, 3, kmip14.SplitKeyMethodXOR, nil)
+		require.NoError(t, err)
+
+		for _, part := range parts {
+			part.SplitKeyThreshold = 2
+		}
+
+		_, err = JoinSplitKeys(parts)
+		require.Error(t, err)
+		assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+	})
+
+	t.Run("invalid prime field", func(t *testing.T) {
+		splitKey := func(id int, prime *big.Int) *SplitKey {
+			return &SplitKey{
+				SplitKeyParts:     5,
+				KeyPartIdentifier: id,
+				SplitKeyThreshold: 2,
+				SplitKeyMethod:    kmip14.SplitKeyMethodPolynomialSharingPrimeField,
+				PrimeFieldSize:    prime,
+				KeyBlock:          KeyBlock{KeyFormatType: kmip14.KeyFormatTypeRaw, KeyValue: &KeyValue{KeyMaterial: []byte{1}}, CryptographicLength: 8},
+			}
+		}
+
+		// not prime
+		_, err := JoinSplitKeys([]*SplitKey{splitKey(1, big.NewInt(4)), splitKey(2, big.NewInt(4))})
+		require.Error(t, err)
+		assert.Contains(t, merry.UserMessage(err), "prime Prime Field Size")
+
+		// 1 and 4 are equal modulo 3
+		_, err = JoinSplitKeys([]*SplitKey{splitKey(1, big.NewInt(3)), splitKey(4, big.NewInt(3))})
+		require.Error(t, err)
+		assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+	})
+
+	t.Run("too many parts", func(t *testing.T) {
+		kb := &KeyBlock{KeyFormatType: kmip14.KeyFormatTypeRaw, KeyValue: &KeyValue{KeyMaterial: RandomBytes(16)}}
+
+		parts, err := NewSplitKeys(kb, 3, 2, kmip14.SplitKeyMethodPolynomialSharingGF2_16, nil)
+		require.NoError(t, err)
+
+		// 1 and 0x10001 would both be 1 in GF(2^16)
+		for _, part := range parts {
+			part.SplitKeyParts = 0x10001
+		}
+
+		parts[1].KeyPartIdentifier = 0x10001
+
+		_, err = JoinSplitKeys(parts[:2])
+		require.Error(t, err)
+		assert.Contains(t, merry.UserMessage(err), "Split Key Parts must be between")
+	})
+
+	invalid := []struct {
+		name             string
+		parts, threshold int
+		method           kmip14.SplitKeyMethod
+		prime            *big.Int
+	}{
+		{name: "threshold above parts", parts: 2, threshold: 3, method: kmip14.SplitKeyMethodPolynomialSharingGF2_16},
+		{name: "zero threshold", parts: 2, threshold: 0, method: kmip14.SplitKeyMethodPolynomialSharingGF2_16},
+		{name: "XOR threshold", parts: 3, threshold: 2, method: kmip14.SplitKeyMethodXOR},
+		{name: "no prime", parts: 3, threshold: 2, method: kmip14.SplitKeyMethodPolynomialSharingPrimeField},
+		{name: "composite", parts: 3, threshold: 2, method: kmip14.SplitKeyMethodPolynomialSharingPrimeField, prime: new(big.Int).Lsh(big.NewInt(1), 300)},
+		{name: "small prime", parts: 3, threshold: 2, method: kmip14.SplitKeyMethodPolynomialSharingPrimeField, prime: big.NewInt(65537)},
+		{name: "GF(2^8)", parts: 3, threshold: 2, method: kmip14.SplitKeyMethodPolynomialSharingGF2_8},
+		{name: "zero parts", parts: 0, threshold: 0, method: kmip14.SplitKeyMethodXOR},
+		{name: "too many XOR parts", parts: math.MaxInt32, threshold: math.MaxInt32, method: kmip14.SplitKeyMethodXOR},
+		{name: "too many GF(2^16) parts", parts: math.MaxInt32, threshold: 2, method: kmip14.SplitKeyMethodPolynomialSharingGF2_16},
+		{name: "too many prime field parts", parts: math.MaxInt32, threshold: 2, method: kmip14.SplitKeyMethodPolynomialSharingPrimeField, prime: prime},
+	}
+
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			kb := &KeyBlock{KeyFormatType: kmip14.KeyFormatTypeRaw, KeyValue: &KeyValue{KeyMaterial: RandomBytes(16)}}
+
+			_, err := NewSplitKeys(kb, tc.parts, tc.threshold, tc.method, tc.prime)
+			require.Error(t, err)
+			assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+		})
+	}
+}
+
+func TestGF2_16(t *testing.T) {
+	// every non-zero element has an inverse, so the polynomial is irreducible
+	for a := 1; a <= 0xffff; a++ {
+		require.Equal(t, uint16(1), gf2_16Mul(uint16(a), gf2_16Inv(uint16(a))), "element %#x", a)
+	}
+}
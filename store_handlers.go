@@ -0,0 +1,1704 @@
+package kmip
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+)
+
+// StoreHandlers implements the object management operations on top of an ObjectStore: Create,
+// CreateKeyPair, ReKey, ReKeyKeyPair, DeriveKey, Certify, ReCertify, CreateSplitKey, JoinSplitKey, Register,
+// Get, GetAttributes, GetAttributeList, AddAttribute, ModifyAttribute, DeleteAttribute, Locate, Activate,
+// Revoke and Destroy.
+// Its methods have the signatures of the corresponding handler funcs, so they can be plugged into
+// the handlers individually:
+//
+//	mux.Handle(kmip14.OperationGet, &kmip.GetHandler{Get: h.Get})
+//
+// or registered all together with Handle.
+type StoreHandlers struct {
+	Store ObjectStore
+
+	// GenerateSymmetricKey generates the key material for Create, ReKey, and CreateSplitKey when it doesn't
+	// split an existing key.  If nil, they fail with Operation Not Supported.
+	GenerateSymmetricKey func(ctx context.Context, payload *CreateRequestPayload) (*SymmetricKey, error)
+
+	// GenerateKeyPair generates the key material for CreateKeyPair and ReKeyKeyPair.  If nil, they fail with
+	// Operation Not Supported.
+	GenerateKeyPair func(ctx context.Context, payload *CreateKeyPairRequestPayload) (*PrivateKey, *PublicKey, error)
+
+	// DeriveKeyMaterial derives size bytes of key material for DeriveKey, from the base objects.  The base
+	// objects are in the order of the request's Unique Identifiers, and may be used for derivation.  If nil,
+	// DeriveKey fails with Operation Not Supported.
+	DeriveKeyMaterial func(ctx context.Context, payload *DeriveKeyRequestPayload, base []*ManagedObject, size int) ([]byte, error)
+
+	// IssueCertificate issues the certificate for Certify and ReCertify.  The subject is the object holding the
+	// public key to certify: the Public Key for Certify, which is nil if the request doesn't identify one, or
+	// the existing Certificate for ReCertify.  If nil, they fail with Operation Not Supported.
+	IssueCertificate func(ctx context.Context, payload *CertifyRequestPayload, subject *ManagedObject) (*Certificate, error)
+
+	// WrapKey wraps the Key Block of a key returned by Get, as requested by the Key Wrapping Specification.
+	// The Key Value already holds the attributes named by the specification.  If nil, Get fails with
+	// Feature Not Supported when a wrapped key is requested.
+	WrapKey func(ctx context.Context, kb *KeyBlock, spec *KeyWrappingSpecification) error
+
+	// UnwrapKey unwraps the Key Block of a wrapped key imported by Register.  If nil, wrapped keys are
+	// stored as they are.
+	UnwrapKey func(ctx context.Context, kb *KeyBlock) error
+
+	// Lifecycle maintains the objects' states.
+	Lifecycle Lifecycle
+
+	// AttributeRules validates the attributes supplied by clients.  If nil, AttributeRules14 is used.
+	AttributeRules AttributeRules
+}
+
+func (h *StoreHandlers) attributeRules() AttributeRules {
+	if h.AttributeRules == nil {
+		return AttributeRules14
+	}
+
+	return h.AttributeRules
+}
+
+// Handle registers the handlers for all the operations implemented by StoreHandlers with the mux.
+func (h *StoreHandlers) Handle(mux *OperationMux) {
+	mux.Handle(kmip14.OperationCreate, &CreateHandler{Create: h.Create})
+	mux.Handle(kmip14.OperationCreateKeyPair, &CreateKeyPairHandler{CreateKeyPair: h.CreateKeyPair})
+	mux.Handle(kmip14.OperationReKey, &ReKeyHandler{ReKey: h.ReKey})
+	mux.Handle(kmip14.OperationReKeyKeyPair, &ReKeyKeyPairHandler{ReKeyKeyPair: h.ReKeyKeyPair})
+	mux.Handle(kmip14.OperationDeriveKey, &DeriveKeyHandler{DeriveKey: h.DeriveKey})
+	mux.Handle(kmip14.OperationCertify, &CertifyHandler{Certify: h.Certify})
+	mux.Handle(kmip14.OperationReCertify, &ReCertifyHandler{ReCertify: h.ReCertify})
+	mux.Handle(kmip14.OperationCreateSplitKey, &CreateSplitKeyHandler{CreateSplitKey: h.CreateSplitKey})
+	mux.Handle(kmip14.OperationJoinSplitKey, &JoinSplitKeyHandler{JoinSplitKey: h.JoinSplitKey})
+	mux.Handle(kmip14.OperationRegister, &RegisterHandler{RegisterFunc: h.Register})
+	mux.Handle(kmip14.OperationGet, &GetHandler{Get: h.Get})
+	mux.Handle(kmip14.OperationGetAttributes, &GetAttributesHandler{GetAttributes: h.GetAttributes})
+	mux.Handle(kmip14.OperationGetAttributeList, &GetAttributeListHandler{GetAttributeList: h.GetAttributeList})
+	mux.Handle(kmip14.OperationAddAttribute, &AddAttributeHandler{AddAttribute: h.AddAttribute})
+	mux.Handle(kmip14.OperationModifyAttribute, &ModifyAttributeHandler{ModifyAttribute: h.ModifyAttribute})
+	mux.Handle(kmip14.OperationDeleteAttribute, &DeleteAttributeHandler{DeleteAttribute: h.DeleteAttribute})
+	mux.Handle(kmip14.OperationLocate, &LocateHandler{Locate: h.Locate})
+	mux.Handle(kmip14.OperationActivate, &ActivateHandler{Activate: h.Activate})
+	mux.Handle(kmip14.OperationRevoke, &RevokeHandler{Revoke: h.Revoke})
+	mux.Handle(kmip14.OperationDestroy, &DestroyHandler{Destroy: h.Destroy})
+}
+
+// Create generates a new symmetric key with GenerateSymmetricKey, and stores it with the requested attributes.
+func (h *StoreHandlers) Create(ctx context.Context, payload *CreateRequestPayload) (*CreateResponsePayload, error) {
+	if payload.ObjectType != kmip14.ObjectTypeSymmetricKey {
+		return nil, WithResultReason(merry.UserErrorf("Create does not support Object Type %s", payload.ObjectType.String()), kmip14.ResultReasonInvalidField)
+	}
+
+	if h.GenerateSymmetricKey == nil {
+		return nil, WithResultReason(merry.UserError("key generation is not supported"), kmip14.ResultReasonOperationNotSupported)
+	}
+
+	if err := checkNoTemplateNames(&payload.TemplateAttribute); err != nil {
+		return nil, err
+	}
+
+	key, err := h.GenerateSymmetricKey(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := h.newStoredObject(key, payload.TemplateAttribute.Attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Store.Update(ctx, func(tx ObjectTx) error {
+		_, err := tx.Create(obj)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateResponsePayload{
+		ObjectType:       obj.ObjectType,
+		UniqueIdentifier: obj.UniqueIdentifier,
+	}, nil
+}
+
+// CreateKeyPair generates a new key pair with GenerateKeyPair, and stores the private and public keys.  Each
+// key gets the common attributes, and the attributes specific to it, which take precedence.  The keys
+// are linked to each other.
+func (h *StoreHandlers) CreateKeyPair(ctx context.Context, payload *CreateKeyPairRequestPayload) (*CreateKeyPairResponsePayload, error) {
+	if h.GenerateKeyPair == nil {
+		return nil, WithResultReason(merry.UserError("key pair generation is not supported"), kmip14.ResultReasonOperationNotSupported)
+	}
+
+	for _, ta := range []*TemplateAttribute{payload.CommonTemplateAttribute, payload.PrivateKeyTemplateAttribute, payload.PublicKeyTemplateAttribute} {
+		if ta == nil {
+			continue
+		}
+
+		if err := checkNoTemplateNames(ta); err != nil {
+			return nil, err
+		}
+	}
+
+	privKey, pubKey, err := h.GenerateKeyPair(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	privObj, err := h.newStoredObject(privKey, mergeAttributes(payload.CommonTemplateAttribute, payload.PrivateKeyTemplateAttribute))
+	if err != nil {
+		return nil, err
+	}
+
+	pubObj, err := h.newStoredObject(pubKey, mergeAttributes(payload.CommonTemplateAttribute, payload.PublicKeyTemplateAttribute))
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Store.Update(ctx, func(tx ObjectTx) error {
+		if _, err := tx.Create(privObj); err != nil {
+			return err
+		}
+
+		pubObj.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypePrivateKeyLink, LinkedObjectIdentifier: privObj.UniqueIdentifier})
+
+		if _, err := tx.Create(pubObj); err != nil {
+			return err
+		}
+
+		privObj.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypePublicKeyLink, LinkedObjectIdentifier: pubObj.UniqueIdentifier})
+
+		return tx.Put(privObj)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateKeyPairResponsePayload{
+		PrivateKeyUniqueIdentifier: privObj.UniqueIdentifier,
+		PublicKeyUniqueIdentifier:  pubObj.UniqueIdentifier,
+	}, nil
+}
+
+// ReKey generates a replacement for a symmetric key with GenerateSymmetricKey.  The replacement gets the attributes
+// of the existing key, except those set by the server for new objects, and the attributes in the request's
+// Template-Attribute, which take precedence.  Its Activation Date, Process Start Date, Protect Stop Date and
+// Deactivation Date are copied from the existing key, or shifted by the Offset, as described in 4.4.
+//
+// The existing key must be in a state which permits Re-key, and mustn't have been replaced already.  Its Names
+// are moved to the replacement, and the keys are linked to each other with Replacement Object and Replaced Object
+// Links.
+func (h *StoreHandlers) ReKey(ctx context.Context, payload *ReKeyRequestPayload) (*ReKeyResponsePayload, error) {
+	if h.GenerateSymmetricKey == nil {
+		return nil, WithResultReason(merry.UserError("key generation is not supported"), kmip14.ResultReasonOperationNotSupported)
+	}
+
+	ta := payload.TemplateAttribute
+	if ta == nil {
+		ta = &TemplateAttribute{}
+	}
+
+	if err := checkNoTemplateNames(ta); err != nil {
+		return nil, err
+	}
+
+	var existing *ManagedObject
+
+	err := h.Store.View(ctx, func(tx ObjectTx) error {
+		var err error
+
+		existing, err = tx.Get(payload.UniqueIdentifier)
+		if err != nil {
+			return err
+		}
+
+		if existing.ObjectType != kmip14.ObjectTypeSymmetricKey {
+			return WithResultReason(merry.UserErrorf("Re-key requires a Symmetric Key, not a %s", existing.ObjectType.String()), kmip14.ResultReasonInvalidField)
+		}
+
+		return h.checkReplaceable(existing, kmip14.OperationReKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := h.GenerateSymmetricKey(ctx, &CreateRequestPayload{
+		ObjectType:        kmip14.ObjectTypeSymmetricKey,
+		TemplateAttribute: TemplateAttribute{Attribute: mergeAttributes(&TemplateAttribute{Attribute: replacedAttributes(existing)}, ta)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var replacement *ManagedObject
+
+	err = h.Store.Update(ctx, func(tx ObjectTx) error {
+		// the key may have been changed since it was read
+		existing, err := tx.Get(payload.UniqueIdentifier)
+		if err != nil {
+			return err
+		}
+
+		if err := h.checkReplaceable(existing, kmip14.OperationReKey); err != nil {
+			return err
+		}
+
+		replacement, err = h.newReplacement(existing, key, ta.Attribute, payload.Offset)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Create(replacement); err != nil {
+			return err
+		}
+
+		h.replace(existing, replacement)
+
+		return tx.Put(existing)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReKeyResponsePayload{
+		UniqueIdentifier: replacement.UniqueIdentifier,
+	}, nil
+}
+
+// ReKeyKeyPair generates a replacement for a key pair with GenerateKeyPair.  The existing public key is the one
+// linked to the existing private key.  Each replacement key gets the attributes of the key it replaces, the
+// common attributes, and the attributes specific to it, as for ReKey.  The replacement keys are linked to each
+// other, and to the keys they replace.  Both existing keys must be in a state which permits Re-key Key Pair, and
+// mustn't have been replaced already.
+func (h *StoreHandlers) ReKeyKeyPair(ctx context.Context, payload *ReKeyKeyPairRequestPayload) (*ReKeyKeyPairResponsePayload, error) {
+	if h.GenerateKeyPair == nil {
+		return nil, WithResultReason(merry.UserError("key pair generation is not supported"), kmip14.ResultReasonOperationNotSupported)
+	}
+
+	for _, ta := range []*TemplateAttribute{payload.CommonTemplateAttribute, payload.PrivateKeyTemplateAttribute, payload.PublicKeyTemplateAttribute} {
+		if ta == nil {
+			continue
+		}
+
+		if err := checkNoTemplateNames(ta); err != nil {
+			return nil, err
+		}
+	}
+
+	var existingPriv, existingPub *ManagedObject
+
+	getKeyPair := func(tx ObjectTx) error {
+		var err error
+
+		existingPriv, err = tx.Get(payload.PrivateKeyUniqueIdentifier)
+		if err != nil {
+			return err
+		}
+
+		if existingPriv.ObjectType != kmip14.ObjectTypePrivateKey {
+			return WithResultReason(merry.UserErrorf("Re-key Key Pair requires a Private Key, not a %s", existingPriv.ObjectType.String()), kmip14.ResultReasonInvalidField)
+		}
+
+		pubID := existingPriv.LinkedObjectIdentifier(kmip14.LinkTypePublicKeyLink)
+		if pubID == "" {
+			return WithResultReason(merry.UserError("the Private Key has no Public Key Link"), kmip14.ResultReasonInvalidField)
+		}
+
+		existingPub, err = tx.Get(pubID)
+		if err != nil {
+			return err
+		}
+
+		if existingPub.ObjectType != kmip14.ObjectTypePublicKey {
+			return WithResultReason(merry.UserErrorf("the Private Key is linked to a %s, not a Public Key", existingPub.ObjectType.String()), kmip14.ResultReasonInvalidField)
+		}
+
+		if err := h.checkReplaceable(existingPriv, kmip14.OperationReKeyKeyPair); err != nil {
+			return err
+		}
+
+		return h.checkReplaceable(existingPub, kmip14.OperationReKeyKeyPair)
+	}
+
+	if err := h.Store.View(ctx, getKeyPair); err != nil {
+		return nil, err
+	}
+
+	privAttrs := mergeAttributes(payload.CommonTemplateAttribute, payload.PrivateKeyTemplateAttribute)
+	pubAttrs := mergeAttributes(payload.CommonTemplateAttribute, payload.PublicKeyTemplateAttribute)
+
+	privKey, pubKey, err := h.GenerateKeyPair(ctx, &CreateKeyPairRequestPayload{
+		PrivateKeyTemplateAttribute: &TemplateAttribute{Attribute: mergeAttributes(&TemplateAttribute{Attribute: replacedAttributes(existingPriv)}, &TemplateAttribute{Attribute: privAttrs})},
+		PublicKeyTemplateAttribute:  &TemplateAttribute{Attribute: mergeAttributes(&TemplateAttribute{Attribute: replacedAttributes(existingPub)}, &TemplateAttribute{Attribute: pubAttrs})},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var privObj, pubObj *ManagedObject
+
+	err = h.Store.Update(ctx, func(tx ObjectTx) error {
+		err := getKeyPair(tx)
+		if err != nil {
+			return err
+		}
+
+		privObj, err = h.newReplacement(existingPriv, privKey, privAttrs, payload.Offset)
+		if err != nil {
+			return err
+		}
+
+		pubObj, err = h.newReplacement(existingPub, pubKey, pubAttrs, payload.Offset)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Create(privObj); err != nil {
+			return err
+		}
+
+		pubObj.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypePrivateKeyLink, LinkedObjectIdentifier: privObj.UniqueIdentifier})
+
+		if _, err := tx.Create(pubObj); err != nil {
+			return err
+		}
+
+		privObj.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypePublicKeyLink, LinkedObjectIdentifier: pubObj.UniqueIdentifier})
+
+		if err := tx.Put(privObj); err != nil {
+			return err
+		}
+
+		h.replace(existingPriv, privObj)
+		h.replace(existingPub, pubObj)
+
+		if err := tx.Put(existingPriv); err != nil {
+			return err
+		}
+
+		return tx.Put(existingPub)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReKeyKeyPairResponsePayload{
+		PrivateKeyUniqueIdentifier: privObj.UniqueIdentifier,
+		PublicKeyUniqueIdentifier:  pubObj.UniqueIdentifier,
+	}, nil
+}
+
+// notReplacedAttributes are the attributes which aren't copied from an existing object to its replacement.  They
+// are set by the server for the replacement, or describe the existing object's key material or history.  The
+// dates which may be shifted by an Offset are handled by newReplacement.
+var notReplacedAttributes = map[string]bool{
+	kmip14.TagState.CanonicalName():                    true,
+	kmip14.TagInitialDate.CanonicalName():              true,
+	kmip14.TagLastChangeDate.CanonicalName():           true,
+	kmip14.TagActivationDate.CanonicalName():           true,
+	kmip14.TagProcessStartDate.CanonicalName():         true,
+	kmip14.TagProtectStopDate.CanonicalName():          true,
+	kmip14.TagDeactivationDate.CanonicalName():         true,
+	kmip14.TagDestroyDate.CanonicalName():              true,
+	kmip14.TagCompromiseOccurrenceDate.CanonicalName(): true,
+	kmip14.TagCompromiseDate.CanonicalName():           true,
+	kmip14.TagRevocationReason.CanonicalName():         true,
+	kmip14.TagArchiveDate.CanonicalName():              true,
+	kmip14.TagOriginalCreationDate.CanonicalName():     true,
+	kmip14.TagDigest.CanonicalName():                   true,
+	kmip14.TagLink.CanonicalName():                     true,
+	kmip14.TagFresh.CanonicalName():                    true,
+	kmip14.TagKeyValuePresent.CanonicalName():          true,
+	kmip14.TagRandomNumberGenerator.CanonicalName():    true,
+	kmip14.TagAlwaysSensitive.CanonicalName():          true,
+	kmip14.TagNeverExtractable.CanonicalName():         true,
+}
+
+// replacedAttributes returns the attributes of an existing object which are copied to its replacement.
+func replacedAttributes(existing *ManagedObject) []Attribute {
+	var attrs []Attribute
+
+	for _, attr := range existing.Attribute {
+		if !notReplacedAttributes[attr.AttributeName] {
+			attrs = append(attrs, attr)
+		}
+	}
+
+	return attrs
+}
+
+// newReplacement creates the replacement for an existing object, holding the new object.  It gets the attributes
+// supplied by the client, then the existing object's attributes which the client didn't supply.  The replacement
+// is linked to the existing object.
+func (h *StoreHandlers) newReplacement(existing *ManagedObject, object interface{}, attrs []Attribute, offset *time.Duration) (*ManagedObject, error) {
+	obj, err := h.newStoredObject(object, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	set := map[string]bool{}
+	for _, attr := range obj.Attribute {
+		set[attr.AttributeName] = true
+	}
+
+	for _, attr := range replacedAttributes(existing) {
+		if !set[attr.AttributeName] {
+			obj.Attribute = append(obj.Attribute, attr)
+		}
+	}
+
+	setDate := func(tag ttlv.Tag, d time.Time) {
+		if !set[tag.CanonicalName()] {
+			obj.SetAttributeTag(tag, d)
+		}
+	}
+
+	dates := []ttlv.Tag{kmip14.TagActivationDate, kmip14.TagProcessStartDate, kmip14.TagProtectStopDate, kmip14.TagDeactivationDate}
+
+	var shift time.Duration
+
+	if offset != nil {
+		activation := objectDate(obj, kmip14.TagInitialDate).Add(*offset)
+		setDate(kmip14.TagActivationDate, activation)
+
+		if d := objectDate(existing, kmip14.TagActivationDate); !d.IsZero() {
+			shift = activation.Sub(d)
+		}
+
+		dates = dates[1:]
+	}
+
+	for _, tag := range dates {
+		if d := objectDate(existing, tag); !d.IsZero() {
+			setDate(tag, d.Add(shift))
+		}
+	}
+
+	obj.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypeReplacedObjectLink, LinkedObjectIdentifier: existing.UniqueIdentifier})
+
+	h.Lifecycle.Apply(obj)
+
+	return obj, nil
+}
+
+// checkReplaceable returns an error if an existing object's state doesn't permit op, or it has already been
+// replaced.
+func (h *StoreHandlers) checkReplaceable(existing *ManagedObject, op kmip14.Operation) error {
+	if err := h.Lifecycle.CheckOperation(existing, op); err != nil {
+		return err
+	}
+
+	if id := existing.LinkedObjectIdentifier(kmip14.LinkTypeReplacementObjectLink); id != "" {
+		return WithResultReason(merry.UserErrorf("the object has already been replaced by %s", id), kmip14.ResultReasonPermissionDenied)
+	}
+
+	return nil
+}
+
+// replace links an existing object to its replacement, and removes its Names, which have been copied to the
+// replacement.
+func (h *StoreHandlers) replace(existing, replacement *ManagedObject) {
+	for i := 0; i < len(existing.Attribute); {
+		if attr := existing.Attribute[i]; attr.AttributeName == kmip14.TagName.CanonicalName() {
+			existing.RemoveAttribute(attr.AttributeName, attr.AttributeIndex)
+			continue
+		}
+
+		i++
+	}
+
+	existing.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypeReplacementObjectLink, LinkedObjectIdentifier: replacement.UniqueIdentifier})
+	existing.SetAttributeTag(kmip14.TagLastChangeDate, h.Lifecycle.now())
+}
+
+// MaxDerivedKeyLength is the longest key material, in bits, which DeriveKey derives.
+const MaxDerivedKeyLength = 8192
+
+// DeriveKey derives a Symmetric Key or Secret Data object from the base objects with DeriveKeyMaterial, and
+// stores it with the requested attributes.  The Template-Attribute must hold the Cryptographic Length of the
+// derived key material, which must be a whole number of bytes, and at most MaxDerivedKeyLength.  A Symmetric Key also needs a Cryptographic
+// Algorithm.  Secret Data can't have these attributes, so they are only used for the derivation.
+//
+// The base objects must be in a state which permits Derive Key, and their Cryptographic Usage Masks, if they have
+// them, must include Derive Key.  The derived object and the base objects are linked to each other.
+func (h *StoreHandlers) DeriveKey(ctx context.Context, payload *DeriveKeyRequestPayload) (*DeriveKeyResponsePayload, error) {
+	if h.DeriveKeyMaterial == nil {
+		return nil, WithResultReason(merry.UserError("key derivation is not supported"), kmip14.ResultReasonOperationNotSupported)
+	}
+
+	ta := &payload.TemplateAttribute
+
+	if err := checkNoTemplateNames(ta); err != nil {
+		return nil, err
+	}
+
+	var length int
+	if attr := ta.GetTag(kmip14.TagCryptographicLength); attr != nil {
+		if err := DecodeAttributeValue(attr.AttributeValue, &length); err != nil {
+			return nil, WithResultReason(merry.Prepend(err, "invalid Cryptographic Length"), kmip14.ResultReasonInvalidField)
+		}
+	}
+
+	if length <= 0 || length%8 != 0 {
+		return nil, WithResultReason(merry.UserError("Derive Key requires a Cryptographic Length which is a positive multiple of 8"), kmip14.ResultReasonInvalidField)
+	}
+
+	if length > MaxDerivedKeyLength {
+		return nil, WithResultReason(merry.UserErrorf("Derive Key can't derive more than %d bits", MaxDerivedKeyLength), kmip14.ResultReasonInvalidField)
+	}
+
+	attrs := ta.Attribute
+
+	var newObject func(material []byte) interface{}
+
+	switch payload.ObjectType {
+	case kmip14.ObjectTypeSymmetricKey:
+		var alg kmip14.CryptographicAlgorithm
+		if attr := ta.GetTag(kmip14.TagCryptographicAlgorithm); attr != nil {
+			if err := DecodeAttributeValue(attr.AttributeValue, &alg); err != nil {
+				return nil, WithResultReason(merry.Prepend(err, "invalid Cryptographic Algorithm"), kmip14.ResultReasonInvalidField)
+			}
+		}
+
+		if alg == 0 {
+			return nil, WithResultReason(merry.UserError("Derive Key requires the Cryptographic Algorithm of the Symmetric Key"), kmip14.ResultReasonInvalidField)
+		}
+
+		newObject = func(material []byte) interface{} {
+			return &SymmetricKey{KeyBlock: KeyBlock{
+				KeyFormatType:          kmip14.KeyFormatTypeRaw,
+				KeyValue:               &KeyValue{KeyMaterial: material},
+				CryptographicAlgorithm: alg,
+				CryptographicLength:    length,
+			}}
+		}
+	case kmip14.ObjectTypeSecretData:
+		attrs = nil
+
+		for _, attr := range ta.Attribute {
+			switch attr.AttributeName {
+			case kmip14.TagCryptographicLength.CanonicalName(), kmip14.TagCryptographicAlgorithm.CanonicalName():
+				continue
+			}
+
+			attrs = append(attrs, attr)
+		}
+
+		newObject = func(material []byte) interface{} {
+			return &SecretData{
+				SecretDataType: kmip14.SecretDataTypeSeed,
+				KeyBlock: KeyBlock{
+					KeyFormatType: kmip14.KeyFormatTypeOpaque,
+					KeyValue:      &KeyValue{KeyMaterial: material},
+				},
+			}
+		}
+	default:
+		return nil, WithResultReason(merry.UserErrorf("Derive Key does not support Object Type %s", payload.ObjectType.String()), kmip14.ResultReasonInvalidField)
+	}
+
+	base := make([]*ManagedObject, len(payload.UniqueIdentifier))
+
+	err := h.Store.View(ctx, func(tx ObjectTx) error {
+		for i, id := range payload.UniqueIdentifier {
+			obj, err := tx.Get(id)
+			if err != nil {
+				return err
+			}
+
+			if err := h.Lifecycle.CheckOperation(obj, kmip14.OperationDeriveKey); err != nil {
+				return err
+			}
+
+			if err := CheckUsageMask(obj, kmip14.CryptographicUsageMaskDeriveKey); err != nil {
+				return err
+			}
+
+			base[i] = obj
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	material, err := h.DeriveKeyMaterial(ctx, payload, base, length/8)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := h.newStoredObject(newObject(material), attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range payload.UniqueIdentifier {
+		obj.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypeDerivationBaseObjectLink, LinkedObjectIdentifier: id})
+	}
+
+	err = h.Store.Update(ctx, func(tx ObjectTx) error {
+		if _, err := tx.Create(obj); err != nil {
+			return err
+		}
+
+		for _, id := range payload.UniqueIdentifier {
+			baseObj, err := tx.Get(id)
+			if err != nil {
+				return err
+			}
+
+			baseObj.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypeDerivedKeyLink, LinkedObjectIdentifier: obj.UniqueIdentifier})
+			baseObj.SetAttributeTag(kmip14.TagLastChangeDate, h.Lifecycle.now())
+
+			if err := tx.Put(baseObj); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeriveKeyResponsePayload{
+		UniqueIdentifier: obj.UniqueIdentifier,
+	}, nil
+}
+
+// Certify issues a certificate with IssueCertificate, and stores it with the requested attributes.  If the request
+// identifies a Public Key, the certificate and the Public Key are linked to each other.
+func (h *StoreHandlers) Certify(ctx context.Context, payload *CertifyRequestPayload) (*CertifyResponsePayload, error) {
+	if h.IssueCertificate == nil {
+		return nil, WithResultReason(merry.UserError("certification is not supported"), kmip14.ResultReasonOperationNotSupported)
+	}
+
+	ta := payload.TemplateAttribute
+	if ta == nil {
+		ta = &TemplateAttribute{}
+	}
+
+	if err := checkNoTemplateNames(ta); err != nil {
+		return nil, err
+	}
+
+	var pub *ManagedObject
+
+	if payload.UniqueIdentifier != "" {
+		err := h.Store.View(ctx, func(tx ObjectTx) error {
+			var err error
+			pub, err = tx.Get(payload.UniqueIdentifier)
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if pub.ObjectType != kmip14.ObjectTypePublicKey {
+			return nil, WithResultReason(merry.UserErrorf("Certify requires a Public Key, not a %s", pub.ObjectType.String()), kmip14.ResultReasonInvalidField)
+		}
+	}
+
+	cert, err := h.IssueCertificate(ctx, payload, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := h.newStoredObject(cert, ta.Attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	if pub != nil {
+		obj.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypePublicKeyLink, LinkedObjectIdentifier: pub.UniqueIdentifier})
+	}
+
+	err = h.Store.Update(ctx, func(tx ObjectTx) error {
+		if _, err := tx.Create(obj); err != nil {
+			return err
+		}
+
+		if pub == nil {
+			return nil
+		}
+
+		return h.linkCertificate(tx, pub.UniqueIdentifier, obj.UniqueIdentifier)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertifyResponsePayload{
+		UniqueIdentifier: obj.UniqueIdentifier,
+	}, nil
+}
+
+// ReCertify issues a new certificate for the public key of an existing certificate with IssueCertificate.  The
+// new certificate gets the attributes of the existing one, and its dates are copied or shifted by the Offset, as
+// for ReKey.  The certificates are linked to each other, and the new certificate and the Public Key the existing
+// certificate is linked to, if any, are linked to each other.
+func (h *StoreHandlers) ReCertify(ctx context.Context, payload *ReCertifyRequestPayload) (*ReCertifyResponsePayload, error) {
+	if h.IssueCertificate == nil {
+		return nil, WithResultReason(merry.UserError("certification is not supported"), kmip14.ResultReasonOperationNotSupported)
+	}
+
+	ta := payload.TemplateAttribute
+	if ta == nil {
+		ta = &TemplateAttribute{}
+	}
+
+	if err := checkNoTemplateNames(ta); err != nil {
+		return nil, err
+	}
+
+	var existing *ManagedObject
+
+	err := h.Store.View(ctx, func(tx ObjectTx) error {
+		var err error
+		existing, err = tx.Get(payload.UniqueIdentifier)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.ObjectType != kmip14.ObjectTypeCertificate {
+		return nil, WithResultReason(merry.UserErrorf("Re-certify requires a Certificate, not a %s", existing.ObjectType.String()), kmip14.ResultReasonInvalidField)
+	}
+
+	pubID := existing.LinkedObjectIdentifier(kmip14.LinkTypePublicKeyLink)
+
+	cert, err := h.IssueCertificate(ctx, &CertifyRequestPayload{
+		UniqueIdentifier:       pubID,
+		CertificateRequestType: payload.CertificateRequestType,
+		CertificateRequest:     payload.CertificateRequest,
+		TemplateAttribute:      &TemplateAttribute{Attribute: mergeAttributes(&TemplateAttribute{Attribute: replacedAttributes(existing)}, ta)},
+	}, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	var replacement *ManagedObject
+
+	err = h.Store.Update(ctx, func(tx ObjectTx) error {
+		existing, err := tx.Get(payload.UniqueIdentifier)
+		if err != nil {
+			return err
+		}
+
+		replacement, err = h.newReplacement(existing, cert, ta.Attribute, payload.Offset)
+		if err != nil {
+			return err
+		}
+
+		if pubID != "" {
+			replacement.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypePublicKeyLink, LinkedObjectIdentifier: pubID})
+		}
+
+		if _, err := tx.Create(replacement); err != nil {
+			return err
+		}
+
+		h.replace(existing, replacement)
+
+		if err := tx.Put(existing); err != nil {
+			return err
+		}
+
+		if pubID == "" {
+			return nil
+		}
+
+		return h.linkCertificate(tx, pubID, replacement.UniqueIdentifier)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReCertifyResponsePayload{
+		UniqueIdentifier: replacement.UniqueIdentifier,
+	}, nil
+}
+
+// linkCertificate links a Public Key to a certificate for it.
+func (h *StoreHandlers) linkCertificate(tx ObjectTx, pubID, certID string) error {
+	pub, err := tx.Get(pubID)
+	if err != nil {
+		return err
+	}
+
+	pub.AddAttributeTag(kmip14.TagLink, Link{LinkType: kmip14.LinkTypeCertificateLink, LinkedObjectIdentifier: certID})
+	pub.SetAttributeTag(kmip14.TagLastChangeDate, h.Lifecycle.now())
+
+	return tx.Put(pub)
+}
+
+// CreateSplitKey splits a key into parts with NewSplitKeys, and stores each part as a Split Key with the
+// requested attributes.  The key is the existing Symmetric Key or Secret Data the request identifies, which must
+// be in a state which permits Get, or else a new Symmetric Key generated with GenerateSymmetricKey.  A generated
+// key is only stored as its parts.
+func (h *StoreHandlers) CreateSplitKey(ctx context.Context, payload *CreateSplitKeyRequestPayload) (*CreateSplitKeyResponsePayload, error) {
+	if err := checkNoTemplateNames(&payload.TemplateAttribute); err != nil {
+		return nil, err
+	}
+
+	var kb *KeyBlock
+
+	if payload.UniqueIdentifier != "" {
+		err := h.Store.View(ctx, func(tx ObjectTx) error {
+			obj, err := tx.Get(payload.UniqueIdentifier)
+			if err != nil {
+				return err
+			}
+
+			if obj.ObjectType != payload.ObjectType {
+				return WithResultReason(merry.UserErrorf("the object is a %s, not a %s", obj.ObjectType.String(), payload.ObjectType.String()), kmip14.ResultReasonInvalidField)
+			}
+
+			if err := h.Lifecycle.CheckOperation(obj, kmip14.OperationGet); err != nil {
+				return err
+			}
+
+			switch obj.ObjectType {
+			case kmip14.ObjectTypeSymmetricKey, kmip14.ObjectTypeSecretData:
+				kb = obj.KeyBlock()
+			default:
+				return WithResultReason(merry.UserErrorf("Create Split Key does not support Object Type %s", obj.ObjectType.String()), kmip14.ResultReasonInvalidField)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if payload.ObjectType != kmip14.ObjectTypeSymmetricKey {
+			return nil, WithResultReason(merry.UserErrorf("Create Split Key can't generate Object Type %s", payload.ObjectType.String()), kmip14.ResultReasonInvalidField)
+		}
+
+		if h.GenerateSymmetricKey == nil {
+			return nil, WithResultReason(merry.UserError("key generation is not supported"), kmip14.ResultReasonOperationNotSupported)
+		}
+
+		key, err := h.GenerateSymmetricKey(ctx, &CreateRequestPayload{
+			ObjectType:        payload.ObjectType,
+			TemplateAttribute: payload.TemplateAttribute,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		kb = &key.KeyBlock
+	}
+
+	splitKeys, err := NewSplitKeys(kb, payload.SplitKeyParts, payload.SplitKeyThreshold, payload.SplitKeyMethod, payload.PrimeFieldSize)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]*ManagedObject, len(splitKeys))
+
+	for i, splitKey := range splitKeys {
+		objs[i], err = h.newStoredObject(splitKey, payload.TemplateAttribute.Attribute)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = h.Store.Update(ctx, func(tx ObjectTx) error {
+		for _, obj := range objs {
+			if _, err := tx.Create(obj); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(objs))
+	for i, obj := range objs {
+		ids[i] = obj.UniqueIdentifier
+	}
+
+	return &CreateSplitKeyResponsePayload{
+		UniqueIdentifier: ids,
+	}, nil
+}
+
+// JoinSplitKey reconstructs a key from Split Keys with JoinSplitKeys, and stores it as a Symmetric Key or Secret
+// Data with the requested attributes.  The Split Keys must be in a state which permits Get.  A Symmetric Key gets
+// the Cryptographic Algorithm of the Split Keys, or else the one in the Template-Attribute.  Secret Data is
+// Opaque, and its Secret Data Type defaults to Seed.
+func (h *StoreHandlers) JoinSplitKey(ctx context.Context, payload *JoinSplitKeyRequestPayload) (*JoinSplitKeyResponsePayload, error) {
+	ta := &payload.TemplateAttribute
+
+	if err := checkNoTemplateNames(ta); err != nil {
+		return nil, err
+	}
+
+	splitKeys := make([]*SplitKey, len(payload.UniqueIdentifier))
+
+	err := h.Store.View(ctx, func(tx ObjectTx) error {
+		for i, id := range payload.UniqueIdentifier {
+			obj, err := tx.Get(id)
+			if err != nil {
+				return err
+			}
+
+			if obj.SplitKey == nil {
+				return WithResultReason(merry.UserErrorf("the object is a %s, not a Split Key", obj.ObjectType.String()), kmip14.ResultReasonInvalidField)
+			}
+
+			if err := h.Lifecycle.CheckOperation(obj, kmip14.OperationGet); err != nil {
+				return err
+			}
+
+			splitKeys[i] = obj.SplitKey
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	kb, err := JoinSplitKeys(splitKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	var object interface{}
+
+	switch payload.ObjectType {
+	case kmip14.ObjectTypeSymmetricKey:
+		if kb.CryptographicAlgorithm == 0 {
+			if attr := ta.GetTag(kmip14.TagCryptographicAlgorithm); attr != nil {
+				if err := DecodeAttributeValue(attr.AttributeValue, &kb.CryptographicAlgorithm); err != nil {
+					return nil, WithResultReason(merry.Prepend(err, "invalid Cryptographic Algorithm"), kmip14.ResultReasonInvalidField)
+				}
+			}
+		}
+
+		if kb.CryptographicAlgorithm == 0 {
+			return nil, WithResultReason(merry.UserError("Join Split Key requires the Cryptographic Algorithm of the Symmetric Key"), kmip14.ResultReasonInvalidField)
+		}
+
+		object = &SymmetricKey{KeyBlock: *kb}
+	case kmip14.ObjectTypeSecretData:
+		secretDataType := payload.SecretDataType
+		if secretDataType == 0 {
+			secretDataType = kmip14.SecretDataTypeSeed
+		}
+
+		object = &SecretData{
+			SecretDataType: secretDataType,
+			KeyBlock: KeyBlock{
+				KeyFormatType: kmip14.KeyFormatTypeOpaque,
+				KeyValue:      kb.KeyValue,
+			},
+		}
+	default:
+		return nil, WithResultReason(merry.UserErrorf("Join Split Key does not support Object Type %s", payload.ObjectType.String()), kmip14.ResultReasonInvalidField)
+	}
+
+	obj, err := h.newStoredObject(object, ta.Attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Store.Update(ctx, func(tx ObjectTx) error {
+		_, err := tx.Create(obj)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &JoinSplitKeyResponsePayload{
+		UniqueIdentifier: obj.UniqueIdentifier,
+	}, nil
+}
+
+// Register stores the object in the request, with the requested attributes.  Wrapped keys are unwrapped with
+// UnwrapKey.
+func (h *StoreHandlers) Register(ctx context.Context, payload *RegisterRequestPayload) (*RegisterResponsePayload, error) {
+	if err := checkNoTemplateNames(&payload.TemplateAttribute); err != nil {
+		return nil, err
+	}
+
+	var object interface{}
+
+	switch payload.ObjectType {
+	case kmip14.ObjectTypeCertificate:
+		object = payload.Certificate
+	case kmip14.ObjectTypeSymmetricKey:
+		object = payload.SymmetricKey
+	case kmip14.ObjectTypePrivateKey:
+		object = payload.PrivateKey
+	case kmip14.ObjectTypePublicKey:
+		object = payload.PublicKey
+	case kmip14.ObjectTypeSplitKey:
+		object = payload.SplitKey
+	case kmip14.ObjectTypeTemplate:
+		object = payload.Template
+	case kmip14.ObjectTypeSecretData:
+		object = payload.SecretData
+	case kmip14.ObjectTypeOpaqueObject:
+		object = payload.OpaqueObject
+	}
+
+	if object == nil || reflect.ValueOf(object).IsNil() {
+		return nil, WithResultReason(merry.UserErrorf("Object Type %s does not match type of cryptographic object provided", payload.ObjectType.String()), kmip14.ResultReasonInvalidField)
+	}
+
+	obj, err := h.newStoredObject(object, payload.TemplateAttribute.Attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	if kb := obj.KeyBlock(); kb != nil && kb.KeyWrappingData != nil && h.UnwrapKey != nil {
+		if err := h.UnwrapKey(ctx, kb); err != nil {
+			return nil, err
+		}
+	}
+
+	err = h.Store.Update(ctx, func(tx ObjectTx) error {
+		_, err := tx.Create(obj)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegisterResponsePayload{
+		UniqueIdentifier: obj.UniqueIdentifier,
+	}, nil
+}
+
+// Get returns the stored object.  Destroyed objects can't be retrieved.
+//
+// Keys are returned as they're stored, unless the request asks otherwise.  With the Not Wrapped Key Wrap Type, a
+// stored wrapped key is unwrapped with UnwrapKey.  The Key Format Type and Key Compression Type convert the key
+// with ConvertKeyBlock.  If the request has a Key Wrapping Specification, the key is then wrapped with WrapKey.
+func (h *StoreHandlers) Get(ctx context.Context, payload *GetRequestPayload) (*GetResponsePayload, error) {
+	var obj *ManagedObject
+
+	err := h.Store.View(ctx, func(tx ObjectTx) error {
+		var err error
+		obj, err = tx.Get(payload.UniqueIdentifier)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.Lifecycle.CheckOperation(obj, kmip14.OperationGet); err != nil {
+		return nil, err
+	}
+
+	if err := h.formatKey(ctx, obj, payload); err != nil {
+		return nil, err
+	}
+
+	return &GetResponsePayload{
+		ObjectType:       obj.ObjectType,
+		UniqueIdentifier: obj.UniqueIdentifier,
+		Certificate:      obj.Certificate,
+		SymmetricKey:     obj.SymmetricKey,
+		PrivateKey:       obj.PrivateKey,
+		PublicKey:        obj.PublicKey,
+		SplitKey:         obj.SplitKey,
+		Template:         obj.Template,
+		SecretData:       obj.SecretData,
+		OpaqueObject:     obj.OpaqueObject,
+	}, nil
+}
+
+// formatKey applies the Key Wrap Type, Key Format Type, Key Compression Type and Key Wrapping Specification of a
+// Get request to the object's Key Block.
+func (h *StoreHandlers) formatKey(ctx context.Context, obj *ManagedObject, payload *GetRequestPayload) error {
+	if payload.KeyWrapType == 0 && payload.KeyFormatType == 0 && payload.KeyCompressionType == 0 && payload.KeyWrappingSpecification == nil {
+		return nil
+	}
+
+	kb := obj.KeyBlock()
+	if kb == nil {
+		return WithResultReason(merry.UserErrorf("Object Type %s has no Key Block", obj.ObjectType.String()), kmip14.ResultReasonInvalidField)
+	}
+
+	switch payload.KeyWrapType {
+	case kmip14.KeyWrapTypeAsRegistered:
+		if payload.KeyFormatType != 0 || payload.KeyCompressionType != 0 || payload.KeyWrappingSpecification != nil {
+			return WithResultReason(merry.UserError("a key can't be converted or wrapped As Registered"), kmip14.ResultReasonInvalidField)
+		}
+
+		return nil
+	case kmip14.KeyWrapTypeNotWrapped:
+		if kb.KeyWrappingData != nil {
+			if h.UnwrapKey == nil {
+				return WithResultReason(merry.UserError("key unwrapping is not supported"), kmip14.ResultReasonFeatureNotSupported)
+			}
+
+			if err := h.UnwrapKey(ctx, kb); err != nil {
+				return err
+			}
+		}
+	}
+
+	if payload.KeyFormatType != 0 || payload.KeyCompressionType != 0 {
+		converted, err := ConvertKeyBlock(obj.ObjectType, kb, payload.KeyFormatType, payload.KeyCompressionType)
+		if err != nil {
+			return err
+		}
+
+		*kb = *converted
+	}
+
+	if payload.KeyWrappingSpecification != nil {
+		return h.wrapKey(ctx, obj, kb, payload.KeyWrappingSpecification)
+	}
+
+	return nil
+}
+
+// wrapKey adds the attributes named by the Key Wrapping Specification to the object's Key Value, and wraps
+// it with WrapKey.
+func (h *StoreHandlers) wrapKey(ctx context.Context, obj *ManagedObject, kb *KeyBlock, spec *KeyWrappingSpecification) error {
+	if kb.KeyValue == nil {
+		return WithResultReason(merry.UserError("the object has no Key Value"), kmip14.ResultReasonKeyValueNotPresent)
+	}
+
+	if kb.KeyWrappingData != nil {
+		return WithResultReason(merry.UserError("the key is stored wrapped, and can't be wrapped again"), kmip14.ResultReasonInvalidField)
+	}
+
+	if h.WrapKey == nil {
+		return WithResultReason(merry.UserError("key wrapping is not supported"), kmip14.ResultReasonFeatureNotSupported)
+	}
+
+	for _, name := range spec.AttributeName {
+		for _, attr := range obj.Attributes() {
+			if attr.AttributeName == name {
+				kb.KeyValue.Attribute = append(kb.KeyValue.Attribute, attr)
+			}
+		}
+	}
+
+	return h.WrapKey(ctx, kb, spec)
+}
+
+// GetAttributes returns the requested attributes of the stored object, or all its attributes if none are requested.
+func (h *StoreHandlers) GetAttributes(ctx context.Context, payload *GetAttributesRequestPayload) (*GetAttributesResponsePayload, error) {
+	var obj *ManagedObject
+
+	err := h.Store.View(ctx, func(tx ObjectTx) error {
+		var err error
+		obj, err = tx.Get(payload.UniqueIdentifier)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.Lifecycle.Apply(obj)
+
+	attrs := obj.Attributes()
+
+	if len(payload.AttributeName) > 0 {
+		selected := attrs[:0]
+
+		for _, attr := range attrs {
+			for _, name := range payload.AttributeName {
+				if attr.AttributeName == name {
+					selected = append(selected, attr)
+					break
+				}
+			}
+		}
+
+		attrs = selected
+	}
+
+	return &GetAttributesResponsePayload{
+		UniqueIdentifier: obj.UniqueIdentifier,
+		Attribute:        attrs,
+	}, nil
+}
+
+// GetAttributeList returns the names of the stored object's attributes.  Each name is listed once, however
+// many instances the attribute has.
+func (h *StoreHandlers) GetAttributeList(ctx context.Context, payload *GetAttributeListRequestPayload) (*GetAttributeListResponsePayload, error) {
+	var obj *ManagedObject
+
+	err := h.Store.View(ctx, func(tx ObjectTx) error {
+		var err error
+		obj, err = tx.Get(payload.UniqueIdentifier)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.Lifecycle.Apply(obj)
+
+	resp := GetAttributeListResponsePayload{UniqueIdentifier: obj.UniqueIdentifier}
+	seen := map[string]bool{}
+
+	for _, attr := range obj.Attributes() {
+		if !seen[attr.AttributeName] {
+			seen[attr.AttributeName] = true
+			resp.AttributeName = append(resp.AttributeName, attr.AttributeName)
+		}
+	}
+
+	return &resp, nil
+}
+
+// AddAttribute adds an attribute instance to the stored object, as validated by AttributeRules.AddAttribute.
+// The response holds the instance with the Attribute Index it was given.
+func (h *StoreHandlers) AddAttribute(ctx context.Context, payload *AddAttributeRequestPayload) (*AddAttributeResponsePayload, error) {
+	var added Attribute
+
+	err := h.UpdateObject(ctx, payload.UniqueIdentifier, func(obj *ManagedObject) error {
+		attr, err := h.attributeRules().AddAttribute(obj, payload.Attribute)
+		if err != nil {
+			return err
+		}
+
+		added = *attr
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AddAttributeResponsePayload{
+		UniqueIdentifier: payload.UniqueIdentifier,
+		Attribute:        added,
+	}, nil
+}
+
+// ModifyAttribute changes the value of an attribute instance of the stored object, as validated by
+// AttributeRules.ModifyAttribute.
+func (h *StoreHandlers) ModifyAttribute(ctx context.Context, payload *ModifyAttributeRequestPayload) (*ModifyAttributeResponsePayload, error) {
+	var modified Attribute
+
+	err := h.UpdateObject(ctx, payload.UniqueIdentifier, func(obj *ManagedObject) error {
+		attr, err := h.attributeRules().ModifyAttribute(obj, payload.Attribute)
+		if err != nil {
+			return err
+		}
+
+		modified = *attr
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ModifyAttributeResponsePayload{
+		UniqueIdentifier: payload.UniqueIdentifier,
+		Attribute:        modified,
+	}, nil
+}
+
+// DeleteAttribute deletes an attribute instance of the stored object, as validated by
+// AttributeRules.DeleteAttribute.  The response holds the deleted instance.
+func (h *StoreHandlers) DeleteAttribute(ctx context.Context, payload *DeleteAttributeRequestPayload) (*DeleteAttributeResponsePayload, error) {
+	var deleted Attribute
+
+	err := h.UpdateObject(ctx, payload.UniqueIdentifier, func(obj *ManagedObject) error {
+		attr, err := h.attributeRules().DeleteAttribute(obj, payload.AttributeName, payload.AttributeIndex)
+		if err != nil {
+			return err
+		}
+
+		deleted = *attr
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteAttributeResponsePayload{
+		UniqueIdentifier: payload.UniqueIdentifier,
+		Attribute:        deleted,
+	}, nil
+}
+
+// UpdateObject changes the stored object in a transaction.  The object's state is brought up to date with
+// Lifecycle.Apply before fn is called.  If fn succeeds, the object's Last Change Date is set, the lifecycle is
+// applied again, in case fn changed the object's dates, and the object is stored.
+//
+// It's intended for operations which change an object's attributes, like the attribute operations of other
+// protocol versions, which can't be expressed with the StoreHandlers' payloads.
+func (h *StoreHandlers) UpdateObject(ctx context.Context, id string, fn func(obj *ManagedObject) error) error {
+	return h.Store.Update(ctx, func(tx ObjectTx) error {
+		obj, err := tx.Get(id)
+		if err != nil {
+			return err
+		}
+
+		h.Lifecycle.Apply(obj)
+
+		if err := fn(obj); err != nil {
+			return err
+		}
+
+		obj.SetAttributeTag(kmip14.TagLastChangeDate, h.Lifecycle.now())
+		h.Lifecycle.Apply(obj)
+
+		return tx.Put(obj)
+	})
+}
+
+// storageStatusMaskDestroyed is the Storage Status Mask bit which selects destroyed objects.  It was added in 2.0,
+// so 1.x requests never locate destroyed objects.
+const storageStatusMaskDestroyed kmip14.StorageStatusMask = 0x00000004
+
+// Locate returns the identifiers of the stored objects which match the attributes in the request, as described
+// by ObjectMatcher.  The located objects are ordered by Initial Date, most recent first, so that clients can
+// page through them with Offset Items and Maximum Items.  The response's Located Items is the number of matching
+// objects, before paging.
+//
+// Objects with an Archive Date are archived; destroyed objects are only located if the Storage Status Mask
+// includes the Destroyed Storage bit added in 2.0; other objects are on-line.  If the mask is omitted, only
+// on-line objects are located.
+//
+// The Object Group Member field requires an Object Group attribute.  Group Member Fresh locates the members of
+// the group which are still fresh, i.e. whose Fresh attribute is true or unset, and marks the returned members
+// as no longer fresh.  Group Member Default locates the group's default member: the most recent member which
+// has been returned as fresh, or, if there isn't one, the most recent member.
+func (h *StoreHandlers) Locate(ctx context.Context, payload *LocateRequestPayload) (*LocateResponsePayload, error) {
+	matcher, err := NewObjectMatcher(payload.Attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.ObjectGroupMember != 0 && !hasAttribute(payload.Attribute, kmip14.TagObjectGroup) {
+		return nil, WithResultReason(merry.UserError("Object Group Member requires an Object Group attribute"), kmip14.ResultReasonInvalidField)
+	}
+
+	mask := payload.StorageStatusMask
+	if mask == 0 {
+		mask = kmip14.StorageStatusMaskOnLineStorage
+	}
+
+	var (
+		located []*ManagedObject
+		total   int
+	)
+
+	locate := func(tx ObjectTx) error {
+		located = nil
+
+		err := tx.ForEach(func(obj *ManagedObject) error {
+			h.Lifecycle.Apply(obj)
+
+			if storageStatus(obj)&mask != 0 && matcher.Match(obj) {
+				located = append(located, obj)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// ForEach returns objects in the order they were created, so the most recent objects are last
+		for i, j := 0, len(located)-1; i < j; i, j = i+1, j-1 {
+			located[i], located[j] = located[j], located[i]
+		}
+
+		sort.SliceStable(located, func(i, j int) bool {
+			return objectDate(located[i], kmip14.TagInitialDate).After(objectDate(located[j], kmip14.TagInitialDate))
+		})
+
+		switch payload.ObjectGroupMember {
+		case kmip14.ObjectGroupMemberGroupMemberFresh:
+			located = filterObjects(located, isFresh)
+		case kmip14.ObjectGroupMemberGroupMemberDefault:
+			if stale := filterObjects(located, func(obj *ManagedObject) bool { return !isFresh(obj) }); len(stale) > 0 {
+				located = stale
+			}
+
+			if len(located) > 1 {
+				located = located[:1]
+			}
+		}
+
+		total = len(located)
+
+		if payload.OffsetItems > 0 {
+			if payload.OffsetItems >= len(located) {
+				located = nil
+			} else {
+				located = located[payload.OffsetItems:]
+			}
+		}
+
+		if payload.MaximumItems > 0 && len(located) > payload.MaximumItems {
+			located = located[:payload.MaximumItems]
+		}
+
+		if payload.ObjectGroupMember != kmip14.ObjectGroupMemberGroupMemberFresh {
+			return nil
+		}
+
+		for _, obj := range located {
+			obj.SetAttributeTag(kmip14.TagFresh, false)
+
+			if err := tx.Put(obj); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if payload.ObjectGroupMember == kmip14.ObjectGroupMemberGroupMemberFresh {
+		err = h.Store.Update(ctx, locate)
+	} else {
+		err = h.Store.View(ctx, locate)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &LocateResponsePayload{
+		LocatedItems: total,
+	}
+
+	for _, obj := range located {
+		resp.UniqueIdentifier = append(resp.UniqueIdentifier, obj.UniqueIdentifier)
+	}
+
+	return resp, nil
+}
+
+// storageStatus returns the Storage Status Mask bit which selects the object.
+func storageStatus(obj *ManagedObject) kmip14.StorageStatusMask {
+	switch {
+	case obj.GetAttributeTag(kmip14.TagArchiveDate) != nil:
+		return kmip14.StorageStatusMaskArchivalStorage
+	case ObjectState(obj) == kmip14.StateDestroyed, ObjectState(obj) == kmip14.StateDestroyedCompromised:
+		return storageStatusMaskDestroyed
+	default:
+		return kmip14.StorageStatusMaskOnLineStorage
+	}
+}
+
+// isFresh returns true if the object's Fresh attribute is true, or unset.
+func isFresh(obj *ManagedObject) bool {
+	fresh := true
+
+	if attr := obj.GetAttributeTag(kmip14.TagFresh); attr != nil {
+		_ = DecodeAttributeValue(attr.AttributeValue, &fresh)
+	}
+
+	return fresh
+}
+
+func filterObjects(objs []*ManagedObject, keep func(obj *ManagedObject) bool) []*ManagedObject {
+	var kept []*ManagedObject
+
+	for _, obj := range objs {
+		if keep(obj) {
+			kept = append(kept, obj)
+		}
+	}
+
+	return kept
+}
+
+func hasAttribute(attrs []Attribute, tag ttlv.Tag) bool {
+	for _, attr := range attrs {
+		if attr.AttributeName == tag.CanonicalName() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Activate changes the state of a Pre-Active object to Active.
+func (h *StoreHandlers) Activate(ctx context.Context, payload *ActivateRequestPayload) (*ActivateResponsePayload, error) {
+	err := h.Store.Update(ctx, func(tx ObjectTx) error {
+		obj, err := tx.Get(payload.UniqueIdentifier)
+		if err != nil {
+			return err
+		}
+
+		if err := h.Lifecycle.Activate(obj); err != nil {
+			return err
+		}
+
+		return tx.Put(obj)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ActivateResponsePayload{
+		UniqueIdentifier: payload.UniqueIdentifier,
+	}, nil
+}
+
+// Revoke changes the state of an object to Compromised if the revocation reason is a compromise, or
+// to Deactivated otherwise.
+func (h *StoreHandlers) Revoke(ctx context.Context, payload *RevokeRequestPayload) (*RevokeResponsePayload, error) {
+	err := h.Store.Update(ctx, func(tx ObjectTx) error {
+		obj, err := tx.Get(payload.UniqueIdentifier)
+		if err != nil {
+			return err
+		}
+
+		if err := h.Lifecycle.Revoke(obj, payload.RevocationReason, payload.CompromiseOccurrenceDate); err != nil {
+			return err
+		}
+
+		return tx.Put(obj)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RevokeResponsePayload{
+		UniqueIdentifier: payload.UniqueIdentifier,
+	}, nil
+}
+
+// Destroy destroys the object's key material, and moves it to the Destroyed or Destroyed Compromised state.  The
+// object's attributes are retained.  Objects without a State, like Templates, are deleted from the store.
+func (h *StoreHandlers) Destroy(ctx context.Context, payload *DestroyRequestPayload) (*DestroyResponsePayload, error) {
+	err := h.Store.Update(ctx, func(tx ObjectTx) error {
+		obj, err := tx.Get(payload.UniqueIdentifier)
+		if err != nil {
+			return err
+		}
+
+		if ObjectState(obj) == 0 {
+			return tx.Delete(payload.UniqueIdentifier)
+		}
+
+		if err := h.Lifecycle.Destroy(obj); err != nil {
+			return err
+		}
+
+		return tx.Put(obj)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DestroyResponsePayload{
+		UniqueIdentifier: payload.UniqueIdentifier,
+	}, nil
+}
+
+// newStoredObject creates a ManagedObject for a new object, with the attributes supplied by the client, and
+// the attributes set by the server when an object is created.  The client's attributes are validated
+// against the AttributeRules.
+func (h *StoreHandlers) newStoredObject(object interface{}, attrs []Attribute) (*ManagedObject, error) {
+	obj := &ManagedObject{}
+	if err := obj.SetObject(object); err != nil {
+		return nil, err
+	}
+
+	if obj.Object() == nil {
+		return nil, merry.New("no object")
+	}
+
+	clientAttrs := make([]Attribute, 0, len(attrs))
+
+	for _, attr := range attrs {
+		switch attr.AttributeName {
+		case kmip14.TagUniqueIdentifier.CanonicalName(), kmip14.TagObjectType.CanonicalName():
+			// held in the ManagedObject's own fields
+			continue
+		}
+
+		clientAttrs = append(clientAttrs, attr)
+	}
+
+	if err := h.attributeRules().InitAttributes(obj, clientAttrs); err != nil {
+		return nil, err
+	}
+
+	if kb := obj.KeyBlock(); kb != nil {
+		if kb.CryptographicAlgorithm != 0 && obj.GetAttributeTag(kmip14.TagCryptographicAlgorithm) == nil {
+			obj.SetAttributeTag(kmip14.TagCryptographicAlgorithm, kb.CryptographicAlgorithm)
+		}
+
+		if kb.CryptographicLength != 0 && obj.GetAttributeTag(kmip14.TagCryptographicLength) == nil {
+			obj.SetAttributeTag(kmip14.TagCryptographicLength, kb.CryptographicLength)
+		}
+	}
+
+	h.Lifecycle.Init(obj)
+
+	return obj, nil
+}
+
+// mergeAttributes combines the attributes from the template attributes.  Attributes in later
+// template attributes replace attributes with the same name in earlier ones.
+func mergeAttributes(tas ...*TemplateAttribute) []Attribute {
+	var attrs []Attribute
+
+	for _, ta := range tas {
+		if ta == nil {
+			continue
+		}
+
+		replaced := map[string]bool{}
+
+		for _, attr := range ta.Attribute {
+			if !replaced[attr.AttributeName] {
+				replaced[attr.AttributeName] = true
+
+				kept := attrs[:0]
+
+				for _, a := range attrs {
+					if a.AttributeName != attr.AttributeName {
+						kept = append(kept, a)
+					}
+				}
+
+				attrs = kept
+			}
+
+			attrs = append(attrs, attr)
+		}
+	}
+
+	return attrs
+}
+
+// checkNoTemplateNames rejects requests which reference Template objects by name.  Templates are
+// deprecated, and not supported by StoreHandlers.
+func checkNoTemplateNames(ta *TemplateAttribute) error {
+	if len(ta.Name) > 0 {
+		return WithResultReason(merry.UserError("Template references are not supported"), kmip14.ResultReasonInvalidField)
+	}
+
+	return nil
+}
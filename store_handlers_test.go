@@ -0,0 +1,713 @@
+package kmip
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ansel1/merry"
+	"github.com/gemalto/kmip-go/kmip14"
+	"github.com/gemalto/kmip-go/ttlv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreHandlers(t *testing.T) {
+	h := &StoreHandlers{Store: &MemoryObjectStore{}}
+	mux := &OperationMux{}
+	h.Handle(mux)
+
+	addr := startTestServer(t, &Server{}, mux)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	secret := newTestSecretData("secret")
+
+	// Get and Destroy use the ID Placeholder set by Register
+	resp := roundTrip(t, conn, newTestRequestMessage(
+		RequestBatchItem{
+			Operation: kmip14.OperationRegister,
+			RequestPayload: RegisterRequestPayload{
+				ObjectType: kmip14.ObjectTypeSecretData,
+				SecretData: secret.SecretData,
+				TemplateAttribute: TemplateAttribute{
+					Attribute: []Attribute{NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group1")},
+				},
+			},
+		},
+		RequestBatchItem{
+			Operation:      kmip14.OperationGet,
+			RequestPayload: GetRequestPayload{},
+		},
+		RequestBatchItem{
+			Operation:      kmip14.OperationDestroy,
+			RequestPayload: DestroyRequestPayload{},
+		},
+		RequestBatchItem{
+			Operation:      kmip14.OperationGet,
+			RequestPayload: GetRequestPayload{},
+		},
+	))
+	require.Len(t, resp.BatchItem, 4)
+
+	var regResp RegisterResponsePayload
+
+	require.Equal(t, kmip14.ResultStatusSuccess, resp.BatchItem[0].ResultStatus, resp.BatchItem[0].ResultMessage)
+	require.NoError(t, ttlv.Unmarshal(resp.BatchItem[0].ResponsePayload.(ttlv.TTLV), &regResp))
+	assert.NotEmpty(t, regResp.UniqueIdentifier)
+
+	var getResp GetResponsePayload
+
+	require.Equal(t, kmip14.ResultStatusSuccess, resp.BatchItem[1].ResultStatus, resp.BatchItem[1].ResultMessage)
+	require.NoError(t, ttlv.Unmarshal(resp.BatchItem[1].ResponsePayload.(ttlv.TTLV), &getResp))
+	assert.Equal(t, regResp.UniqueIdentifier, getResp.UniqueIdentifier)
+	assert.Equal(t, kmip14.ObjectTypeSecretData, getResp.ObjectType)
+	require.NotNil(t, getResp.SecretData)
+	assert.Equal(t, []byte("secret"), getResp.SecretData.KeyBlock.KeyValue.KeyMaterial)
+
+	require.Equal(t, kmip14.ResultStatusSuccess, resp.BatchItem[2].ResultStatus, resp.BatchItem[2].ResultMessage)
+
+	// destroyed objects keep their attributes, but can't be retrieved
+	assert.Equal(t, kmip14.ResultStatusOperationFailed, resp.BatchItem[3].ResultStatus)
+	assert.Equal(t, kmip14.ResultReasonPermissionDenied, resp.BatchItem[3].ResultReason)
+
+	// Create needs a key generator
+	createReq := RequestBatchItem{
+		Operation: kmip14.OperationCreate,
+		RequestPayload: CreateRequestPayload{
+			ObjectType: kmip14.ObjectTypeSymmetricKey,
+			TemplateAttribute: TemplateAttribute{
+				Attribute: []Attribute{
+					NewAttributeFromTag(kmip14.TagCryptographicAlgorithm, 0, kmip14.CryptographicAlgorithmAES),
+					NewAttributeFromTag(kmip14.TagCryptographicLength, 0, 128),
+				},
+			},
+		},
+	}
+
+	resp = roundTrip(t, conn, newTestRequestMessage(createReq))
+	require.Len(t, resp.BatchItem, 1)
+	assert.Equal(t, kmip14.ResultReasonOperationNotSupported, resp.BatchItem[0].ResultReason)
+
+	h.GenerateSymmetricKey = func(_ context.Context, payload *CreateRequestPayload) (*SymmetricKey, error) {
+		return &SymmetricKey{KeyBlock: KeyBlock{
+			KeyFormatType:          kmip14.KeyFormatTypeRaw,
+			KeyValue:               &KeyValue{KeyMaterial: make([]byte, 16)},
+			CryptographicAlgorithm: kmip14.CryptographicAlgorithmAES,
+			CryptographicLength:    128,
+		}}, nil
+	}
+
+	resp = roundTrip(t, conn, newTestRequestMessage(createReq, RequestBatchItem{
+		Operation:      kmip14.OperationGet,
+		RequestPayload: GetRequestPayload{},
+	}))
+	require.Len(t, resp.BatchItem, 2)
+	require.Equal(t, kmip14.ResultStatusSuccess, resp.BatchItem[0].ResultStatus, resp.BatchItem[0].ResultMessage)
+	require.Equal(t, kmip14.ResultStatusSuccess, resp.BatchItem[1].ResultStatus, resp.BatchItem[1].ResultMessage)
+
+	getResp = GetResponsePayload{}
+	require.NoError(t, ttlv.Unmarshal(resp.BatchItem[1].ResponsePayload.(ttlv.TTLV), &getResp))
+	require.NotNil(t, getResp.SymmetricKey)
+	assert.Len(t, getResp.SymmetricKey.KeyBlock.KeyValue.KeyMaterial, 16)
+}
+
+func TestStoreHandlers_Locate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := &StoreHandlers{
+		Store:     &MemoryObjectStore{},
+		Lifecycle: Lifecycle{Now: func() time.Time { return now }},
+	}
+
+	var ids []string
+
+	for i, group := range []string{"group1", "group1", "group2", "group1"} {
+		now = now.Add(time.Hour)
+
+		resp, err := h.Register(ctx, &RegisterRequestPayload{
+			ObjectType: kmip14.ObjectTypeSecretData,
+			SecretData: newTestSecretData("secret").SecretData,
+			TemplateAttribute: TemplateAttribute{
+				Attribute: []Attribute{
+					NewAttributeFromTag(kmip14.TagObjectGroup, 0, group),
+					NewAttributeFromTag(kmip14.TagName, 0, Name{NameValue: fmt.Sprintf("key%d", i), NameType: kmip14.NameTypeUninterpretedTextString}),
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		ids = append(ids, resp.UniqueIdentifier)
+	}
+
+	locate := func(payload LocateRequestPayload) *LocateResponsePayload {
+		t.Helper()
+
+		resp, err := h.Locate(ctx, &payload)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	group1 := NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group1")
+
+	// most recent first
+	resp := locate(LocateRequestPayload{Attribute: []Attribute{group1}})
+	assert.Equal(t, []string{ids[3], ids[1], ids[0]}, resp.UniqueIdentifier)
+	assert.Equal(t, 3, resp.LocatedItems)
+
+	resp = locate(LocateRequestPayload{OffsetItems: 1, MaximumItems: 1, Attribute: []Attribute{group1}})
+	assert.Equal(t, []string{ids[1]}, resp.UniqueIdentifier)
+	assert.Equal(t, 3, resp.LocatedItems)
+
+	// Name matching ignores the Name Type if it isn't given
+	resp = locate(LocateRequestPayload{Attribute: []Attribute{NewAttributeFromTag(kmip14.TagName, 0, Name{NameValue: "key2"})}})
+	assert.Equal(t, []string{ids[2]}, resp.UniqueIdentifier)
+
+	// two instances of a date attribute are a range
+	start := time.Date(2020, 1, 1, 2, 0, 0, 0, time.UTC)
+	resp = locate(LocateRequestPayload{Attribute: []Attribute{
+		NewAttributeFromTag(kmip14.TagInitialDate, 0, start),
+		NewAttributeFromTag(kmip14.TagInitialDate, 1, start.Add(time.Hour)),
+	}})
+	assert.Equal(t, []string{ids[2], ids[1]}, resp.UniqueIdentifier)
+
+	resp = locate(LocateRequestPayload{Attribute: []Attribute{NewAttributeFromTag(kmip14.TagInitialDate, 0, start)}})
+	assert.Equal(t, []string{ids[1]}, resp.UniqueIdentifier)
+
+	_, err := h.Locate(ctx, &LocateRequestPayload{Attribute: []Attribute{
+		NewAttributeFromTag(kmip14.TagInitialDate, 0, start),
+		NewAttributeFromTag(kmip14.TagInitialDate, 1, start),
+		NewAttributeFromTag(kmip14.TagInitialDate, 2, start),
+	}})
+	require.Error(t, err)
+	assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+
+	// destroyed objects are only located when the mask asks for them
+	_, err = h.Destroy(ctx, &DestroyRequestPayload{UniqueIdentifier: ids[3]})
+	require.NoError(t, err)
+
+	resp = locate(LocateRequestPayload{Attribute: []Attribute{group1}})
+	assert.Equal(t, []string{ids[1], ids[0]}, resp.UniqueIdentifier)
+
+	resp = locate(LocateRequestPayload{StorageStatusMask: storageStatusMaskDestroyed, Attribute: []Attribute{group1}})
+	assert.Equal(t, []string{ids[3]}, resp.UniqueIdentifier)
+
+	// fresh members are only returned once, and then become the default member
+	resp = locate(LocateRequestPayload{ObjectGroupMember: kmip14.ObjectGroupMemberGroupMemberDefault, Attribute: []Attribute{group1}})
+	assert.Equal(t, []string{ids[1]}, resp.UniqueIdentifier)
+
+	resp = locate(LocateRequestPayload{ObjectGroupMember: kmip14.ObjectGroupMemberGroupMemberFresh, MaximumItems: 1, Attribute: []Attribute{group1}})
+	assert.Equal(t, []string{ids[1]}, resp.UniqueIdentifier)
+
+	resp = locate(LocateRequestPayload{ObjectGroupMember: kmip14.ObjectGroupMemberGroupMemberFresh, Attribute: []Attribute{group1}})
+	assert.Equal(t, []string{ids[0]}, resp.UniqueIdentifier)
+
+	resp = locate(LocateRequestPayload{ObjectGroupMember: kmip14.ObjectGroupMemberGroupMemberFresh, Attribute: []Attribute{group1}})
+	assert.Empty(t, resp.UniqueIdentifier)
+
+	resp = locate(LocateRequestPayload{ObjectGroupMember: kmip14.ObjectGroupMemberGroupMemberDefault, Attribute: []Attribute{group1}})
+	assert.Equal(t, []string{ids[1]}, resp.UniqueIdentifier)
+
+	_, err = h.Locate(ctx, &LocateRequestPayload{ObjectGroupMember: kmip14.ObjectGroupMemberGroupMemberFresh})
+	require.Error(t, err)
+}
+
+func TestStoreHandlers_attributes(t *testing.T) {
+	ctx := context.Background()
+	h := &StoreHandlers{Store: &MemoryObjectStore{}}
+
+	reg, err := h.Register(ctx, &RegisterRequestPayload{
+		ObjectType: kmip14.ObjectTypeSecretData,
+		SecretData: newTestSecretData("secret").SecretData,
+		TemplateAttribute: TemplateAttribute{
+			Attribute: []Attribute{NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group0")},
+		},
+	})
+	require.NoError(t, err)
+
+	id := reg.UniqueIdentifier
+
+	added, err := h.AddAttribute(ctx, &AddAttributeRequestPayload{
+		UniqueIdentifier: id,
+		Attribute:        NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group1"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added.Attribute.AttributeIndex)
+
+	modified, err := h.ModifyAttribute(ctx, &ModifyAttributeRequestPayload{
+		UniqueIdentifier: id,
+		Attribute:        NewAttributeFromTag(kmip14.TagObjectGroup, 1, "group2"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "group2", modified.Attribute.AttributeValue)
+
+	deleted, err := h.DeleteAttribute(ctx, &DeleteAttributeRequestPayload{
+		UniqueIdentifier: id,
+		AttributeName:    kmip14.TagObjectGroup.CanonicalName(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "group0", deleted.Attribute.AttributeValue)
+
+	list, err := h.GetAttributeList(ctx, &GetAttributeListRequestPayload{UniqueIdentifier: id})
+	require.NoError(t, err)
+	assert.Contains(t, list.AttributeName, kmip14.TagObjectGroup.CanonicalName())
+	assert.Contains(t, list.AttributeName, kmip14.TagState.CanonicalName())
+	assert.Contains(t, list.AttributeName, kmip14.TagLastChangeDate.CanonicalName())
+
+	attrs, err := h.GetAttributes(ctx, &GetAttributesRequestPayload{
+		UniqueIdentifier: id,
+		AttributeName:    []string{kmip14.TagObjectGroup.CanonicalName()},
+	})
+	require.NoError(t, err)
+	require.Len(t, attrs.Attribute, 1)
+	assert.Equal(t, NewAttributeFromTag(kmip14.TagObjectGroup, 1, "group2"), attrs.Attribute[0])
+
+	_, err = h.ModifyAttribute(ctx, &ModifyAttributeRequestPayload{
+		UniqueIdentifier: id,
+		Attribute:        NewAttributeFromTag(kmip14.TagDescription, 0, "description"),
+	})
+	require.ErrorIs(t, err, ErrAttributeNotFound)
+
+	_, err = h.ModifyAttribute(ctx, &ModifyAttributeRequestPayload{
+		UniqueIdentifier: id,
+		Attribute:        NewAttributeFromTag(kmip14.TagState, 0, kmip14.StateActive),
+	})
+	require.ErrorIs(t, err, ErrAttributeReadOnly)
+
+	_, err = h.AddAttribute(ctx, &AddAttributeRequestPayload{
+		UniqueIdentifier: id,
+		Attribute:        NewAttributeFromTag(kmip14.TagState, 0, kmip14.StateActive),
+	})
+	require.ErrorIs(t, err, ErrAttributeReadOnly)
+
+	// setting an Activation Date in the past activates the object
+	_, err = h.AddAttribute(ctx, &AddAttributeRequestPayload{
+		UniqueIdentifier: id,
+		Attribute:        NewAttributeFromTag(kmip14.TagActivationDate, 0, time.Now().Add(-time.Minute)),
+	})
+	require.NoError(t, err)
+
+	attrs, err = h.GetAttributes(ctx, &GetAttributesRequestPayload{
+		UniqueIdentifier: id,
+		AttributeName:    []string{kmip14.TagState.CanonicalName()},
+	})
+	require.NoError(t, err)
+	require.Len(t, attrs.Attribute, 1)
+
+	var state kmip14.State
+	require.NoError(t, DecodeAttributeValue(attrs.Attribute[0].AttributeValue, &state))
+	assert.Equal(t, kmip14.StateActive, state)
+}
+
+func TestStoreHandlers_getKeyFormat(t *testing.T) {
+	ctx := context.Background()
+	h := &StoreHandlers{Store: &MemoryObjectStore{}}
+
+	material := RandomBytes(16)
+
+	reg, err := h.Register(ctx, &RegisterRequestPayload{
+		ObjectType: kmip14.ObjectTypeSymmetricKey,
+		SymmetricKey: &SymmetricKey{KeyBlock: KeyBlock{
+			KeyFormatType:          kmip14.KeyFormatTypeRaw,
+			KeyValue:               &KeyValue{KeyMaterial: material},
+			CryptographicAlgorithm: kmip14.CryptographicAlgorithmAES,
+			CryptographicLength:    128,
+		}},
+	})
+	require.NoError(t, err)
+
+	resp, err := h.Get(ctx, &GetRequestPayload{
+		UniqueIdentifier: reg.UniqueIdentifier,
+		KeyFormatType:    kmip14.KeyFormatTypeTransparentSymmetricKey,
+	})
+	require.NoError(t, err)
+
+	kb := resp.SymmetricKey.KeyBlock
+	assert.Equal(t, kmip14.KeyFormatTypeTransparentSymmetricKey, kb.KeyFormatType)
+	assert.Equal(t, &TransparentSymmetricKey{Key: material}, kb.KeyValue.KeyMaterial)
+
+	_, err = h.Get(ctx, &GetRequestPayload{
+		UniqueIdentifier: reg.UniqueIdentifier,
+		KeyFormatType:    kmip14.KeyFormatTypePKCS_1,
+	})
+	assert.Equal(t, kmip14.ResultReasonKeyFormatTypeNotSupported, GetResultReason(err))
+
+	_, err = h.Get(ctx, &GetRequestPayload{
+		UniqueIdentifier: reg.UniqueIdentifier,
+		KeyFormatType:    kmip14.KeyFormatTypeTransparentSymmetricKey,
+		KeyWrapType:      kmip14.KeyWrapTypeAsRegistered,
+	})
+	assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+
+	// without WrapKey, wrapped keys can't be requested
+	_, err = h.Get(ctx, &GetRequestPayload{
+		UniqueIdentifier: reg.UniqueIdentifier,
+		KeyWrappingSpecification: &KeyWrappingSpecification{
+			WrappingMethod:           kmip14.WrappingMethodEncrypt,
+			EncryptionKeyInformation: &EncryptionKeyInformation{UniqueIdentifier: reg.UniqueIdentifier},
+		},
+	})
+	assert.Equal(t, kmip14.ResultReasonFeatureNotSupported, GetResultReason(err))
+
+	// objects without a Key Block can't be converted
+	opaque, err := h.Register(ctx, &RegisterRequestPayload{
+		ObjectType: kmip14.ObjectTypeOpaqueObject,
+		OpaqueObject: &OpaqueObject{
+			OpaqueDataType:  kmip14.OpaqueDataType(0x80000001),
+			OpaqueDataValue: []byte("opaque"),
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = h.Get(ctx, &GetRequestPayload{UniqueIdentifier: opaque.UniqueIdentifier, KeyFormatType: kmip14.KeyFormatTypeRaw})
+	assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+}
+
+func TestStoreHandlers_ReKey(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := &StoreHandlers{
+		Store:     &MemoryObjectStore{},
+		Lifecycle: Lifecycle{Now: func() time.Time { return now }},
+		GenerateSymmetricKey: func(_ context.Context, _ *CreateRequestPayload) (*SymmetricKey, error) {
+			return &SymmetricKey{KeyBlock: KeyBlock{
+				KeyFormatType:          kmip14.KeyFormatTypeRaw,
+				KeyValue:               &KeyValue{KeyMaterial: RandomBytes(16)},
+				CryptographicAlgorithm: kmip14.CryptographicAlgorithmAES,
+				CryptographicLength:    128,
+			}}, nil
+		},
+	}
+
+	get := func(id string) *ManagedObject {
+		t.Helper()
+
+		var obj *ManagedObject
+
+		require.NoError(t, h.Store.View(ctx, func(tx ObjectTx) error {
+			var err error
+			obj, err = tx.Get(id)
+
+			return err
+		}))
+
+		return obj
+	}
+
+	name := Name{NameValue: "key", NameType: kmip14.NameTypeUninterpretedTextString}
+	activation := now.Add(time.Hour)
+	deactivation := now.Add(48 * time.Hour)
+
+	created, err := h.Create(ctx, &CreateRequestPayload{
+		ObjectType: kmip14.ObjectTypeSymmetricKey,
+		TemplateAttribute: TemplateAttribute{Attribute: []Attribute{
+			NewAttributeFromTag(kmip14.TagName, 0, name),
+			NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group1"),
+			NewAttributeFromTag(kmip14.TagActivationDate, 0, activation),
+			NewAttributeFromTag(kmip14.TagDeactivationDate, 0, deactivation),
+		}},
+	})
+	require.NoError(t, err)
+
+	now = now.Add(24 * time.Hour)
+	offset := 2 * time.Hour
+
+	rekeyed, err := h.ReKey(ctx, &ReKeyRequestPayload{
+		UniqueIdentifier: created.UniqueIdentifier,
+		Offset:           &offset,
+		TemplateAttribute: &TemplateAttribute{Attribute: []Attribute{
+			NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group2"),
+		}},
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, created.UniqueIdentifier, rekeyed.UniqueIdentifier)
+
+	existing := get(created.UniqueIdentifier)
+	replacement := get(rekeyed.UniqueIdentifier)
+
+	// the Name moves to the replacement, and the request's attributes take precedence over the copied ones
+	assert.Nil(t, existing.GetAttributeTag(kmip14.TagName))
+	var replacementName Name
+	require.NoError(t, DecodeAttributeValue(replacement.GetAttributeTag(kmip14.TagName).AttributeValue, &replacementName))
+	assert.Equal(t, name, replacementName)
+	assert.Equal(t, "group2", replacement.GetAttributeTag(kmip14.TagObjectGroup).AttributeValue)
+
+	assert.Equal(t, rekeyed.UniqueIdentifier, existing.LinkedObjectIdentifier(kmip14.LinkTypeReplacementObjectLink))
+	assert.Equal(t, created.UniqueIdentifier, replacement.LinkedObjectIdentifier(kmip14.LinkTypeReplacedObjectLink))
+	assert.Equal(t, now, objectDate(existing, kmip14.TagLastChangeDate))
+
+	// the Activation Date is the Initial Date plus the Offset, and the other dates are shifted with it
+	newActivation := now.Add(offset)
+	assert.Equal(t, now, objectDate(replacement, kmip14.TagInitialDate))
+	assert.Equal(t, newActivation, objectDate(replacement, kmip14.TagActivationDate))
+	assert.Equal(t, deactivation.Add(newActivation.Sub(activation)), objectDate(replacement, kmip14.TagDeactivationDate))
+	assert.Equal(t, kmip14.StatePreActive, ObjectState(replacement))
+
+	// without an Offset, the dates are copied, and the ID Placeholder identifies the key to replace
+	mux := &OperationMux{}
+	h.Handle(mux)
+
+	addr := startTestServer(t, &Server{}, mux)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	resp := roundTrip(t, conn, newTestRequestMessage(
+		RequestBatchItem{
+			Operation:      kmip14.OperationLocate,
+			RequestPayload: LocateRequestPayload{Attribute: []Attribute{NewAttributeFromTag(kmip14.TagName, 0, name)}},
+		},
+		RequestBatchItem{
+			Operation:      kmip14.OperationReKey,
+			RequestPayload: ReKeyRequestPayload{},
+		},
+	))
+	require.Len(t, resp.BatchItem, 2)
+	require.Equal(t, kmip14.ResultStatusSuccess, resp.BatchItem[1].ResultStatus, resp.BatchItem[1].ResultMessage)
+
+	var rekeyResp ReKeyResponsePayload
+	require.NoError(t, ttlv.Unmarshal(resp.BatchItem[1].ResponsePayload.(ttlv.TTLV), &rekeyResp))
+
+	second := get(rekeyResp.UniqueIdentifier)
+	assert.Equal(t, rekeyed.UniqueIdentifier, second.LinkedObjectIdentifier(kmip14.LinkTypeReplacedObjectLink))
+	assert.Equal(t, newActivation, objectDate(second, kmip14.TagActivationDate))
+	assert.Equal(t, objectDate(replacement, kmip14.TagDeactivationDate), objectDate(second, kmip14.TagDeactivationDate))
+
+	// a key can only be replaced once
+	_, err = h.ReKey(ctx, &ReKeyRequestPayload{UniqueIdentifier: created.UniqueIdentifier})
+	assert.Equal(t, kmip14.ResultReasonPermissionDenied, GetResultReason(err))
+	assert.Contains(t, merry.UserMessage(err), "already been replaced")
+
+	// destroyed keys can't be re-keyed
+	_, err = h.Destroy(ctx, &DestroyRequestPayload{UniqueIdentifier: rekeyResp.UniqueIdentifier})
+	require.NoError(t, err)
+
+	_, err = h.ReKey(ctx, &ReKeyRequestPayload{UniqueIdentifier: rekeyResp.UniqueIdentifier})
+	require.ErrorIs(t, err, ErrWrongKeyLifecycleState)
+	assert.Equal(t, kmip14.ResultReasonPermissionDenied, GetResultReason(err))
+
+	// only symmetric keys can be re-keyed
+	secret, err := h.Register(ctx, &RegisterRequestPayload{
+		ObjectType: kmip14.ObjectTypeSecretData,
+		SecretData: newTestSecretData("secret").SecretData,
+	})
+	require.NoError(t, err)
+
+	_, err = h.ReKey(ctx, &ReKeyRequestPayload{UniqueIdentifier: secret.UniqueIdentifier})
+	assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+}
+
+func TestStoreHandlers_ReKeyKeyPair(t *testing.T) {
+	ctx := context.Background()
+	h := &StoreHandlers{
+		Store: &MemoryObjectStore{},
+		GenerateKeyPair: func(_ context.Context, _ *CreateKeyPairRequestPayload) (*PrivateKey, *PublicKey, error) {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			privBlock, err := NewPrivateKeyBlock(key, 0)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			pubBlock, err := NewPublicKeyBlock(key.Public(), 0)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return &PrivateKey{KeyBlock: *privBlock}, &PublicKey{KeyBlock: *pubBlock}, nil
+		},
+	}
+
+	get := func(id string) *ManagedObject {
+		t.Helper()
+
+		var obj *ManagedObject
+
+		require.NoError(t, h.Store.View(ctx, func(tx ObjectTx) error {
+			var err error
+			obj, err = tx.Get(id)
+
+			return err
+		}))
+
+		return obj
+	}
+
+	created, err := h.CreateKeyPair(ctx, &CreateKeyPairRequestPayload{
+		CommonTemplateAttribute: &TemplateAttribute{Attribute: []Attribute{
+			NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group1"),
+		}},
+		PrivateKeyTemplateAttribute: &TemplateAttribute{Attribute: []Attribute{
+			NewAttributeFromTag(kmip14.TagName, 0, Name{NameValue: "priv", NameType: kmip14.NameTypeUninterpretedTextString}),
+		}},
+	})
+	require.NoError(t, err)
+
+	rekeyed, err := h.ReKeyKeyPair(ctx, &ReKeyKeyPairRequestPayload{
+		PrivateKeyUniqueIdentifier: created.PrivateKeyUniqueIdentifier,
+		PublicKeyTemplateAttribute: &TemplateAttribute{Attribute: []Attribute{
+			NewAttributeFromTag(kmip14.TagObjectGroup, 0, "group2"),
+		}},
+	})
+	require.NoError(t, err)
+
+	priv := get(rekeyed.PrivateKeyUniqueIdentifier)
+	pub := get(rekeyed.PublicKeyUniqueIdentifier)
+
+	// the replacement keys are linked to each other, and to the keys they replace
+	assert.Equal(t, rekeyed.PublicKeyUniqueIdentifier, priv.LinkedObjectIdentifier(kmip14.LinkTypePublicKeyLink))
+	assert.Equal(t, rekeyed.PrivateKeyUniqueIdentifier, pub.LinkedObjectIdentifier(kmip14.LinkTypePrivateKeyLink))
+	assert.Equal(t, created.PrivateKeyUniqueIdentifier, priv.LinkedObjectIdentifier(kmip14.LinkTypeReplacedObjectLink))
+	assert.Equal(t, created.PublicKeyUniqueIdentifier, pub.LinkedObjectIdentifier(kmip14.LinkTypeReplacedObjectLink))
+	assert.Equal(t, rekeyed.PrivateKeyUniqueIdentifier, get(created.PrivateKeyUniqueIdentifier).LinkedObjectIdentifier(kmip14.LinkTypeReplacementObjectLink))
+	assert.Equal(t, rekeyed.PublicKeyUniqueIdentifier, get(created.PublicKeyUniqueIdentifier).LinkedObjectIdentifier(kmip14.LinkTypeReplacementObjectLink))
+
+	assert.Equal(t, "group1", priv.GetAttributeTag(kmip14.TagObjectGroup).AttributeValue)
+	assert.Equal(t, "group2", pub.GetAttributeTag(kmip14.TagObjectGroup).AttributeValue)
+	assert.NotNil(t, priv.GetAttributeTag(kmip14.TagName))
+	assert.Nil(t, get(created.PrivateKeyUniqueIdentifier).GetAttributeTag(kmip14.TagName))
+
+	// a public key can't be re-keyed as a pair
+	_, err = h.ReKeyKeyPair(ctx, &ReKeyKeyPairRequestPayload{PrivateKeyUniqueIdentifier: created.PublicKeyUniqueIdentifier})
+	assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+
+	// a key pair can only be replaced once
+	_, err = h.ReKeyKeyPair(ctx, &ReKeyKeyPairRequestPayload{PrivateKeyUniqueIdentifier: created.PrivateKeyUniqueIdentifier})
+	assert.Equal(t, kmip14.ResultReasonPermissionDenied, GetResultReason(err))
+
+	// destroyed keys can't be re-keyed
+	_, err = h.Destroy(ctx, &DestroyRequestPayload{UniqueIdentifier: rekeyed.PublicKeyUniqueIdentifier})
+	require.NoError(t, err)
+
+	_, err = h.ReKeyKeyPair(ctx, &ReKeyKeyPairRequestPayload{PrivateKeyUniqueIdentifier: rekeyed.PrivateKeyUniqueIdentifier})
+	require.ErrorIs(t, err, ErrWrongKeyLifecycleState)
+}
+
+func TestStoreHandlers_SplitKey(t *testing.T) {
+	ctx := context.Background()
+	h := &StoreHandlers{
+		Store: &MemoryObjectStore{},
+		GenerateSymmetricKey: func(_ context.Context, _ *CreateRequestPayload) (*SymmetricKey, error) {
+			return &SymmetricKey{KeyBlock: KeyBlock{
+				KeyFormatType:          kmip14.KeyFormatTypeRaw,
+				KeyValue:               &KeyValue{KeyMaterial: RandomBytes(16)},
+				CryptographicAlgorithm: kmip14.CryptographicAlgorithmAES,
+				CryptographicLength:    128,
+			}}, nil
+		},
+	}
+
+	get := func(id string) *ManagedObject {
+		t.Helper()
+
+		var obj *ManagedObject
+
+		require.NoError(t, h.Store.View(ctx, func(tx ObjectTx) error {
+			var err error
+			obj, err = tx.Get(id)
+
+			return err
+		}))
+
+		return obj
+	}
+
+	// split an existing Secret Data
+	secret, err := h.Register(ctx, &RegisterRequestPayload{
+		ObjectType: kmip14.ObjectTypeSecretData,
+		SecretData: newTestSecretData("split me").SecretData,
+	})
+	require.NoError(t, err)
+
+	split, err := h.CreateSplitKey(ctx, &CreateSplitKeyRequestPayload{
+		ObjectType:        kmip14.ObjectTypeSecretData,
+		UniqueIdentifier:  secret.UniqueIdentifier,
+		SplitKeyParts:     3,
+		SplitKeyThreshold: 2,
+		SplitKeyMethod:    kmip14.SplitKeyMethodPolynomialSharingGF2_16,
+		TemplateAttribute: TemplateAttribute{Attribute: []Attribute{
+			NewAttributeFromTag(kmip14.TagObjectGroup, 0, "parts"),
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, split.UniqueIdentifier, 3)
+
+	for i, id := range split.UniqueIdentifier {
+		part := get(id)
+		require.NotNil(t, part.SplitKey)
+		assert.Equal(t, i+1, part.SplitKey.KeyPartIdentifier)
+		assert.Equal(t, "parts", part.GetAttributeTag(kmip14.TagObjectGroup).AttributeValue)
+	}
+
+	joined, err := h.JoinSplitKey(ctx, &JoinSplitKeyRequestPayload{
+		ObjectType:       kmip14.ObjectTypeSecretData,
+		UniqueIdentifier: []string{split.UniqueIdentifier[2], split.UniqueIdentifier[0]},
+		SecretDataType:   kmip14.SecretDataTypePassword,
+	})
+	require.NoError(t, err)
+
+	joinedObj := get(joined.UniqueIdentifier)
+	require.NotNil(t, joinedObj.SecretData)
+	assert.Equal(t, kmip14.SecretDataTypePassword, joinedObj.SecretData.SecretDataType)
+	assert.Equal(t, []byte("split me"), joinedObj.SecretData.KeyBlock.KeyValue.KeyMaterial)
+
+	// split a generated key, which isn't stored itself
+	split, err = h.CreateSplitKey(ctx, &CreateSplitKeyRequestPayload{
+		ObjectType:        kmip14.ObjectTypeSymmetricKey,
+		SplitKeyParts:     2,
+		SplitKeyThreshold: 2,
+		SplitKeyMethod:    kmip14.SplitKeyMethodXOR,
+	})
+	require.NoError(t, err)
+	require.Len(t, split.UniqueIdentifier, 2)
+
+	joined, err = h.JoinSplitKey(ctx, &JoinSplitKeyRequestPayload{
+		ObjectType:       kmip14.ObjectTypeSymmetricKey,
+		UniqueIdentifier: split.UniqueIdentifier,
+	})
+	require.NoError(t, err)
+
+	joinedObj = get(joined.UniqueIdentifier)
+	require.NotNil(t, joinedObj.SymmetricKey)
+	assert.Equal(t, kmip14.CryptographicAlgorithmAES, joinedObj.SymmetricKey.KeyBlock.CryptographicAlgorithm)
+	assert.Equal(t, 128, joinedObj.SymmetricKey.KeyBlock.CryptographicLength)
+	assert.Len(t, joinedObj.SymmetricKey.KeyBlock.KeyValue.KeyMaterial, 16)
+
+	// the XOR method needs all the parts
+	_, err = h.JoinSplitKey(ctx, &JoinSplitKeyRequestPayload{
+		ObjectType:       kmip14.ObjectTypeSymmetricKey,
+		UniqueIdentifier: split.UniqueIdentifier[:1],
+	})
+	assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+
+	// only Split Keys can be joined
+	_, err = h.JoinSplitKey(ctx, &JoinSplitKeyRequestPayload{
+		ObjectType:       kmip14.ObjectTypeSecretData,
+		UniqueIdentifier: []string{secret.UniqueIdentifier, split.UniqueIdentifier[0]},
+	})
+	assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+
+	// the Object Type must match the key to split
+	_, err = h.CreateSplitKey(ctx, &CreateSplitKeyRequestPayload{
+		ObjectType:        kmip14.ObjectTypeSymmetricKey,
+		UniqueIdentifier:  secret.UniqueIdentifier,
+		SplitKeyParts:     2,
+		SplitKeyThreshold: 2,
+		SplitKeyMethod:    kmip14.SplitKeyMethodXOR,
+	})
+	assert.Equal(t, kmip14.ResultReasonInvalidField, GetResultReason(err))
+}
@@ -330,6 +330,22 @@ func TestMarshal_tagPrecedence(t *testing.T) {
 	}, v)
 }
 
+type LateRegisteredStruct struct {
+	Comment string
+}
+
+func TestMarshal_lateRegisteredTag(t *testing.T) {
+	// tags registered after a type was first encoded, e.g. vendor tags registered in a later init, are used
+	_, err := Marshal(LateRegisteredStruct{Comment: "hi"})
+	require.Error(t, err)
+
+	DefaultRegistry.RegisterTag(Tag(0x54fff0), "LateRegisteredStruct")
+
+	b, err := Marshal(LateRegisteredStruct{Comment: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, Tag(0x54fff0), TTLV(b).Tag())
+}
+
 func TestEncoder_Encode(t *testing.T) {
 	_, err := Marshal(MarshalerStruct{})
 	require.NoError(t, err)